@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// convert runs fn, turning a panic into an error. A Go panic unwinding into
+// the C caller would abort the whole host process, so every exported
+// function converts through it.
+func convert(fn func() (string, error)) (out string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = "", fmt.Errorf("%w: recovered from panic: %v", rehydrate.ErrInvalidInput, r)
+		}
+	}()
+	return fn()
+}
+
+func main() {}
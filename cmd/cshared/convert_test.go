@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestConvert(t *testing.T) {
+	out, err := convert(func() (string, error) { return rehydrate.Rehydrate(`[{"a":1},"x"]`) })
+	if err != nil || out != "{\n  \"a\": \"x\"\n}" {
+		t.Errorf("got %q, %v", out, err)
+	}
+	if _, err := convert(func() (string, error) { return rehydrate.Rehydrate(`[[0]]`) }); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("invalid payload: got %v", err)
+	}
+
+	out, err = convert(func() (string, error) {
+		var m map[string]int
+		m["boom"]++
+		return "unreachable", nil
+	})
+	if out != "" || !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("panic: got %q, %v", out, err)
+	}
+}
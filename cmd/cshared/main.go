@@ -0,0 +1,67 @@
+// Command cshared builds the rehydrate parser as a C shared library, so it can
+// be called through FFI from languages such as Python and Ruby:
+//
+//	go build -buildmode=c-shared -o librehydrate.so ./cmd/cshared
+//
+// Every returned string is allocated with malloc and must be released with
+// RehydrateFree. On failure the functions return NULL and, when err is not
+// NULL, store an error message in *err, which must be released the same way.
+// Panics are reported as failures rather than aborting the host process.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// RehydrateCString converts a devalue payload to indented JSON, applying the
+// default Nuxt revivers.
+//
+//export RehydrateCString
+func RehydrateCString(input *C.char, err **C.char) *C.char {
+	payload := C.GoString(input)
+	out, e := convert(func() (string, error) {
+		return rehydrate.Rehydrate(payload)
+	})
+	return result(out, e, err)
+}
+
+// ParseToJSON converts a devalue payload to JSON, indenting each level with
+// indent, or compactly when indent is NULL or empty.
+//
+//export ParseToJSON
+func ParseToJSON(input *C.char, indent *C.char, err **C.char) *C.char {
+	var ind string
+	if indent != nil {
+		ind = C.GoString(indent)
+	}
+	payload := C.GoString(input)
+	out, e := convert(func() (string, error) {
+		return rehydrate.RehydrateWith(payload, nil, rehydrate.WithIndent("", ind))
+	})
+	return result(out, e, err)
+}
+
+// RehydrateFree releases a string returned by this library.
+//
+//export RehydrateFree
+func RehydrateFree(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// result converts out to a C string, or stores e in *err and returns NULL.
+func result(out string, e error, err **C.char) *C.char {
+	if e != nil {
+		if err != nil {
+			*err = C.CString(e.Error())
+		}
+		return nil
+	}
+	return C.CString(out)
+}
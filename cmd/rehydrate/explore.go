@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+const exploreUsage = `usage: rehydrate explore [-plain] [-locale tag] file
+
+Opens an interactive view for navigating the hydrated payload. On a
+terminal, it shows the payload as a tree whose objects, arrays, sets and
+maps expand and collapse with the arrow keys; press ? for the list of keys.
+Otherwise, or with -plain, it reads commands from a line-based prompt,
+which works in scripts and on terminals without cursor control. Type
+"help" at the prompt for the list of commands.
+`
+
+const exploreHelp = `commands:
+  ls               list the children of the current value
+  cd <name>        enter a child; ".." goes up, "/" to the root
+  pwd              print the path of the current value
+  show [depth]     print the current value, expanding depth levels (default 2)
+  raw              print the value-table entry behind the current value
+  find <text>      search strings below the current value
+  copy [name]      copy the path of the current value or a child to the
+                   clipboard, through the terminal
+  help             show this help
+  quit             leave the explorer
+`
+
+func (c *command) explore(args []string) error {
+	// explore is interactive, so it does not take the shared output flags.
+	fs := flag.NewFlagSet("explore", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), exploreUsage)
+		fs.PrintDefaults()
+	}
+	localeTag := fs.String("locale", "", "format numbers, sizes and dates for a locale such as de or en-GB")
+	plain := fs.Bool("plain", false, "use the line-based prompt even on a terminal")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	locale, err := parseLocale(*localeTag)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("explore: expected exactly one file")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	root, err := rehydrate.ParseWithOptions(string(data), rehydrate.WithRevivers(rehydrate.DefaultNuxtRevivers()))
+	if err != nil {
+		return err
+	}
+	var table []json.RawMessage
+	_ = json.Unmarshal(data, &table)
+
+	e := &explorer{
+		payload: string(data),
+		table:   table,
+		out:     c.stdout,
+		locale:  locale,
+		stack:   []exploreNode{{value: root, index: 0}},
+	}
+	in, inFile := c.stdin.(*os.File)
+	out, outFile := c.stdout.(*os.File)
+	if !*plain && inFile && outFile && term.IsTerminal(int(in.Fd())) && term.IsTerminal(int(out.Fd())) {
+		return e.runTree(in, out)
+	}
+	return e.run(c.stdin)
+}
+
+type exploreNode struct {
+	name  string
+	path  string
+	value interface{}
+	// index is the value-table entry the value was hydrated from, or -1.
+	index int
+}
+
+type explorer struct {
+	payload string
+	table   []json.RawMessage
+	out     io.Writer
+	locale  rehydrate.Locale
+	stack   []exploreNode
+}
+
+func (e *explorer) current() exploreNode {
+	return e.stack[len(e.stack)-1]
+}
+
+func (e *explorer) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(e.out, "%s> ", displayPath(e.current().path))
+		if !scanner.Scan() {
+			fmt.Fprintln(e.out)
+			return scanner.Err()
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch cmd {
+		case "":
+		case "ls":
+			for _, child := range e.children(e.current()) {
+				fmt.Fprintf(e.out, "  %-24s %s\n", child.name, summarize(child.value, e.locale))
+			}
+		case "cd":
+			e.cd(arg)
+		case "pwd":
+			fmt.Fprintln(e.out, displayPath(e.current().path))
+		case "show":
+			depth := 2
+			if arg != "" {
+				if n, err := strconv.Atoi(arg); err == nil {
+					depth = n
+				}
+			}
+			_ = rehydrate.Dump(e.current().value, e.out, rehydrate.DumpMaxDepth(depth), rehydrate.DumpLocale(e.locale))
+		case "raw":
+			e.raw()
+		case "find":
+			e.find(arg)
+		case "copy":
+			e.copy(arg)
+		case "help":
+			fmt.Fprint(e.out, exploreHelp)
+		case "quit", "exit":
+			return nil
+		default:
+			fmt.Fprintf(e.out, "unknown command %q, try help\n", cmd)
+		}
+	}
+}
+
+func (e *explorer) cd(arg string) {
+	switch arg {
+	case "", "/":
+		e.stack = e.stack[:1]
+		return
+	case "..":
+		if len(e.stack) > 1 {
+			e.stack = e.stack[:len(e.stack)-1]
+		}
+		return
+	}
+	if child, ok := e.child(arg); ok {
+		e.stack = append(e.stack, child)
+	}
+}
+
+// child returns the child of the current value called name, reporting
+// unknown names.
+func (e *explorer) child(name string) (exploreNode, bool) {
+	for _, child := range e.children(e.current()) {
+		if child.name == name {
+			return child, true
+		}
+	}
+	fmt.Fprintf(e.out, "no child named %q\n", name)
+	return exploreNode{}, false
+}
+
+// copy copies the path of the current value, or of its child name, to the
+// clipboard with an OSC 52 escape sequence, which terminals and multiplexers
+// that allow it, including over SSH, pass to the system clipboard. The path
+// is printed as well for terminals that do not.
+func (e *explorer) copy(name string) {
+	node := e.current()
+	if name != "" {
+		child, ok := e.child(name)
+		if !ok {
+			return
+		}
+		node = child
+	}
+	fmt.Fprintf(e.out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(node.path)))
+	fmt.Fprintf(e.out, "copied %s\n", displayPath(node.path))
+}
+
+func (e *explorer) raw() {
+	index := e.current().index
+	if index < 0 || index >= len(e.table) {
+		fmt.Fprintln(e.out, "no value-table entry for this value")
+		return
+	}
+	fmt.Fprintf(e.out, "#%d %s\n", index, e.table[index])
+}
+
+func (e *explorer) find(query string) {
+	matches, err := rehydrate.Search(e.payload, query,
+		rehydrate.SearchParseOptions(rehydrate.WithRevivers(rehydrate.DefaultNuxtRevivers())))
+	if err != nil {
+		fmt.Fprintln(e.out, err)
+		return
+	}
+	prefix := e.current().path
+	for _, m := range matches {
+		if prefix == "" || m.Path == prefix || strings.HasPrefix(m.Path, prefix+"/") {
+			fmt.Fprintf(e.out, "  %s\t%q\n", m.Path, m.Value)
+		}
+	}
+}
+
+// children lists the children of n, pairing each with the value-table entry
+// it was hydrated from when that can be determined.
+func (e *explorer) children(n exploreNode) []exploreNode {
+	entry := e.entry(n.index)
+	ref := func(v interface{}) int {
+		i, ok := v.(float64)
+		if !ok || i < 0 {
+			return -1
+		}
+		return int(i)
+	}
+
+	var out []exploreNode
+	switch value := n.value.(type) {
+	case map[string]interface{}:
+		rawObj, _ := entry.(map[string]interface{})
+		rawNull, _ := entry.([]interface{})
+		for _, key := range sortedKeys(value) {
+			index := ref(rawObj[key])
+			for i := 1; rawObj == nil && i+1 < len(rawNull); i += 2 {
+				if rawNull[i] == key {
+					index = ref(rawNull[i+1])
+				}
+			}
+			out = append(out, exploreNode{name: key, path: rehydrate.KeyPath(n.path, key), value: value[key], index: index})
+		}
+	case []interface{}:
+		rawArr, _ := entry.([]interface{})
+		for i, item := range value {
+			index := -1
+			if i < len(rawArr) {
+				index = ref(rawArr[i])
+			}
+			out = append(out, exploreNode{name: strconv.Itoa(i), path: rehydrate.IndexPath(n.path, i), value: item, index: index})
+		}
+	case *rehydrate.Set:
+		rawArr, _ := entry.([]interface{})
+		for i, item := range value.Values() {
+			index := -1
+			if i+1 < len(rawArr) {
+				index = ref(rawArr[i+1])
+			}
+			out = append(out, exploreNode{name: strconv.Itoa(i), path: rehydrate.IndexPath(n.path, i), value: item, index: index})
+		}
+	case *rehydrate.OrderedMap:
+		rawArr, _ := entry.([]interface{})
+		for i, me := range value.Entries() {
+			name, ok := rehydrate.MapKeyString(me.Key)
+			if !ok {
+				name = fmt.Sprintf("#%d", i)
+			}
+			index := -1
+			if 2*i+2 < len(rawArr) {
+				index = ref(rawArr[2*i+2])
+			}
+			out = append(out, exploreNode{name: name, path: rehydrate.MapKeyPath(n.path, me.Key), value: me.Value, index: index})
+		}
+	}
+	return out
+}
+
+// entry decodes the value-table entry at index, looking through custom tags
+// such as Nuxt's ["Reactive", i] to the entry they wrap.
+func (e *explorer) entry(index int) interface{} {
+	for depth := 0; depth < len(e.table) && index >= 0 && index < len(e.table); depth++ {
+		var v interface{}
+		if json.Unmarshal(e.table[index], &v) != nil {
+			return nil
+		}
+		arr, ok := v.([]interface{})
+		if !ok || len(arr) != 2 {
+			return v
+		}
+		tag, ok := arr[0].(string)
+		if !ok {
+			return v
+		}
+		if _, builtin := rehydrate.ParseTag(tag); builtin {
+			return v
+		}
+		next, ok := arr[1].(float64)
+		if !ok {
+			return v
+		}
+		index = int(next)
+	}
+	return nil
+}
+
+func summarize(v interface{}, locale rehydrate.Locale) string {
+	switch value := v.(type) {
+	case []interface{}:
+		return fmt.Sprintf("Array(%d)", len(value))
+	case map[string]interface{}:
+		return fmt.Sprintf("Object(%d)", len(value))
+	case *rehydrate.Set:
+		return fmt.Sprintf("Set(%d)", value.Len())
+	case *rehydrate.OrderedMap:
+		return fmt.Sprintf("Map(%d)", value.Len())
+	}
+	var b strings.Builder
+	_ = rehydrate.Dump(v, &b, rehydrate.DumpLocale(locale))
+	s := strings.TrimSpace(b.String())
+	if runes := []rune(s); len(runes) > 60 {
+		s = string(runes[:57]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+const genFixtureUsage = `usage: rehydrate gen-fixture [flags] [input.json]
+
+Converts plain JSON into a devalue payload. The optional hints file maps
+paths, as JSON Pointers (/user/createdAt, /items/0) or dotted (user.createdAt,
+items[0]), to the tag the value should be encoded as:
+
+  Date, Set, Map, null, BigInt, RegExp, Object, ArrayBuffer,
+  Int8Array ... BigUint64Array    built-in tags
+  undefined, hole, NaN, Infinity,
+  -Infinity, -0                   sentinels, ignoring the JSON value
+  ref:<path>                      reference to the value at another path,
+                                  e.g. "ref:" for the root to build a cycle
+  any other name                  custom tag wrapping the value
+`
+
+func (c *command) genFixture(args []string) error {
+	fs := c.flagSet("gen-fixture", genFixtureUsage)
+	typesPath := fs.String("types", "", "JSON file mapping paths to type hints")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	hints := map[string]string{}
+	if *typesPath != "" {
+		data, err := os.ReadFile(*typesPath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &hints); err != nil {
+			return fmt.Errorf("types: %w", err)
+		}
+	}
+
+	data, err := readInput(fs.Args(), c.stdin)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var input interface{}
+	if err := dec.Decode(&input); err != nil {
+		return err
+	}
+
+	payload, err := encodeFixture(input, hints)
+	if err != nil {
+		return err
+	}
+	return c.emitJSON(payload, "")
+}
+
+// pathRef is a placeholder for a reference to the value at another path,
+// resolved once every value has been encoded.
+type pathRef string
+
+type fixtureEncoder struct {
+	hints      map[string]string
+	entries    []interface{}
+	paths      map[string]int
+	primitives map[string]int
+}
+
+func encodeFixture(input interface{}, hints map[string]string) ([]byte, error) {
+	hints, err := pointerHints(hints)
+	if err != nil {
+		return nil, err
+	}
+	e := &fixtureEncoder{
+		hints:      hints,
+		paths:      map[string]int{},
+		primitives: map[string]int{},
+	}
+	root, err := e.encode("", input)
+	if err != nil {
+		return nil, err
+	}
+	if index, ok := root.(int); !ok || index < 0 {
+		// A payload consisting only of a sentinel is the bare number.
+		return json.Marshal(root)
+	}
+	if err := e.resolveRefs(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(e.entries)
+}
+
+func (e *fixtureEncoder) encode(path string, v interface{}) (interface{}, error) {
+	hint := e.hints[path]
+	if target, ok := strings.CutPrefix(hint, "ref:"); ok {
+		return pathRef(target), nil
+	}
+	switch hint {
+	case "undefined":
+		return -1, nil
+	case "hole":
+		return -2, nil
+	case "NaN":
+		return -3, nil
+	case "Infinity":
+		return -4, nil
+	case "-Infinity":
+		return -5, nil
+	case "-0":
+		return -6, nil
+	}
+	return e.encodeAs(path, v, hint)
+}
+
+func (e *fixtureEncoder) encodeAs(path string, v interface{}, hint string) (interface{}, error) {
+	if hint == "" {
+		switch v.(type) {
+		case nil, bool, json.Number, string:
+			key := fmt.Sprintf("%T:%v", v, v)
+			if index, ok := e.primitives[key]; ok {
+				e.paths[path] = index
+				return index, nil
+			}
+			index := e.alloc(path)
+			e.primitives[key] = index
+			e.entries[index] = v
+			return index, nil
+		}
+	}
+
+	index := e.alloc(path)
+	entry, err := e.entry(path, v, hint)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", displayPath(path), err)
+	}
+	e.entries[index] = entry
+	return index, nil
+}
+
+func (e *fixtureEncoder) alloc(path string) int {
+	index := len(e.entries)
+	e.entries = append(e.entries, nil)
+	e.paths[path] = index
+	return index
+}
+
+func (e *fixtureEncoder) entry(path string, v interface{}, hint string) (interface{}, error) {
+	if hint == "" {
+		switch value := v.(type) {
+		case []interface{}:
+			return e.children(path, value, nil)
+		case map[string]interface{}:
+			obj := map[string]interface{}{}
+			for _, key := range sortedKeys(value) {
+				ref, err := e.encode(rehydrate.KeyPath(path, key), value[key])
+				if err != nil {
+					return nil, err
+				}
+				obj[key] = ref
+			}
+			return obj, nil
+		}
+		return nil, fmt.Errorf("cannot encode %T", v)
+	}
+
+	tag, builtin := rehydrate.ParseTag(hint)
+	if !builtin {
+		inner, err := e.encodeAs(path+"#value", v, "")
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{hint, inner}, nil
+	}
+
+	switch tag {
+	case rehydrate.TagDate:
+		switch value := v.(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{tag.String(), formatISO(t)}, nil
+		case json.Number:
+			ms, err := value.Int64()
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{tag.String(), formatISO(time.UnixMilli(ms))}, nil
+		}
+
+	case rehydrate.TagSet:
+		if value, ok := v.([]interface{}); ok {
+			return e.children(path, value, []interface{}{tag.String()})
+		}
+
+	case rehydrate.TagMap:
+		switch value := v.(type) {
+		case map[string]interface{}:
+			entry := []interface{}{tag.String()}
+			for _, key := range sortedKeys(value) {
+				keyRef, err := e.encodeAs(rehydrate.KeyPath(path, key)+"#key", key, "")
+				if err != nil {
+					return nil, err
+				}
+				valRef, err := e.encode(rehydrate.KeyPath(path, key), value[key])
+				if err != nil {
+					return nil, err
+				}
+				entry = append(entry, keyRef, valRef)
+			}
+			return entry, nil
+		case []interface{}:
+			entry := []interface{}{tag.String()}
+			for i, pair := range value {
+				kv, ok := pair.([]interface{})
+				if !ok || len(kv) != 2 {
+					return nil, fmt.Errorf("Map entries must be [key, value] pairs")
+				}
+				pairPath := rehydrate.IndexPath(path, i)
+				keyRef, err := e.encode(rehydrate.IndexPath(pairPath, 0), kv[0])
+				if err != nil {
+					return nil, err
+				}
+				valRef, err := e.encode(rehydrate.IndexPath(pairPath, 1), kv[1])
+				if err != nil {
+					return nil, err
+				}
+				entry = append(entry, keyRef, valRef)
+			}
+			return entry, nil
+		}
+
+	case rehydrate.TagNull:
+		if value, ok := v.(map[string]interface{}); ok {
+			entry := []interface{}{tag.String()}
+			for _, key := range sortedKeys(value) {
+				ref, err := e.encode(rehydrate.KeyPath(path, key), value[key])
+				if err != nil {
+					return nil, err
+				}
+				entry = append(entry, key, ref)
+			}
+			return entry, nil
+		}
+
+	case rehydrate.TagBigInt:
+		switch value := v.(type) {
+		case string:
+			return []interface{}{tag.String(), value}, nil
+		case json.Number:
+			return []interface{}{tag.String(), value.String()}, nil
+		}
+
+	case rehydrate.TagRegExp:
+		if value, ok := v.(string); ok {
+			source, flags := value, ""
+			if strings.HasPrefix(value, "/") {
+				if end := strings.LastIndex(value, "/"); end > 0 {
+					source, flags = value[1:end], value[end+1:]
+				}
+			}
+			return []interface{}{tag.String(), source, flags}, nil
+		}
+
+	case rehydrate.TagObject:
+		return []interface{}{tag.String(), v}, nil
+
+	default:
+		switch value := v.(type) {
+		case string:
+			if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+				return nil, err
+			}
+			return []interface{}{hint, value}, nil
+		case []interface{}:
+			data, err := encodeTypedArray(tag, value)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{hint, base64.StdEncoding.EncodeToString(data)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot encode %T as %s", v, hint)
+}
+
+func (e *fixtureEncoder) children(path string, items []interface{}, entry []interface{}) ([]interface{}, error) {
+	if entry == nil {
+		entry = []interface{}{}
+	}
+	for i, item := range items {
+		ref, err := e.encode(rehydrate.IndexPath(path, i), item)
+		if err != nil {
+			return nil, err
+		}
+		entry = append(entry, ref)
+	}
+	return entry, nil
+}
+
+func (e *fixtureEncoder) resolveRefs() error {
+	resolve := func(v interface{}) (interface{}, error) {
+		target, ok := v.(pathRef)
+		if !ok {
+			return v, nil
+		}
+		index, ok := e.paths[string(target)]
+		if !ok {
+			return nil, fmt.Errorf("ref:%s: no value at that path", target)
+		}
+		return index, nil
+	}
+
+	for _, entry := range e.entries {
+		var err error
+		switch value := entry.(type) {
+		case []interface{}:
+			for i := range value {
+				if value[i], err = resolve(value[i]); err != nil {
+					return err
+				}
+			}
+		case map[string]interface{}:
+			for key := range value {
+				if value[key], err = resolve(value[key]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func encodeTypedArray(tag rehydrate.Tag, items []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, item := range items {
+		n, ok := item.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("%s elements must be numbers", tag)
+		}
+		var err error
+		switch tag {
+		case rehydrate.TagFloat32Array:
+			var f float64
+			if f, err = n.Float64(); err == nil {
+				err = binary.Write(&buf, binary.LittleEndian, math.Float32bits(float32(f)))
+			}
+		case rehydrate.TagFloat64Array:
+			var f float64
+			if f, err = n.Float64(); err == nil {
+				err = binary.Write(&buf, binary.LittleEndian, math.Float64bits(f))
+			}
+		case rehydrate.TagBigUint64Array:
+			var u uint64
+			if u, err = strconv.ParseUint(n.String(), 10, 64); err == nil {
+				err = binary.Write(&buf, binary.LittleEndian, u)
+			}
+		default:
+			var i int64
+			if i, err = n.Int64(); err != nil {
+				break
+			}
+			switch tag {
+			case rehydrate.TagInt8Array, rehydrate.TagUint8Array, rehydrate.TagUint8ClampedArray, rehydrate.TagArrayBuffer:
+				buf.WriteByte(byte(i))
+			case rehydrate.TagInt16Array, rehydrate.TagUint16Array:
+				err = binary.Write(&buf, binary.LittleEndian, uint16(i))
+			case rehydrate.TagInt32Array, rehydrate.TagUint32Array:
+				err = binary.Write(&buf, binary.LittleEndian, uint32(i))
+			case rehydrate.TagBigInt64Array:
+				err = binary.Write(&buf, binary.LittleEndian, i)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s element %s: %w", tag, n, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// formatISO formats t the way JavaScript's Date.prototype.toISOString does.
+func formatISO(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pointerHints converts the paths of hints, and the targets of their ref:
+// hints, to the JSON Pointers the encoder tracks values by. A #key suffix,
+// addressing the key of an object encoded as a Map, is kept as is.
+func pointerHints(hints map[string]string) (map[string]string, error) {
+	pointer := func(path string) (string, error) {
+		path, suffix := path, ""
+		if p, ok := strings.CutSuffix(path, "#key"); ok {
+			path, suffix = p, "#key"
+		}
+		p, err := rehydrate.ToPointer(path)
+		if err != nil {
+			return "", fmt.Errorf("types: %w", err)
+		}
+		return p + suffix, nil
+	}
+	out := make(map[string]string, len(hints))
+	for path, hint := range hints {
+		p, err := pointer(path)
+		if err != nil {
+			return nil, err
+		}
+		if target, ok := strings.CutPrefix(hint, "ref:"); ok {
+			if target, err = pointer(target); err != nil {
+				return nil, err
+			}
+			hint = "ref:" + target
+		}
+		out[p] = hint
+	}
+	return out, nil
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,217 @@
+// Command rehydrate converts devalue payloads, such as Nuxt's _payload.json,
+// to plain JSON and provides tooling around the format.
+//
+// Usage:
+//
+//	rehydrate [-format auto|devalue|json] [-annotated] [-output format] [-quiet] [-config file] [-plugin file.so] [-reviver-exec Tag=cmd] [file]
+//	rehydrate -ndjson [-annotated] [-quiet] [-config file] [-plugin file.so] [-reviver-exec Tag=cmd] [file]
+//	rehydrate search [-regexp] [-i] [-binary] [-config file] [-plugin file.so] [-reviver-exec Tag=cmd] query [file]
+//	rehydrate size [-depth n] [-locale tag] [file]
+//	rehydrate explore [-plain] [-locale tag] file
+//	rehydrate gen-fixture -types hints.json input.json
+//	rehydrate synth [-depth n] [-fanout n] [-mix type=weight,...] [-binary n] [-sharing p] [-seed n] [-count n]
+//
+// Every subcommand except explore accepts -output json|ndjson|yaml|msgpack
+// to select a machine-readable format and -quiet to suppress output
+// entirely. synth ignores -output and always writes payloads, one per line.
+// Failures are reported on stderr, as a JSON object when a machine-readable
+// format was selected, and the exit status identifies the category of the
+// failure:
+//
+//	1  unexpected error
+//	2  invalid command line
+//	3  invalid input
+//	4  unknown type tag
+//	5  bad reference
+//	6  limit exceeded
+//	7  I/O error
+//
+// The input format is detected with rehydrate.RehydrateAny, which also
+// converts superjson and flatted payloads, unless -format selects it. Plain
+// JSON input is passed through unchanged.
+//
+// With -ndjson, every line of the input is a separate payload and every
+// line of the output the result for the corresponding input line. Lines
+// that fail are written as {"line": n, "error": ..., "category": ...}
+// objects in place, and the exit status reflects the first failure.
+//
+// The -config flag loads a configuration file, such as rehydrate.yaml, as
+// described in package config. Its revivers, limits, key settings and output
+// indentation apply before the -plugin and -reviver-exec flags. With
+// -ndjson the file is watched, and changes take effect from the next line;
+// sending SIGHUP forces a reload.
+//
+// The -plugin and -reviver-exec flags add revivers for custom tags. -plugin
+// loads a Go plugin exporting a Revivers symbol of type rehydrate.Revivers or
+// func() rehydrate.Revivers. -reviver-exec runs the command once per value
+// tagged Tag, writing {"tag": ..., "value": ...} to its stdin and reading
+// {"value": ...} or {"error": "..."} from its stdout, so revivers can be
+// written in any language.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func main() {
+	c := &command{stdin: os.Stdin, stdout: os.Stdout}
+	if err := c.run(os.Args[1:]); err != nil {
+		os.Exit(c.fail(os.Stderr, err))
+	}
+}
+
+// command holds the I/O and output settings shared by all subcommands.
+type command struct {
+	stdin  io.Reader
+	stdout io.Writer
+	output string
+	quiet  bool
+	reviverFlags
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	c := &command{stdin: stdin, stdout: stdout}
+	return c.run(args)
+}
+
+func (c *command) run(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "search":
+			return c.search(args[1:])
+		case "size":
+			return c.size(args[1:])
+		case "explore":
+			return c.explore(args[1:])
+		case "gen-fixture":
+			return c.genFixture(args[1:])
+		case "synth":
+			return c.synth(args[1:])
+		}
+	}
+	return c.convert(args)
+}
+
+func (c *command) convert(args []string) error {
+	fs := c.flagSet("rehydrate", "usage: rehydrate [flags] [file]\n")
+	format := fs.String("format", "auto", "input `format`: auto (devalue, superjson, flatted or json), devalue or json")
+	annotated := fs.Bool("annotated", false, "keep type information as $type annotations")
+	ndjson := fs.Bool("ndjson", false, "read one payload per line and write one result per line")
+	c.reviverFlags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("expected at most one input file")
+	}
+	if *ndjson {
+		return c.convertNDJSON(fs.Args(), *annotated)
+	}
+	data, err := readInput(fs.Args(), c.stdin)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "auto", "devalue":
+	case "json":
+		return c.emitJSON(data, "json")
+	default:
+		return usageError("unknown input format %q", *format)
+	}
+	opts, err := c.reviverFlags.load()
+	if err != nil {
+		return err
+	}
+	if *annotated {
+		opts = append(opts, rehydrate.WithAnnotatedOutput())
+	}
+	var out string
+	if *format == "auto" {
+		var detected rehydrate.Format
+		out, detected, err = rehydrate.RehydrateAny(data, nil, opts...)
+		if err == nil && detected == rehydrate.FormatJSON {
+			// Plain JSON is passed through, keeping its key order and
+			// numbers float64 cannot hold.
+			return c.emitJSON(data, "json")
+		}
+	} else {
+		out, err = rehydrate.RehydrateWith(string(data), nil, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	return c.emitJSON([]byte(out), "json")
+}
+
+// flagSet returns a FlagSet with the shared -output and -quiet flags.
+func (c *command) flagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&c.output, "output", "", "output format: json, ndjson, yaml or msgpack")
+	fs.BoolVar(&c.quiet, "quiet", false, "suppress all output")
+	return fs
+}
+
+// readInput reads the file named by the first argument, or stdin when there
+// is none or it is "-".
+func readInput(args []string, stdin io.Reader) ([]byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(args[0])
+}
+
+var errUsage = errors.New("invalid usage")
+
+func usageError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+// exitStatus maps err to the exit status and category name reported for it.
+func exitStatus(err error) (int, string) {
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return 2, "usage"
+	case errors.Is(err, rehydrate.ErrUnknownType):
+		return 4, "unknown_type"
+	case errors.Is(err, rehydrate.ErrBadReference):
+		return 5, "bad_reference"
+	case errors.Is(err, rehydrate.ErrLimitExceeded):
+		return 6, "limit_exceeded"
+	case errors.Is(err, rehydrate.ErrInvalidInput):
+		return 3, "invalid_input"
+	case errors.As(err, &pathErr):
+		return 7, "io"
+	}
+	return 1, "error"
+}
+
+// fail reports err on w and returns the exit status to use.
+func (c *command) fail(w io.Writer, err error) int {
+	status, category := exitStatus(err)
+	if errors.Is(err, flag.ErrHelp) {
+		return status
+	}
+	if c.output == "" || c.output == "text" {
+		fmt.Fprintln(w, "rehydrate:", err)
+		return status
+	}
+	_ = writeJSONLine(w, map[string]interface{}{
+		"error":     err.Error(),
+		"category":  category,
+		"exit_code": status,
+	})
+	return status
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestGenFixtureRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	hints := filepath.Join(dir, "hints.json")
+	err := os.WriteFile(hints, []byte(`{
+		"createdAt": "Date",
+		"tags": "Set",
+		"scores": "Map",
+		"bytes": "Uint16Array",
+		"ratio": "NaN",
+		"list[1]": "hole",
+		"self": "ref:"
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{
+		"createdAt": "2024-01-02T03:04:05Z",
+		"tags": ["a", "b", "a"],
+		"scores": {"x": 1},
+		"bytes": [1, 513],
+		"ratio": 0,
+		"list": [1, 2, 3],
+		"self": null
+	}`
+	var out bytes.Buffer
+	if err := run([]string{"gen-fixture", "-types", hints}, strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := rehydrate.Parse(out.String(), nil)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	root := result.(map[string]interface{})
+
+	if got := root["createdAt"].(time.Time); !got.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("createdAt = %v", got)
+	}
+	if root["tags"].(*rehydrate.Set).Len() != 2 {
+		t.Errorf("tags = %v", root["tags"])
+	}
+	if v, _ := root["scores"].(*rehydrate.OrderedMap).Get("x"); v != 1.0 {
+		t.Errorf("scores.x = %v", v)
+	}
+	if got := root["bytes"].([]uint16); len(got) != 2 || got[0] != 1 || got[1] != 513 {
+		t.Errorf("bytes = %v", got)
+	}
+	if !math.IsNaN(root["ratio"].(float64)) {
+		t.Errorf("ratio = %v", root["ratio"])
+	}
+	if list := root["list"].([]interface{}); len(list) != 3 || list[1] != nil {
+		t.Errorf("list = %v", list)
+	}
+	if self := root["self"].(map[string]interface{}); self["ratio"] == nil {
+		t.Errorf("self should reference the root")
+	}
+}
+
+func TestSearchCommand(t *testing.T) {
+	input := `[{"a":1,"b":2},"needle in haystack","hay"]`
+	var out bytes.Buffer
+	if err := run([]string{"search", "needle"}, strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/a\t\"needle in haystack\"\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestExploreCommand(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "payload.json")
+	payload := `[["Reactive",1],{"data":2},{"items":3},[4,5],"first","second"]`
+	if err := os.WriteFile(file, []byte(payload), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := "ls\ncd data\ncd items\nls\nraw\ncd 1\npwd\ncd /\nfind sec\nquit\n"
+	var out bytes.Buffer
+	if err := run([]string{"explore", file}, strings.NewReader(commands), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"data                     Object(1)",
+		`0                        "first"`,
+		"#3 [4,5]",
+		"/data/items/1\n",
+		"/data/items/1\t\"second\"",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestExploreTree(t *testing.T) {
+	payload := `[["Reactive",1],{"data":2,"n":5},{"items":3},[4,4],"first",7]`
+	root, err := rehydrate.ParseWithOptions(payload, rehydrate.WithRevivers(rehydrate.DefaultNuxtRevivers()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var table []json.RawMessage
+	json.Unmarshal([]byte(payload), &table)
+	view := newTreeView(&explorer{payload: payload, table: table, stack: []exploreNode{{value: root, index: 0}}})
+
+	// Press keys as a terminal sends them, rendering after each like the
+	// explorer does, and return the last screen.
+	press := func(keys string) string {
+		t.Helper()
+		r := bufio.NewReader(strings.NewReader(keys))
+		var screen strings.Builder
+		for {
+			k, err := readKey(r)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if view.key(k) {
+				t.Fatalf("key %q quit the view", k)
+			}
+			screen.Reset()
+			view.render(&screen, 40, 10)
+		}
+		return screen.String()
+	}
+
+	screen := press("\x1b[B")
+	for _, want := range []string{"▾ /  Object(2)", "\x1b[7m  ▸ data  Object(1)\x1b[0m", "    n  7", "/data  (? for help)"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("initial screen missing %q:\n%q", want, screen)
+		}
+	}
+
+	// Expand data, move to items and expand it.
+	screen = press("\x1b[C\x1b[B\r")
+	for _, want := range []string{"  ▾ data  Object(1)", "\x1b[7m    ▾ items  Array(2)\x1b[0m", `      0  "first"`, `      1  "first"`} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("expanded screen missing %q:\n%q", want, screen)
+		}
+	}
+	if screen = press("r"); !strings.Contains(screen, "#3 [4,4]") {
+		t.Errorf("raw entry: %q", screen)
+	}
+	if screen = press("\x1b[Bc"); !strings.Contains(screen, "\x1b]52;c;"+base64.StdEncoding.EncodeToString([]byte("/data/items/0"))+"\a") {
+		t.Errorf("copy: %q", screen)
+	}
+
+	// Left goes up to the parent, then collapses it.
+	screen = press("\x1b[D\x1b[D")
+	if !strings.Contains(screen, "\x1b[7m    ▸ items  Array(2)\x1b[0m") || strings.Contains(screen, "first") {
+		t.Errorf("collapsed screen: %q", screen)
+	}
+
+	// Only the rows around the cursor that fit are drawn.
+	view.expanded["/data/items"] = true
+	view.rebuild()
+	press("G")
+	var small strings.Builder
+	view.render(&small, 40, 3)
+	if got := strings.Count(small.String(), "\r\n"); got != 2 || !strings.Contains(small.String(), "n  7") {
+		t.Errorf("small screen: %q", small.String())
+	}
+
+	if !view.key("q") {
+		t.Error("q did not quit")
+	}
+}
+
+func TestExplorePaths(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "payload.json")
+	payload := `[{"first name":1,"scores":2,"bio":5},"Ada",["Map",3,4],1000000,"top score",` +
+		`"` + strings.Repeat("é", 70) + `"]`
+	if err := os.WriteFile(file, []byte(payload), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := "ls\ncd first name\nfind Ad\ncd /\ncd scores\nfind top\ncopy 1000000\nquit\n"
+	var out bytes.Buffer
+	if err := run([]string{"explore", file}, strings.NewReader(commands), &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"/first name\t\"Ada\"",
+		"/scores/1000000\t\"top score\"",
+		"\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("/scores/1000000")) + "\a",
+		"copied /scores/1000000\n",
+		`"` + strings.Repeat("é", 56) + "...",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+	if !utf8.ValidString(out.String()) {
+		t.Errorf("output is not valid UTF-8:\n%s", out.String())
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	input := `[{"name":1,"tags":2},"demo",[3,4],"x","y"]`
+	tests := map[string]string{
+		"ndjson":  "{\"name\":\"demo\",\"tags\":[\"x\",\"y\"]}\n",
+		"yaml":    "name: \"demo\"\ntags:\n  - \"x\"\n  - \"y\"\n",
+		"msgpack": "\x82\xa4name\xa4demo\xa4tags\x92\xa1x\xa1y",
+	}
+	for format, want := range tests {
+		var out bytes.Buffer
+		if err := run([]string{"-output", format}, strings.NewReader(input), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("%s: got %q, want %q", format, out.String(), want)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"-quiet"}, strings.NewReader(input), &out); err != nil || out.Len() != 0 {
+		t.Errorf("quiet produced %q, %v", out.String(), err)
+	}
+}
+
+func TestStructuredErrors(t *testing.T) {
+	c := &command{stdin: strings.NewReader(`[["Widget",0]]`), stdout: &bytes.Buffer{}}
+	err := c.run([]string{"-output", "json"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	var stderr bytes.Buffer
+	if status := c.fail(&stderr, err); status != 4 {
+		t.Errorf("exit status %d, want 4", status)
+	}
+	if !strings.Contains(stderr.String(), `"category":"unknown_type"`) {
+		t.Errorf("unexpected error output %s", stderr.String())
+	}
+}
+
+func TestReviverExec(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "reviver.sh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\ncat >/dev/null\necho '{\"value\":\"revived\"}'\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `[{"widget":1},["Widget",2],{"id":3},7]`
+	var out bytes.Buffer
+	args := []string{"-output", "ndjson", "-reviver-exec", "Widget=" + script}
+	if err := run(args, strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `{"widget":"revived"}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConvertFormats(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"-output", "ndjson"}, strings.NewReader(`{"plain":true}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `{"plain":true}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, input := range []string{
+		`{"json":{"tags":["x"]},"meta":{"values":{"tags":["set"]}}}`,
+		`[{"tags":"1"},["2"],"x"]`,
+	} {
+		out.Reset()
+		if err := run([]string{"-output", "ndjson"}, strings.NewReader(input), &out); err != nil {
+			t.Errorf("%s: %v", input, err)
+		} else if got, want := out.String(), `{"tags":["x"]}`+"\n"; got != want {
+			t.Errorf("%s: got %q, want %q", input, got, want)
+		}
+	}
+
+	// A corrupt devalue table fails the same way in auto mode as with
+	// -format devalue, rather than being passed through as plain JSON.
+	for _, format := range []string{"auto", "devalue"} {
+		c := &command{stdin: strings.NewReader(`[{"a":7}]`), stdout: &bytes.Buffer{}}
+		err := c.run([]string{"-format", format, "-output", "json"})
+		if !errors.Is(err, rehydrate.ErrBadReference) {
+			t.Errorf("-format %s: expected ErrBadReference, got %v", format, err)
+		}
+		if status := c.fail(io.Discard, err); status != 5 {
+			t.Errorf("-format %s: exit status %d, want 5", format, status)
+		}
+	}
+
+	err := run([]string{"-format", "devalue"}, strings.NewReader(`{"json":{},"meta":{"values":{}}}`), &out)
+	if !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for superjson as devalue, got %v", err)
+	}
+}
+
+func TestConvertNDJSON(t *testing.T) {
+	input := "[{\"a\":1},\"x\"]\n[[\"Widget\",0]]\n\n[[\"Set\",1],\"y\"]\n"
+	var out bytes.Buffer
+	err := run([]string{"-ndjson"}, strings.NewReader(input), &out)
+	if !errors.Is(err, rehydrate.ErrUnknownType) {
+		t.Errorf("expected the first line error to be reported, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || lines[0] != `{"a":"x"}` || lines[2] != `["y"]` {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	if !strings.Contains(lines[1], `"line":2`) || !strings.Contains(lines[1], `"category":"unknown_type"`) {
+		t.Errorf("unexpected error line %s", lines[1])
+	}
+}
+
+func TestConfigFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rehydrate.yaml")
+	conf := "revivers:\n  Secret: \"null\"\nkeys:\n  transform: [snake_case]\noutput:\n  indent: 0\n"
+	if err := os.WriteFile(path, []byte(conf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	input := `[{"userName":1,"token":2},"ann",["Secret",1]]`
+	if err := run([]string{"-config", path}, strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), `{"token":null,"user_name":"ann"}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	out.Reset()
+	if err := run([]string{"-ndjson", "-config", path}, strings.NewReader(input+"\n"+input), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), strings.Repeat(`{"token":null,"user_name":"ann"}`+"\n", 2); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	err := run([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, strings.NewReader(input), &out)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestSizeLocale(t *testing.T) {
+	payload := `[{"text":1},"` + strings.Repeat("x", 2000) + `"]`
+	var out bytes.Buffer
+	if err := run([]string{"size", "-locale", "de"}, strings.NewReader(payload), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "2,0KB total, 0B unreachable\n") || !strings.Contains(out.String(), "99,") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := run([]string{"size"}, strings.NewReader(payload), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "2.0KB total") {
+		t.Errorf("unexpected default output:\n%s", out.String())
+	}
+
+	if err := run([]string{"size", "-locale", "xx"}, strings.NewReader(payload), &out); !errors.Is(err, errUsage) {
+		t.Errorf("unknown locale: got %v", err)
+	}
+}
+
+func TestSynth(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"synth", "-count", "3", "-depth", "3", "-sharing", "0.5", "-seed", "9"}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || lines[0] == lines[1] {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	for _, line := range lines {
+		if _, err := rehydrate.Parse(line, nil); err != nil {
+			t.Errorf("%v: %s", err, line)
+		}
+	}
+
+	if err := run([]string{"synth", "-mix", "regexp=1"}, nil, &out); !errors.Is(err, errUsage) {
+		t.Errorf("unknown type: got %v", err)
+	}
+}
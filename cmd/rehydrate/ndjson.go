@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/config"
+)
+
+// convertNDJSON converts one payload per input line, isolating failures to
+// their line.
+func (c *command) convertNDJSON(args []string, annotated bool) error {
+	in := c.stdin
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	parseOpts, stop, err := c.ndjsonOptions()
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	out := io.Discard
+	if !c.quiet {
+		out = c.stdout
+	}
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	var first error
+	failed, total := 0, 0
+	opts := append([]rehydrate.Option{
+		rehydrate.WithRevivers(rehydrate.DefaultNuxtRevivers()),
+		rehydrate.WithReviverValidation(),
+	}, parseOpts...)
+	for line := range rehydrate.ParseNDJSON(in, opts...) {
+		total++
+		err := line.Err
+		if err == nil {
+			err = writeNDJSONValue(w, line.Value, annotated)
+		}
+		if err == nil {
+			continue
+		}
+		if line.Err == nil {
+			err = &rehydrate.LineError{Line: line.Number, Err: err}
+		}
+		failed++
+		if first == nil {
+			first = err
+		}
+		_, category := exitStatus(err)
+		if werr := writeJSONLine(w, map[string]interface{}{
+			"line":     line.Number,
+			"error":    err.Error(),
+			"category": category,
+		}); werr != nil {
+			return werr
+		}
+	}
+	if first != nil {
+		return fmt.Errorf("%d of %d lines failed, first: %w", failed, total, first)
+	}
+	return nil
+}
+
+// ndjsonOptions returns the parse options for convertNDJSON. Streams can run
+// for a long time, so a configuration file is watched and reloaded when it
+// changes or on SIGHUP; a file that fails to reload is reported on stderr and
+// the previous configuration stays in effect. stop ends the watch.
+func (c *command) ndjsonOptions() (opts []rehydrate.Option, stop func(), err error) {
+	if c.config == "" {
+		opts, err = c.reviverFlags.load()
+		return opts, func() {}, err
+	}
+	revivers, err := c.reviverFlags.revivers()
+	if err != nil {
+		return nil, nil, err
+	}
+	watcher, err := config.Watch(c.config,
+		config.ReloadOnSignal(syscall.SIGHUP),
+		config.OnReload(func(_ *config.Config, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "rehydrate: keeping previous config: %v\n", err)
+			}
+		}),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	reload := func() []rehydrate.Option {
+		current := watcher.ParseOptions()
+		return append(current[:len(current):len(current)], rehydrate.WithRevivers(revivers))
+	}
+	return []rehydrate.Option{rehydrate.WithReloadedOptions(reload)}, func() { watcher.Close() }, nil
+}
+
+func writeNDJSONValue(w io.Writer, v interface{}, annotated bool) error {
+	var data []byte
+	var err error
+	if annotated {
+		data, err = rehydrate.MarshalAnnotated(v)
+	} else {
+		data, err = json.Marshal(rehydrate.ConvertUnsupportedTypes(v))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// emit writes v in the selected output format. text renders the human
+// readable form used when no format was selected; if it is nil, JSON is
+// written instead.
+func (c *command) emit(v interface{}, text func(io.Writer) error) error {
+	if c.quiet {
+		return nil
+	}
+	if (c.output == "" || c.output == "text") && text != nil {
+		return text(c.stdout)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.emitJSON(data, "json")
+}
+
+// emitJSON writes a JSON document in the selected output format, falling back
+// to defaultFormat when none was selected. An empty defaultFormat writes data
+// unchanged.
+func (c *command) emitJSON(data []byte, defaultFormat string) error {
+	if c.quiet {
+		return nil
+	}
+	format := c.output
+	if format == "" || format == "text" {
+		format = defaultFormat
+	}
+
+	w := bufio.NewWriter(c.stdout)
+	switch format {
+	case "":
+		w.Write(bytes.TrimSpace(data))
+		w.WriteByte('\n')
+	case "json":
+		indent := "  "
+		if c.indent != nil {
+			indent = *c.indent
+		}
+		var buf bytes.Buffer
+		var err error
+		if indent == "" {
+			err = json.Compact(&buf, bytes.TrimSpace(data))
+		} else {
+			err = json.Indent(&buf, bytes.TrimSpace(data), "", indent)
+		}
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		w.Write(buf.Bytes())
+	case "ndjson", "yaml", "msgpack":
+		v, err := decodeOrdered(data)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case "ndjson":
+			err = writeNDJSON(w, v)
+		case "yaml":
+			writeYAML(w, v, 0, false)
+		case "msgpack":
+			writeMsgpack(w, v)
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		return usageError("unknown output format %q", c.output)
+	}
+	return w.Flush()
+}
+
+func writeJSONLine(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// jsonObject is a decoded JSON object that keeps its members in order.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(m.key)
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeOrdered decodes a JSON document into nil, bool, json.Number, string,
+// []interface{} and jsonObject values.
+func decodeOrdered(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeOrderedValue(dec)
+}
+
+func decodeOrderedValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			v, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err := dec.Token()
+		return arr, err
+	case json.Delim('{'):
+		obj := jsonObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonMember{key.(string), v})
+		}
+		_, err := dec.Token()
+		return obj, err
+	}
+	return tok, nil
+}
+
+// writeNDJSON writes each element of an array on its own line, or any other
+// value as a single line.
+func writeNDJSON(w io.Writer, v interface{}) error {
+	items, ok := v.([]interface{})
+	if !ok {
+		return writeJSONLine(w, v)
+	}
+	for _, item := range items {
+		if err := writeJSONLine(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var plainYAMLKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-]*$`)
+
+// writeYAML writes v as a YAML block at the given indentation level. When
+// inline is set, the first line continues the current one, as after "- ".
+func writeYAML(w *bufio.Writer, v interface{}, level int, inline bool) {
+	pad := strings.Repeat("  ", level)
+	first := true
+	startLine := func() {
+		if !first || !inline {
+			w.WriteString(pad)
+		}
+		first = false
+	}
+
+	switch value := v.(type) {
+	case jsonObject:
+		if len(value) == 0 {
+			w.WriteString("{}\n")
+			return
+		}
+		for _, m := range value {
+			startLine()
+			w.WriteString(yamlKey(m.key) + ":")
+			writeYAMLChild(w, m.value, level)
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			w.WriteString("[]\n")
+			return
+		}
+		for _, item := range value {
+			startLine()
+			w.WriteString("-")
+			if isYAMLScalar(item) {
+				w.WriteString(" " + yamlScalar(item) + "\n")
+			} else {
+				w.WriteString(" ")
+				writeYAML(w, item, level+1, true)
+			}
+		}
+	default:
+		w.WriteString(yamlScalar(v) + "\n")
+	}
+}
+
+func writeYAMLChild(w *bufio.Writer, v interface{}, level int) {
+	if isYAMLScalar(v) {
+		w.WriteString(" " + yamlScalar(v) + "\n")
+		return
+	}
+	w.WriteString("\n")
+	writeYAML(w, v, level+1, false)
+}
+
+func isYAMLScalar(v interface{}) bool {
+	switch value := v.(type) {
+	case jsonObject:
+		return len(value) == 0
+	case []interface{}:
+		return len(value) == 0
+	}
+	return true
+}
+
+func yamlScalar(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if value {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return value.String()
+	case jsonObject:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	quoted, _ := json.Marshal(v)
+	return string(quoted)
+}
+
+func yamlKey(key string) string {
+	switch strings.ToLower(key) {
+	case "true", "false", "null", "yes", "no", "on", "off", "y", "n":
+	default:
+		if plainYAMLKey.MatchString(key) {
+			return key
+		}
+	}
+	quoted, _ := json.Marshal(key)
+	return string(quoted)
+}
+
+// writeMsgpack encodes v in the MessagePack format.
+func writeMsgpack(w *bufio.Writer, v interface{}) {
+	switch value := v.(type) {
+	case nil:
+		w.WriteByte(0xc0)
+	case bool:
+		if value {
+			w.WriteByte(0xc3)
+		} else {
+			w.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			writeMsgpackInt(w, i)
+			return
+		}
+		f, _ := value.Float64()
+		w.WriteByte(0xcb)
+		binary.Write(w, binary.BigEndian, math.Float64bits(f))
+	case string:
+		writeMsgpackHeader(w, len(value), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		w.WriteString(value)
+	case []interface{}:
+		writeMsgpackHeader(w, len(value), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range value {
+			writeMsgpack(w, item)
+		}
+	case jsonObject:
+		writeMsgpackHeader(w, len(value), 0x80, 15, 0, 0xde, 0xdf)
+		for _, m := range value {
+			writeMsgpack(w, m.key)
+			writeMsgpack(w, m.value)
+		}
+	}
+}
+
+func writeMsgpackInt(w *bufio.Writer, i int64) {
+	switch {
+	case i >= 0 && i <= 127:
+		w.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		w.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		w.WriteByte(0xd0)
+		w.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		w.WriteByte(0xd1)
+		binary.Write(w, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		w.WriteByte(0xd2)
+		binary.Write(w, binary.BigEndian, int32(i))
+	default:
+		w.WriteByte(0xd3)
+		binary.Write(w, binary.BigEndian, i)
+	}
+}
+
+// writeMsgpackHeader writes the type and length prefix of a string, array or
+// map. fix is the fixed-size type whose low bits hold lengths up to fixMax;
+// b8, b16 and b32 are the types with 8, 16 and 32-bit lengths, b8 being zero
+// for types that lack it.
+func writeMsgpackHeader(w *bufio.Writer, n int, fix byte, fixMax int, b8, b16, b32 byte) {
+	switch {
+	case n <= fixMax:
+		w.WriteByte(fix | byte(n))
+	case b8 != 0 && n <= math.MaxUint8:
+		w.WriteByte(b8)
+		w.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(b16)
+		binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		w.WriteByte(b32)
+		binary.Write(w, binary.BigEndian, uint32(n))
+	}
+}
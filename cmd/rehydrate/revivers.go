@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os/exec"
+	"plugin"
+	"strings"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/config"
+)
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// reviverFlags holds the flags selecting extra revivers and the
+// configuration file.
+type reviverFlags struct {
+	plugins stringList
+	execs   stringList
+	config  string
+	// indent overrides the JSON output indentation when set by the
+	// configuration file.
+	indent *string
+}
+
+func (r *reviverFlags) register(fs *flag.FlagSet) {
+	fs.Var(&r.plugins, "plugin", "load revivers from a Go plugin (repeatable)")
+	fs.Var(&r.execs, "reviver-exec", "revive `Tag=command` by running command (repeatable)")
+	fs.StringVar(&r.config, "config", "", "load revivers, limits and output options from a config `file`")
+}
+
+// load returns the parse options selected by the flags. The configuration
+// file is applied first, and later flags take precedence over earlier ones
+// for the same tag.
+func (r *reviverFlags) load() ([]rehydrate.Option, error) {
+	var opts []rehydrate.Option
+	if r.config != "" {
+		c, err := config.Load(r.config)
+		if err != nil {
+			return nil, err
+		}
+		if opts, err = c.ParseOptions(); err != nil {
+			return nil, err
+		}
+		if c.Output.Indent != nil {
+			indent := strings.Repeat(" ", *c.Output.Indent)
+			r.indent = &indent
+		}
+	}
+	revivers, err := r.revivers()
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, rehydrate.WithRevivers(revivers)), nil
+}
+
+// revivers returns the revivers selected by -plugin and -reviver-exec.
+func (r *reviverFlags) revivers() (rehydrate.Revivers, error) {
+	revivers := rehydrate.Revivers{}
+	for _, path := range r.plugins {
+		loaded, err := loadPluginRevivers(path)
+		if err != nil {
+			return nil, err
+		}
+		for tag, fn := range loaded {
+			revivers[tag] = fn
+		}
+	}
+	for _, spec := range r.execs {
+		tag, command, ok := strings.Cut(spec, "=")
+		args := strings.Fields(command)
+		if !ok || tag == "" || len(args) == 0 {
+			return nil, usageError("invalid -reviver-exec %q, expected Tag=command", spec)
+		}
+		revivers[tag] = execReviver(tag, args)
+	}
+	return revivers, nil
+}
+
+// loadPluginRevivers opens the Go plugin at path and returns the revivers it
+// exports as a symbol named Revivers, which is either a variable of type
+// rehydrate.Revivers or a func() rehydrate.Revivers.
+func loadPluginRevivers(path string) (rehydrate.Revivers, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	sym, err := p.Lookup("Revivers")
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", path, err)
+	}
+	switch v := sym.(type) {
+	case *rehydrate.Revivers:
+		return *v, nil
+	case *map[string]rehydrate.ReviverFunc:
+		return *v, nil
+	case func() rehydrate.Revivers:
+		return v(), nil
+	case func() map[string]rehydrate.ReviverFunc:
+		return v(), nil
+	}
+	return nil, fmt.Errorf("plugin %s: Revivers has unsupported type %T", path, sym)
+}
+
+// execRequest is written to the stdin of a reviver executable.
+type execRequest struct {
+	Tag   string      `json:"tag"`
+	Value interface{} `json:"value"`
+}
+
+// execResponse is read from the stdout of a reviver executable. A non-empty
+// Error fails hydration.
+type execResponse struct {
+	Value interface{} `json:"value"`
+	Error string      `json:"error,omitempty"`
+}
+
+// execReviver returns a reviver that runs args once per tagged value. The
+// executable receives an execRequest on stdin, with the value converted to
+// plain JSON, and must write an execResponse to stdout.
+func execReviver(tag string, args []string) rehydrate.ReviverFunc {
+	return func(value interface{}) (interface{}, error) {
+		input, err := json.Marshal(execRequest{Tag: tag, Value: rehydrate.ConvertUnsupportedTypes(value)})
+		if err != nil {
+			return nil, err
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("reviver %s: %w: %s", args[0], err, msg)
+			}
+			return nil, fmt.Errorf("reviver %s: %w", args[0], err)
+		}
+
+		var resp execResponse
+		if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+			return nil, fmt.Errorf("reviver %s: invalid response: %w", args[0], err)
+		}
+		if resp.Error != "" {
+			return nil, fmt.Errorf("reviver %s: %s", args[0], resp.Error)
+		}
+		return resp.Value, nil
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+const searchUsage = `usage: rehydrate search [flags] query [file]
+
+Prints the path and value of every string in the hydrated payload matching
+query, one per line.
+`
+
+func (c *command) search(args []string) error {
+	fs := c.flagSet("search", searchUsage)
+	useRegexp := fs.Bool("regexp", false, "treat query as a regular expression")
+	ignoreCase := fs.Bool("i", false, "match case-insensitively")
+	binary := fs.Bool("binary", false, "also search typed arrays holding UTF-8 text")
+	c.reviverFlags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return usageError("search: expected a query and at most one file")
+	}
+
+	data, err := readInput(fs.Args()[1:], c.stdin)
+	if err != nil {
+		return err
+	}
+
+	parseOpts, err := c.reviverFlags.load()
+	if err != nil {
+		return err
+	}
+	opts := []rehydrate.SearchOption{
+		rehydrate.SearchParseOptions(append([]rehydrate.Option{rehydrate.WithRevivers(rehydrate.DefaultNuxtRevivers())}, parseOpts...)...),
+	}
+	if *useRegexp {
+		opts = append(opts, rehydrate.SearchRegexp())
+	}
+	if *ignoreCase {
+		opts = append(opts, rehydrate.SearchIgnoreCase())
+	}
+	if *binary {
+		opts = append(opts, rehydrate.SearchBinary())
+	}
+
+	matches, err := rehydrate.Search(string(data), fs.Arg(0), opts...)
+	if err != nil {
+		return err
+	}
+	return c.emit(matches, func(w io.Writer) error {
+		for _, m := range matches {
+			path := m.Path
+			if m.Key {
+				path += " (key)"
+			}
+			if _, err := fmt.Fprintf(w, "%s\t%q\n", displayPath(path), m.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
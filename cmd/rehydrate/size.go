@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+const sizeUsage = `usage: rehydrate size [flags] [file]
+
+Attributes the payload's byte size to the paths of its values and prints
+the result as a tree, largest first. With -output the full report is
+written instead, regardless of -depth.
+`
+
+func (c *command) size(args []string) error {
+	fs := c.flagSet("size", sizeUsage)
+	depth := fs.Int("depth", 3, "levels of the tree to print")
+	localeTag := fs.String("locale", "", "format sizes for a locale such as de or en-GB")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	locale, err := parseLocale(*localeTag)
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(fs.Args(), c.stdin)
+	if err != nil {
+		return err
+	}
+	report, err := rehydrate.SizeReport(string(data))
+	if err != nil {
+		return err
+	}
+
+	return c.emit(report, func(w io.Writer) error {
+		fmt.Fprintf(w, "%s total, %s unreachable\n", locale.FormatSize(float64(report.Bytes)), locale.FormatSize(float64(report.Unreachable)))
+		if report.Root != nil {
+			printSizeNode(w, locale, report.Root, float64(report.Bytes), 0, *depth)
+		}
+		return nil
+	})
+}
+
+const barWidth = 20
+
+func printSizeNode(w io.Writer, locale rehydrate.Locale, n *rehydrate.SizeNode, total float64, level, maxDepth int) {
+	share := 0.0
+	if total > 0 {
+		share = n.Attributed / total
+	}
+	label := displayPath(n.Path)
+	if n.Tag != "" {
+		label += " <" + n.Tag + ">"
+	}
+	if n.Refs > 1 {
+		label += fmt.Sprintf(" (shared x%d)", n.Refs)
+	}
+	bar := strings.Repeat("#", int(share*barWidth+0.5))
+	fmt.Fprintf(w, "%-*s%9s %6s %-*s %s\n",
+		level*2, "", locale.FormatSize(n.Attributed), locale.FormatPercent(share), barWidth, bar, label)
+
+	if level+1 >= maxDepth {
+		return
+	}
+	for _, child := range n.Children {
+		printSizeNode(w, locale, child, total, level+1, maxDepth)
+	}
+}
+
+// parseLocale parses the -locale flag, where an empty tag selects the default
+// formatting.
+func parseLocale(tag string) (rehydrate.Locale, error) {
+	if tag == "" {
+		return rehydrate.Locale{}, nil
+	}
+	l, err := rehydrate.ParseLocale(tag)
+	if err != nil {
+		return l, usageError("%v", err)
+	}
+	return l, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/synth"
+)
+
+const synthUsage = `usage: rehydrate synth [flags]
+
+Writes random devalue payloads, one per line, for benchmarks and load
+tests. The same flags and seed always produce the same payloads.
+`
+
+func (c *command) synth(args []string) error {
+	fs := c.flagSet("synth", synthUsage)
+	var shape synth.Shape
+	fs.IntVar(&shape.Depth, "depth", 4, "maximum nesting of containers, counting the root")
+	fs.IntVar(&shape.FanOut, "fanout", 4, "maximum number of children per container")
+	fs.IntVar(&shape.BinarySize, "binary", 64, "bytes per typed array")
+	fs.Float64Var(&shape.Sharing, "sharing", 0, "probability of referring to an existing value")
+	fs.Uint64Var(&shape.Seed, "seed", 1, "random seed")
+	mix := fs.String("mix", "", "type weights such as `string=3,object=1`")
+	count := fs.Int("count", 1, "number of payloads")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("unexpected arguments")
+	}
+	if *mix != "" {
+		m, err := synth.ParseMix(*mix)
+		if err != nil {
+			return usageError("%v", err)
+		}
+		shape.Mix = m
+	}
+	g, err := synth.New(shape)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if c.quiet {
+		return nil
+	}
+
+	w := bufio.NewWriter(c.stdout)
+	for i := 0; i < *count; i++ {
+		w.WriteString(g.Next())
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+const treeHelp = "↑↓ move  → expand  ← collapse  enter toggle  c copy path  r raw entry  q quit"
+
+// treeView is the full-screen interface of explore: the payload as a tree
+// whose containers expand and collapse in place.
+type treeView struct {
+	e *explorer
+	// expanded holds the paths of the expanded containers.
+	expanded map[string]bool
+	rows     []treeRow
+	cursor   int
+	// offset is the first row on screen.
+	offset int
+	status string
+}
+
+type treeRow struct {
+	node  exploreNode
+	depth int
+	// container is set for values with children, even when they have none.
+	container bool
+}
+
+func newTreeView(e *explorer) *treeView {
+	t := &treeView{e: e, expanded: map[string]bool{"": true}}
+	t.rebuild()
+	return t
+}
+
+// rebuild lists the rows visible with the current expansions, keeping the
+// cursor on the same path where it still exists.
+func (t *treeView) rebuild() {
+	var path string
+	if t.cursor < len(t.rows) {
+		path = t.rows[t.cursor].node.path
+	}
+	t.rows = t.rows[:0]
+	t.appendRows(t.e.stack[0], 0)
+	t.cursor = 0
+	for i, row := range t.rows {
+		if row.node.path == path {
+			t.cursor = i
+			break
+		}
+	}
+}
+
+func (t *treeView) appendRows(n exploreNode, depth int) {
+	t.rows = append(t.rows, treeRow{node: n, depth: depth, container: isContainer(n.value)})
+	if !t.expanded[n.path] {
+		return
+	}
+	for _, child := range t.e.children(n) {
+		t.appendRows(child, depth+1)
+	}
+}
+
+func isContainer(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}, *rehydrate.Set, *rehydrate.OrderedMap:
+		return true
+	}
+	return false
+}
+
+// key applies a key press, as decoded by readKey, and reports whether it
+// quits the view.
+func (t *treeView) key(k string) bool {
+	t.status = ""
+	row := t.rows[t.cursor]
+	switch k {
+	case "q", "ctrl-c", "esc":
+		return true
+	case "up", "k":
+		t.move(-1)
+	case "down", "j":
+		t.move(1)
+	case "pgup":
+		t.move(-10)
+	case "pgdn":
+		t.move(10)
+	case "home", "g":
+		t.cursor = 0
+	case "end", "G":
+		t.cursor = len(t.rows) - 1
+	case "right", "l":
+		if row.container && !t.expanded[row.node.path] {
+			t.expanded[row.node.path] = true
+			t.rebuild()
+		} else if row.container {
+			t.move(1)
+		}
+	case "left", "h":
+		if row.container && t.expanded[row.node.path] {
+			delete(t.expanded, row.node.path)
+			t.rebuild()
+		} else {
+			// Go to the parent, the closest row above at a lower depth.
+			for i := t.cursor - 1; i >= 0; i-- {
+				if t.rows[i].depth < row.depth {
+					t.cursor = i
+					break
+				}
+			}
+		}
+	case "enter", " ":
+		if row.container {
+			if t.expanded[row.node.path] {
+				delete(t.expanded, row.node.path)
+			} else {
+				t.expanded[row.node.path] = true
+			}
+			t.rebuild()
+		}
+	case "c":
+		t.status = "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(row.node.path)) + "\acopied " + displayPath(row.node.path)
+	case "r":
+		index := row.node.index
+		if index < 0 || index >= len(t.e.table) {
+			t.status = "no value-table entry for this value"
+		} else {
+			t.status = fmt.Sprintf("#%d %s", index, t.e.table[index])
+		}
+	case "?":
+		t.status = treeHelp
+	}
+	return false
+}
+
+func (t *treeView) move(delta int) {
+	t.cursor = min(max(t.cursor+delta, 0), len(t.rows)-1)
+}
+
+// render draws the rows around the cursor that fit in width by height cells,
+// followed by a status line.
+func (t *treeView) render(w io.Writer, width, height int) {
+	lines := max(height-1, 1)
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+lines {
+		t.offset = t.cursor - lines + 1
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	for i := t.offset; i < len(t.rows) && i < t.offset+lines; i++ {
+		row := t.rows[i]
+		marker := "  "
+		if row.container {
+			marker = "▸ "
+			if t.expanded[row.node.path] {
+				marker = "▾ "
+			}
+		}
+		name := row.node.name
+		if i == 0 {
+			name = "/"
+		}
+		line := truncate(strings.Repeat("  ", row.depth)+marker+name+"  "+summarize(row.node.value, t.e.locale), width)
+		if i == t.cursor {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		b.WriteString(line + "\r\n")
+	}
+	status := t.status
+	if status == "" {
+		status = displayPath(t.rows[t.cursor].node.path) + "  (? for help)"
+	}
+	if !strings.HasPrefix(status, "\x1b]") {
+		status = truncate(status, width)
+	}
+	b.WriteString(status)
+	io.WriteString(w, b.String())
+}
+
+// truncate cuts s to width runes.
+func truncate(s string, width int) string {
+	if runes := []rune(s); width > 0 && len(runes) > width {
+		return string(runes[:width])
+	}
+	return s
+}
+
+// readKey reads one key press from a terminal in raw mode, naming special
+// keys such as "up" or "enter" and returning other keys as typed.
+func readKey(r *bufio.Reader) (string, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch c {
+	case '\r', '\n':
+		return "enter", nil
+	case 3:
+		return "ctrl-c", nil
+	case 0x1b:
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+		if next, _ := r.ReadByte(); next != '[' && next != 'O' {
+			return "esc", nil
+		}
+		var seq []byte
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			seq = append(seq, b)
+			if b >= 0x40 && b <= 0x7e {
+				break
+			}
+		}
+		switch string(seq) {
+		case "A":
+			return "up", nil
+		case "B":
+			return "down", nil
+		case "C":
+			return "right", nil
+		case "D":
+			return "left", nil
+		case "H", "1~":
+			return "home", nil
+		case "F", "4~":
+			return "end", nil
+		case "5~":
+			return "pgup", nil
+		case "6~":
+			return "pgdn", nil
+		}
+		return "", nil
+	}
+	return string(c), nil
+}
+
+// runTree runs the tree view on the terminal of in and out until it quits.
+func (e *explorer) runTree(in, out *os.File) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	// Use the alternate screen and hide the cursor while the view is open.
+	io.WriteString(out, "\x1b[?1049h\x1b[?25l")
+	defer io.WriteString(out, "\x1b[?25h\x1b[?1049l")
+
+	t := newTreeView(e)
+	keys := bufio.NewReader(in)
+	for {
+		// The size is read on every redraw so that resizes take effect.
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		t.render(out, width, height)
+		k, err := readKey(keys)
+		if err != nil {
+			return err
+		}
+		if t.key(k) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+// The gRPC service exchanges JSON messages rather than protocol buffers, so
+// clients need no generated code: they call the methods of serviceName with
+// the content subtype "json", as grpcurl or grpc.CallContentSubtype("json")
+// do, and send the request types below.
+const serviceName = "rehydrate.v1.Rehydrate"
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec marshals gRPC messages as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+// rehydrateRequest is the request of the Rehydrate and RehydrateStream RPCs.
+type rehydrateRequest struct {
+	Payload string `json:"payload"`
+	// Indent is the indent width of the JSON result; zero renders it
+	// compact.
+	Indent int `json:"indent,omitempty"`
+	// Annotated keeps types as $type annotations.
+	Annotated bool `json:"annotated,omitempty"`
+}
+
+// stringifyRequest is the request of the Stringify RPC.
+type stringifyRequest struct {
+	// JSON is the JSON to serialize, optionally annotated.
+	JSON json.RawMessage `json:"json"`
+}
+
+// result is the response of every RPC. The unary RPCs fail with a status
+// instead of setting Error; RehydrateStream reports failures in place of the
+// result so the stream carries on.
+type result struct {
+	// JSON is the result of Rehydrate and RehydrateStream.
+	JSON json.RawMessage `json:"json,omitempty"`
+	// Payload is the result of Stringify and Transform.
+	Payload  string `json:"payload,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// newGRPCServer returns a gRPC server serving s and the standard health
+// service. Messages larger than the payload size limit of s are rejected.
+func newGRPCServer(s *server) *grpc.Server {
+	g := grpc.NewServer(
+		grpc.MaxRecvMsgSize(int(s.maxBytes)),
+		grpc.ChainUnaryInterceptor(s.unaryMetrics),
+		grpc.ChainStreamInterceptor(s.streamMetrics),
+	)
+	g.RegisterService(&serviceDesc, s)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(g, healthServer)
+	return g
+}
+
+// rehydrateServer is the interface serviceDesc requires of its
+// implementation.
+type rehydrateServer interface {
+	rehydrateRPC(context.Context, *rehydrateRequest) (*result, error)
+	stringifyRPC(context.Context, *stringifyRequest) (*result, error)
+	transformRPC(context.Context, *transformRequest) (*result, error)
+	rehydrateStreamRPC(grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*rehydrateServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Rehydrate", Handler: unaryHandler("Rehydrate", rehydrateServer.rehydrateRPC)},
+		{MethodName: "Stringify", Handler: unaryHandler("Stringify", rehydrateServer.stringifyRPC)},
+		{MethodName: "Transform", Handler: unaryHandler("Transform", rehydrateServer.transformRPC)},
+	},
+	Streams: []grpc.StreamDesc{{
+		StreamName: "RehydrateStream",
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			return srv.(rehydrateServer).rehydrateStreamRPC(stream)
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
+
+// unaryHandler adapts a method of rehydrateServer to a grpc.MethodDesc
+// handler, the code protoc would otherwise generate.
+func unaryHandler[Req any](name string, method func(rehydrateServer, context.Context, *Req) (*result, error)) grpc.MethodHandler {
+	fullMethod := "/" + serviceName + "/" + name
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req any) (any, error) {
+			return method(srv.(rehydrateServer), ctx, req.(*Req))
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}, handler)
+	}
+}
+
+func (s *server) rehydrateRPC(ctx context.Context, req *rehydrateRequest) (*result, error) {
+	opts, err := rehydrateOptions(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	out, err := s.rehydrate(ctx, []byte(req.Payload), opts...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &result{JSON: json.RawMessage(out)}, nil
+}
+
+func (s *server) stringifyRPC(ctx context.Context, req *stringifyRequest) (*result, error) {
+	out, err := s.stringify(ctx, req.JSON)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &result{Payload: out}, nil
+}
+
+func (s *server) transformRPC(ctx context.Context, req *transformRequest) (*result, error) {
+	out, err := s.transform(ctx, *req)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &result{Payload: out}, nil
+}
+
+// rehydrateStreamRPC converts each request of the stream as a separate
+// payload and sends each result as soon as it is ready, like
+// /v1/rehydrate/stream.
+func (s *server) rehydrateStreamRPC(stream grpc.ServerStream) error {
+	for {
+		var req rehydrateRequest
+		if err := stream.RecvMsg(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		res := &result{}
+		opts, err := rehydrateOptions(&req)
+		if err == nil {
+			var out string
+			out, err = s.rehydrate(stream.Context(), []byte(req.Payload), opts...)
+			res.JSON = json.RawMessage(out)
+		}
+		if err != nil {
+			res = &result{Error: err.Error(), Category: errorCategory(err)}
+		}
+		if err := stream.SendMsg(res); err != nil {
+			return err
+		}
+	}
+}
+
+func rehydrateOptions(req *rehydrateRequest) ([]rehydrate.Option, error) {
+	if req.Indent < 0 || req.Indent > 16 {
+		return nil, fmt.Errorf("invalid indent %d", req.Indent)
+	}
+	opts := []rehydrate.Option{rehydrate.WithIndent("", strings.Repeat(" ", req.Indent))}
+	if req.Annotated {
+		opts = append(opts, rehydrate.WithAnnotatedOutput())
+	}
+	return opts, nil
+}
+
+// statusError converts a conversion error to a gRPC status, mapping the
+// categories of errorCategory to the closest codes.
+func statusError(err error) error {
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, pipeline.ErrOverBudget), errors.Is(err, rehydrate.ErrBudgetExceeded),
+		errors.Is(err, rehydrate.ErrLimitExceeded):
+		code = codes.ResourceExhausted
+	case errors.Is(err, rehydrate.ErrUnknownType), errors.Is(err, rehydrate.ErrBadReference),
+		errors.Is(err, rehydrate.ErrInvalidInput):
+		code = codes.InvalidArgument
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
+
+func (s *server) unaryMetrics(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	s.metrics.inFlight(1)
+	defer s.metrics.inFlight(-1)
+	resp, err := handler(ctx, req)
+	s.metrics.rpc(info.FullMethod, status.Code(err))
+	return resp, err
+}
+
+func (s *server) streamMetrics(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	s.metrics.inFlight(1)
+	defer s.metrics.inFlight(-1)
+	err := handler(srv, stream)
+	s.metrics.rpc(info.FullMethod, status.Code(err))
+	return err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+func dialGRPC(t *testing.T, s *server) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := newGRPCServer(s)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype("json")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGRPC(t *testing.T) {
+	s := newServer(1 << 20)
+	conn := dialGRPC(t, s)
+	ctx := context.Background()
+
+	var res result
+	err := conn.Invoke(ctx, "/rehydrate.v1.Rehydrate/Rehydrate", &rehydrateRequest{Payload: `[{"a":1},"x"]`}, &res)
+	if err != nil || string(res.JSON) != `{"a":"x"}` {
+		t.Errorf("Rehydrate: %s %v", res.JSON, err)
+	}
+	err = conn.Invoke(ctx, "/rehydrate.v1.Rehydrate/Rehydrate", &rehydrateRequest{Payload: `[["Widget",0]]`}, &res)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Rehydrate unknown type: %v", err)
+	}
+
+	res = result{}
+	err = conn.Invoke(ctx, "/rehydrate.v1.Rehydrate/Stringify", &stringifyRequest{JSON: json.RawMessage(`{"a":[1,2]}`)}, &res)
+	if err != nil || res.Payload != `[{"a":1},[2,3],1,2]` {
+		t.Errorf("Stringify: %q %v", res.Payload, err)
+	}
+
+	res = result{}
+	req := &transformRequest{
+		Payload: `[{"first_name":1},"Ada"]`,
+		Config:  json.RawMessage(`{"keys":{"transform":["camel_case"]}}`),
+	}
+	err = conn.Invoke(ctx, "/rehydrate.v1.Rehydrate/Transform", req, &res)
+	if err != nil || res.Payload != `[{"firstName":1},"Ada"]` {
+		t.Errorf("Transform: %q %v", res.Payload, err)
+	}
+
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], "/rehydrate.v1.Rehydrate/RehydrateStream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range []string{`[{"a":1},1]`, `[["Widget",0]]`, `[2]`} {
+		if err := stream.SendMsg(&rehydrateRequest{Payload: payload}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stream.CloseSend()
+	var got []string
+	for {
+		var res result
+		if err := stream.RecvMsg(&res); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(res.JSON)+res.Category)
+	}
+	if want := []string{`{"a":1}`, "unknown_type", "2"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("RehydrateStream: %q, want %q", got, want)
+	}
+
+	health := healthpb.NewHealthClient(conn)
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: serviceName}, grpc.CallContentSubtype("proto"))
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health: %v %v", resp, err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.metrics.serveHTTP(rec, httptest.NewRequest("GET", "/metrics", nil), nil)
+	for _, want := range []string{
+		`rehydrated_grpc_requests_total{method="/rehydrate.v1.Rehydrate/Rehydrate",code="InvalidArgument"} 1`,
+		`rehydrated_grpc_requests_total{method="/rehydrate.v1.Rehydrate/RehydrateStream",code="OK"} 1`,
+		`rehydrated_payloads_total{result="ok"} 5`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, rec.Body.String())
+		}
+	}
+}
+
+func TestGRPCMemoryBudget(t *testing.T) {
+	s := newServer(1 << 20)
+	s.budget = pipeline.NewMemoryBudget(rehydrate.EstimateMemory(`[{"a":1},"x"]`))
+	conn := dialGRPC(t, s)
+
+	var res result
+	payload := `[{"a":1},"` + strings.Repeat("x", 100) + `"]`
+	for _, call := range []struct {
+		method string
+		req    any
+	}{
+		{"Rehydrate", &rehydrateRequest{Payload: payload}},
+		{"Stringify", &stringifyRequest{JSON: json.RawMessage(`"` + strings.Repeat("x", 100) + `"`)}},
+		{"Transform", &transformRequest{Payload: payload}},
+	} {
+		err := conn.Invoke(context.Background(), "/rehydrate.v1.Rehydrate/"+call.method, call.req, &res)
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("%s over budget: %v", call.method, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Command rehydrated serves the rehydrate package over HTTP and gRPC, for
+// deployments that prefer a sidecar service to linking the Go library.
+//
+// Usage:
+//
+//	rehydrated [-addr :8080] [-grpc-addr :9090] [-max-bytes n] [-memory-budget n] [-budget-wait d] [-time-budget d] [-max-depth n]
+//
+// With -memory-budget, the estimated memory of the payloads being converted
+// at once is bounded: a payload waits up to -budget-wait for room and is
+// otherwise rejected with 429 Too Many Requests, or RESOURCE_EXHAUSTED over
+// gRPC, so a burst of large payloads cannot exhaust the service's memory.
+// -time-budget limits the time spent converting each payload. Both budgets
+// apply to every endpoint and RPC. -max-depth caps the nesting depth the
+// config of a transform may allow, as client configs cannot lift the
+// server's limits.
+//
+// Endpoints:
+//
+//	POST /v1/rehydrate         convert one payload to JSON; ?indent=n sets the indent width
+//	                           and ?annotated keeps types as $type annotations
+//	POST /v1/rehydrate/stream  convert newline-delimited payloads, one JSON result per line
+//	POST /v1/stringify         serialize JSON, optionally annotated, into a payload
+//	POST /v1/transform         hydrate {"payload": ..., "config": ...} with the settings of a
+//	                           configuration file in JSON and serialize it back into a payload
+//	GET  /healthz              liveness check
+//	GET  /metrics              metrics in the Prometheus text format
+//
+// Failed conversions respond with {"error": ..., "category": ...}; on the
+// streaming endpoint they are reported in place of the line's result.
+//
+// The gRPC service rehydrate.v1.Rehydrate exchanges JSON messages, with the
+// content subtype "json", and serves the standard health service:
+//
+//	Rehydrate        {"payload", "indent", "annotated"} to {"json"}
+//	RehydrateStream  the same, as a bidirectional stream reporting failures in place
+//	Stringify        {"json"} to {"payload"}
+//	Transform        the body of /v1/transform to {"payload"}
+//
+// An empty -grpc-addr disables it.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "HTTP listen address")
+	grpcAddr := flag.String("grpc-addr", ":9090", "gRPC listen address; empty to disable gRPC")
+	maxBytes := flag.Int64("max-bytes", 64<<20, "maximum size of a single payload")
+	memoryBudget := flag.Int64("memory-budget", 0, "maximum estimated memory of the payloads converted at once; 0 for no limit")
+	budgetWait := flag.Duration("budget-wait", 0, "how long a payload waits for room in the memory budget")
+	timeBudget := flag.Duration("time-budget", 0, "maximum time spent converting a single payload; 0 for no limit")
+	maxDepth := flag.Int("max-depth", defaultMaxDepth, "maximum nesting depth a transform config may allow; 0 for no limit")
+	flag.Parse()
+
+	s := newServer(*maxBytes)
+	if *memoryBudget > 0 {
+		s.budget = pipeline.NewMemoryBudget(*memoryBudget)
+	}
+	s.budgetWait = *budgetWait
+	s.timeBudget = *timeBudget
+	s.maxDepth = *maxDepth
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var grpcServer *grpc.Server
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		grpcServer = newGRPCServer(s)
+		go func() {
+			log.Printf("rehydrated serving gRPC on %s", *grpcAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if grpcServer != nil {
+			go func() {
+				<-shutdown.Done()
+				grpcServer.Stop()
+			}()
+			grpcServer.GracefulStop()
+		}
+		srv.Shutdown(shutdown)
+	}()
+
+	log.Printf("rehydrated listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
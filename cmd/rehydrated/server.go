@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/config"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+type server struct {
+	mux      *http.ServeMux
+	maxBytes int64
+	metrics  *metrics
+
+	// budget, if set, bounds the estimated memory of the payloads being
+	// converted. Payloads wait up to budgetWait for room before they are
+	// rejected with 429.
+	budget     *pipeline.MemoryBudget
+	budgetWait time.Duration
+	// timeBudget, if positive, limits the time spent on each payload.
+	timeBudget time.Duration
+	// maxDepth, if positive, is the deepest nesting a /v1/transform config
+	// may allow; configs asking for more, or for no limit, get maxDepth.
+	maxDepth int
+}
+
+// defaultMaxDepth is the default of -max-depth, the nesting the recursive
+// engine of the rehydrate package hydrates at most.
+const defaultMaxDepth = 10000
+
+func newServer(maxBytes int64) *server {
+	s := &server{
+		mux:      http.NewServeMux(),
+		maxBytes: maxBytes,
+		metrics:  newMetrics(),
+		maxDepth: defaultMaxDepth,
+	}
+	s.mux.HandleFunc("POST /v1/rehydrate", s.handleRehydrate)
+	s.mux.HandleFunc("POST /v1/rehydrate/stream", s.handleStream)
+	s.mux.HandleFunc("POST /v1/stringify", s.handleStringify)
+	s.mux.HandleFunc("POST /v1/transform", s.handleTransform)
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok\n")
+	})
+	s.mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		s.metrics.serveHTTP(w, r, s.budget)
+	})
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.metrics.inFlight(1)
+	defer s.metrics.inFlight(-1)
+	s.mux.ServeHTTP(rec, r)
+	s.metrics.request(route(r), rec.status)
+}
+
+// route returns the path of the pattern the mux matched r against, so
+// metrics are labelled by registered route rather than by the path clients
+// send, or "unmatched" if no route matched.
+func route(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(r.Pattern, " "); ok {
+		return path
+	}
+	return r.Pattern
+}
+
+func (s *server) handleRehydrate(w http.ResponseWriter, r *http.Request) {
+	indent := "  "
+	if v := r.URL.Query().Get("indent"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 16 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid indent %q", v))
+			return
+		}
+		indent = strings.Repeat(" ", n)
+	}
+
+	body, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	opts := []rehydrate.Option{rehydrate.WithIndent("", indent)}
+	if r.URL.Query().Has("annotated") {
+		opts = append(opts, rehydrate.WithAnnotatedOutput())
+	}
+	out, err := s.rehydrate(r.Context(), body, opts...)
+	writeResult(w, out, err)
+}
+
+// writeResult responds with out, or with err: 429 if the memory budget had
+// no room and 422 otherwise.
+func writeResult(w http.ResponseWriter, out string, err error) {
+	if errors.Is(err, pipeline.ErrOverBudget) {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, out+"\n")
+}
+
+// handleStringify serializes the annotated JSON of the request body, as
+// produced by /v1/rehydrate?annotated or WithAnnotatedOutput, into a
+// payload. Plain JSON without $type annotations is accepted as is.
+func (s *server) handleStringify(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	out, err := s.stringify(r.Context(), body)
+	writeResult(w, out, err)
+}
+
+// transformRequest is the body of /v1/transform and the request of the
+// Transform RPC.
+type transformRequest struct {
+	// Payload is the payload to transform.
+	Payload string `json:"payload"`
+	// Config holds the settings to hydrate it with, in the JSON form of a
+	// configuration file as accepted by config.ParseJSON. Profiles are
+	// ignored.
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// handleTransform hydrates a payload under the settings of a configuration,
+// such as key transforms or limits, and serializes the result back into a
+// payload.
+func (s *server) handleTransform(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	var req transformRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	out, err := s.transform(r.Context(), req)
+	writeResult(w, out, err)
+}
+
+// readBody reads the request body, responding with an error and reporting
+// false if it cannot be read or exceeds the size limit.
+func (s *server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBytes))
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err)
+		return nil, false
+	}
+	return body, true
+}
+
+// handleStream converts each line of the request body as a separate payload
+// and writes each result as soon as it is ready, so arbitrarily many payloads
+// can be converted with one request.
+func (s *server) handleStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	// HTTP/1 servers stop reading the body once the response is flushed
+	// unless told otherwise, which would cut off lines past the first read.
+	http.NewResponseController(w).EnableFullDuplex()
+
+	// The initial buffer bounds lines as well as maxBytes does, so it must
+	// not be larger.
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, min(64<<10, s.maxBytes)), int(s.maxBytes))
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		out, err := s.rehydrate(r.Context(), line, rehydrate.WithIndent("", ""))
+		if err != nil {
+			enc.Encode(errorBody(err))
+		} else {
+			io.WriteString(w, out+"\n")
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		enc.Encode(errorBody(err))
+	}
+}
+
+func (s *server) rehydrate(ctx context.Context, payload []byte, opts ...rehydrate.Option) (string, error) {
+	start := time.Now()
+	out, err := s.convert(ctx, string(payload), opts)
+	s.metrics.payload(len(payload), time.Since(start), err)
+	return out, err
+}
+
+func (s *server) convert(ctx context.Context, payload string, opts []rehydrate.Option) (string, error) {
+	release, err := s.admit(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return rehydrate.RehydrateWith(payload, nil, append(opts, s.timeLimit(time.Now()))...)
+}
+
+// stringify serializes annotated JSON, as produced by /v1/rehydrate?annotated
+// or WithAnnotatedOutput, into a payload. Plain JSON without $type
+// annotations is accepted as is.
+func (s *server) stringify(ctx context.Context, body []byte) (string, error) {
+	start := time.Now()
+	out, err := s.serialize(ctx, body)
+	s.metrics.payload(len(body), time.Since(start), err)
+	return out, err
+}
+
+func (s *server) serialize(ctx context.Context, body []byte) (string, error) {
+	release, err := s.admit(ctx, string(body))
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	start := time.Now()
+	v, err := rehydrate.UnmarshalAnnotated(body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", rehydrate.ErrInvalidInput, err)
+	}
+	return rehydrate.StringifyWithOptions(v, s.timeLimit(start))
+}
+
+// transform hydrates req.Payload with the options of req.Config and
+// serializes the result back into a payload.
+func (s *server) transform(ctx context.Context, req transformRequest) (string, error) {
+	start := time.Now()
+	out, err := s.transformPayload(ctx, req)
+	s.metrics.payload(len(req.Payload), time.Since(start), err)
+	return out, err
+}
+
+func (s *server) transformPayload(ctx context.Context, req transformRequest) (string, error) {
+	var opts []rehydrate.Option
+	if len(req.Config) > 0 {
+		c, err := config.ParseJSON(req.Config)
+		if err != nil {
+			return "", fmt.Errorf("%w: config: %w", rehydrate.ErrInvalidInput, err)
+		}
+		s.clampLimits(&c.Limits)
+		if opts, err = c.ParseOptions(); err != nil {
+			return "", fmt.Errorf("%w: config: %w", rehydrate.ErrInvalidInput, err)
+		}
+	}
+	release, err := s.admit(ctx, req.Payload)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	start := time.Now()
+	v, err := rehydrate.ParseWithOptions(req.Payload, append(opts, s.timeLimit(start))...)
+	if err != nil {
+		return "", err
+	}
+	return rehydrate.StringifyWithOptions(v, append(opts, s.timeLimit(start))...)
+}
+
+// clampLimits lowers the depth limit a client config asks for, directly or
+// through its preset, to that of the server, so that clients cannot lift it.
+// The time budget of the server replaces that of the config anyway.
+func (s *server) clampLimits(l *config.Limits) {
+	if s.maxDepth <= 0 {
+		return
+	}
+	depth := l.MaxDepth
+	if depth == 0 && l.Preset != "" {
+		depth = config.LimitPresets[l.Preset].MaxDepth
+	}
+	if depth <= 0 || depth > s.maxDepth {
+		l.MaxDepth = s.maxDepth
+	}
+}
+
+// admit reserves room for payload in the memory budget, waiting up to
+// budgetWait, and returns the function releasing it.
+func (s *server) admit(ctx context.Context, payload string) (release func(), err error) {
+	if s.budget == nil {
+		return func() {}, nil
+	}
+	n := rehydrate.EstimateMemory(payload)
+	ctx, cancel := context.WithTimeout(ctx, s.budgetWait)
+	err = s.budget.Acquire(ctx, n)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	return func() { s.budget.Release(n) }, nil
+}
+
+// timeLimit returns the option applying what is left of the time budget of
+// a payload whose conversion began at start. Once it has run out, the
+// option fails the next step at once.
+func (s *server) timeLimit(start time.Time) rehydrate.Option {
+	if s.timeBudget <= 0 {
+		return rehydrate.WithTimeBudget(0)
+	}
+	return rehydrate.WithTimeBudget(max(s.timeBudget-time.Since(start), time.Nanosecond))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody(err))
+}
+
+func errorBody(err error) map[string]string {
+	return map[string]string{"error": err.Error(), "category": errorCategory(err)}
+}
+
+func errorCategory(err error) string {
+	switch {
+	case errors.Is(err, pipeline.ErrOverBudget):
+		return "over_budget"
+	case errors.Is(err, rehydrate.ErrUnknownType):
+		return "unknown_type"
+	case errors.Is(err, rehydrate.ErrBadReference):
+		return "bad_reference"
+	case errors.Is(err, rehydrate.ErrLimitExceeded):
+		return "limit_exceeded"
+	case errors.Is(err, rehydrate.ErrInvalidInput):
+		return "invalid_input"
+	}
+	return "error"
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// metrics collects counters exposed in the Prometheus text format.
+type metrics struct {
+	mu       sync.Mutex
+	requests map[string]int // keyed by "route\x00code"
+	rpcs     map[string]int // keyed by "method\x00code"
+	payloads map[string]int // keyed by result category
+	bytes    int64
+	seconds  float64
+	inflight int
+}
+
+func newMetrics() *metrics {
+	return &metrics{requests: make(map[string]int), rpcs: make(map[string]int), payloads: make(map[string]int)}
+}
+
+func (m *metrics) inFlight(delta int) {
+	m.mu.Lock()
+	m.inflight += delta
+	m.mu.Unlock()
+}
+
+func (m *metrics) request(path string, status int) {
+	m.mu.Lock()
+	m.requests[path+"\x00"+strconv.Itoa(status)]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) rpc(method string, code codes.Code) {
+	m.mu.Lock()
+	m.rpcs[method+"\x00"+code.String()]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) payload(size int, elapsed time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = errorCategory(err)
+	}
+	m.mu.Lock()
+	m.payloads[result]++
+	m.bytes += int64(size)
+	m.seconds += elapsed.Seconds()
+	m.mu.Unlock()
+}
+
+func (m *metrics) serveHTTP(w http.ResponseWriter, r *http.Request, budget *pipeline.MemoryBudget) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP rehydrated_requests_total HTTP requests by path and status code.")
+	fmt.Fprintln(w, "# TYPE rehydrated_requests_total counter")
+	for _, key := range sortedCounterKeys(m.requests) {
+		path, code, _ := strings.Cut(key, "\x00")
+		fmt.Fprintf(w, "rehydrated_requests_total{path=\"%s\",code=\"%s\"} %d\n", labelValue(path), labelValue(code), m.requests[key])
+	}
+	fmt.Fprintln(w, "# HELP rehydrated_grpc_requests_total gRPC calls by method and status code.")
+	fmt.Fprintln(w, "# TYPE rehydrated_grpc_requests_total counter")
+	for _, key := range sortedCounterKeys(m.rpcs) {
+		method, code, _ := strings.Cut(key, "\x00")
+		fmt.Fprintf(w, "rehydrated_grpc_requests_total{method=\"%s\",code=\"%s\"} %d\n", labelValue(method), labelValue(code), m.rpcs[key])
+	}
+	fmt.Fprintln(w, "# HELP rehydrated_payloads_total Converted payloads by result.")
+	fmt.Fprintln(w, "# TYPE rehydrated_payloads_total counter")
+	for _, key := range sortedCounterKeys(m.payloads) {
+		fmt.Fprintf(w, "rehydrated_payloads_total{result=\"%s\"} %d\n", labelValue(key), m.payloads[key])
+	}
+	fmt.Fprintln(w, "# HELP rehydrated_payload_bytes_total Size of all converted payloads.")
+	fmt.Fprintln(w, "# TYPE rehydrated_payload_bytes_total counter")
+	fmt.Fprintf(w, "rehydrated_payload_bytes_total %d\n", m.bytes)
+	fmt.Fprintln(w, "# HELP rehydrated_conversion_seconds_total Time spent converting payloads.")
+	fmt.Fprintln(w, "# TYPE rehydrated_conversion_seconds_total counter")
+	fmt.Fprintf(w, "rehydrated_conversion_seconds_total %g\n", m.seconds)
+	fmt.Fprintln(w, "# HELP rehydrated_requests_in_flight HTTP requests and gRPC calls currently being served.")
+	fmt.Fprintln(w, "# TYPE rehydrated_requests_in_flight gauge")
+	fmt.Fprintf(w, "rehydrated_requests_in_flight %d\n", m.inflight)
+	if budget != nil {
+		fmt.Fprintln(w, "# HELP rehydrated_memory_reserved_bytes Estimated memory of the payloads being converted.")
+		fmt.Fprintln(w, "# TYPE rehydrated_memory_reserved_bytes gauge")
+		fmt.Fprintf(w, "rehydrated_memory_reserved_bytes %d\n", budget.InUse())
+	}
+}
+
+// labelValue escapes a label value as the Prometheus text format requires.
+var labelValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
+
+func sortedCounterKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
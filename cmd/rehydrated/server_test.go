@@ -0,0 +1,292 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+func TestServer(t *testing.T) {
+	ts := httptest.NewServer(newServer(1 << 20))
+	defer ts.Close()
+
+	post := func(path, body string) (int, string) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var sb strings.Builder
+		if _, err := io.Copy(&sb, resp.Body); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, sb.String()
+	}
+
+	if code, body := post("/v1/rehydrate?indent=0", `[{"a":1},"x"]`); code != 200 || body != `{"a":"x"}`+"\n" {
+		t.Errorf("rehydrate: %d %q", code, body)
+	}
+	if code, body := post("/v1/rehydrate", `[["Widget",0]]`); code != 422 || !strings.Contains(body, `"category":"unknown_type"`) {
+		t.Errorf("rehydrate error: %d %q", code, body)
+	}
+
+	code, body := post("/v1/rehydrate/stream", "[{\"a\":1},1]\n\n[[\"Widget\",0]]\n[2]\n")
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if code != 200 || len(lines) != 3 || lines[0] != `{"a":1}` || !strings.Contains(lines[1], "unknown_type") || lines[2] != "2" {
+		t.Errorf("stream: %d %q", code, body)
+	}
+
+	if code, _ := post("/v1/bogus%22%0A", ""); code != 404 {
+		t.Errorf("unknown path: %d", code)
+	}
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var sb strings.Builder
+	io.Copy(&sb, resp.Body)
+	for _, want := range []string{
+		`rehydrated_payloads_total{result="ok"} 3`,
+		`rehydrated_payloads_total{result="unknown_type"} 2`,
+		`rehydrated_requests_total{path="/v1/rehydrate",code="422"} 1`,
+		`rehydrated_requests_total{path="/v1/rehydrate/stream",code="200"} 1`,
+		`rehydrated_requests_total{path="unmatched",code="404"} 1`,
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, sb.String())
+		}
+	}
+	if strings.Contains(sb.String(), "bogus") {
+		t.Errorf("metrics label a path clients sent:\n%s", sb.String())
+	}
+}
+
+func TestServerCycle(t *testing.T) {
+	ts := httptest.NewServer(newServer(1 << 20))
+	defer ts.Close()
+
+	for _, path := range []string{"/v1/rehydrate", "/v1/rehydrate/stream"} {
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader("[[0]]"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), `"category":"invalid_input"`) {
+			t.Errorf("%s: %d %s", path, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestServerStringify(t *testing.T) {
+	ts := httptest.NewServer(newServer(1 << 20))
+	defer ts.Close()
+
+	post := func(path, body string) (int, string) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(out)
+	}
+
+	payload := `[{"tags":1,"when":3},["Set",2],"a",["Date","2024-01-02T00:00:00.000Z"]]`
+	code, annotated := post("/v1/rehydrate?annotated&indent=0", payload)
+	if code != 200 {
+		t.Fatalf("rehydrate: %d %s", code, annotated)
+	}
+	if code, body := post("/v1/stringify", annotated); code != 200 || body != payload+"\n" {
+		t.Errorf("stringify: %d %q, want %q", code, body, payload)
+	}
+	if code, body := post("/v1/stringify", `{"a":[1,2]}`); code != 200 || body != `[{"a":1},[2,3],1,2]`+"\n" {
+		t.Errorf("stringify plain JSON: %d %q", code, body)
+	}
+	if code, body := post("/v1/stringify", `{"a":`); code != 422 || !strings.Contains(body, "invalid_input") {
+		t.Errorf("stringify invalid JSON: %d %q", code, body)
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	if got, want := labelValue("a\\b\"c\nd"), `a\\b\"c\nd`; got != want {
+		t.Errorf("labelValue = %s, want %s", got, want)
+	}
+}
+
+func TestServerMemoryBudget(t *testing.T) {
+	s := newServer(1 << 20)
+	s.budget = pipeline.NewMemoryBudget(rehydrate.EstimateMemory(`[{"a":1},"x"]`))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	post := func(body string) *http.Response {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/v1/rehydrate", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post(`[{"a":1},"x"]`); resp.StatusCode != 200 {
+		t.Errorf("within budget: %d", resp.StatusCode)
+	}
+	if resp := post(`[{"a":1},"` + strings.Repeat("x", 100) + `"]`); resp.StatusCode != 429 || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("over budget: %d", resp.StatusCode)
+	}
+
+	// A payload waiting for room is rejected once budgetWait elapses.
+	s.budgetWait = 10 * time.Millisecond
+	s.budget.TryAcquire(1)
+	if resp := post(`[{"a":1},"x"]`); resp.StatusCode != 429 {
+		t.Errorf("budget in use: %d", resp.StatusCode)
+	}
+	s.budget.Release(1)
+	if resp := post(`[{"a":1},"x"]`); resp.StatusCode != 200 {
+		t.Errorf("budget released: %d", resp.StatusCode)
+	}
+}
+
+func TestServerTransform(t *testing.T) {
+	ts := httptest.NewServer(newServer(1 << 20))
+	defer ts.Close()
+
+	for _, c := range []struct {
+		body string
+		code int
+		want string
+	}{
+		{`{"payload":"[{\"first_name\":1},\"Ada\"]","config":{"keys":{"transform":["camel_case"]}}}`, 200, `[{"firstName":1},"Ada"]` + "\n"},
+		{`{"payload":"[{\"a\":1},2]"}`, 200, `[{"a":1},2]` + "\n"},
+		{`{"payload":"[{\"a\":1},2]","config":{"keys":{"transform":["bogus"]}}}`, 422, "invalid_input"},
+		{`{"payload":`, 400, ""},
+	} {
+		resp, err := http.Post(ts.URL+"/v1/transform", "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.code || !strings.Contains(string(body), c.want) {
+			t.Errorf("%s: %d %q", c.body, resp.StatusCode, body)
+		}
+	}
+}
+
+// TestServerTransformLimits checks that client configs cannot lift the
+// server's depth limit.
+func TestServerTransformLimits(t *testing.T) {
+	s := newServer(64 << 20)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	post := func(payload, limits string) (int, string) {
+		body := `{"payload":` + strconv.Quote(payload) + `,"config":{"limits":` + limits + `}}`
+		resp, err := http.Post(ts.URL+"/v1/transform", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(out)
+	}
+
+	// Arrays nested a million levels deep, which the recursive engine
+	// WithArraySampling selects cannot hydrate without a limit.
+	var deep strings.Builder
+	deep.WriteByte('[')
+	for i := 1; i <= 1_000_000; i++ {
+		deep.WriteString("[" + strconv.Itoa(i) + "],")
+	}
+	deep.WriteString("0]")
+	if code, out := post(deep.String(), `{"maxDepth":1073741824,"arraySampling":10}`); code != 422 || !strings.Contains(out, "limit_exceeded") {
+		t.Errorf("deep payload: %d %.200s", code, out)
+	}
+
+	s.maxDepth = 2
+	nested := `[[1],[2],0]`
+	for _, limits := range []string{`{"maxDepth":5}`, `{}`, `{"preset":"forensic"}`} {
+		if code, out := post(nested, limits); code != 422 || !strings.Contains(out, "limit_exceeded") {
+			t.Errorf("%s: %d %s", limits, code, out)
+		}
+	}
+	s.maxDepth = 3
+	if code, out := post(nested, `{"maxDepth":5}`); code != 200 {
+		t.Errorf("within the server's limit: %d %s", code, out)
+	}
+}
+
+// TestServerBudgets checks that every endpoint applies the memory and time
+// budgets.
+func TestServerBudgets(t *testing.T) {
+	s := newServer(1 << 20)
+	s.budget = pipeline.NewMemoryBudget(rehydrate.EstimateMemory(`[{"a":1},"x"]`))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	large := strings.Repeat("x", 100)
+	for path, body := range map[string]string{
+		"/v1/rehydrate": `[{"a":1},"` + large + `"]`,
+		"/v1/stringify": `"` + large + `"`,
+		"/v1/transform": `{"payload":"[{\"a\":1},\"` + large + `\"]"}`,
+	} {
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 429 {
+			t.Errorf("%s over memory budget: %d", path, resp.StatusCode)
+		}
+	}
+
+	s.budget = nil
+	s.timeBudget = time.Nanosecond
+	items := strings.Repeat(`{"a":1},`, 5000)
+	for path, body := range map[string]string{
+		"/v1/stringify": `[` + items + `{}]`,
+		"/v1/transform": `{"payload":` + strconv.Quote(`[[`+strings.Repeat("1,", 5000)+`1],{"a":2},3]`) + `}`,
+	} {
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 422 || !strings.Contains(string(out), "budget") {
+			t.Errorf("%s over time budget: %d %s", path, resp.StatusCode, out)
+		}
+	}
+}
+
+// TestServerStreamSmallLimit checks that the streaming endpoint enforces a
+// -max-bytes below the scanner's default buffer size.
+func TestServerStreamSmallLimit(t *testing.T) {
+	ts := httptest.NewServer(newServer(16))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/rehydrate/stream", "application/json",
+		strings.NewReader("[1]\n[{\"a\":1},\""+strings.Repeat("x", 100)+"\"]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 2 || lines[0] != "1" || !strings.Contains(lines[1], "too long") {
+		t.Errorf("stream: %q", body)
+	}
+}
@@ -0,0 +1,494 @@
+package rehydrate
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Annotated JSON is plain JSON in which values without a JSON counterpart are
+// wrapped in objects naming their type, so the output survives JSON-only
+// storage and can be turned back into the same values with
+// UnmarshalAnnotated:
+//
+//	{"$type": "Date", "value": "2024-01-02T03:04:05Z"}
+//	{"$type": "Set", "values": [...]}
+//	{"$type": "Map", "entries": [[key, value], ...]}
+//	{"$type": "BigInt", "value": "123"}
+//	{"$type": "RegExp", "source": "a+", "flags": "gi"}
+//	{"$type": "Binary", "kind": "Uint8Array", "value": "<base64>"} // or ArrayBuffer
+//	{"$type": "Int16Array", "value": [1, -2]}  // and the other typed arrays
+//	{"$type": "Number", "value": "NaN"}     // also Infinity, -Infinity and -0
+//	{"$type": "Sample", "length": 100000, "indices": [...], "values": [...]}
+//	{"$type": "Truncated", "length": 5000, "value": "prefix"}
+//	{"$type": "LazyRef", "index": 42}
+//	{"$type": "Wrapped", "kind": "Number", "value": 5}
+//	{"$type": "Ref", "id": 3}
+//
+// The elements of typed arrays are numbers, Number annotations for NaN and
+// the infinities, and decimal strings for BigInt64Array and BigUint64Array.
+// A Ref stands for a container that occurred before: containers (objects,
+// arrays, Sets, Maps and samples) are numbered from 0 in the order they appear in the
+// document, so shared and cyclic references are preserved. Object keys
+// starting with "$" are escaped by doubling the "$".
+const annotationKey = "$type"
+
+// WithAnnotatedOutput makes RehydrateWith render annotated JSON instead of
+// plain JSON. See MarshalAnnotated.
+func WithAnnotatedOutput() Option {
+	return func(o *options) {
+		o.annotated = true
+	}
+}
+
+// MarshalAnnotated renders a hydrated value as annotated JSON, keeping the
+// type information plain JSON would drop.
+func MarshalAnnotated(v interface{}) ([]byte, error) {
+	tree, err := annotate(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+func annotate(v interface{}) (interface{}, error) {
+	a := &annotator{ids: make(map[uintptr]int)}
+	return a.annotate(v)
+}
+
+type annotator struct {
+	ids  map[uintptr]int
+	next int
+}
+
+func typed(tag string, fields ...interface{}) map[string]interface{} {
+	m := map[string]interface{}{annotationKey: tag}
+	for i := 0; i+1 < len(fields); i += 2 {
+		m[fields[i].(string)] = fields[i+1]
+	}
+	return m
+}
+
+// container returns a Ref annotation if v was annotated before, and
+// otherwise numbers it.
+func (a *annotator) container(v interface{}) map[string]interface{} {
+	id, ok := containerID(v)
+	if ok {
+		if n, seen := a.ids[id]; seen {
+			return typed("Ref", "id", n)
+		}
+		a.ids[id] = a.next
+	}
+	a.next++
+	return nil
+}
+
+func (a *annotator) annotate(v interface{}) (interface{}, error) {
+	if obj, ok := v.(NullObject); ok {
+		v = map[string]interface{}(obj)
+	}
+	switch value := v.(type) {
+	case float64:
+		switch {
+		case math.IsNaN(value):
+			return typed("Number", "value", "NaN"), nil
+		case math.IsInf(value, 1):
+			return typed("Number", "value", "Infinity"), nil
+		case math.IsInf(value, -1):
+			return typed("Number", "value", "-Infinity"), nil
+		case value == 0 && math.Signbit(value):
+			return typed("Number", "value", "-0"), nil
+		}
+		return value, nil
+	case nil, bool, string, UTF16String:
+		return value, nil
+	case time.Time:
+		return typed("Date", "value", value.Format(time.RFC3339Nano)), nil
+	case []byte:
+		return typed("Binary", "kind", TagUint8Array.String(), "value", base64.StdEncoding.EncodeToString(value)), nil
+	case ArrayBuffer:
+		return typed("Binary", "kind", TagArrayBuffer.String(), "value", base64.StdEncoding.EncodeToString(value)), nil
+	case *LazyRef:
+		return typed("LazyRef", "index", value.Index), nil
+	case *Truncated:
+		prefix, err := a.annotate(value.Value)
+		if err != nil {
+			return nil, err
+		}
+		return typed("Truncated", "length", value.Length, "value", prefix), nil
+	case *Wrapped:
+		primitive, err := a.annotate(value.Value)
+		if err != nil {
+			return nil, err
+		}
+		return typed("Wrapped", "kind", value.Kind, "value", primitive), nil
+	case []interface{}:
+		if ref := a.container(value); ref != nil {
+			return ref, nil
+		}
+		return a.annotateSlice(value)
+	case map[string]interface{}:
+		if ref := a.container(value); ref != nil {
+			return ref, nil
+		}
+		escaped := make(map[string]string, len(value))
+		for key := range value {
+			if strings.HasPrefix(key, "$") {
+				escaped["$"+key] = key
+			} else {
+				escaped[key] = key
+			}
+		}
+		// Visit keys in the order json.Marshal writes them, so containers are
+		// numbered in document order.
+		keys := make([]string, 0, len(escaped))
+		for key := range escaped {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := make(map[string]interface{}, len(value))
+		for _, key := range keys {
+			item, err := a.annotate(value[escaped[key]])
+			if err != nil {
+				return nil, err
+			}
+			out[key] = item
+		}
+		return out, nil
+	case *Set:
+		if ref := a.container(value); ref != nil {
+			return ref, nil
+		}
+		values, err := a.annotateSlice(value.Values())
+		if err != nil {
+			return nil, err
+		}
+		return typed("Set", "values", values), nil
+	case *SampledArray:
+		if ref := a.container(value); ref != nil {
+			return ref, nil
+		}
+		values, err := a.annotateSlice(value.Values)
+		if err != nil {
+			return nil, err
+		}
+		return typed("Sample", "length", value.Length, "indices", value.Indices, "values", values), nil
+	case *OrderedMap:
+		if ref := a.container(value); ref != nil {
+			return ref, nil
+		}
+		entries := make([]interface{}, 0, value.Len())
+		for _, e := range value.Entries() {
+			key, err := a.annotate(e.Key)
+			if err != nil {
+				return nil, err
+			}
+			item, err := a.annotate(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, []interface{}{key, item})
+		}
+		return typed("Map", "entries", entries), nil
+	}
+	if digits, ok := bigIntDigits(v); ok {
+		return typed("BigInt", "value", digits), nil
+	}
+	if source, flags, ok := regExpSource(v); ok {
+		return typed("RegExp", "source", source, "flags", flags), nil
+	}
+	if items, ok := typedArrayElements(v); ok {
+		return a.annotateTypedArray(v, items)
+	}
+	return nil, fmt.Errorf("%w: cannot annotate value of type %T", ErrInvalidInput, v)
+}
+
+// annotateTypedArray annotates the typed numeric slice v, whose elements,
+// as returned by typedArrayElements, are items. A []*big.Int is written as
+// a BigInt64Array unless an element only fits a BigUint64Array.
+func (a *annotator) annotateTypedArray(v interface{}, items []interface{}) (interface{}, error) {
+	tag, ok := typedArrayTag(v)
+	if !ok {
+		tag = TagBigInt64Array
+		for _, item := range items {
+			if _, err := strconv.ParseInt(item.(string), 10, 64); err != nil {
+				tag = TagBigUint64Array
+				break
+			}
+		}
+	}
+	out := make([]interface{}, len(items))
+	for i, item := range items {
+		if f, ok := item.(float32); ok {
+			item = float64(f)
+		}
+		annotated, err := a.annotate(item)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = annotated
+	}
+	return typed(tag.String(), "value", out), nil
+}
+
+func (a *annotator) annotateSlice(values []interface{}) ([]interface{}, error) {
+	out := make([]interface{}, len(values))
+	for i, item := range values {
+		annotated, err := a.annotate(item)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = annotated
+	}
+	return out, nil
+}
+
+// UnmarshalAnnotated parses annotated JSON produced by MarshalAnnotated or
+// WithAnnotatedOutput back into the values Parse returns.
+func UnmarshalAnnotated(data []byte) (interface{}, error) {
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	u := &unannotator{}
+	return u.value(tree)
+}
+
+type unannotator struct {
+	containers []interface{}
+}
+
+func (u *unannotator) value(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		u.containers = append(u.containers, out)
+		for i, item := range value {
+			elem, err := u.value(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = elem
+		}
+		return out, nil
+	case map[string]interface{}:
+		if tag, ok := value[annotationKey].(string); ok {
+			return u.typed(tag, value)
+		}
+		out := make(map[string]interface{}, len(value))
+		u.containers = append(u.containers, out)
+		for _, key := range sortedKeys(value) {
+			item, err := u.value(value[key])
+			if err != nil {
+				return nil, err
+			}
+			out[strings.TrimPrefix(key, "$")] = item
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func (u *unannotator) typed(tag string, fields map[string]interface{}) (interface{}, error) {
+	str, _ := fields["value"].(string)
+	invalid := func(cause error) error {
+		if cause != nil {
+			return fmt.Errorf("%w: %s annotation: %w", ErrInvalidInput, tag, cause)
+		}
+		return fmt.Errorf("%w: malformed %s annotation", ErrInvalidInput, tag)
+	}
+
+	switch tag {
+	case "Date":
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return t, nil
+	case "BigInt":
+		n, ok := parseBigInt(str)
+		if !ok {
+			return nil, invalid(nil)
+		}
+		return n, nil
+	case "RegExp":
+		source, _ := fields["source"].(string)
+		flags, _ := fields["flags"].(string)
+		re, err := compileRegExp(source, flags)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return re, nil
+	case "Binary":
+		data, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		if fields["kind"] == TagArrayBuffer.String() {
+			return ArrayBuffer(data), nil
+		}
+		return data, nil
+	case "Number":
+		switch str {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		case "-0":
+			return math.Copysign(0, -1), nil
+		}
+		return nil, invalid(nil)
+	case "Ref":
+		id, ok := fields["id"].(float64)
+		if !ok || id < 0 || int(id) >= len(u.containers) || id != math.Trunc(id) {
+			return nil, fmt.Errorf("%w: unknown container %v", ErrBadReference, fields["id"])
+		}
+		return u.containers[int(id)], nil
+	case "Set":
+		items, ok := fields["values"].([]interface{})
+		if !ok {
+			return nil, invalid(nil)
+		}
+		set := NewSet()
+		u.containers = append(u.containers, set)
+		for _, item := range items {
+			elem, err := u.value(item)
+			if err != nil {
+				return nil, err
+			}
+			set.Add(elem)
+		}
+		return set, nil
+	case "LazyRef":
+		index, ok := fields["index"].(float64)
+		if !ok {
+			return nil, invalid(nil)
+		}
+		return &LazyRef{Index: int(index)}, nil
+	case "Truncated":
+		length, _ := fields["length"].(float64)
+		prefix, err := u.value(fields["value"])
+		if err != nil {
+			return nil, err
+		}
+		switch prefix.(type) {
+		case string, []byte:
+			return &Truncated{Value: prefix, Length: int(length)}, nil
+		}
+		if _, ok := typedArrayTag(prefix); ok {
+			return &Truncated{Value: prefix, Length: int(length)}, nil
+		}
+		return nil, invalid(nil)
+	case "Wrapped":
+		kind, _ := fields["kind"].(string)
+		primitive, err := u.value(fields["value"])
+		if err != nil {
+			return nil, err
+		}
+		_, isBigInt := bigIntDigits(primitive)
+		if w := wrapLiteral(primitive); (w != nil && w.Kind == kind) || (isBigInt && kind == "BigInt") {
+			return &Wrapped{Kind: kind, Value: primitive}, nil
+		}
+		return nil, invalid(nil)
+	case "Sample":
+		length, _ := fields["length"].(float64)
+		indices, ok1 := fields["indices"].([]interface{})
+		items, ok2 := fields["values"].([]interface{})
+		if !ok1 || !ok2 || len(indices) != len(items) {
+			return nil, invalid(nil)
+		}
+		sample := &SampledArray{Length: int(length), Indices: make([]int, len(indices)), Values: make([]interface{}, len(items))}
+		u.containers = append(u.containers, sample)
+		for i, item := range items {
+			pos, ok := indices[i].(float64)
+			if !ok {
+				return nil, invalid(nil)
+			}
+			elem, err := u.value(item)
+			if err != nil {
+				return nil, err
+			}
+			sample.Indices[i] = int(pos)
+			sample.Values[i] = elem
+		}
+		return sample, nil
+	case "Map":
+		entries, ok := fields["entries"].([]interface{})
+		if !ok {
+			return nil, invalid(nil)
+		}
+		m := NewOrderedMap()
+		u.containers = append(u.containers, m)
+		for _, entry := range entries {
+			pair, ok := entry.([]interface{})
+			if !ok || len(pair) != 2 {
+				return nil, invalid(nil)
+			}
+			key, err := u.value(pair[0])
+			if err != nil {
+				return nil, err
+			}
+			item, err := u.value(pair[1])
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, item)
+		}
+		return m, nil
+	}
+	if t, ok := ParseTag(tag); ok && t.IsBinary() && t != TagArrayBuffer {
+		items, ok := fields["value"].([]interface{})
+		if !ok {
+			return nil, invalid(nil)
+		}
+		v, err := u.typedArray(t, items)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("%w: annotation %q", ErrUnknownType, tag)
+}
+
+// typedArray decodes the elements of a typed array annotation.
+func (u *unannotator) typedArray(tag Tag, items []interface{}) (interface{}, error) {
+	if tag == TagBigInt64Array || tag == TagBigUint64Array {
+		words := make([]uint64, len(items))
+		for i, item := range items {
+			s, _ := item.(string)
+			var err error
+			if tag == TagBigInt64Array {
+				var n int64
+				n, err = strconv.ParseInt(s, 10, 64)
+				words[i] = uint64(n)
+			} else {
+				words[i], err = strconv.ParseUint(s, 10, 64)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return bigInt64s(words, tag == TagBigInt64Array), nil
+	}
+	numbers := make([]interface{}, len(items))
+	for i, item := range items {
+		n, err := u.value(item)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = n
+	}
+	data, err := typedArrayBytes(tag.String(), numbers)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTypedArray(tag, data, binary.LittleEndian)
+}
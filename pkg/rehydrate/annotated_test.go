@@ -0,0 +1,117 @@
+//go:build !rehydrate_min
+
+package rehydrate_test
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestAnnotatedRoundTrip(t *testing.T) {
+	input := `[{"when":1,"tags":2,"prices":4,"n":6,"nan":-3,"zero":-6,"bytes":7,"$type":3,"self":0,"again":2},` +
+		`["Date","2024-01-02T03:04:05Z"],["Set",3],"x",["Map",5,3],1,["BigInt","12345678901234567890"],["Uint8Array","AQI="]]`
+
+	out, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithAnnotatedOutput(), rehydrate.WithIndent("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := rehydrate.UnmarshalAnnotated([]byte(out))
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	root := v.(map[string]interface{})
+
+	if got := root["when"].(time.Time); !got.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("when = %v", got)
+	}
+	tags := root["tags"].(*rehydrate.Set)
+	if !tags.Has("x") || root["again"] != tags {
+		t.Errorf("tags = %v, again = %v", tags, root["again"])
+	}
+	if got, _ := root["prices"].(*rehydrate.OrderedMap).Get(1.0); got != "x" {
+		t.Errorf("prices[1] = %v", got)
+	}
+	if got := root["n"].(*big.Int); got.String() != "12345678901234567890" {
+		t.Errorf("n = %v", got)
+	}
+	if !math.IsNaN(root["nan"].(float64)) || !math.Signbit(root["zero"].(float64)) {
+		t.Errorf("nan = %v, zero = %v", root["nan"], root["zero"])
+	}
+	if got := root["bytes"].([]byte); string(got) != "\x01\x02" {
+		t.Errorf("bytes = %v", got)
+	}
+	if root["$type"] != "x" {
+		t.Errorf("$type = %v", root["$type"])
+	}
+	if self := root["self"].(map[string]interface{}); self["$type"] != "x" {
+		t.Errorf("self is not the root: %v", self)
+	}
+}
+
+func TestAnnotatedTypedArrays(t *testing.T) {
+	large, _ := new(big.Int).SetString("18446744073709551615", 10)
+	values := []interface{}{
+		[]byte{1, 2},
+		rehydrate.ArrayBuffer{3},
+		[]int8{-1},
+		[]int16{1, -2},
+		[]uint32{math.MaxUint32},
+		[]float32{0.1, float32(math.Inf(-1))},
+		[]float64{math.Inf(1), -0.5},
+		[]*big.Int{big.NewInt(-1), big.NewInt(2)},
+		[]*big.Int{large},
+	}
+	for _, v := range values {
+		data, err := rehydrate.MarshalAnnotated(v)
+		if err != nil {
+			t.Errorf("%#v: %v", v, err)
+			continue
+		}
+		got, err := rehydrate.UnmarshalAnnotated(data)
+		if err != nil || !reflect.DeepEqual(got, v) {
+			t.Errorf("%s: got %#v, %v, want %#v", data, got, err, v)
+		}
+	}
+
+	data, err := rehydrate.MarshalAnnotated([]int16{1, -2})
+	if want := `{"$type":"Int16Array","value":[1,-2]}`; err != nil || string(data) != want {
+		t.Errorf("got %s, %v, want %s", data, err, want)
+	}
+	out, err := rehydrate.RehydrateWith(`[["Float64Array","AAAAAAAA+H8="]]`, nil, rehydrate.WithAnnotatedOutput(), rehydrate.WithIndent("", ""))
+	if want := `{"$type":"Float64Array","value":[{"$type":"Number","value":"NaN"}]}`; err != nil || out != want {
+		t.Errorf("WithAnnotatedOutput: got %s, %v, want %s", out, err, want)
+	}
+}
+
+func TestAnnotatedRegExpFlags(t *testing.T) {
+	out, err := rehydrate.RehydrateWith(`[["RegExp","a+","gi"]]`, nil, rehydrate.WithAnnotatedOutput(), rehydrate.WithIndent("", ""))
+	if want := `{"$type":"RegExp","flags":"gi","source":"a+"}`; err != nil || out != want {
+		t.Fatalf("got %s, %v, want %s", out, err, want)
+	}
+	v, err := rehydrate.UnmarshalAnnotated([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re := v.(*rehydrate.RegExp); re.String() != "/a+/gi" || !re.Regexp.MatchString("AA") {
+		t.Errorf("got %v", re)
+	}
+}
+
+func TestUnmarshalAnnotatedErrors(t *testing.T) {
+	tests := map[string]error{
+		`{"$type":"Widget"}`:           rehydrate.ErrUnknownType,
+		`[{"$type":"Ref","id":1}]`:     rehydrate.ErrBadReference,
+		`{"$type":"Date","value":"x"}`: rehydrate.ErrInvalidInput,
+	}
+	for input, want := range tests {
+		if _, err := rehydrate.UnmarshalAnnotated([]byte(input)); !errors.Is(err, want) {
+			t.Errorf("%s: got %v, want %v", input, err, want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+//go:build !rehydrate_min
+
+package rehydrate
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Detector recognises one kind of sensitive data inside string values.
+type Detector struct {
+	// Name identifies the detector and is used in the default mask.
+	Name string
+	// Pattern matches candidate substrings.
+	Pattern *regexp.Regexp
+	// Validate, if set, rejects candidates that merely look sensitive, such
+	// as digit runs failing a checksum.
+	Validate func(match string) bool
+	// Mask, if set, returns the replacement for a match. By default matches
+	// are replaced with "[Name]".
+	Mask func(match string) string
+}
+
+func (d Detector) replace(s string) string {
+	return d.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+		if d.Validate != nil && !d.Validate(match) {
+			return match
+		}
+		if d.Mask != nil {
+			return d.Mask(match)
+		}
+		return "[" + d.Name + "]"
+	})
+}
+
+// DefaultDetectors returns detectors for e-mail addresses, JSON Web Tokens,
+// payment card numbers and phone numbers, in the order they are applied.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		{
+			Name:    "email",
+			Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		},
+		{
+			Name:    "jwt",
+			Pattern: regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`),
+		},
+		{
+			Name:     "card",
+			Pattern:  regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+			Validate: luhnValid,
+		},
+		{
+			Name:    "phone",
+			Pattern: regexp.MustCompile(`\+?\(?\d{1,4}\)?(?:[ .\-]?\(?\d{2,4}\)?){2,4}\d`),
+			Validate: func(match string) bool {
+				digits := 0
+				for _, r := range match {
+					if r >= '0' && r <= '9' {
+						digits++
+					}
+				}
+				return digits >= 7 && digits <= 15
+			},
+		},
+	}
+}
+
+// Anonymize returns a copy of the hydrated value v in which every string,
+// including object keys, Map keys and Set elements, has the matches of
+// detectors masked. Without detectors, DefaultDetectors is used. Shared and
+// cyclic values keep their shape in the copy.
+func Anonymize(v interface{}, detectors ...Detector) interface{} {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+	return transformStrings(v, func(s string) string {
+		for _, d := range detectors {
+			s = d.replace(s)
+		}
+		return s
+	})
+}
+
+func luhnValid(match string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, match)
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return len(digits) >= 13 && sum%10 == 0
+}
+
+// transformStrings returns a copy of v with fn applied to every string it
+// contains. Containers are copied at most once, so sharing and cycles in v
+// are reproduced in the result.
+func transformStrings(v interface{}, fn func(string) string) interface{} {
+	t := &stringTransformer{fn: fn, seen: make(map[uintptr]interface{})}
+	return t.transform(v)
+}
+
+type stringTransformer struct {
+	fn   func(string) string
+	seen map[uintptr]interface{}
+}
+
+func (t *stringTransformer) transform(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return t.fn(value)
+	case UTF16String:
+		return t.fn(value.String())
+	case []interface{}:
+		if len(value) == 0 {
+			return value
+		}
+		id := reflect.ValueOf(value).Pointer()
+		if copied, ok := t.seen[id]; ok {
+			return copied
+		}
+		arr := make([]interface{}, len(value))
+		t.seen[id] = arr
+		for i, item := range value {
+			arr[i] = t.transform(item)
+		}
+		return arr
+	case map[string]interface{}:
+		id := reflect.ValueOf(value).Pointer()
+		if copied, ok := t.seen[id]; ok {
+			return copied
+		}
+		m := make(map[string]interface{}, len(value))
+		t.seen[id] = m
+		for key, item := range value {
+			m[t.fn(key)] = t.transform(item)
+		}
+		return m
+	case *OrderedMap:
+		id := reflect.ValueOf(value).Pointer()
+		if copied, ok := t.seen[id]; ok {
+			return copied
+		}
+		m := NewOrderedMap()
+		t.seen[id] = m
+		for _, e := range value.entries {
+			m.set(t.transform(e.Key), e.KeyRef, t.transform(e.Value))
+		}
+		return m
+	case *Set:
+		id := reflect.ValueOf(value).Pointer()
+		if copied, ok := t.seen[id]; ok {
+			return copied
+		}
+		set := NewSet()
+		t.seen[id] = set
+		for _, e := range value.m.entries {
+			set.add(t.transform(e.Key), e.KeyRef)
+		}
+		return set
+	default:
+		return v
+	}
+}
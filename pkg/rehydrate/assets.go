@@ -0,0 +1,208 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// AssetKind classifies an AssetRef.
+type AssetKind int
+
+const (
+	// AssetBinary: a typed array or ArrayBuffer holding media data.
+	AssetBinary AssetKind = iota
+	// AssetDataURI: a string holding a data: URI.
+	AssetDataURI
+	// AssetURL: a URL, or a string holding one, whose path has a media file
+	// extension such as .png or .mp4.
+	AssetURL
+)
+
+var assetKindNames = [...]string{
+	AssetBinary:  "binary",
+	AssetDataURI: "data-uri",
+	AssetURL:     "url",
+}
+
+func (k AssetKind) String() string {
+	if k < 0 || int(k) >= len(assetKindNames) {
+		return ""
+	}
+	return assetKindNames[k]
+}
+
+// MarshalText renders the kind by name.
+func (k AssetKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// AssetRef is an image or other media asset found by ExtractAssets.
+type AssetRef struct {
+	// Path is the path of the value, empty for the root.
+	Path string    `json:"path"`
+	Kind AssetKind `json:"kind"`
+	// MediaType is the media type, such as image/png: detected from the
+	// content of binary data, declared by data: URIs and derived from the
+	// extension of URLs. It is empty if unknown.
+	MediaType string `json:"mediaType,omitempty"`
+	// URL is the URL of AssetURL references, as written in the payload.
+	URL string `json:"url,omitempty"`
+	// Data is the content of AssetBinary and AssetDataURI references; the
+	// kept prefix of binary data truncated by WithMaxBinaryInline.
+	Data []byte `json:"-"`
+	// Size is the length in bytes of the content, before any truncation.
+	Size int `json:"size,omitempty"`
+}
+
+// ExtractAssets returns the media assets referenced by the hydrated value v,
+// in path order:
+//
+//   - binary data whose content is an image, audio, video, font or PDF file;
+//     the content of typed arrays is their little-endian memory
+//   - strings holding data: URIs
+//   - *url.URL values and strings holding a URL whose path has a media file
+//     extension, absolute or relative
+//
+// A value held at several paths is reported at each of them, except in
+// containers shared by several parents, which are searched once, at the
+// first path they are reached by.
+func ExtractAssets(v interface{}) []AssetRef {
+	var assets []AssetRef
+	w := &walker{
+		visit: func(p string, v interface{}) bool {
+			if asset, ok := assetOf(v); ok {
+				asset.Path = p
+				assets = append(assets, asset)
+			}
+			return true
+		},
+	}
+	w.walk("", v)
+	return assets
+}
+
+func assetOf(v interface{}) (AssetRef, bool) {
+	if data, ok := binaryData(v); ok {
+		if mediaType := sniffMediaType(data); mediaType != "" {
+			return AssetRef{Kind: AssetBinary, MediaType: mediaType, Data: data, Size: len(data)}, true
+		}
+		return AssetRef{}, false
+	}
+	switch value := v.(type) {
+	case *Truncated:
+		if data, ok := binaryData(value.Value); ok {
+			if mediaType := sniffMediaType(data); mediaType != "" {
+				return AssetRef{Kind: AssetBinary, MediaType: mediaType, Data: data, Size: value.Length}, true
+			}
+		}
+	case string:
+		if strings.HasPrefix(value, "data:") {
+			return dataURIAsset(value)
+		}
+		if value == "" || strings.ContainsAny(value, " \t\r\n") {
+			return AssetRef{}, false
+		}
+		if u, err := url.Parse(value); err == nil {
+			if mediaType := extensionMediaType(u.Path); mediaType != "" {
+				return AssetRef{Kind: AssetURL, MediaType: mediaType, URL: value}, true
+			}
+		}
+	case *url.URL:
+		if value == nil {
+			return AssetRef{}, false
+		}
+		if mediaType := extensionMediaType(value.Path); mediaType != "" {
+			return AssetRef{Kind: AssetURL, MediaType: mediaType, URL: value.String()}, true
+		}
+	}
+	return AssetRef{}, false
+}
+
+// dataURIAsset decodes a data: URI. Malformed URIs are not reported.
+func dataURIAsset(s string) (AssetRef, bool) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(s, "data:"), ",")
+	if !ok {
+		return AssetRef{}, false
+	}
+	mediaType, params, _ := strings.Cut(header, ";")
+	var data []byte
+	var err error
+	if params == "base64" || strings.HasSuffix(params, ";base64") {
+		data, err = base64.StdEncoding.DecodeString(payload)
+	} else {
+		var text string
+		text, err = url.PathUnescape(payload)
+		data = []byte(text)
+	}
+	if err != nil {
+		return AssetRef{}, false
+	}
+	if mediaType == "" {
+		mediaType = "text/plain"
+	}
+	return AssetRef{Kind: AssetDataURI, MediaType: strings.ToLower(mediaType), Data: data, Size: len(data)}, true
+}
+
+// mediaExtensions maps the extensions of media files to their media type.
+var mediaExtensions = map[string]string{
+	".apng": "image/apng", ".avif": "image/avif", ".bmp": "image/bmp",
+	".gif": "image/gif", ".ico": "image/x-icon", ".jpeg": "image/jpeg",
+	".jpg": "image/jpeg", ".png": "image/png", ".svg": "image/svg+xml",
+	".tif": "image/tiff", ".tiff": "image/tiff", ".webp": "image/webp",
+	".m4a": "audio/mp4", ".mp3": "audio/mpeg", ".oga": "audio/ogg",
+	".ogg": "audio/ogg", ".wav": "audio/wav",
+	".m4v": "video/mp4", ".mov": "video/quicktime", ".mp4": "video/mp4",
+	".ogv": "video/ogg", ".webm": "video/webm",
+	".otf": "font/otf", ".ttf": "font/ttf", ".woff": "font/woff", ".woff2": "font/woff2",
+	".pdf": "application/pdf",
+}
+
+func extensionMediaType(p string) string {
+	return mediaExtensions[strings.ToLower(path.Ext(p))]
+}
+
+// mediaSignatures holds the leading bytes of media files, checked in order.
+var mediaSignatures = []struct {
+	offset    int
+	signature string
+	mediaType string
+}{
+	{0, "\x89PNG\r\n\x1a\n", "image/png"},
+	{0, "\xff\xd8\xff", "image/jpeg"},
+	{0, "GIF87a", "image/gif"},
+	{0, "GIF89a", "image/gif"},
+	{8, "WEBP", "image/webp"},
+	{8, "WAVE", "audio/wav"},
+	{4, "ftypavif", "image/avif"},
+	{4, "ftypqt", "video/quicktime"},
+	{4, "ftyp", "video/mp4"},
+	{0, "II*\x00", "image/tiff"},
+	{0, "MM\x00*", "image/tiff"},
+	{0, "ID3", "audio/mpeg"},
+	{0, "OggS", "audio/ogg"},
+	{0, "\x1aE\xdf\xa3", "video/webm"},
+	{0, "wOFF", "font/woff"},
+	{0, "wOF2", "font/woff2"},
+	{0, "%PDF-", "application/pdf"},
+}
+
+// sniffMediaType returns the media type of data from its leading bytes, or
+// the empty string if it is not a known media format.
+func sniffMediaType(data []byte) string {
+	for _, s := range mediaSignatures {
+		if len(data) >= s.offset+len(s.signature) && string(data[s.offset:s.offset+len(s.signature)]) == s.signature {
+			if s.offset == 8 && !bytes.HasPrefix(data, []byte("RIFF")) {
+				continue
+			}
+			return s.mediaType
+		}
+	}
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	if bytes.HasPrefix(trimmed, []byte("<svg")) || (bytes.HasPrefix(trimmed, []byte("<?xml")) && bytes.Contains(trimmed, []byte("<svg"))) {
+		return "image/svg+xml"
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package rehydrate_test
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestExtractAssets(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	payload := `[{"logo":1,"hero":2,"icon":3,"page":4,"counts":5,"docs":6,"gallery":7},` +
+		`["Uint8Array","` + base64.StdEncoding.EncodeToString([]byte(png)) + `"],` +
+		`"https://cdn.example.com/img/Hero.JPG?w=800",` +
+		`"data:image/svg+xml,%3Csvg%2F%3E",` +
+		`"/about/team",` +
+		`["Uint8Array","AQIDBA=="],` +
+		`"files/manual.pdf",` +
+		`[2,8],"not an image.png"]`
+	v, err := rehydrate.Parse(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rehydrate.ExtractAssets(v)
+	want := []rehydrate.AssetRef{
+		{Path: "/docs", Kind: rehydrate.AssetURL, MediaType: "application/pdf", URL: "files/manual.pdf"},
+		{Path: "/gallery/0", Kind: rehydrate.AssetURL, MediaType: "image/jpeg", URL: "https://cdn.example.com/img/Hero.JPG?w=800"},
+		{Path: "/hero", Kind: rehydrate.AssetURL, MediaType: "image/jpeg", URL: "https://cdn.example.com/img/Hero.JPG?w=800"},
+		{Path: "/icon", Kind: rehydrate.AssetDataURI, MediaType: "image/svg+xml", Data: []byte("<svg/>"), Size: 6},
+		{Path: "/logo", Kind: rehydrate.AssetBinary, MediaType: "image/png", Data: []byte(png), Size: len(png)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+
+	out, err := json.Marshal(got[3])
+	if err != nil || string(out) != `{"path":"/icon","kind":"data-uri","mediaType":"image/svg+xml","size":6}` {
+		t.Errorf("got %s, %v", out, err)
+	}
+}
+
+func TestExtractAssetsValues(t *testing.T) {
+	u, _ := url.Parse("https://example.com/v/clip.mp4")
+	png := []byte("\x89PNG\r\n\x1a\n")
+	got := rehydrate.ExtractAssets([]interface{}{
+		u,
+		"data:;base64,aGk=",
+		"data:image/png;base64,!!",
+		&rehydrate.Truncated{Value: []byte("%PDF-1.7"), Length: 4096},
+		rehydrate.ArrayBuffer("GIF89a"),
+		[]int32{int32(binary.LittleEndian.Uint32(png)), int32(binary.LittleEndian.Uint32(png[4:]))},
+		[]float64{1, 2},
+	})
+	want := []rehydrate.AssetRef{
+		{Path: "/0", Kind: rehydrate.AssetURL, MediaType: "video/mp4", URL: "https://example.com/v/clip.mp4"},
+		{Path: "/1", Kind: rehydrate.AssetDataURI, MediaType: "text/plain", Data: []byte("hi"), Size: 2},
+		{Path: "/3", Kind: rehydrate.AssetBinary, MediaType: "application/pdf", Data: []byte("%PDF-1.7"), Size: 4096},
+		{Path: "/4", Kind: rehydrate.AssetBinary, MediaType: "image/gif", Data: []byte("GIF89a"), Size: 6},
+		{Path: "/5", Kind: rehydrate.AssetBinary, MediaType: "image/png", Data: png, Size: 8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
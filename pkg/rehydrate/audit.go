@@ -0,0 +1,77 @@
+package rehydrate
+
+import (
+	"sync"
+	"time"
+)
+
+// AuditEvent records a single reviver invocation.
+type AuditEvent struct {
+	// Tag is the type tag the reviver was registered for.
+	Tag string
+	// Index is the position of the tagged entry in the value table.
+	Index int
+	// Path is the path of the revived value, empty for the root.
+	Path string
+	// Start is when the reviver was called and Duration how long it ran.
+	Start    time.Time
+	Duration time.Duration
+	// Err is the error returned by the reviver, if any.
+	Err error
+}
+
+// WithAudit calls fn after every reviver invocation, for example to prove
+// which external lookups were performed while processing user data. fn runs
+// synchronously on the hydrating goroutine; it may forward events to a
+// structured logger or collect them with an AuditLog.
+func WithAudit(fn func(AuditEvent)) Option {
+	return func(o *options) {
+		o.audit = fn
+	}
+}
+
+// AuditLog collects audit events. Its Record method can be passed to
+// WithAudit and is safe for concurrent use, so one log can be shared by
+// several parses.
+type AuditLog struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+// Record appends e to the log.
+func (l *AuditLog) Record(e AuditEvent) {
+	l.mu.Lock()
+	l.events = append(l.events, e)
+	l.mu.Unlock()
+}
+
+// Events returns a copy of the recorded events, in the order they occurred.
+func (l *AuditLog) Events() []AuditEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]AuditEvent(nil), l.events...)
+}
+
+// trackPaths reports whether the hydrator needs to track value paths.
+func (o *options) trackPaths() bool {
+	return len(o.pathPolicies) > 0 || o.audit != nil || o.coerce != nil || o.provenance != nil ||
+		o.secretScanner != nil
+}
+
+// revive calls the reviver for the entry at index, auditing the call.
+func (h *hydrator) revive(reviver ReviverFunc, tag string, index int, v interface{}) (interface{}, error) {
+	if h.opts.audit == nil {
+		return reviver(v)
+	}
+	start := h.opts.clock.Now()
+	res, err := reviver(v)
+	h.opts.audit(AuditEvent{
+		Tag:      tag,
+		Index:    index,
+		Path:     h.currentPath(),
+		Start:    start,
+		Duration: h.opts.clock.Now().Sub(start),
+		Err:      err,
+	})
+	return res, err
+}
@@ -0,0 +1,43 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestAudit(t *testing.T) {
+	input := `[{"user":1,"items":3},["Lookup",2],"ada",[4],["Lookup",5],"bob"]`
+	lookup := func(v interface{}) (interface{}, error) {
+		if v == "bob" {
+			return nil, errors.New("not found")
+		}
+		return v, nil
+	}
+
+	var log rehydrate.AuditLog
+	_, err := rehydrate.ParseWithOptions(input,
+		rehydrate.WithRevivers(rehydrate.Revivers{"Lookup": lookup}),
+		rehydrate.WithAudit(log.Record))
+	if err == nil {
+		t.Fatal("expected the failing lookup to fail the parse")
+	}
+
+	events := log.Events()
+	byPath := make(map[string]rehydrate.AuditEvent)
+	for _, e := range events {
+		if e.Tag != "Lookup" || e.Duration < 0 || e.Start.IsZero() {
+			t.Errorf("unexpected event %+v", e)
+		}
+		byPath[e.Path] = e
+	}
+	if e, ok := byPath["/items/0"]; !ok || e.Err == nil || e.Index != 4 {
+		t.Errorf("items[0] event = %+v", e)
+	}
+	// Object keys are hydrated in no particular order, so the successful
+	// lookup may not have run before the failure.
+	if e, ok := byPath["/user"]; ok && (e.Err != nil || e.Index != 1) {
+		t.Errorf("user event = %+v", e)
+	}
+}
@@ -0,0 +1,158 @@
+//go:build !rehydrate_min
+
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseBigInt returns the BigInt with the decimal digits s.
+func parseBigInt(s string) (interface{}, bool) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, false
+	}
+	return n, true
+}
+
+// bigIntDigits returns the decimal digits of a BigInt value.
+func bigIntDigits(v interface{}) (string, bool) {
+	n, ok := v.(*big.Int)
+	if !ok {
+		return "", false
+	}
+	return n.String(), true
+}
+
+// bigIntSliceDigits returns the decimal digits of the elements of a
+// []*big.Int.
+func bigIntSliceDigits(v interface{}) ([]string, bool) {
+	ns, ok := v.([]*big.Int)
+	if !ok {
+		return nil, false
+	}
+	digits := make([]string, len(ns))
+	for i, n := range ns {
+		digits[i] = n.String()
+	}
+	return digits, true
+}
+
+// bigInt64s returns the elements of a BigInt64Array, or of a BigUint64Array
+// when signed is false, as []*big.Int.
+func bigInt64s(words []uint64, signed bool) interface{} {
+	out := make([]*big.Int, len(words))
+	for i, w := range words {
+		if signed {
+			out[i] = big.NewInt(int64(w))
+		} else {
+			out[i] = new(big.Int).SetUint64(w)
+		}
+	}
+	return out
+}
+
+// RegExp is the hydrated form of a JavaScript RegExp.
+type RegExp struct {
+	// Source and Flags are the pattern and flags as written in JavaScript,
+	// such as "a+b" and "gi".
+	Source string
+	Flags  string
+	// Regexp is Source compiled with the flags that change what Go matches.
+	Regexp *regexp.Regexp
+}
+
+// CompileRegExp compiles the JavaScript regular expression with source and
+// flags. The flags i, m and s translate to the Go flags of the same name;
+// u, and the flags d, g, v and y, which only affect how JavaScript applies
+// the expression, are accepted without effect. Other flags, repeated ones
+// and sources Go's RE2 syntax rejects, such as those with lookarounds or
+// backreferences, fail.
+func CompileRegExp(source, flags string) (*RegExp, error) {
+	goFlags, err := regExpFlags(flags)
+	if err != nil {
+		return nil, err
+	}
+	pattern := source
+	if goFlags != "" {
+		pattern = "(?" + goFlags + ")" + source
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &RegExp{Source: source, Flags: flags, Regexp: re}, nil
+}
+
+// regExpFlags returns the Go flags for the JavaScript flags.
+func regExpFlags(flags string) (string, error) {
+	var goFlags []byte
+	for i := 0; i < len(flags); i++ {
+		c := flags[i]
+		if strings.IndexByte(flags[:i], c) >= 0 {
+			return "", fmt.Errorf("repeated RegExp flag %q", c)
+		}
+		switch c {
+		case 'i', 'm', 's':
+			goFlags = append(goFlags, c)
+		case 'd', 'g', 'u', 'v', 'y':
+		default:
+			return "", fmt.Errorf("invalid RegExp flag %q", c)
+		}
+	}
+	return string(goFlags), nil
+}
+
+// String returns the expression in JavaScript literal syntax, /source/flags.
+func (r *RegExp) String() string {
+	return "/" + r.Source + "/" + r.Flags
+}
+
+// MarshalJSON encodes the expression as its source, like a *regexp.Regexp.
+func (r *RegExp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Source)
+}
+
+// compileRegExp returns the RegExp with source and flags.
+func compileRegExp(source, flags string) (interface{}, error) {
+	return CompileRegExp(source, flags)
+}
+
+// regExpSource returns the source and flags of a RegExp value. A
+// *regexp.Regexp has no flags.
+func regExpSource(v interface{}) (source, flags string, ok bool) {
+	switch re := v.(type) {
+	case *RegExp:
+		return re.Source, re.Flags, true
+	case *regexp.Regexp:
+		return re.String(), "", true
+	}
+	return "", "", false
+}
+
+// regExpLiteral returns a Go expression evaluating to the RegExp value v.
+func regExpLiteral(v interface{}) (string, bool) {
+	switch re := v.(type) {
+	case *RegExp:
+		return "&rehydrate.RegExp{Source: " + strconv.Quote(re.Source) + ", Flags: " + strconv.Quote(re.Flags) +
+			", Regexp: regexp.MustCompile(" + goRawString(re.Regexp.String()) + ")}", true
+	case *regexp.Regexp:
+		return "regexp.MustCompile(" + goRawString(re.String()) + ")", true
+	}
+	return "", false
+}
+
+// compileMatcher returns a function matching strings against the regular
+// expression pattern.
+func compileMatcher(pattern string) (func(string) bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
@@ -0,0 +1,88 @@
+//go:build rehydrate_min
+
+package rehydrate
+
+import (
+	"errors"
+	"strings"
+)
+
+// parseBigInt returns a *Tagged holding the decimal digits s, in canonical
+// form.
+func parseBigInt(s string) (interface{}, bool) {
+	sign, digits := "", s
+	if digits != "" && (digits[0] == '+' || digits[0] == '-') {
+		if digits[0] == '-' {
+			sign = "-"
+		}
+		digits = digits[1:]
+	}
+	if digits == "" {
+		return nil, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return nil, false
+		}
+	}
+	if digits = strings.TrimLeft(digits, "0"); digits == "" {
+		sign, digits = "", "0"
+	}
+	return &Tagged{Tag: TagBigInt.String(), Args: []interface{}{sign + digits}}, true
+}
+
+// bigIntDigits returns the decimal digits of a BigInt value.
+func bigIntDigits(v interface{}) (string, bool) {
+	t, ok := v.(*Tagged)
+	if !ok || t.Tag != TagBigInt.String() || len(t.Args) != 1 {
+		return "", false
+	}
+	s, ok := t.Args[0].(string)
+	return s, ok
+}
+
+// bigIntSliceDigits reports false: the minimal build has no []*big.Int.
+func bigIntSliceDigits(v interface{}) ([]string, bool) {
+	return nil, false
+}
+
+// bigInt64s returns the elements of a BigInt64Array as []int64, or of a
+// BigUint64Array as []uint64 when signed is false.
+func bigInt64s(words []uint64, signed bool) interface{} {
+	if !signed {
+		return words
+	}
+	out := make([]int64, len(words))
+	for i, w := range words {
+		out[i] = int64(w)
+	}
+	return out
+}
+
+// compileRegExp returns a *Tagged holding source and flags, which are not
+// checked.
+func compileRegExp(source, flags string) (interface{}, error) {
+	return &Tagged{Tag: TagRegExp.String(), Args: []interface{}{source, flags}}, nil
+}
+
+// regExpSource returns the source and flags of a RegExp value.
+func regExpSource(v interface{}) (source, flags string, ok bool) {
+	t, ok := v.(*Tagged)
+	if !ok || t.Tag != TagRegExp.String() || len(t.Args) != 2 {
+		return "", "", false
+	}
+	source, ok1 := t.Args[0].(string)
+	flags, ok2 := t.Args[1].(string)
+	return source, flags, ok1 && ok2
+}
+
+// regExpLiteral reports false: RegExps hydrate to *Tagged, which GoLiteral
+// renders itself.
+func regExpLiteral(v interface{}) (string, bool) {
+	return "", false
+}
+
+// compileMatcher fails: the minimal build has no regular expressions.
+func compileMatcher(pattern string) (func(string) bool, error) {
+	return nil, errors.New("regular expressions are not supported by the rehydrate_min build")
+}
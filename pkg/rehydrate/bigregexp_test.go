@@ -0,0 +1,109 @@
+//go:build !rehydrate_min
+
+package rehydrate_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestBigIntRegExpOutput(t *testing.T) {
+	large, _ := new(big.Int).SetString("123456789012345678901234", 10)
+	tests := []struct {
+		in        interface{}
+		stringify string
+		literal   string
+	}{
+		{big.NewInt(42), `[["BigInt","42"]]`, `big.NewInt(42)`},
+		{large, `[["BigInt","123456789012345678901234"]]`,
+			`func() *big.Int { n, _ := new(big.Int).SetString("123456789012345678901234", 10); return n }()`},
+		{regexp.MustCompile(`a+\d`), `[["RegExp","a+\\d",""]]`, "regexp.MustCompile(`a+\\d`)"},
+		{&rehydrate.RegExp{Source: "^a.b$", Flags: "gms", Regexp: regexp.MustCompile(`(?ms)^a.b$`)}, `[["RegExp","^a.b$","gms"]]`,
+			"&rehydrate.RegExp{Source: \"^a.b$\", Flags: \"gms\", Regexp: regexp.MustCompile(`(?ms)^a.b$`)}"},
+	}
+	for _, tt := range tests {
+		s, err := rehydrate.Stringify(tt.in, nil)
+		if err != nil || s != tt.stringify {
+			t.Errorf("Stringify(%v) = %s, %v, want %s", tt.in, s, err, tt.stringify)
+		}
+		lit, err := rehydrate.GoLiteral(tt.in)
+		if err != nil || lit != tt.literal {
+			t.Errorf("GoLiteral(%v) = %s, %v, want %s", tt.in, lit, err, tt.literal)
+		}
+	}
+}
+
+func TestRegExpFlags(t *testing.T) {
+	v, err := rehydrate.Parse(`[{"ci":1,"dotall":2,"plain":3},["RegExp","^hello","gi"],["RegExp","a.b","su"],["RegExp","a.b",""]]`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	tests := []struct {
+		key   string
+		flags string
+		in    string
+		match bool
+	}{
+		{"ci", "gi", "HELLO world", true},
+		{"dotall", "su", "a\nb", true},
+		{"plain", "", "a\nb", false},
+	}
+	for _, tt := range tests {
+		re, ok := root[tt.key].(*rehydrate.RegExp)
+		if !ok {
+			t.Fatalf("%s: got %T", tt.key, root[tt.key])
+		}
+		if re.Flags != tt.flags || re.Regexp.MatchString(tt.in) != tt.match {
+			t.Errorf("%s: %s matches %q: %v", tt.key, re, tt.in, re.Regexp.MatchString(tt.in))
+		}
+	}
+	if re := root["ci"].(*rehydrate.RegExp); re.String() != "/^hello/gi" || re.Source != "^hello" {
+		t.Errorf("got %s", re)
+	}
+
+	for _, flags := range []string{"x", "gg"} {
+		_, err := rehydrate.Parse(`[["RegExp","a",`+strconv.Quote(flags)+`]]`, nil)
+		var typeErr *rehydrate.TypeError
+		if !errors.As(err, &typeErr) || !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("%q: expected a TypeError, got %v", flags, err)
+		}
+	}
+}
+
+func TestBigInt64Arrays(t *testing.T) {
+	data := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	tests := []struct {
+		tag     string
+		want    []string
+		literal string
+	}{
+		{"BigInt64Array", []string{"1", "-1"}, "[]*big.Int{big.NewInt(1), big.NewInt(-1)}"},
+		{"BigUint64Array", []string{"1", "18446744073709551615"},
+			`[]*big.Int{big.NewInt(1), func() *big.Int { n, _ := new(big.Int).SetString("18446744073709551615", 10); return n }()}`},
+	}
+	for _, tt := range tests {
+		v, err := rehydrate.Parse(`[["`+tt.tag+`","`+base64.StdEncoding.EncodeToString(data)+`"]]`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := v.([]*big.Int)
+		if !ok || len(got) != len(tt.want) {
+			t.Fatalf("%s: got %#v", tt.tag, v)
+		}
+		for i, n := range got {
+			if n.String() != tt.want[i] {
+				t.Errorf("%s[%d] = %s, want %s", tt.tag, i, n, tt.want[i])
+			}
+		}
+		if literal, err := rehydrate.GoLiteral(v); err != nil || literal != tt.literal {
+			t.Errorf("%s: GoLiteral = %s, %v", tt.tag, literal, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package rehydrate
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// BinaryValidator checks the decoded contents of a typed array or
+// ArrayBuffer, for example against a checksum appended by a custom reducer.
+// A non-nil error fails hydration.
+type BinaryValidator func(data []byte) error
+
+// WithBinaryValidator runs fn on the decoded bytes of every value tagged tag,
+// such as "Uint8Array", before any truncation. Validators registered for the
+// same tag run in order. The error returned by Parse wraps ErrInvalidInput
+// and the validator's error.
+func WithBinaryValidator(tag string, fn BinaryValidator) Option {
+	return func(o *options) {
+		if o.binaryValidators == nil {
+			o.binaryValidators = make(map[string][]BinaryValidator)
+		}
+		o.binaryValidators[tag] = append(o.binaryValidators[tag], fn)
+	}
+}
+
+// BinaryCodec converts the data of typed arrays and ArrayBuffers to and from
+// the string held by their payload entry, which standard serializers encode
+// in base64.
+type BinaryCodec interface {
+	Encode(data []byte) string
+	Decode(s string) ([]byte, error)
+}
+
+// Base64Codec is the standard base64 encoding used by devalue.
+var Base64Codec BinaryCodec = base64Codec{}
+
+type base64Codec struct{}
+
+func (base64Codec) Encode(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func (base64Codec) Decode(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// WithBinaryCodec decodes the data of values tagged tag, such as
+// "Float32Array", with codec instead of base64, for serializers that encode
+// binary data differently.
+func WithBinaryCodec(tag string, codec BinaryCodec) Option {
+	return func(o *options) {
+		if o.binaryCodecs == nil {
+			o.binaryCodecs = make(map[string]BinaryCodec)
+		}
+		o.binaryCodecs[tag] = codec
+	}
+}
+
+// binaryCodec returns the codec of values tagged tag.
+func (o *options) binaryCodec(tag string) BinaryCodec {
+	if codec, ok := o.binaryCodecs[tag]; ok {
+		return codec
+	}
+	return Base64Codec
+}
+
+// decodeBinary decodes the data of a value tagged tag, validating it and
+// truncating it to the binary limit rounded down to whole elements, so the
+// kept prefix decodes like the full data would.
+func (o *options) decodeBinary(tag, b64 string) (interface{}, error) {
+	size := 1
+	if t, builtin := ParseTag(tag); builtin && t.elementSize() > 0 {
+		size = t.elementSize()
+	}
+	_, custom := o.binaryCodecs[tag]
+	if validators := o.binaryValidators[tag]; len(validators) > 0 || custom {
+		data, err := o.binaryCodec(tag).Decode(b64)
+		if err != nil {
+			return nil, err
+		}
+		for _, validate := range validators {
+			if err := validate(data); err != nil {
+				return nil, fmt.Errorf("validation failed: %w", err)
+			}
+		}
+		if n := o.maxBinary; n > 0 && len(data) > n {
+			if len(data)%size != 0 {
+				return nil, fmt.Errorf("byte length %d of %s is not a multiple of %d", len(data), tag, size)
+			}
+			return &Truncated{Value: data[:n/size*size], Length: len(data)}, nil
+		}
+		return data, nil
+	}
+
+	n := o.maxBinary
+	length := len(b64) / 4 * 3
+	if length > 0 {
+		length -= len(b64) - len(strings.TrimRight(b64, "="))
+	}
+	if n <= 0 || length <= n || len(b64)%4 != 0 {
+		return base64.StdEncoding.DecodeString(b64)
+	}
+	if length%size != 0 {
+		return nil, fmt.Errorf("byte length %d of %s is not a multiple of %d", length, tag, size)
+	}
+	n = n / size * size
+	// Decode whole quanta covering the prefix, then cut it to size.
+	data, err := base64.StdEncoding.DecodeString(b64[:(n+2)/3*4])
+	if err != nil {
+		return nil, err
+	}
+	return &Truncated{Value: data[:n], Length: length}, nil
+}
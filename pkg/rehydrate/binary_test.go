@@ -0,0 +1,94 @@
+package rehydrate_test
+
+import (
+	"encoding/ascii85"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// crcValidator checks a CRC-32 appended to the data in big-endian order.
+func crcValidator(data []byte) error {
+	if len(data) < 4 {
+		return errors.New("missing checksum")
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return errors.New("checksum mismatch")
+	}
+	return nil
+}
+
+func TestBinaryValidator(t *testing.T) {
+	body := []byte("payload")
+	good := binary.BigEndian.AppendUint32(append([]byte{}, body...), crc32.ChecksumIEEE(body))
+	bad := append(append([]byte{}, good[:len(good)-1]...), good[len(good)-1]^1)
+
+	payload := func(data []byte) string {
+		return `[["Uint8Array","` + base64.StdEncoding.EncodeToString(data) + `"]]`
+	}
+	opt := rehydrate.WithBinaryValidator("Uint8Array", crcValidator)
+
+	v, err := rehydrate.ParseWithOptions(payload(good), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v.([]byte)) != string(good) {
+		t.Errorf("got %q", v)
+	}
+
+	_, err = rehydrate.ParseWithOptions(payload(bad), opt)
+	var typeErr *rehydrate.TypeError
+	if !errors.Is(err, rehydrate.ErrInvalidInput) || !errors.As(err, &typeErr) || typeErr.Tag != "Uint8Array" {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	// Validators only run for their own tag.
+	if _, err := rehydrate.ParseWithOptions(`[["Int8Array","AQ=="]]`, opt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ascii85Codec encodes binary data in Ascii85, as some serializer forks do.
+type ascii85Codec struct{}
+
+func (ascii85Codec) Encode(data []byte) string {
+	buf := make([]byte, ascii85.MaxEncodedLen(len(data)))
+	return string(buf[:ascii85.Encode(buf, data)])
+}
+
+func (ascii85Codec) Decode(s string) ([]byte, error) {
+	buf := make([]byte, len(s))
+	n, _, err := ascii85.Decode(buf, []byte(s), true)
+	return buf[:n], err
+}
+
+func TestBinaryCodec(t *testing.T) {
+	data := []byte{0, 0, 128, 63, 0, 0, 0, 64}
+	encoded := ascii85Codec{}.Encode(data)
+	payload := `[{"f":1,"b":2},["Float32Array","` + encoded + `"],["Uint8Array","` +
+		rehydrate.Base64Codec.Encode(data) + `"]]`
+
+	v, err := rehydrate.ParseWithOptions(payload,
+		rehydrate.WithBinaryCodec("Float32Array", ascii85Codec{}),
+		rehydrate.WithMaxBinaryInline(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	if tr, ok := root["f"].(*rehydrate.Truncated); !ok || !reflect.DeepEqual(tr.Value, []float32{1}) || tr.Length != len(data) {
+		t.Errorf("f: got %#v", root["f"])
+	}
+	if tr, ok := root["b"].(*rehydrate.Truncated); !ok || string(tr.Value.([]byte)) != string(data[:4]) || tr.Length != len(data) {
+		t.Errorf("b: got %#v", root["b"])
+	}
+
+	if _, err := rehydrate.ParseWithOptions(payload); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("without the codec: got %v, want ErrInvalidInput", err)
+	}
+}
@@ -0,0 +1,109 @@
+package rehydrate
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"reflect"
+	"sync"
+)
+
+// CachingParser hydrates payloads with a fixed set of options and keeps the
+// results of the most recently used ones, for services that see many
+// byte-identical payloads. Results are keyed by the SHA-256 of the payload
+// and the revivers and type handlers in effect, so updates to a Registry
+// given with WithRegistry invalidate them.
+//
+// Cached results are shared by every caller parsing the same payload and
+// must be treated as read-only. Errors are not cached, and options that
+// report through pointers or callbacks, such as WithAudit or WithProvenance,
+// only take effect when a payload is actually hydrated.
+//
+// A CachingParser is safe for concurrent use.
+type CachingParser struct {
+	opts []Option
+	size int
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	recent  *list.List // of *cacheEntry, most recently used first
+
+	hits, misses int
+}
+
+type cacheKey struct {
+	sum      [sha256.Size]byte
+	registry uintptr
+	types    uintptr
+}
+
+type cacheEntry struct {
+	key   cacheKey
+	value interface{}
+}
+
+// NewCachingParser returns a parser hydrating payloads with opts and
+// caching up to size results. A size of zero or less disables caching.
+func NewCachingParser(size int, opts ...Option) *CachingParser {
+	return &CachingParser{
+		opts:    opts,
+		size:    size,
+		entries: make(map[cacheKey]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// Parse hydrates serialized like ParseWithOptions, returning the cached
+// result if the same payload was hydrated before.
+func (p *CachingParser) Parse(serialized string) (interface{}, error) {
+	o := newOptions(p.opts)
+	key := cacheKey{
+		sum:      sha256.Sum256([]byte(serialized)),
+		registry: reflect.ValueOf(o.registry).Pointer(),
+		types:    reflect.ValueOf(o.registryTypes).Pointer(),
+	}
+
+	p.mu.Lock()
+	if e, ok := p.entries[key]; ok {
+		p.recent.MoveToFront(e)
+		p.hits++
+		p.mu.Unlock()
+		return e.Value.(*cacheEntry).value, nil
+	}
+	p.misses++
+	p.mu.Unlock()
+
+	v, err := parse(serialized, o)
+	if err != nil || p.size <= 0 {
+		return v, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.entries[key]; ok {
+		// Hydrated concurrently by another caller; keep a single result.
+		p.recent.MoveToFront(e)
+		return e.Value.(*cacheEntry).value, nil
+	}
+	p.entries[key] = p.recent.PushFront(&cacheEntry{key: key, value: v})
+	for p.recent.Len() > p.size {
+		oldest := p.recent.Back()
+		p.recent.Remove(oldest)
+		delete(p.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return v, nil
+}
+
+// Len returns the number of cached results.
+func (p *CachingParser) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.recent.Len()
+}
+
+// Stats returns the number of calls to Parse answered from the cache and
+// the number that hydrated their payload.
+func (p *CachingParser) Stats() (hits, misses int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.hits, p.misses
+}
@@ -0,0 +1,65 @@
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestCachingParser(t *testing.T) {
+	registry := rehydrate.NewRegistry(rehydrate.Revivers{
+		"Price": func(v interface{}) (interface{}, error) { return v.(float64) * 100, nil },
+	})
+	p := rehydrate.NewCachingParser(2, rehydrate.WithRegistry(registry))
+
+	a := `[{"price":1},["Price",2],5]`
+	first, err := p.Parse(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Parse(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(first).Pointer() != reflect.ValueOf(second).Pointer() {
+		t.Error("expected the cached result")
+	}
+	if hits, misses := p.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("got %d hits and %d misses", hits, misses)
+	}
+
+	// Updating the registry changes the reviver set.
+	registry.Register("Price", func(v interface{}) (interface{}, error) { return v, nil })
+	third, err := p.Parse(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price := third.(map[string]interface{})["price"]; price != 5.0 {
+		t.Errorf("got price %v after the update", price)
+	}
+
+	// The least recently used result is evicted.
+	for _, payload := range []string{`["b"]`, `["c"]`} {
+		if _, err := p.Parse(payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p.Len() != 2 {
+		t.Errorf("cached %d results, want 2", p.Len())
+	}
+	p.Parse(a)
+	if hits, _ := p.Stats(); hits != 1 {
+		t.Errorf("expected an evicted result to be hydrated again, got %d hits", hits)
+	}
+
+	// Errors are not cached.
+	for i := 0; i < 2; i++ {
+		if _, err := p.Parse(`[{"a":9}]`); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if _, misses := p.Stats(); misses != 7 {
+		t.Errorf("got %d misses, want 7", misses)
+	}
+}
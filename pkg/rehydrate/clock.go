@@ -0,0 +1,49 @@
+package rehydrate
+
+import "time"
+
+// Clock supplies the current time to everything in a parse that depends on
+// it: the time budget, audit timestamps and revivers added with
+// WithClockedReviver. Injecting a fixed clock makes hydration deterministic in
+// tests and replays.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the default Clock, reading the system time.
+var SystemClock Clock = systemClock{}
+
+// FixedClock is a Clock that always returns the same time.
+type FixedClock time.Time
+
+// Now returns the fixed time.
+func (c FixedClock) Now() time.Time { return time.Time(c) }
+
+// WithClock sets the Clock used by the parse. A nil Clock selects
+// SystemClock.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		if c == nil {
+			c = SystemClock
+		}
+		o.clock = c
+	}
+}
+
+// ClockedReviverFunc is a reviver that depends on the current time, such as
+// one resolving relative dates.
+type ClockedReviverFunc func(clock Clock, v interface{}) (interface{}, error)
+
+// WithClockedReviver registers fn as the reviver for tag, passing it the
+// Clock configured for the parse.
+func WithClockedReviver(tag string, fn ClockedReviverFunc) Option {
+	return func(o *options) {
+		o.revivers[tag] = func(v interface{}) (interface{}, error) {
+			return fn(o.clock, v)
+		}
+	}
+}
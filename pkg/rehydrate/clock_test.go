@@ -0,0 +1,67 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// stepClock advances by step every time it is read.
+type stepClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *stepClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestClockedReviver(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	relative := func(clock rehydrate.Clock, v interface{}) (interface{}, error) {
+		return clock.Now().Add(time.Duration(v.(float64)) * time.Second), nil
+	}
+
+	v, err := rehydrate.ParseWithOptions(`[["RelativeDate",1],-60]`,
+		rehydrate.WithClockedReviver("RelativeDate", relative),
+		rehydrate.WithClock(rehydrate.FixedClock(now)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.(time.Time); !got.Equal(now.Add(-time.Minute)) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestClockDrivesBudgetAndAudit(t *testing.T) {
+	refs := make([]string, 1000)
+	for i := range refs {
+		refs[i] = fmt.Sprint(i + 1)
+	}
+	input := "[[" + strings.Join(refs, ",") + "]" + strings.Repeat(`,"x"`, len(refs)) + "]"
+
+	// Every read of the clock advances it by a second, so a generous budget
+	// still runs out without any real time passing.
+	clock := &stepClock{step: time.Second}
+	_, err := rehydrate.ParseWithOptions(input, rehydrate.WithClock(clock), rehydrate.WithTimeBudget(time.Second))
+	if !errors.Is(err, rehydrate.ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded, got %v", err)
+	}
+
+	var log rehydrate.AuditLog
+	clock = &stepClock{step: time.Second}
+	_, err = rehydrate.ParseWithOptions(`[["Tag",1],"x"]`,
+		rehydrate.WithRevivers(rehydrate.Revivers{"Tag": func(v interface{}) (interface{}, error) { return v, nil }}),
+		rehydrate.WithClock(clock), rehydrate.WithAudit(log.Record))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events := log.Events(); len(events) != 1 || events[0].Duration != time.Second {
+		t.Errorf("unexpected events %+v", events)
+	}
+}
@@ -0,0 +1,52 @@
+package rehydrate
+
+import "encoding/json"
+
+// Coercion inspects a scalar hydrated at path and returns its replacement
+// and true, or false to keep the value unchanged.
+type Coercion func(path string, v interface{}) (interface{}, bool)
+
+// WithCoercion calls fn for every scalar as it is hydrated: strings, numbers
+// (including NaN, infinities and -0, in the form WithNumberMode selects),
+// booleans, null and undefined. It enables cross-cutting changes, such as
+// turning numeric strings into numbers at known paths or trimming
+// whitespace, without a second walk over the hydrated tree. Paths are RFC
+// 6901 JSON Pointers, such as "/items/0/name", and are empty for the root;
+// ParsePointer and Path.Dotted turn them into the dotted form. The arguments
+// of revivers and Map keys are coerced at the path of the value they belong
+// to.
+//
+// A scalar shared by several values is passed to fn once per path, with its
+// raw value each time: the replacement at one path is not seen at the
+// others, so every path gets the coercion it calls for.
+func WithCoercion(fn Coercion) Option {
+	return func(o *options) {
+		o.coerce = fn
+	}
+}
+
+// coerce applies the coercion to v, the hydrated value of the entry at index,
+// if that entry is a scalar.
+func (h *hydrator) coerce(index int, v interface{}) interface{} {
+	if !h.isScalar(index) {
+		return v
+	}
+	if replaced, ok := h.opts.coerce(h.currentPath(), v); ok {
+		return replaced
+	}
+	return v
+}
+
+func (h *hydrator) isScalar(index int) bool {
+	if index < 0 {
+		return index != HOLE
+	}
+	if index >= len(h.values) {
+		return false
+	}
+	switch h.values[index].(type) {
+	case string, nil, bool, float64, json.Number, int64, UTF16String:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package rehydrate_test
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestWithCoercion(t *testing.T) {
+	payload := `[{"id":1,"name":2,"tags":3,"price":4,"missing":-1},"42"," Ada ",[2,1],"9.5"]`
+
+	var paths []string
+	coerce := func(path string, v interface{}) (interface{}, bool) {
+		paths = append(paths, path)
+		if s, ok := v.(string); ok {
+			if path == "/id" || path == "/price" {
+				n, err := strconv.ParseFloat(s, 64)
+				return n, err == nil
+			}
+			return strings.TrimSpace(s), true
+		}
+		if v == nil {
+			return "none", true
+		}
+		return nil, false
+	}
+	v, err := rehydrate.ParseWithOptions(payload, rehydrate.WithCoercion(coerce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"id":      42.0,
+		"name":    "Ada",
+		"tags":    []interface{}{"Ada", "42"},
+		"price":   9.5,
+		"missing": "none",
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+	// Shared scalars are coerced once per path; containers are not passed.
+	sort.Strings(paths)
+	wantPaths := []string{"/id", "/missing", "/name", "/price", "/tags/0", "/tags/1"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("got paths %q, want %q", paths, wantPaths)
+	}
+}
+
+func TestWithCoercionRoot(t *testing.T) {
+	double := func(path string, v interface{}) (interface{}, bool) {
+		if path != "" {
+			t.Errorf("unexpected path %q", path)
+		}
+		f, ok := v.(float64)
+		return f * 2, ok
+	}
+	v, err := rehydrate.ParseWithOptions(`[21]`, rehydrate.WithCoercion(double))
+	if err != nil || v != 42.0 {
+		t.Errorf("got %v, %v", v, err)
+	}
+	v, err = rehydrate.ParseWithOptions(`-4`, rehydrate.WithCoercion(double))
+	if err != nil || !math.IsInf(v.(float64), 1) {
+		t.Errorf("got %v, %v", v, err)
+	}
+}
+
+func TestWithCoercionArgumentsAndMapKeys(t *testing.T) {
+	trim := rehydrate.WithCoercion(func(path string, v interface{}) (interface{}, bool) {
+		s, ok := v.(string)
+		return strings.TrimSpace(s), ok
+	})
+	nuxt := rehydrate.WithRevivers(rehydrate.DefaultNuxtRevivers())
+	v, err := rehydrate.ParseWithOptions(`[{"a":1},["Ref",2],"  x  "]`, nuxt, trim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"a": "x"}; !reflect.DeepEqual(v, want) {
+		t.Errorf("reviver argument: got %#v, want %#v", v, want)
+	}
+
+	v, err = rehydrate.ParseWithOptions(`[{"m":1},["Map",2,3]," k "," v "]`, trim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]interface{})["m"].(*rehydrate.OrderedMap)
+	if got, ok := m.Get("k"); !ok || got != "v" || m.Len() != 1 {
+		t.Errorf("Map: got %v", m)
+	}
+}
+
+func TestWithCoercionSharedScalar(t *testing.T) {
+	// The replacement at /a does not leak to /b, which shares the entry.
+	upper := rehydrate.WithCoercion(func(path string, v interface{}) (interface{}, bool) {
+		s, ok := v.(string)
+		return strings.ToUpper(s), ok && path == "/a"
+	})
+	v, err := rehydrate.ParseWithOptions(`[{"a":1,"b":1,"c":2},"x",[1]]`, upper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"a": "X", "b": "x", "c": []interface{}{"x"}}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+}
+
+func TestWithCoercionNumberModes(t *testing.T) {
+	tests := []struct {
+		mode rehydrate.NumberMode
+		want interface{}
+	}{
+		{rehydrate.NumberFloat64, 5.0},
+		{rehydrate.NumberJSON, json.Number("5")},
+		{rehydrate.NumberInt64, int64(5)},
+	}
+	for _, tt := range tests {
+		got := map[string]interface{}{}
+		record := func(path string, v interface{}) (interface{}, bool) {
+			got[path] = v
+			return nil, false
+		}
+		_, err := rehydrate.ParseWithOptions(`[{"a":1,"b":2},5,"s"]`,
+			rehydrate.WithNumberMode(tt.mode), rehydrate.WithCoercion(record))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]interface{}{"/a": tt.want, "/b": "s"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("mode %d: coerced %#v, want %#v", tt.mode, got, want)
+		}
+	}
+}
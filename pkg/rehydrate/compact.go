@@ -0,0 +1,203 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// CompactReport describes the effect of Compact on a payload.
+type CompactReport struct {
+	// Removed is the number of value-table entries that were dropped.
+	Removed int
+	// BytesBefore and BytesAfter are the serialized sizes of the payload.
+	BytesBefore int
+	BytesAfter  int
+}
+
+// BytesSaved returns how many bytes compaction removed.
+func (r *CompactReport) BytesSaved() int {
+	return r.BytesBefore - r.BytesAfter
+}
+
+// Compact drops value-table entries that are unreachable from the root and
+// remaps the remaining indices. Payloads edited by hand or by tools that
+// splice values in and out tend to accumulate such orphans. Entries keep
+// their relative order and their original encoding, including object key
+// order.
+func Compact(serialized string) (string, *CompactReport, error) {
+	report := &CompactReport{BytesBefore: len(serialized), BytesAfter: len(serialized)}
+
+	values, err := unmarshalTable(serialized)
+	if err != nil {
+		return "", nil, err
+	}
+	if values == nil {
+		return serialized, report, nil
+	}
+
+	reachable := make([]bool, len(values))
+	stack := []int{0}
+	reachable[0] = true
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		children, err := childRefs(values[index])
+		if err != nil {
+			return "", nil, fmt.Errorf("index %d: %w", index, err)
+		}
+		for _, child := range children {
+			if child >= len(values) {
+				return "", nil, fmt.Errorf("%w: index %d out of range", ErrBadReference, child)
+			}
+			if !reachable[child] {
+				reachable[child] = true
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	mapping := make([]int, len(values))
+	next := 0
+	for i, ok := range reachable {
+		if ok {
+			mapping[i] = next
+			next++
+		} else {
+			mapping[i] = -1
+			report.Removed++
+		}
+	}
+	if report.Removed == 0 {
+		return serialized, report, nil
+	}
+
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return "", nil, err
+	}
+
+	entries := make([]json.RawMessage, 0, next)
+	for i, entry := range raw {
+		if !reachable[i] {
+			continue
+		}
+		remapped, err := remapEntry(entry, func(index int) (int, error) {
+			return mapping[index], nil
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		entries = append(entries, remapped)
+	}
+
+	out := string(joinRawTable(entries))
+	report.BytesAfter = len(out)
+	return out, report, nil
+}
+
+func unmarshalRawTable(serialized string) ([]json.RawMessage, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(serialized), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	return raw, nil
+}
+
+func joinRawTable(entries []json.RawMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, entry := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(entry)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// remapEntry rewrites every value-table reference held by a raw entry using
+// remap, leaving sentinels and all other bytes untouched.
+func remapEntry(entry json.RawMessage, remap func(int) (int, error)) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(entry)
+	if len(trimmed) == 0 {
+		return entry, nil
+	}
+
+	rewrite := func(ref json.RawMessage) (json.RawMessage, error) {
+		var v interface{}
+		if err := json.Unmarshal(ref, &v); err != nil {
+			return nil, err
+		}
+		index, err := toInt(v)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 {
+			return ref, nil
+		}
+		mapped, err := remap(index)
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(strconv.Itoa(mapped)), nil
+	}
+
+	switch trimmed[0] {
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+		typeStr, tagged := "", false
+		if len(items) > 0 {
+			tagged = json.Unmarshal(items[0], &typeStr) == nil
+		}
+		for _, slot := range refSlots(typeStr, tagged, len(items)) {
+			rewritten, err := rewrite(items[slot])
+			if err != nil {
+				return nil, err
+			}
+			items[slot] = rewritten
+		}
+		return joinRawTable(items), nil
+
+	case '{':
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for first := true; dec.More(); first = false {
+			start := dec.InputOffset()
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			// Copy the key as written: re-encoding it would escape HTML
+			// characters and change escapes the payload chose.
+			key := trimmed[start:dec.InputOffset()]
+			key = key[bytes.IndexByte(key, '"'):]
+			var ref json.RawMessage
+			if err := dec.Decode(&ref); err != nil {
+				return nil, err
+			}
+			rewritten, err := rewrite(ref)
+			if err != nil {
+				return nil, err
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(rewritten)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,36 @@
+package rehydrate_test
+
+import (
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestCompact(t *testing.T) {
+	input := `[{"b":2,"a":3},"orphan",["Set",3],"kept",["Map",1,1]]`
+	out, report, err := rehydrate.Compact(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"b":1,"a":2},["Set",2],"kept"]`; out != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+	if report.Removed != 2 {
+		t.Fatalf("expected 2 removed entries, got %d", report.Removed)
+	}
+	if report.BytesSaved() != len(input)-len(out) {
+		t.Fatalf("unexpected bytes saved %d", report.BytesSaved())
+	}
+}
+
+func TestCompactKeepsKeyEncoding(t *testing.T) {
+	// Keys are copied as written, without HTML escaping or re-escaping.
+	input := `[{"<a>&b":2, "caf\u00e9":2},"orphan","x"]`
+	out, _, err := rehydrate.Compact(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"<a>&b":1,"caf\u00e9":1},"x"]`; out != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+}
@@ -0,0 +1,220 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PathPair maps a path of a payload to the path of the same value in a JSON
+// document, such as the API response a page was rendered from. Paths are
+// JSON Pointers such as "/data/product/price", like those of Flatten, or use
+// the syntax of Path.Dotted, e.g. "data.product.price"; the empty path is the
+// root and takes the syntax of the other path of the pair.
+type PathPair struct {
+	Payload string `json:"payload"`
+	JSON    string `json:"json"`
+}
+
+// MismatchKind classifies a Mismatch.
+type MismatchKind int
+
+const (
+	// MismatchValue: the value differs between the payload and the JSON.
+	MismatchValue MismatchKind = iota
+	// MismatchMissingInPayload: the value only exists in the JSON.
+	MismatchMissingInPayload
+	// MismatchMissingInJSON: the value only exists in the payload.
+	MismatchMissingInJSON
+)
+
+var mismatchKindNames = [...]string{
+	MismatchValue:            "value",
+	MismatchMissingInPayload: "missing-in-payload",
+	MismatchMissingInJSON:    "missing-in-json",
+}
+
+func (k MismatchKind) String() string {
+	if k < 0 || int(k) >= len(mismatchKindNames) {
+		return ""
+	}
+	return mismatchKindNames[k]
+}
+
+// MarshalText renders the kind by name.
+func (k MismatchKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Mismatch is a scalar that differs between a payload and a JSON document.
+type Mismatch struct {
+	Kind MismatchKind `json:"kind"`
+	// PayloadPath and JSONPath locate the scalar on both sides, below the
+	// paths of the pair that produced the mismatch and in the same syntax.
+	PayloadPath string `json:"payloadPath"`
+	JSONPath    string `json:"jsonPath"`
+	// Payload and JSON are the values as flattened by Flatten, nil for a
+	// missing side.
+	Payload interface{} `json:"payload"`
+	JSON    interface{} `json:"json"`
+}
+
+// CompareWithJSON checks that the values of a payload match those of a JSON
+// document, such as the public API response the page was rendered from, for
+// every pair of mapping. A pair may map whole objects or arrays, which are
+// compared scalar by scalar. Dates match strings holding the same instant,
+// BigInts match numbers with the same digits and binary data matches its
+// base64 encoding. opts are used to hydrate the payload. Mismatches are
+// returned in the order of mapping, then by path.
+func CompareWithJSON(payload string, liveJSON []byte, mapping []PathPair, opts ...Option) ([]Mismatch, error) {
+	v, err := ParseWithOptions(payload, opts...)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(liveJSON))
+	dec.UseNumber()
+	var live interface{}
+	if err := dec.Decode(&live); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+
+	payloadRows := flattenValue(v, true)
+	jsonRows := flattenValue(live, true)
+	var mismatches []Mismatch
+	for _, pair := range mapping {
+		payloadPath, err := pointerPath(pair.Payload)
+		if err != nil {
+			return nil, err
+		}
+		jsonPath, err := pointerPath(pair.JSON)
+		if err != nil {
+			return nil, err
+		}
+		// The root has the same form in both syntaxes, so it follows the
+		// other side of the pair.
+		payloadDotted, jsonDotted := isDotted(pair.Payload), isDotted(pair.JSON)
+		if pair.Payload == "" {
+			payloadDotted = jsonDotted
+		}
+		if pair.JSON == "" {
+			jsonDotted = payloadDotted
+		}
+		left := rowsBelow(payloadRows, payloadPath)
+		right := rowsBelow(jsonRows, jsonPath)
+		suffixes := make(map[string]bool, len(left)+len(right))
+		for suffix := range left {
+			suffixes[suffix] = true
+		}
+		for suffix := range right {
+			suffixes[suffix] = true
+		}
+		sorted := make([]string, 0, len(suffixes))
+		for suffix := range suffixes {
+			sorted = append(sorted, suffix)
+		}
+		sort.Strings(sorted)
+
+		for _, suffix := range sorted {
+			l, inPayload := left[suffix]
+			r, inJSON := right[suffix]
+			m := Mismatch{
+				PayloadPath: joinPath(payloadPath, suffix, payloadDotted),
+				JSONPath:    joinPath(jsonPath, suffix, jsonDotted),
+				Payload:     l,
+				JSON:        r,
+			}
+			switch {
+			case !inPayload:
+				m.Kind = MismatchMissingInPayload
+			case !inJSON:
+				m.Kind = MismatchMissingInJSON
+			case sameScalar(l, r):
+				continue
+			default:
+				m.Kind = MismatchValue
+			}
+			mismatches = append(mismatches, m)
+		}
+	}
+	return mismatches, nil
+}
+
+// rowsBelow returns the rows at or below pointer, keyed by their pointer
+// relative to it: "" for pointer itself, otherwise starting with "/".
+func rowsBelow(rows map[string]interface{}, pointer string) map[string]interface{} {
+	below := make(map[string]interface{})
+	for p, v := range rows {
+		switch {
+		case p == pointer:
+			below[""] = v
+		case strings.HasPrefix(p, pointer) && p[len(pointer)] == '/':
+			below[p[len(pointer):]] = v
+		}
+	}
+	return below
+}
+
+// joinPath appends a suffix returned by rowsBelow to pointer, rendering the
+// result in the syntax of Path.Dotted if dotted is set.
+func joinPath(pointer, suffix string, dotted bool) string {
+	if !dotted {
+		return pointer + suffix
+	}
+	path, _ := ToDotted(pointer + suffix)
+	return path
+}
+
+// isDotted reports whether path is in the syntax of Path.Dotted rather than
+// a JSON Pointer.
+func isDotted(path string) bool {
+	return path != "" && !strings.HasPrefix(path, "/")
+}
+
+// sameScalar reports whether a flattened payload value p equals a flattened
+// JSON value j.
+func sameScalar(p, j interface{}) bool {
+	switch j := j.(type) {
+	case json.Number:
+		switch p := p.(type) {
+		case float64:
+			f, err := j.Float64()
+			return err == nil && f == p
+		case float32:
+			// Float32Array elements match at single precision.
+			f, err := j.Float64()
+			return err == nil && float32(f) == p
+		case string:
+			// BigInts are flattened to their digits.
+			n, ok := parseBigInt(j.String())
+			if !ok {
+				return false
+			}
+			digits, _ := bigIntDigits(n)
+			return digits == p
+		}
+		return false
+	case string:
+		switch p := p.(type) {
+		case string:
+			return p == j
+		case time.Time:
+			t, err := time.Parse(time.RFC3339Nano, j)
+			return err == nil && t.Equal(p)
+		case []byte:
+			return base64.StdEncoding.EncodeToString(p) == j
+		case ArrayBuffer:
+			return base64.StdEncoding.EncodeToString(p) == j
+		}
+		return false
+	case bool:
+		b, ok := p.(bool)
+		return ok && b == j
+	case nil:
+		return p == nil
+	}
+	return false
+}
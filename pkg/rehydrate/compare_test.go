@@ -0,0 +1,83 @@
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestCompareWithJSON(t *testing.T) {
+	payload := `[{"product":1},{"id":2,"price":3,"stock":4,"updated":5,"tags":6,"views":8},"p1",9.99,3,["Date","2024-01-02T00:00:00.000Z"],[7],"new",["BigInt","12345678901234567890"]]`
+	live := []byte(`{"data":{"item":{"id":"p1","price":10.49,"updated":"2024-01-02T00:00:00Z","tags":["new","sale"],"views":12345678901234567890,"sku":"X"}}}`)
+
+	mismatches, err := rehydrate.CompareWithJSON(payload, live, []rehydrate.PathPair{
+		{Payload: "product", JSON: "data.item"},
+		{Payload: "/product/id", JSON: "/data/item/id"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rehydrate.Mismatch{
+		{Kind: rehydrate.MismatchValue, PayloadPath: "product.price", JSONPath: "data.item.price", Payload: 9.99, JSON: mismatches[0].JSON},
+		{Kind: rehydrate.MismatchMissingInPayload, PayloadPath: "product.sku", JSONPath: "data.item.sku", JSON: "X"},
+		{Kind: rehydrate.MismatchMissingInJSON, PayloadPath: "product.stock", JSONPath: "data.item.stock", Payload: 3.0},
+		{Kind: rehydrate.MismatchMissingInPayload, PayloadPath: "product.tags[1]", JSONPath: "data.item.tags[1]", JSON: "sale"},
+	}
+	if !reflect.DeepEqual(mismatches, want) {
+		t.Fatalf("got %+v\nwant %+v", mismatches, want)
+	}
+	if mismatches[0].JSON.(interface{ String() string }).String() != "10.49" {
+		t.Errorf("unexpected JSON value %v", mismatches[0].JSON)
+	}
+}
+
+func TestCompareWithJSONPointers(t *testing.T) {
+	payload := `[{"product":1},{"price":2,"tags":3},9.99,[4],"new"]`
+	live := []byte(`{"data":{"item":{"price":10.49,"tags":["new","sale"]}}}`)
+
+	mismatches, err := rehydrate.CompareWithJSON(payload, live, []rehydrate.PathPair{
+		{Payload: "/product", JSON: "/data/item"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rehydrate.Mismatch{
+		{Kind: rehydrate.MismatchValue, PayloadPath: "/product/price", JSONPath: "/data/item/price", Payload: 9.99, JSON: mismatches[0].JSON},
+		{Kind: rehydrate.MismatchMissingInPayload, PayloadPath: "/product/tags/1", JSONPath: "/data/item/tags/1", JSON: "sale"},
+	}
+	if !reflect.DeepEqual(mismatches, want) {
+		t.Fatalf("got %+v\nwant %+v", mismatches, want)
+	}
+}
+
+func TestCompareWithJSONRoot(t *testing.T) {
+	mismatches, err := rehydrate.CompareWithJSON(`[[1,2],"a","b"]`, []byte(`{"list":["a","c"]}`),
+		[]rehydrate.PathPair{{Payload: "", JSON: "list"}, {Payload: "[0]", JSON: "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 ||
+		mismatches[0].PayloadPath != "[1]" || mismatches[0].JSONPath != "list[1]" ||
+		mismatches[1].Kind != rehydrate.MismatchMissingInJSON || mismatches[1].PayloadPath != "[0]" {
+		t.Errorf("unexpected mismatches %+v", mismatches)
+	}
+
+	if _, err := rehydrate.CompareWithJSON(`[1]`, []byte(`{`), nil); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestCompareWithJSONTypedArrays(t *testing.T) {
+	// Float32Array [1, 0.1] and Int16Array [1, 2].
+	payload := `[{"f":1,"i":2},["Float32Array","AACAP83MzD0="],["Int16Array","AQACAA=="]]`
+	mismatches, err := rehydrate.CompareWithJSON(payload, []byte(`{"f":[1,0.1],"i":[1,3]}`),
+		[]rehydrate.PathPair{{Payload: "", JSON: ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Kind != rehydrate.MismatchValue ||
+		mismatches[0].PayloadPath != "/i/1" || mismatches[0].Payload != 2.0 {
+		t.Errorf("unexpected mismatches %+v", mismatches)
+	}
+}
@@ -0,0 +1,89 @@
+package rehydrate
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Compatibility selects the default behaviors of a release, so consumers can
+// upgrade the package without adopting changed defaults at the same time.
+// See WithCompatibility.
+type Compatibility int
+
+const (
+	// Latest uses the current defaults.
+	Latest Compatibility = iota
+	// V1 pins the behaviors of the first release: Sets hydrate to
+	// map[interface{}]struct{} and Maps to map[interface{}]interface{}, both
+	// unordered; array holes hydrate to nil; and tags without a reviver fail
+	// with ErrUnknownType. Set elements and Map keys that are not comparable,
+	// such as objects, fail with ErrInvalidInput. Typed arrays hydrate to
+	// []byte, as with WithRawTypedArrays.
+	V1
+)
+
+// WithCompatibility pins the hydrated representation to that of the given
+// release. Options such as WithArraySampling still apply on top of it.
+func WithCompatibility(c Compatibility) Option {
+	return func(o *options) {
+		o.compat = c
+	}
+}
+
+// hydrateSetV1 hydrates a Set entry in the V1 representation.
+func (h *hydrator) hydrateSetV1(index int, typeStr string, arr []interface{}) (interface{}, error) {
+	set := make(map[interface{}]struct{}, len(arr)-1)
+	h.store(index, set)
+	for i := 1; i < len(arr); i++ {
+		elemIndex, err := toInt(arr[i])
+		if err != nil {
+			return nil, err
+		}
+		elem, err := h.hydrateElem(elemIndex, i-1)
+		if err != nil {
+			return nil, err
+		}
+		if !isComparable(elem) {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: Set element of type %T is not comparable", ErrInvalidInput, elem))
+		}
+		set[elem] = struct{}{}
+	}
+	return set, nil
+}
+
+// hydrateMapV1 hydrates a Map entry in the V1 representation.
+func (h *hydrator) hydrateMapV1(index int, typeStr string, arr []interface{}) (interface{}, error) {
+	if len(arr)%2 != 1 {
+		return nil, typeError(typeStr, index, fmt.Errorf("%w: odd number of Map entries", ErrInvalidInput))
+	}
+	m := make(map[interface{}]interface{}, len(arr)/2)
+	h.store(index, m)
+	for i := 1; i < len(arr); i += 2 {
+		keyIndex, err := toInt(arr[i])
+		if err != nil {
+			return nil, err
+		}
+		valIndex, err := toInt(arr[i+1])
+		if err != nil {
+			return nil, err
+		}
+		key, err := h.hydrateInPlace(keyIndex)
+		if err != nil {
+			return nil, err
+		}
+		if !isComparable(key) {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: Map key of type %T is not comparable", ErrInvalidInput, key))
+		}
+		val, err := h.hydrateMapValue(valIndex, key)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = val
+	}
+	return m, nil
+}
+
+// isComparable reports whether v can be used as a Go map key without panicking.
+func isComparable(v interface{}) bool {
+	return v == nil || reflect.ValueOf(v).Comparable()
+}
@@ -0,0 +1,59 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestCompatibilityV1(t *testing.T) {
+	payload := `[{"set":1,"map":4,"list":7,"self":0},["Set",2,3],"a","b",["Map",2,5,3,6],1,2,[2,-2]]`
+	v, err := rehydrate.ParseWithOptions(payload, rehydrate.WithCompatibility(rehydrate.V1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	if want := (map[interface{}]struct{}{"a": {}, "b": {}}); !reflect.DeepEqual(root["set"], want) {
+		t.Errorf("set: got %#v, want %#v", root["set"], want)
+	}
+	if want := (map[interface{}]interface{}{"a": 1.0, "b": 2.0}); !reflect.DeepEqual(root["map"], want) {
+		t.Errorf("map: got %#v, want %#v", root["map"], want)
+	}
+	if want := []interface{}{"a", nil}; !reflect.DeepEqual(root["list"], want) {
+		t.Errorf("list: got %#v, want %#v", root["list"], want)
+	}
+
+	latest, err := rehydrate.ParseWithOptions(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := latest.(map[string]interface{})["set"].(*rehydrate.Set); !ok {
+		t.Errorf("expected *Set by default, got %T", latest.(map[string]interface{})["set"])
+	}
+
+	out, err := rehydrate.RehydrateWith(`[{"m":1},["Map",2,3],"k",5]`, nil, rehydrate.WithCompatibility(rehydrate.V1), rehydrate.WithIndent("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `{"m":{"k":5}}` {
+		t.Errorf("unexpected output %s", out)
+	}
+}
+
+func TestCompatibilityV1Errors(t *testing.T) {
+	for name, payload := range map[string]string{
+		"object in set": `[["Set",1],{}]`,
+		"array map key": `[["Map",1,2],[2],3]`,
+	} {
+		_, err := rehydrate.ParseWithOptions(payload, rehydrate.WithCompatibility(rehydrate.V1))
+		var typeErr *rehydrate.TypeError
+		if !errors.Is(err, rehydrate.ErrInvalidInput) || !errors.As(err, &typeErr) {
+			t.Errorf("%s: expected a TypeError wrapping ErrInvalidInput, got %v", name, err)
+		}
+	}
+	if _, err := rehydrate.ParseWithOptions(`[["Widget",1],2]`, rehydrate.WithCompatibility(rehydrate.V1)); !errors.Is(err, rehydrate.ErrUnknownType) {
+		t.Errorf("expected ErrUnknownType, got %v", err)
+	}
+}
@@ -0,0 +1,359 @@
+// Package config loads rehydrate settings from a file, so the CLI and
+// services processing payloads can share one description of their revivers,
+// limits, output options and per-host profiles:
+//
+//	# rehydrate.yaml
+//	preset: nuxt
+//	revivers:
+//	  Money: passthrough
+//	  Secret: "null"
+//	limits:
+//	  timeBudget: 2s
+//	  maxDepth: 64
+//	  maxStringLength: 4096
+//	  deniedTags: [RegExp]
+//	keys:
+//	  transform: [snake_case]
+//	  reserved: drop
+//	output:
+//	  indent: 2
+//	profiles:
+//	  "*.example.com":
+//	    revivers:
+//	      Price: passthrough
+//
+// Files ending in .json are decoded as JSON; all others as YAML. Only a
+// subset of YAML is understood: block and flow mappings and sequences, plain
+// and quoted scalars, and comments.
+//
+// Long-running workers can use Watch to pick up changes to the file without
+// restarting.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/profiles"
+)
+
+// DefaultFile is the name of the configuration file tools look for when none
+// is given explicitly.
+const DefaultFile = "rehydrate.yaml"
+
+// Config is the decoded content of a configuration file. Its Section applies
+// to every payload; Profiles override it for payloads from matching hosts.
+type Config struct {
+	Section
+	// Profiles maps host patterns, as accepted by profiles.Profiles.Add, to
+	// the settings applied on top of the top-level ones.
+	Profiles map[string]Section `json:"profiles,omitempty"`
+}
+
+// Section holds the settings that can appear at the top level of a file and
+// in each profile.
+type Section struct {
+	// Preset names a set of revivers from Presets, such as "nuxt".
+	Preset string `json:"preset,omitempty"`
+	// Revivers maps tags to the name of a reviver in Revivers, applied on top
+	// of the preset.
+	Revivers map[string]string `json:"revivers,omitempty"`
+	Limits   Limits            `json:"limits"`
+	Keys     Keys              `json:"keys"`
+	Output   Output            `json:"output"`
+}
+
+// Limits configures the limits applied during hydration. Zero values leave
+// the corresponding limit unset, or as set by the preset.
+type Limits struct {
+	// Preset names a group of limits from LimitPresets, such as "web",
+	// which the other fields override.
+	Preset          string   `json:"preset,omitempty"`
+	TimeBudget      Duration `json:"timeBudget,omitempty"`
+	MaxDepth        int      `json:"maxDepth,omitempty"`
+	MaxStringLength int      `json:"maxStringLength,omitempty"`
+	MaxBinaryInline int      `json:"maxBinaryInline,omitempty"`
+	ArraySampling   int      `json:"arraySampling,omitempty"`
+	// DeniedTags lists tags rejected during hydration, in addition to those
+	// denied by the preset.
+	DeniedTags []string `json:"deniedTags,omitempty"`
+}
+
+// Keys configures how object keys are hydrated.
+type Keys struct {
+	// Transform lists key transforms applied in order: snake_case,
+	// camel_case or strip_prefix:PREFIX.
+	Transform []string `json:"transform,omitempty"`
+	// Reserved is the reserved key policy: preserve, drop or prefix.
+	Reserved string `json:"reserved,omitempty"`
+	// ReservedPrefix is the prefix used by the prefix policy.
+	ReservedPrefix string `json:"reservedPrefix,omitempty"`
+}
+
+// Output configures how results are rendered.
+type Output struct {
+	// Indent is the number of spaces per level of JSON indentation. Nil
+	// keeps the default; zero produces compact output.
+	Indent *int `json:"indent,omitempty"`
+	// Annotated keeps type information as $type annotations.
+	Annotated bool `json:"annotated,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "1.5s" or a number
+// of seconds.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Presets holds the reviver sets that can be selected with preset. Programs
+// may register their own before loading configuration.
+var Presets = map[string]func() rehydrate.Revivers{
+	"nuxt": rehydrate.DefaultNuxtRevivers,
+}
+
+// LimitPresets holds the limit groups that can be selected with
+// limits.preset. Programs may register their own before loading
+// configuration.
+var LimitPresets = map[string]rehydrate.Limits{
+	"web":      rehydrate.LimitsWeb,
+	"internal": rehydrate.LimitsInternal,
+	"forensic": rehydrate.LimitsForensic,
+}
+
+// Revivers holds the revivers that can be assigned to tags by name. Programs
+// may register their own before loading configuration.
+var Revivers = map[string]rehydrate.ReviverFunc{
+	"passthrough": func(v interface{}) (interface{}, error) { return v, nil },
+	"null":        func(interface{}) (interface{}, error) { return nil, nil },
+}
+
+// Load reads and decodes the configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c *Config
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		c, err = ParseJSON(data)
+	} else {
+		c, err = Parse(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse decodes a YAML configuration and checks that every name it refers
+// to is known.
+func Parse(data []byte) (*Config, error) {
+	v, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("expected a mapping at the top level")
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSON(encoded)
+}
+
+// ParseJSON is like Parse for a JSON configuration.
+func ParseJSON(data []byte) (*Config, error) {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	c := &Config{}
+	if err := dec.Decode(c); err != nil {
+		return nil, err
+	}
+	if _, err := c.ParseOptions(); err != nil {
+		return nil, err
+	}
+	if _, err := c.HostProfiles(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ParseOptions returns the options applying the top-level settings.
+func (c *Config) ParseOptions() ([]rehydrate.Option, error) {
+	return c.Section.ParseOptions()
+}
+
+// HostProfiles returns the top-level settings as the profile for every host
+// together with one profile per entry of Profiles.
+func (c *Config) HostProfiles() (*profiles.Profiles, error) {
+	p := profiles.New()
+	top, err := c.Section.profile("*")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Add("*", top); err != nil {
+		return nil, err
+	}
+
+	patterns := make([]string, 0, len(c.Profiles))
+	for pattern := range c.Profiles {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		profile, err := c.Profiles[pattern].profile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", pattern, err)
+		}
+		if err := p.Add(pattern, profile); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// ParseOptions returns the options applying the section: its revivers
+// followed by its limits, key and output settings.
+func (s Section) ParseOptions() ([]rehydrate.Option, error) {
+	profile, err := s.profile("")
+	if err != nil {
+		return nil, err
+	}
+	return profile.ParseOptions(), nil
+}
+
+func (s Section) revivers() (rehydrate.Revivers, error) {
+	revivers := rehydrate.Revivers{}
+	if s.Preset != "" {
+		preset, ok := Presets[s.Preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q", s.Preset)
+		}
+		for tag, fn := range preset() {
+			revivers[tag] = fn
+		}
+	}
+	for tag, name := range s.Revivers {
+		fn, ok := Revivers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown reviver %q for tag %s", name, tag)
+		}
+		revivers[tag] = fn
+	}
+	return revivers, nil
+}
+
+func (s Section) profile(name string) (profiles.Profile, error) {
+	revivers, err := s.revivers()
+	if err != nil {
+		return profiles.Profile{}, err
+	}
+	var opts []rehydrate.Option
+
+	if s.Limits.Preset != "" {
+		limits, ok := LimitPresets[s.Limits.Preset]
+		if !ok {
+			return profiles.Profile{}, fmt.Errorf("unknown limits preset %q", s.Limits.Preset)
+		}
+		opts = append(opts, rehydrate.WithLimits(limits))
+	}
+	if s.Limits.TimeBudget != 0 {
+		opts = append(opts, rehydrate.WithTimeBudget(time.Duration(s.Limits.TimeBudget)))
+	}
+	if s.Limits.MaxDepth != 0 {
+		opts = append(opts, rehydrate.WithMaxDepth(s.Limits.MaxDepth))
+	}
+	if s.Limits.MaxStringLength != 0 {
+		opts = append(opts, rehydrate.WithMaxStringLength(s.Limits.MaxStringLength))
+	}
+	if s.Limits.MaxBinaryInline != 0 {
+		opts = append(opts, rehydrate.WithMaxBinaryInline(s.Limits.MaxBinaryInline))
+	}
+	if s.Limits.ArraySampling != 0 {
+		opts = append(opts, rehydrate.WithArraySampling(s.Limits.ArraySampling))
+	}
+	if len(s.Limits.DeniedTags) > 0 {
+		opts = append(opts, rehydrate.WithDeniedTags(s.Limits.DeniedTags...))
+	}
+
+	transforms, err := keyTransforms(s.Keys.Transform)
+	if err != nil {
+		return profiles.Profile{}, err
+	}
+	if len(transforms) > 0 {
+		opts = append(opts, rehydrate.WithKeyTransform(transforms...))
+	}
+	switch s.Keys.Reserved {
+	case "":
+	case "preserve":
+		opts = append(opts, rehydrate.WithReservedKeys(rehydrate.PreserveReservedKeys))
+	case "drop":
+		opts = append(opts, rehydrate.WithReservedKeys(rehydrate.DropReservedKeys))
+	case "prefix":
+		opts = append(opts, rehydrate.WithReservedKeys(rehydrate.PrefixReservedKeys))
+	default:
+		return profiles.Profile{}, fmt.Errorf("unknown reserved key policy %q", s.Keys.Reserved)
+	}
+	if s.Keys.ReservedPrefix != "" {
+		opts = append(opts, rehydrate.WithReservedKeyPrefix(s.Keys.ReservedPrefix))
+	}
+
+	if s.Output.Indent != nil {
+		if *s.Output.Indent < 0 {
+			return profiles.Profile{}, fmt.Errorf("negative indent %d", *s.Output.Indent)
+		}
+		opts = append(opts, rehydrate.WithIndent("", strings.Repeat(" ", *s.Output.Indent)))
+	}
+	if s.Output.Annotated {
+		opts = append(opts, rehydrate.WithAnnotatedOutput())
+	}
+	return profiles.Profile{Name: name, Revivers: revivers, Options: opts}, nil
+}
+
+func keyTransforms(names []string) ([]rehydrate.KeyTransform, error) {
+	var transforms []rehydrate.KeyTransform
+	for _, name := range names {
+		switch {
+		case name == "snake_case":
+			transforms = append(transforms, rehydrate.SnakeCase)
+		case name == "camel_case":
+			transforms = append(transforms, rehydrate.CamelCase)
+		case strings.HasPrefix(name, "strip_prefix:"):
+			transforms = append(transforms, rehydrate.StripPrefix(strings.TrimPrefix(name, "strip_prefix:")))
+		default:
+			return nil, fmt.Errorf("unknown key transform %q", name)
+		}
+	}
+	return transforms, nil
+}
@@ -0,0 +1,165 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/config"
+)
+
+const sample = `
+preset: nuxt
+revivers:
+  Secret: "null"
+limits:
+  timeBudget: 2s
+  maxStringLength: 4096
+keys:
+  transform: [snake_case]
+  reserved: drop
+output:
+  indent: 0
+profiles:
+  "*.example.com":
+    revivers:
+      Price: passthrough
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.DefaultFile)
+	if err := os.WriteFile(path, []byte(sample), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Preset != "nuxt" || time.Duration(c.Limits.TimeBudget) != 2*time.Second || c.Limits.MaxStringLength != 4096 {
+		t.Errorf("unexpected config %+v", c.Section)
+	}
+
+	opts, err := c.ParseOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := rehydrate.RehydrateWith(`[{"userName":1,"secret":2,"__proto__":1},"ann",["Secret",1]]`, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `{"secret":null,"user_name":"ann"}` {
+		t.Errorf("unexpected output %s", out)
+	}
+}
+
+func TestHostProfiles(t *testing.T) {
+	c, err := config.Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.HostProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := `[{"price":1,"ref":2},["Price",3],["Ref",3],5]`
+	v, err := rehydrate.ParseWithOptions(payload, p.For("shop.example.com").ParseOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := v.(map[string]interface{}); root["price"] != 5.0 || root["ref"] != 5.0 {
+		t.Errorf("unexpected result %v", root)
+	}
+	if _, err := rehydrate.ParseWithOptions(payload, p.For("other.org").ParseOptions()...); !errors.Is(err, rehydrate.ErrUnknownType) {
+		t.Errorf("expected ErrUnknownType for an unmatched host, got %v", err)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rehydrate.json")
+	if err := os.WriteFile(path, []byte(`{"limits":{"timeBudget":1.5},"keys":{"transform":["strip_prefix:x_"]}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(c.Limits.TimeBudget) != 1500*time.Millisecond {
+		t.Errorf("unexpected time budget %v", time.Duration(c.Limits.TimeBudget))
+	}
+}
+
+func TestLimits(t *testing.T) {
+	c, err := config.Parse([]byte("limits:\n  preset: internal\n  maxDepth: 3\n  deniedTags: [Secret, RegExp]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Limits.MaxDepth != 3 || strings.Join(c.Limits.DeniedTags, ",") != "Secret,RegExp" {
+		t.Errorf("unexpected limits %+v", c.Limits)
+	}
+	opts, err := c.ParseOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	revivers := rehydrate.WithRevivers(map[string]rehydrate.ReviverFunc{
+		"Secret": func(v interface{}) (interface{}, error) { return v, nil },
+	})
+	opts = append(opts, revivers)
+
+	if _, err := rehydrate.ParseWithOptions(`[{"a":1},{"b":2},3]`, opts...); err != nil {
+		t.Errorf("within maxDepth: %v", err)
+	}
+	if _, err := rehydrate.ParseWithOptions(`[{"a":1},{"b":2},{"c":3},4]`, opts...); !errors.Is(err, rehydrate.ErrLimitExceeded) {
+		t.Errorf("beyond maxDepth: got %v, want ErrLimitExceeded", err)
+	}
+	for _, payload := range []string{`[["Secret",1],"x"]`, `[["RegExp","a+","g"]]`} {
+		if _, err := rehydrate.ParseWithOptions(payload, opts...); !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("%s: got %v, want ErrInvalidInput", payload, err)
+		}
+	}
+
+	// The preset's denied tags stay denied alongside the listed ones.
+	c, err = config.ParseJSON([]byte(`{"limits":{"preset":"web","deniedTags":["Secret"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts, err = c.ParseOptions(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rehydrate.ParseWithOptions(`[["RegExp","a+","g"]]`, opts...); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("preset tag: got %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"preset: vue",
+		"revivers:\n  Money: decimal",
+		"keys:\n  transform: [kebab_case]",
+		"keys:\n  reserved: rename",
+		"limits:\n  timeBudget: soon",
+		"limits:\n  preset: strict",
+		"output:\n  indent: -1",
+		"unknown: 1",
+		"profiles:\n  \"a*b\": {}",
+		"- a",
+	} {
+		if _, err := config.Parse([]byte(input)); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	_, err := config.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("expected the path in %v", err)
+	}
+}
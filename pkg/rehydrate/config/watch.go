@@ -0,0 +1,200 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/profiles"
+)
+
+// DefaultPollInterval is how often a Watcher checks its file for changes
+// unless PollInterval says otherwise.
+const DefaultPollInterval = 5 * time.Second
+
+// WatchOption configures a Watcher.
+type WatchOption func(*Watcher)
+
+// PollInterval sets how often the file's modification time and size are
+// checked. Zero disables polling, leaving only signals and explicit calls to
+// Reload.
+func PollInterval(d time.Duration) WatchOption {
+	return func(w *Watcher) {
+		w.interval = d
+	}
+}
+
+// ReloadOnSignal reloads the file whenever one of sigs is received, such as
+// syscall.SIGHUP.
+func ReloadOnSignal(sigs ...os.Signal) WatchOption {
+	return func(w *Watcher) {
+		w.signals = append(w.signals, sigs...)
+	}
+}
+
+// OnReload sets a function called after every reload attempt with the new
+// configuration, or with the error that kept the previous one in place.
+func OnReload(fn func(*Config, error)) WatchOption {
+	return func(w *Watcher) {
+		w.onReload = fn
+	}
+}
+
+// Watcher keeps the configuration loaded from a file up to date, so
+// long-running workers pick up new limits and profiles without a restart.
+// A file that fails to load leaves the previous configuration in effect.
+// It is safe for concurrent use.
+type Watcher struct {
+	path     string
+	interval time.Duration
+	signals  []os.Signal
+	onReload func(*Config, error)
+
+	current atomic.Pointer[loaded]
+
+	mu      sync.Mutex // serializes reloads
+	modTime time.Time
+	size    int64
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// loaded is a configuration together with the values derived from it, which
+// are computed once per reload.
+type loaded struct {
+	config   *Config
+	opts     []rehydrate.Option
+	profiles *profiles.Profiles
+}
+
+// Watch loads the configuration file at path and starts watching it for
+// changes. It fails if the file cannot be loaded initially. Call Close to
+// stop watching.
+func Watch(path string, opts ...WatchOption) (*Watcher, error) {
+	w := &Watcher{
+		path:     path,
+		interval: DefaultPollInterval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	// Subscribe before returning, so no signal sent after Watch is missed.
+	var sig chan os.Signal
+	if len(w.signals) > 0 {
+		sig = make(chan os.Signal, 1)
+		signal.Notify(sig, w.signals...)
+	}
+	go w.run(sig)
+	return w, nil
+}
+
+// Config returns the current configuration.
+func (w *Watcher) Config() *Config {
+	return w.current.Load().config
+}
+
+// ParseOptions returns the options applying the current top-level settings.
+// It is suited to rehydrate.WithReloadedOptions.
+func (w *Watcher) ParseOptions() []rehydrate.Option {
+	return w.current.Load().opts
+}
+
+// HostProfiles returns the profiles of the current configuration.
+func (w *Watcher) HostProfiles() *profiles.Profiles {
+	return w.current.Load().profiles
+}
+
+// Reload loads the file again, whether or not it changed.
+func (w *Watcher) Reload() error {
+	err := w.load()
+	if w.onReload != nil {
+		var c *Config
+		if err == nil {
+			c = w.Config()
+		}
+		w.onReload(c, err)
+	}
+	return err
+}
+
+// Close stops watching the file. The last configuration remains available.
+func (w *Watcher) Close() error {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+	return nil
+}
+
+func (w *Watcher) load() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	// Record the attempt even if it fails, so a broken file is reported
+	// once rather than on every poll.
+	w.modTime, w.size = info.ModTime(), info.Size()
+	c, err := Load(w.path)
+	if err != nil {
+		return err
+	}
+	opts, err := c.ParseOptions()
+	if err != nil {
+		return err
+	}
+	p, err := c.HostProfiles()
+	if err != nil {
+		return err
+	}
+	w.current.Store(&loaded{config: c, opts: opts, profiles: p})
+	return nil
+}
+
+// changed reports whether the file's modification time or size differ from
+// those seen by the last reload.
+func (w *Watcher) changed() bool {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !info.ModTime().Equal(w.modTime) || info.Size() != w.size
+}
+
+func (w *Watcher) run(sig chan os.Signal) {
+	defer close(w.done)
+	if sig != nil {
+		defer signal.Stop(sig)
+	}
+
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-tick:
+			if w.changed() {
+				w.Reload()
+			}
+		case <-sig:
+			w.Reload()
+		}
+	}
+}
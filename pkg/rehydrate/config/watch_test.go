@@ -0,0 +1,91 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/config"
+)
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.DefaultFile)
+	write := func(content string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("limits:\n  maxStringLength: 10\n", start)
+
+	reloads := make(chan error, 10)
+	w, err := config.Watch(path,
+		config.PollInterval(10*time.Millisecond),
+		config.OnReload(func(_ *config.Config, err error) { reloads <- err }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.Config().Limits.MaxStringLength != 10 {
+		t.Fatalf("unexpected config %+v", w.Config())
+	}
+
+	write("revivers:\n  Widget: passthrough\n", start.Add(time.Minute))
+	if err := waitReload(t, reloads); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rehydrate.ParseWithOptions(`[["Widget",1],2]`, w.ParseOptions()...); err != nil {
+		t.Errorf("expected the reloaded reviver to apply, got %v", err)
+	}
+
+	write("preset: unknown\n", start.Add(2*time.Minute))
+	if err := waitReload(t, reloads); err == nil {
+		t.Fatal("expected an error reloading an invalid file")
+	}
+	if w.Config().Revivers["Widget"] != "passthrough" {
+		t.Errorf("expected the previous config to remain, got %+v", w.Config())
+	}
+}
+
+func TestWatcherSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.DefaultFile)
+	if err := os.WriteFile(path, []byte("preset: nuxt\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reloads := make(chan error, 10)
+	w, err := config.Watch(path,
+		config.PollInterval(0),
+		config.ReloadOnSignal(syscall.SIGHUP),
+		config.OnReload(func(_ *config.Config, err error) { reloads <- err }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitReload(t, reloads); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitReload(t *testing.T, reloads <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-reloads:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a reload")
+		return nil
+	}
+}
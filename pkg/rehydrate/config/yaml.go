@@ -0,0 +1,345 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML decodes the subset of YAML used by configuration files: block
+// mappings and sequences nested by indentation, flow sequences and mappings
+// on a single line, plain and quoted scalars, and comments. Anchors, tags,
+// multi-line scalars and multiple documents are not supported.
+func parseYAML(data []byte) (interface{}, error) {
+	var lines []yamlLine
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(stripComment(text), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	p := &yamlParser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return v, nil
+}
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block parses the mapping or sequence whose items start at indent.
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if strings.HasPrefix(p.lines[p.pos].text, "-") && isSequenceItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isSequenceItem(line.text) {
+			break
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		switch {
+		case rest == "":
+			p.pos++
+			item, err := p.nested(indent, line.number)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		case isMappingEntry(rest):
+			// "- key: value" starts a mapping indented past the dash.
+			p.lines[p.pos] = yamlLine{number: line.number, indent: indent + 2, text: rest}
+			item, err := p.mapping(indent + 2)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		default:
+			p.pos++
+			item, err := parseScalar(rest, line.number)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+		key, rest, err := splitMappingEntry(line.text, line.number)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		p.pos++
+		if rest == "" {
+			if m[key], err = p.nested(indent, line.number); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if m[key], err = parseScalar(rest, line.number); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// nested parses the block below a key or dash with no inline value, which is
+// null if the next line is not indented further. A sequence may also start
+// at the indentation of its parent key, as is common in YAML files.
+func (p *yamlParser) nested(indent, number int) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (next.indent == indent && isSequenceItem(next.text) && !isSequenceItem(p.lines[p.pos-1].text)) {
+		return p.block(next.indent)
+	}
+	return nil, nil
+}
+
+func isMappingEntry(text string) bool {
+	_, _, err := splitMappingEntry(text, 0)
+	return err == nil && text[0] != '[' && text[0] != '{'
+}
+
+func splitMappingEntry(text string, number int) (key, rest string, err error) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", fmt.Errorf("line %d: unterminated string", number)
+		}
+		k, err := parseScalar(text[:end+1], number)
+		if err != nil {
+			return "", "", err
+		}
+		after := text[end+1:]
+		if !strings.HasPrefix(after, ":") {
+			return "", "", fmt.Errorf("line %d: expected ':' after key", number)
+		}
+		return k.(string), strings.TrimSpace(after[1:]), nil
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", fmt.Errorf("line %d: expected 'key: value'", number)
+		}
+		i = len(text) - 1
+	}
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), nil
+}
+
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment removes a trailing comment that is not inside quotes.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// parseScalar parses an inline value: a scalar or a flow collection.
+func parseScalar(text string, number int) (interface{}, error) {
+	f := &flowParser{text: text, number: number}
+	v, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	if f.skipSpace(); f.pos < len(f.text) {
+		return nil, fmt.Errorf("line %d: unexpected %q", number, f.text[f.pos:])
+	}
+	return v, nil
+}
+
+type flowParser struct {
+	text   string
+	pos    int
+	number int
+	inFlow bool
+}
+
+func (f *flowParser) skipSpace() {
+	for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *flowParser) value() (interface{}, error) {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return nil, nil
+	}
+	switch c := f.text[f.pos]; c {
+	case '[':
+		return f.collection(']')
+	case '{':
+		return f.collection('}')
+	case '"', '\'':
+		end := closingQuote(f.text[f.pos:])
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unterminated string", f.number)
+		}
+		quoted := f.text[f.pos : f.pos+end+1]
+		f.pos += end + 1
+		if c == '\'' {
+			return strings.ReplaceAll(quoted[1:len(quoted)-1], "''", "'"), nil
+		}
+		s, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", f.number, err)
+		}
+		return s, nil
+	}
+
+	start := f.pos
+	stop := "\x00"
+	if f.inFlow {
+		stop = ",]}"
+	}
+	for f.pos < len(f.text) && !strings.ContainsRune(stop, rune(f.text[f.pos])) {
+		if f.inFlow && f.text[f.pos] == ':' && (f.pos+1 == len(f.text) || f.text[f.pos+1] == ' ') {
+			break
+		}
+		f.pos++
+	}
+	return plainScalar(strings.TrimSpace(f.text[start:f.pos])), nil
+}
+
+func (f *flowParser) collection(closer byte) (interface{}, error) {
+	f.pos++
+	outer := f.inFlow
+	f.inFlow = true
+	defer func() { f.inFlow = outer }()
+
+	var items []interface{}
+	m := map[string]interface{}{}
+	for {
+		f.skipSpace()
+		if f.pos >= len(f.text) {
+			return nil, fmt.Errorf("line %d: unterminated flow collection", f.number)
+		}
+		if f.text[f.pos] == closer {
+			f.pos++
+			break
+		}
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		if closer == '}' {
+			f.skipSpace()
+			if f.pos >= len(f.text) || f.text[f.pos] != ':' {
+				return nil, fmt.Errorf("line %d: expected ':' in flow mapping", f.number)
+			}
+			f.pos++
+			item, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(v)] = item
+		} else {
+			items = append(items, v)
+		}
+		f.skipSpace()
+		if f.pos < len(f.text) && f.text[f.pos] == ',' {
+			f.pos++
+		}
+	}
+	if closer == '}' {
+		return m, nil
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	return items, nil
+}
+
+func plainScalar(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return float64(i)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
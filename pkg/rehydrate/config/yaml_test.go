@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	input := `
+# comment
+name: "quoted # not a comment"
+count: 3
+ratio: 0.5
+enabled: true
+empty:
+list:
+  - a
+  - 'it''s'
+inline: [x, 2, {k: v}]
+items:
+- name: first
+  tags: [a, b]
+- name: second
+nested:
+  deeper:
+    key: value   # trailing comment
+`
+	got, err := parseYAML([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":    "quoted # not a comment",
+		"count":   3.0,
+		"ratio":   0.5,
+		"enabled": true,
+		"empty":   nil,
+		"list":    []interface{}{"a", "it's"},
+		"inline":  []interface{}{"x", 2.0, map[string]interface{}{"k": "v"}},
+		"items": []interface{}{
+			map[string]interface{}{"name": "first", "tags": []interface{}{"a", "b"}},
+			map[string]interface{}{"name": "second"},
+		},
+		"nested": map[string]interface{}{"deeper": map[string]interface{}{"key": "value"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, input := range []string{
+		"a: 1\n  b: 2",
+		"a: 1\na: 2",
+		"a: [1, 2",
+		"just text",
+	} {
+		if _, err := parseYAML([]byte(input)); err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
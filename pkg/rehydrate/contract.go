@@ -0,0 +1,162 @@
+package rehydrate
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"strconv"
+)
+
+// ReviverContract is a set of rules revivers are checked against with
+// WithReviverContracts. Violations otherwise surface far from their cause,
+// for example as a nil dereference in code consuming the hydrated value.
+type ReviverContract int
+
+const (
+	// ContractNonNil requires revivers to return a non-nil value or an
+	// error.
+	ContractNonNil ReviverContract = 1 << iota
+	// ContractNoMutation requires revivers to leave their input unchanged,
+	// since other values of the payload may share it. Checking it costs a
+	// walk over the input before and after each call.
+	ContractNoMutation
+
+	// ContractAll enables every check.
+	ContractAll = ContractNonNil | ContractNoMutation
+)
+
+// WithReviverContracts checks the revivers for tags against contract, or
+// every reviver if no tags are given. A violation fails the parse with a
+// *ContractError. Calls add up, so tag-specific contracts can be combined
+// with one for every reviver.
+func WithReviverContracts(contract ReviverContract, tags ...string) Option {
+	return func(o *options) {
+		if len(tags) == 0 {
+			o.contract |= contract
+			return
+		}
+		if o.tagContracts == nil {
+			o.tagContracts = make(map[string]ReviverContract)
+		}
+		for _, tag := range tags {
+			o.tagContracts[tag] |= contract
+		}
+	}
+}
+
+// ContractError reports a reviver that violated its contract. It wraps
+// ErrInvalidInput.
+type ContractError struct {
+	// Tag is the tag the reviver is registered for.
+	Tag string
+	// Path is the path of the revived value, empty for the root.
+	Path string
+	// Violation is the rule that was broken.
+	Violation ReviverContract
+}
+
+func (e *ContractError) Error() string {
+	var rule string
+	switch e.Violation {
+	case ContractNonNil:
+		rule = "returned nil without an error"
+	case ContractNoMutation:
+		rule = "modified its input"
+	default:
+		rule = "violated contract " + strconv.Itoa(int(e.Violation))
+	}
+	return fmt.Sprintf("reviver for %s at %s %s", e.Tag, displayPath(e.Path), rule)
+}
+
+func (e *ContractError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+func (o *options) reviverContract(tag string) ReviverContract {
+	return o.contract | o.tagContracts[tag]
+}
+
+// reviveChecked calls the reviver for the entry at index, checking its
+// contract.
+func (h *hydrator) reviveChecked(reviver ReviverFunc, tag string, index int, in interface{}) (interface{}, error) {
+	contract := h.opts.reviverContract(tag)
+	if contract == 0 {
+		return h.revive(reviver, tag, index, in)
+	}
+	var before uint64
+	if contract&ContractNoMutation != 0 {
+		before = fingerprint(in)
+	}
+	res, err := h.revive(reviver, tag, index, in)
+	if err != nil {
+		return nil, err
+	}
+	violation := ReviverContract(0)
+	switch {
+	case contract&ContractNonNil != 0 && res == nil:
+		violation = ContractNonNil
+	case contract&ContractNoMutation != 0 && fingerprint(in) != before:
+		violation = ContractNoMutation
+	}
+	if violation != 0 {
+		return nil, &ContractError{Tag: tag, Path: h.pathOf(index), Violation: violation}
+	}
+	return res, nil
+}
+
+// fingerprint hashes the content of a hydrated value, following containers.
+func fingerprint(v interface{}) uint64 {
+	f := &fingerprinter{h: fnv.New64a(), seen: make(map[uintptr]int)}
+	f.write(v)
+	return f.h.Sum64()
+}
+
+type fingerprinter struct {
+	h    hash.Hash64
+	seen map[uintptr]int
+}
+
+func (f *fingerprinter) write(v interface{}) {
+	if id, ok := containerID(v); ok {
+		if n, seen := f.seen[id]; seen {
+			fmt.Fprintf(f.h, "<%d>", n)
+			return
+		}
+		f.seen[id] = len(f.seen)
+	}
+	switch value := v.(type) {
+	case []interface{}:
+		fmt.Fprintf(f.h, "[%d", len(value))
+		for _, item := range value {
+			f.write(item)
+		}
+	case map[string]interface{}:
+		fmt.Fprintf(f.h, "{%d", len(value))
+		for _, key := range sortedKeys(value) {
+			fmt.Fprintf(f.h, "%q:", key)
+			f.write(value[key])
+		}
+	case *Set:
+		fmt.Fprintf(f.h, "Set%d", value.Len())
+		for _, item := range value.Values() {
+			f.write(item)
+		}
+	case *OrderedMap:
+		fmt.Fprintf(f.h, "Map%d", value.Len())
+		for _, e := range value.Entries() {
+			f.write(e.Key)
+			f.write(e.Value)
+		}
+	case *SampledArray:
+		fmt.Fprintf(f.h, "Sampled%d:%v", value.Length, value.Indices)
+		for _, item := range value.Values {
+			f.write(item)
+		}
+	case float64:
+		fmt.Fprintf(f.h, "n%x", math.Float64bits(value))
+	default:
+		fmt.Fprintf(f.h, "%T:%v;", v, v)
+	}
+	f.h.Write([]byte{0})
+}
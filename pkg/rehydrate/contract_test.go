@@ -0,0 +1,50 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestReviverContracts(t *testing.T) {
+	payload := `[{"price":1,"tags":3,"again":3},["Money",2],{"amount":4},["Tags",5],10,[6],"a"]`
+	revivers := rehydrate.Revivers{
+		"Money": func(interface{}) (interface{}, error) { return nil, nil },
+		"Tags": func(v interface{}) (interface{}, error) {
+			arr := v.([]interface{})
+			arr[0] = "mutated"
+			return len(arr), nil
+		},
+	}
+
+	// Without contracts, both revivers are accepted.
+	if _, err := rehydrate.ParseWithOptions(payload, rehydrate.WithRevivers(revivers)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := rehydrate.ParseWithOptions(payload, rehydrate.WithRevivers(revivers),
+		rehydrate.WithReviverContracts(rehydrate.ContractNonNil, "Money"))
+	var contractErr *rehydrate.ContractError
+	if !errors.As(err, &contractErr) || !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Fatalf("expected a ContractError, got %v", err)
+	}
+	if contractErr.Tag != "Money" || contractErr.Path != "/price" || contractErr.Violation != rehydrate.ContractNonNil {
+		t.Errorf("unexpected error %+v", contractErr)
+	}
+
+	// The contract only applies to the listed tags.
+	if _, err := rehydrate.ParseWithOptions(payload, rehydrate.WithRevivers(revivers),
+		rehydrate.WithReviverContracts(rehydrate.ContractNonNil, "Tags")); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	_, err = rehydrate.ParseWithOptions(payload, rehydrate.WithRevivers(revivers),
+		rehydrate.WithReviverContracts(rehydrate.ContractNoMutation))
+	if !errors.As(err, &contractErr) || contractErr.Tag != "Tags" || contractErr.Violation != rehydrate.ContractNoMutation {
+		t.Errorf("expected a mutation violation, got %v", err)
+	}
+	if err != nil && err.Error() != `Tags at index 3: reviver for Tags at /again modified its input` {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
@@ -0,0 +1,276 @@
+// Package debug collects live statistics about hydration in a running
+// service and serves them over HTTP, for mounting next to net/http/pprof:
+//
+//	stats := debug.NewStats()
+//	mux.Handle("/debug/rehydrate", stats)
+//	stats.Publish("rehydrate") // also expose them through expvar
+//
+//	v, err := stats.Parse(r.Host, payload)
+//
+// Parses are attributed to a caller-chosen source, such as the host the
+// payload was fetched from, so the slowest sources can be identified.
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// rateWindow is the number of seconds parse rates are averaged over.
+const rateWindow = 60
+
+// Stats collects hydration statistics. It is safe for concurrent use and
+// serves a JSON Snapshot as an http.Handler.
+type Stats struct {
+	clock      rehydrate.Clock
+	topSources int
+
+	mu      sync.Mutex
+	start   time.Time
+	parses  int64
+	errors  map[string]int64
+	hits    int64
+	misses  int64
+	buckets [rateWindow]bucket
+	sources map[string]*sourceStats
+}
+
+type bucket struct {
+	second int64
+	count  int64
+}
+
+type sourceStats struct {
+	parses int64
+	total  time.Duration
+	max    time.Duration
+}
+
+// Option configures NewStats.
+type Option func(*Stats)
+
+// WithClock sets the clock used to time parses and compute rates. The
+// default is rehydrate.SystemClock.
+func WithClock(c rehydrate.Clock) Option {
+	return func(s *Stats) {
+		if c != nil {
+			s.clock = c
+		}
+	}
+}
+
+// WithTopSources sets how many of the slowest sources a Snapshot lists. The
+// default is 10.
+func WithTopSources(n int) Option {
+	return func(s *Stats) {
+		if n >= 0 {
+			s.topSources = n
+		}
+	}
+}
+
+// NewStats returns an empty collector.
+func NewStats(opts ...Option) *Stats {
+	s := &Stats{
+		clock:      rehydrate.SystemClock,
+		topSources: 10,
+		errors:     make(map[string]int64),
+		sources:    make(map[string]*sourceStats),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.start = s.clock.Now()
+	return s
+}
+
+// Parse hydrates serialized with rehydrate.ParseWithOptions and records the
+// parse under source.
+func (s *Stats) Parse(source, serialized string, opts ...rehydrate.Option) (interface{}, error) {
+	start := s.clock.Now()
+	v, err := rehydrate.ParseWithOptions(serialized, opts...)
+	s.Record(source, s.clock.Now().Sub(start), err)
+	return v, err
+}
+
+// Rehydrate renders serialized with rehydrate.RehydrateWith and records the
+// conversion under source.
+func (s *Stats) Rehydrate(source, serialized string, extra rehydrate.Revivers, opts ...rehydrate.Option) (string, error) {
+	start := s.clock.Now()
+	out, err := rehydrate.RehydrateWith(serialized, extra, opts...)
+	s.Record(source, s.clock.Now().Sub(start), err)
+	return out, err
+}
+
+// Record adds a parse of a payload from source that took elapsed and failed
+// with err, if non-nil. Parse and Rehydrate call it; services that hydrate
+// by other means call it directly.
+func (s *Stats) Record(source string, elapsed time.Duration, err error) {
+	now := s.clock.Now().Unix()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parses++
+	if err != nil {
+		s.errors[category(err)]++
+	}
+	b := &s.buckets[now%rateWindow]
+	if b.second != now {
+		*b = bucket{second: now}
+	}
+	b.count++
+
+	src := s.sources[source]
+	if src == nil {
+		src = &sourceStats{}
+		s.sources[source] = src
+	}
+	src.parses++
+	src.total += elapsed
+	if elapsed > src.max {
+		src.max = elapsed
+	}
+}
+
+// RecordCache records a lookup in a cache of hydrated payloads kept by the
+// caller.
+func (s *Stats) RecordCache(hit bool) {
+	s.mu.Lock()
+	if hit {
+		s.hits++
+	} else {
+		s.misses++
+	}
+	s.mu.Unlock()
+}
+
+// Snapshot is the state of a Stats collector at one point in time.
+type Snapshot struct {
+	UptimeSeconds float64 `json:"uptimeSeconds"`
+	Parses        int64   `json:"parses"`
+	// ParsesPerSecond is averaged over the last minute.
+	ParsesPerSecond float64 `json:"parsesPerSecond"`
+	// Errors counts failed parses by category: invalid_input,
+	// unknown_type, bad_reference, limit_exceeded or error.
+	Errors    map[string]int64 `json:"errors"`
+	ErrorRate float64          `json:"errorRate"`
+	Cache     CacheStats       `json:"cache"`
+	// SlowSources lists the sources with the highest mean parse time,
+	// slowest first.
+	SlowSources []SourceStats `json:"slowSources"`
+}
+
+// CacheStats summarizes the lookups passed to RecordCache.
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+}
+
+// SourceStats summarizes the parses recorded for one source.
+type SourceStats struct {
+	Source string        `json:"source"`
+	Parses int64         `json:"parses"`
+	Mean   time.Duration `json:"meanNanos"`
+	Max    time.Duration `json:"maxNanos"`
+}
+
+// Snapshot returns the current statistics.
+func (s *Stats) Snapshot() Snapshot {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := Snapshot{
+		UptimeSeconds: now.Sub(s.start).Seconds(),
+		Parses:        s.parses,
+		Errors:        make(map[string]int64, len(s.errors)),
+		Cache:         CacheStats{Hits: s.hits, Misses: s.misses},
+		SlowSources:   []SourceStats{},
+	}
+
+	var failed int64
+	for cat, n := range s.errors {
+		snap.Errors[cat] = n
+		failed += n
+	}
+	if s.parses > 0 {
+		snap.ErrorRate = float64(failed) / float64(s.parses)
+	}
+	if lookups := s.hits + s.misses; lookups > 0 {
+		snap.Cache.HitRate = float64(s.hits) / float64(lookups)
+	}
+
+	var recent int64
+	for _, b := range s.buckets {
+		if now.Unix()-b.second < rateWindow {
+			recent += b.count
+		}
+	}
+	window := float64(rateWindow)
+	if snap.UptimeSeconds < window {
+		window = snap.UptimeSeconds
+	}
+	if window < 1 {
+		window = 1
+	}
+	snap.ParsesPerSecond = float64(recent) / window
+
+	for source, src := range s.sources {
+		snap.SlowSources = append(snap.SlowSources, SourceStats{
+			Source: source,
+			Parses: src.parses,
+			Mean:   src.total / time.Duration(src.parses),
+			Max:    src.max,
+		})
+	}
+	sort.Slice(snap.SlowSources, func(i, j int) bool {
+		a, b := snap.SlowSources[i], snap.SlowSources[j]
+		if a.Mean != b.Mean {
+			return a.Mean > b.Mean
+		}
+		return a.Source < b.Source
+	})
+	if len(snap.SlowSources) > s.topSources {
+		snap.SlowSources = snap.SlowSources[:s.topSources]
+	}
+	return snap
+}
+
+// ServeHTTP writes the current Snapshot as JSON.
+func (s *Stats) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(s.Snapshot())
+}
+
+// Publish exposes the statistics as the expvar variable name, so they also
+// appear under /debug/vars. Like expvar.Publish, it panics if the name is
+// already in use.
+func (s *Stats) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return s.Snapshot()
+	}))
+}
+
+func category(err error) string {
+	switch {
+	case errors.Is(err, rehydrate.ErrUnknownType):
+		return "unknown_type"
+	case errors.Is(err, rehydrate.ErrBadReference):
+		return "bad_reference"
+	case errors.Is(err, rehydrate.ErrLimitExceeded):
+		return "limit_exceeded"
+	case errors.Is(err, rehydrate.ErrInvalidInput):
+		return "invalid_input"
+	}
+	return "error"
+}
@@ -0,0 +1,51 @@
+package debug_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/debug"
+)
+
+// tickClock advances by a millisecond every time it is read.
+type tickClock struct{ now time.Time }
+
+func (c *tickClock) Now() time.Time {
+	c.now = c.now.Add(time.Millisecond)
+	return c.now
+}
+
+func TestStats(t *testing.T) {
+	clock := &tickClock{now: time.Unix(1700000000, 0)}
+	stats := debug.NewStats(debug.WithClock(clock), debug.WithTopSources(1))
+
+	if _, err := stats.Parse("a.example", `[{"x":1},"y"]`); err != nil {
+		t.Fatal(err)
+	}
+	stats.Parse("b.example", `[["Widget",0]]`)
+	stats.Record("c.example", time.Second, nil)
+	stats.RecordCache(true)
+	stats.RecordCache(false)
+
+	rec := httptest.NewRecorder()
+	stats.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/rehydrate", nil))
+	var snap debug.Snapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if snap.Parses != 3 || snap.Errors["unknown_type"] != 1 {
+		t.Errorf("unexpected counts %+v", snap)
+	}
+	if snap.ErrorRate != 1.0/3 || snap.Cache.HitRate != 0.5 {
+		t.Errorf("unexpected rates %+v", snap)
+	}
+	if snap.ParsesPerSecond != 3 {
+		t.Errorf("expected 3 parses/s within the first second, got %v", snap.ParsesPerSecond)
+	}
+	if len(snap.SlowSources) != 1 || snap.SlowSources[0].Source != "c.example" || snap.SlowSources[0].Max != time.Second {
+		t.Errorf("unexpected slow sources %+v", snap.SlowSources)
+	}
+}
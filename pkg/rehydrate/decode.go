@@ -0,0 +1,453 @@
+package rehydrate
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseInto hydrates a serialized payload into dst, which must be a non-nil
+// pointer, consulting revivers for custom type tags. See Decode.
+func ParseInto(serialized string, dst interface{}, revivers Revivers) error {
+	return ParseIntoWithOptions(serialized, dst, WithRevivers(revivers))
+}
+
+// ParseIntoWithOptions hydrates a serialized payload into dst using the
+// behaviour configured by opts. See Decode.
+func ParseIntoWithOptions(serialized string, dst interface{}, opts ...Option) error {
+	v, err := ParseWithOptions(serialized, opts...)
+	if err != nil {
+		return err
+	}
+	return Decode(v, dst)
+}
+
+// Decode stores the hydrated value v in dst, which must be a non-nil
+// pointer, following the rules of encoding/json where they apply:
+//
+//   - objects fill structs, matching keys to the names given by json
+//     struct tags or to field names, case-insensitively as a fallback, and
+//     fill maps whose keys are strings, integers or implement
+//     encoding.TextUnmarshaler
+//   - Maps fill maps and structs the same way, with keys of any type
+//   - arrays and Sets fill slices and arrays
+//   - Dates fill time.Time, which also accepts RFC 3339 strings
+//   - numbers fill integer fields only if they are integral and in range;
+//     BigInts fill integers too
+//   - values assignable to the destination, such as *big.Int, []byte or
+//     the result of a reviver, are stored as they are
+//   - types implementing json.Unmarshaler receive v rendered as JSON
+//
+// Keys without a matching field are ignored. Shared values are decoded
+// separately at every place they appear; cyclic values cannot be decoded.
+// Errors wrap ErrInvalidInput and name the path of the value that did not
+// fit.
+func Decode(v interface{}, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%w: Decode needs a non-nil pointer, got %T", ErrInvalidInput, dst)
+	}
+	d := &decoder{active: make(map[uintptr]bool)}
+	return d.decode("", v, rv.Elem())
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type decoder struct {
+	// active holds the containers being decoded, to detect cycles.
+	active map[uintptr]bool
+}
+
+func (d *decoder) mismatch(path string, v interface{}, dst reflect.Value) error {
+	return fmt.Errorf("%w: cannot decode %s into %s at %s", ErrInvalidInput, typeName(v), dst.Type(), displayPath(path))
+}
+
+// typeName names the JavaScript type of a hydrated value in errors.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string, UTF16String:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case time.Time:
+		return TagDate.String()
+	case *Set:
+		return TagSet.String()
+	case *OrderedMap:
+		return TagMap.String()
+	}
+	if _, ok := bigIntDigits(v); ok {
+		return TagBigInt.String()
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func (d *decoder) decode(path string, v interface{}, dst reflect.Value) error {
+	if v == nil {
+		switch dst.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			dst.SetZero()
+		}
+		return nil
+	}
+	if src := reflect.ValueOf(v); src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return d.decode(path, v, dst.Elem())
+	}
+	if id, ok := containerID(v); ok {
+		if d.active[id] {
+			return fmt.Errorf("%w: cyclic value at %s", ErrInvalidInput, displayPath(path))
+		}
+		d.active[id] = true
+		defer delete(d.active, id)
+	}
+
+	if dst.Type() == timeType {
+		var t time.Time
+		switch value := v.(type) {
+		case string:
+			var err error
+			if t, err = time.Parse(time.RFC3339Nano, value); err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrInvalidInput, displayPath(path), err)
+			}
+		default:
+			return d.mismatch(path, v, dst)
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if dst.CanAddr() {
+		if u, ok := dst.Addr().Interface().(json.Unmarshaler); ok {
+			data, err := json.Marshal(v)
+			if err == nil {
+				err = u.UnmarshalJSON(data)
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrInvalidInput, displayPath(path), err)
+			}
+			return nil
+		}
+		if u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			s, ok := v.(string)
+			if !ok {
+				return d.mismatch(path, v, dst)
+			}
+			if err := u.UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrInvalidInput, displayPath(path), err)
+			}
+			return nil
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return d.mismatch(path, v, dst)
+		}
+		dst.SetBool(b)
+	case reflect.String:
+		switch value := v.(type) {
+		case string:
+			dst.SetString(value)
+		case UTF16String:
+			dst.SetString(value.String())
+		default:
+			return d.mismatch(path, v, dst)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := integer(v)
+		i, err := strconv.ParseInt(n, 10, 64)
+		if !ok || err != nil || dst.OverflowInt(i) {
+			return d.mismatch(path, v, dst)
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := integer(v)
+		u, err := strconv.ParseUint(n, 10, 64)
+		if !ok || err != nil || dst.OverflowUint(u) {
+			return d.mismatch(path, v, dst)
+		}
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, ok := v.(float64)
+		if !ok || dst.OverflowFloat(f) {
+			return d.mismatch(path, v, dst)
+		}
+		dst.SetFloat(f)
+	case reflect.Slice, reflect.Array:
+		var items []interface{}
+		switch value := v.(type) {
+		case []interface{}:
+			items = value
+		case *Set:
+			items = value.Values()
+		default:
+			return d.mismatch(path, v, dst)
+		}
+		if dst.Kind() == reflect.Slice {
+			dst.Set(reflect.MakeSlice(dst.Type(), len(items), len(items)))
+		} else if len(items) > dst.Len() {
+			return fmt.Errorf("%w: %d elements do not fit %s at %s", ErrInvalidInput, len(items), dst.Type(), displayPath(path))
+		}
+		for i, item := range items {
+			if err := d.decode(indexPath(path, i), item, dst.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		return d.decodeMap(path, v, dst)
+	case reflect.Struct:
+		return d.decodeStruct(path, v, dst)
+	default:
+		return d.mismatch(path, v, dst)
+	}
+	return nil
+}
+
+// integer returns the digits of a number or BigInt holding an integer.
+func integer(v interface{}) (string, bool) {
+	if f, ok := v.(float64); ok {
+		if f != math.Trunc(f) || math.IsInf(f, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	}
+	return bigIntDigits(v)
+}
+
+// entries returns the keys and values of an object or Map.
+func entries(v interface{}) ([]MapEntry, bool) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		keys := sortedKeys(value)
+		es := make([]MapEntry, len(keys))
+		for i, key := range keys {
+			es[i] = MapEntry{Key: key, Value: value[key]}
+		}
+		return es, true
+	case *OrderedMap:
+		return value.Entries(), true
+	}
+	return nil, false
+}
+
+func (d *decoder) decodeMap(path string, v interface{}, dst reflect.Value) error {
+	es, ok := entries(v)
+	if !ok {
+		return d.mismatch(path, v, dst)
+	}
+	t := dst.Type()
+	m := reflect.MakeMapWithSize(t, len(es))
+	for _, e := range es {
+		entryPath := mapKeyPath(path, e.Key)
+		key := reflect.New(t.Key()).Elem()
+		if err := d.decodeKey(entryPath, e.Key, key); err != nil {
+			return err
+		}
+		val := reflect.New(t.Elem()).Elem()
+		if err := d.decode(entryPath, e.Value, val); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, val)
+	}
+	dst.Set(m)
+	return nil
+}
+
+// decodeKey decodes a map key. Object keys are strings, so they also fill
+// integer keys and keys implementing encoding.TextUnmarshaler, as with
+// encoding/json.
+func (d *decoder) decodeKey(path string, key interface{}, dst reflect.Value) error {
+	s, ok := key.(string)
+	if !ok {
+		return d.decode(path, key, dst)
+	}
+	if u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidInput, displayPath(path), err)
+		}
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%w: key %q does not fit %s at %s", ErrInvalidInput, s, dst.Type(), displayPath(path))
+		}
+		return d.decode(path, f, dst)
+	}
+	return d.decode(path, key, dst)
+}
+
+func (d *decoder) decodeStruct(path string, v interface{}, dst reflect.Value) error {
+	es, ok := entries(v)
+	if !ok {
+		return d.mismatch(path, v, dst)
+	}
+	fields := structFields(dst.Type())
+	for _, e := range es {
+		name, ok := MapKeyString(e.Key)
+		if !ok {
+			continue
+		}
+		f, ok := fields.byName[name]
+		if !ok {
+			f, ok = fields.byFold[strings.ToLower(name)]
+		}
+		if !ok {
+			continue
+		}
+		field, err := fieldByIndex(dst, f.index)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidInput, displayPath(path), err)
+		}
+		if err := d.decode(keyPath(path, name), e.Value, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndex returns the field at index, allocating embedded struct
+// pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+type field struct {
+	name  string
+	index []int
+}
+
+type fieldSet struct {
+	byName map[string]field
+	byFold map[string]field
+}
+
+var fieldCache sync.Map // map[reflect.Type]fieldSet
+
+// structFields returns the fields of a struct type that keys decode into,
+// named and promoted from embedded structs as encoding/json does: a field
+// shallower in the embedding hierarchy, or the only tagged one at its
+// depth, wins over others of the same name, and ambiguous names are
+// dropped.
+func structFields(t reflect.Type) fieldSet {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.(fieldSet)
+	}
+
+	type candidate struct {
+		field
+		tagged bool
+	}
+	var all []candidate
+	var walk func(t reflect.Type, index []int, visited map[reflect.Type]bool)
+	walk = func(t reflect.Type, index []int, visited map[reflect.Type]bool) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			idx := append(append([]int(nil), index...), i)
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, idx, visited)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = sf.Name
+			}
+			all = append(all, candidate{field{name, idx}, tagged})
+		}
+		visited[t] = false
+	}
+	walk(t, nil, make(map[reflect.Type]bool))
+
+	// Group the candidates by name and keep the dominant one.
+	dominant := make(map[string]candidate)
+	ambiguous := make(map[string]bool)
+	for _, c := range all {
+		best, seen := dominant[c.name]
+		switch {
+		case !seen || len(c.index) < len(best.index):
+			dominant[c.name] = c
+			delete(ambiguous, c.name)
+		case len(c.index) == len(best.index):
+			switch {
+			case c.tagged && !best.tagged:
+				dominant[c.name] = c
+				delete(ambiguous, c.name)
+			case c.tagged == best.tagged:
+				ambiguous[c.name] = true
+			}
+		}
+	}
+
+	fs := fieldSet{byName: make(map[string]field), byFold: make(map[string]field)}
+	for name, c := range dominant {
+		if ambiguous[name] {
+			continue
+		}
+		fs.byName[name] = c.field
+	}
+	// The case-insensitive fallback prefers the first field in declaration
+	// order.
+	for _, c := range all {
+		if f, ok := fs.byName[c.name]; ok && slices.Equal(f.index, c.index) {
+			folded := strings.ToLower(c.name)
+			if _, taken := fs.byFold[folded]; !taken {
+				fs.byFold[folded] = f
+			}
+		}
+	}
+	fieldCache.Store(t, fs)
+	return fs
+}
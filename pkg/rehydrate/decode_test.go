@@ -0,0 +1,91 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+type decodeBase struct {
+	ID int `json:"id"`
+}
+
+type decodeProduct struct {
+	decodeBase
+	Name    string         `json:"name"`
+	Created time.Time      `json:"created"`
+	Tags    []string       `json:"tags"`
+	Prices  map[string]int `json:"prices"`
+	Stock   map[int]bool   `json:"stock"`
+	Parent  *decodeProduct `json:"parent"`
+	Extra   interface{}    `json:"extra"`
+	Skipped string         `json:"-"`
+	Title   string
+	Sizes   [2]float64       `json:"sizes"`
+	Owner   *rehydrate.Set   `json:"owner"`
+	Counts  map[string][]int `json:"counts"`
+}
+
+type decodeNode struct {
+	Self *decodeNode `json:"self"`
+}
+
+func TestParseInto(t *testing.T) {
+	payload := `[{"id":1,"name":2,"created":3,"tags":4,"prices":7,"stock":9,"parent":12,"extra":14,"Skipped":2,"title":2,"sizes":15,"owner":4,"counts":17},` +
+		`7,"shoe",["Date","2024-01-02T00:00:00.000Z"],["Set",5,6],"red","blue",{"eur":8},1999,` +
+		`["Map",10,11],36,true,{"id":13},8,[5],[16,16],0.5,{}]`
+
+	var p decodeProduct
+	if err := rehydrate.ParseInto(payload, &p, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := decodeProduct{
+		decodeBase: decodeBase{ID: 7},
+		Name:       "shoe",
+		Created:    time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Tags:       []string{"red", "blue"},
+		Prices:     map[string]int{"eur": 1999},
+		Stock:      map[int]bool{36: true},
+		Parent:     &decodeProduct{decodeBase: decodeBase{ID: 8}},
+		Extra:      []interface{}{"red"},
+		Title:      "shoe",
+		Sizes:      [2]float64{0.5, 0.5},
+		Counts:     map[string][]int{},
+	}
+	owner := p.Owner
+	p.Owner = nil
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v\nwant %+v", p, want)
+	}
+	if owner == nil || !owner.Has("red") {
+		t.Errorf("owner = %v", owner)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		payload string
+		dst     interface{}
+		want    string
+	}{
+		{`[{"id":1},1.5]`, &decodeBase{}, "cannot decode number into int at /id"},
+		{`[{"id":1},"x"]`, &decodeBase{}, "cannot decode string into int at /id"},
+		{`[[1],300]`, &[]int8{}, "cannot decode number into int8 at /0"},
+		{`[{"self":0}]`, &decodeNode{}, "cyclic value at /self"},
+	}
+	for _, tt := range tests {
+		err := rehydrate.ParseInto(tt.payload, tt.dst, nil)
+		if !errors.Is(err, rehydrate.ErrInvalidInput) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseInto(%s) error = %v, want %q", tt.payload, err, tt.want)
+		}
+	}
+
+	var p decodeProduct
+	if err := rehydrate.Decode(map[string]interface{}{}, p); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("Decode into a non-pointer: error = %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Package dedupe stores payloads content-addressed by their canonical hash,
+// so a payload seen under many URLs is kept once:
+//
+//	d := dedupe.New(dedupe.NewMemoryStore())
+//	ref, _, err := d.Put(payload)
+//	...
+//	payload, err := d.Get(ref)
+//
+// Payloads are stored in the canonical form produced by rehydrate.Canonical,
+// so equivalent payloads from different serializers share one entry.
+package dedupe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// ErrNotFound is returned by Get for references with no stored payload.
+var ErrNotFound = errors.New("dedupe: payload not found")
+
+// Ref identifies a stored payload. It is the payload's rehydrate.Hash.
+type Ref string
+
+// Store holds payloads by reference. Implementations must be safe for
+// concurrent use; Put must tolerate storing the same reference twice.
+type Store interface {
+	// Put stores payload under ref.
+	Put(ref Ref, payload []byte) error
+	// Get returns the payload stored under ref, or ErrNotFound.
+	Get(ref Ref) ([]byte, error)
+	// Has reports whether a payload is stored under ref.
+	Has(ref Ref) (bool, error)
+}
+
+// Dedupe stores payloads in a Store, each unique payload once.
+type Dedupe struct {
+	store Store
+}
+
+// New returns a Dedupe backed by store.
+func New(store Store) *Dedupe {
+	return &Dedupe{store: store}
+}
+
+// Put stores serialized unless an equivalent payload is already stored, and
+// returns its reference. created reports whether the payload was new.
+func (d *Dedupe) Put(serialized string) (ref Ref, created bool, err error) {
+	canonical, err := rehydrate.Canonical(serialized)
+	if err != nil {
+		return "", false, err
+	}
+	// Hashing the canonical form directly matches rehydrate.Hash without
+	// canonicalizing twice.
+	sum := sha256.Sum256([]byte(canonical))
+	ref = Ref(hex.EncodeToString(sum[:]))
+
+	exists, err := d.store.Has(ref)
+	if err != nil || exists {
+		return ref, false, err
+	}
+	if err := d.store.Put(ref, []byte(canonical)); err != nil {
+		return "", false, err
+	}
+	return ref, true, nil
+}
+
+// Get returns the canonical payload stored under ref.
+func (d *Dedupe) Get(ref Ref) (string, error) {
+	payload, err := d.store.Get(ref)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
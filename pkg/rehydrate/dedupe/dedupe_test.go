@@ -0,0 +1,40 @@
+package dedupe_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/dedupe"
+)
+
+func TestDedupe(t *testing.T) {
+	store := dedupe.NewMemoryStore()
+	d := dedupe.New(store)
+
+	first, created, err := d.Put(`[{"b":1,"a":2},"x","y"]`)
+	if err != nil || !created {
+		t.Fatalf("first put: %v %v", created, err)
+	}
+	second, created, err := d.Put(`[{"a":2,"b":3},"orphan","y","x"]`)
+	if err != nil || created || second != first {
+		t.Fatalf("equivalent put: %s %v %v", second, created, err)
+	}
+	if hash, _ := rehydrate.Hash(`[{"b":1,"a":2},"x","y"]`); dedupe.Ref(hash) != first {
+		t.Errorf("reference %s is not the payload hash %s", first, hash)
+	}
+	if store.Len() != 1 {
+		t.Errorf("expected one stored payload, got %d", store.Len())
+	}
+
+	payload, err := d.Get(first)
+	if err != nil || payload != `[{"a":1,"b":2},"y","x"]` {
+		t.Errorf("get: %s %v", payload, err)
+	}
+	if _, err := d.Get("missing"); !errors.Is(err, dedupe.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, _, err := d.Put(`[`); err == nil {
+		t.Error("expected an error for a malformed payload")
+	}
+}
@@ -0,0 +1,122 @@
+package dedupe
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryStore is a Store that keeps payloads in memory.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	payloads map[Ref][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{payloads: make(map[Ref][]byte)}
+}
+
+func (s *MemoryStore) Put(ref Ref, payload []byte) error {
+	s.mu.Lock()
+	s.payloads[ref] = append([]byte(nil), payload...)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Get(ref Ref) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	payload, ok := s.payloads[ref]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), payload...), nil
+}
+
+func (s *MemoryStore) Has(ref Ref) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.payloads[ref]
+	return ok, nil
+}
+
+// Len returns the number of stored payloads.
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.payloads)
+}
+
+// DirStore is a Store that keeps each payload in a file below a directory,
+// fanned out by the first two characters of the reference. Files are written
+// to a temporary name and renamed, so readers never see partial payloads and
+// several processes may share the directory.
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore returns a DirStore rooted at dir, creating it if needed.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+func (s *DirStore) path(ref Ref) (string, error) {
+	if len(ref) < 3 || filepath.Base(string(ref)) != string(ref) {
+		return "", fmt.Errorf("dedupe: invalid reference %q", ref)
+	}
+	return filepath.Join(s.dir, string(ref[:2]), string(ref[2:])), nil
+}
+
+func (s *DirStore) Put(ref Ref, payload []byte) error {
+	path, err := s.path(ref)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *DirStore) Get(ref Ref) ([]byte, error) {
+	path, err := s.path(ref)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return payload, err
+}
+
+func (s *DirStore) Has(ref Ref) (bool, error) {
+	path, err := s.path(ref)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,37 @@
+package dedupe_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/dedupe"
+)
+
+func TestDirStore(t *testing.T) {
+	store, err := dedupe.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := dedupe.New(store)
+
+	ref, created, err := d.Put(`[["Set",1],"x"]`)
+	if err != nil || !created {
+		t.Fatalf("put: %v %v", created, err)
+	}
+	if _, created, _ := d.Put(`[["Set",1],"x"]`); created {
+		t.Error("expected the second put to find the stored payload")
+	}
+	if payload, err := d.Get(ref); err != nil || payload != `[["Set",1],"x"]` {
+		t.Errorf("get: %s %v", payload, err)
+	}
+
+	if ok, err := store.Has("ab0000"); ok || err != nil {
+		t.Errorf("has missing: %v %v", ok, err)
+	}
+	if _, err := store.Get("ab0000"); !errors.Is(err, dedupe.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Get("../etc"); err == nil {
+		t.Error("expected an error for a reference escaping the directory")
+	}
+}
@@ -0,0 +1,38 @@
+// Package rehydrate hydrates payloads produced by devalue, the serializer used
+// by Nuxt and SvelteKit to ship server state to the browser.
+//
+// A payload is a JSON array acting as a value table: entry 0 is the root and
+// every other entry is referenced by its index, which lets the format express
+// shared and cyclic values as well as types JSON lacks, such as Date, Map,
+// Set, BigInt and typed arrays.
+//
+// # Concurrency
+//
+// Parsing functions may be called from multiple goroutines. Values returned
+// by a parse are fully materialised before the call returns and nothing in
+// this package mutates them afterwards, so a result may be read from any
+// number of goroutines without synchronisation. Functions that post-process
+// results, such as ConvertUnsupportedTypes, build new values instead of
+// modifying their input. Callers that modify a result themselves must
+// provide their own synchronisation.
+//
+// # Paths
+//
+// Reports, errors and functions such as Search and Flatten locate values with
+// RFC 6901 JSON Pointers: /data/items/0/first name. Positions of array and
+// Set elements are tokens like array indices, Map values are addressed by the
+// string form of their key, see MapKeyString, and the empty path is the
+// root. Every function taking a path, such as TypeAt, Tree.Lookup or
+// WithPathPolicy, also accepts the JavaScript-like syntax of Path.Dotted,
+// like data.items[0]["first name"]. The Path type converts between the two
+// forms.
+//
+// # Minimal build
+//
+// Building with the rehydrate_min tag leaves out the regexp and math/big
+// packages, so the parser compiles with TinyGo for edge and WebAssembly
+// runtimes. BigInts and RegExps then hydrate to *Tagged values holding the
+// data of their payload entry, BigInt64Arrays and BigUint64Arrays hydrate to
+// []int64 and []uint64, regular expression search is unavailable and
+// Anonymize is left out.
+package rehydrate
@@ -0,0 +1,36 @@
+package rehydrate_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// TestConcurrentReadsOfResult is meant to be run with -race.
+func TestConcurrentReadsOfResult(t *testing.T) {
+	input := `[{"map":1,"set":6,"list":8},["Map",2,3,4,5],"a",1,{"x":3},"b",["Set",2,4],0,[2,4,7]]`
+	result, err := rehydrate.Parse(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			root := result.(map[string]interface{})
+			m := root["map"].(*rehydrate.OrderedMap)
+			for _, key := range m.Keys() {
+				m.Get(key)
+			}
+			root["set"].(*rehydrate.Set).Has("a")
+			if _, err := rehydrate.RehydrateWith(input, nil); err != nil {
+				t.Error(err)
+			}
+			rehydrate.ConvertUnsupportedTypes(result)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,219 @@
+package rehydrate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DumpOption configures Dump.
+type DumpOption func(*dumper)
+
+// DumpIndent sets the string used for each level of indentation.
+func DumpIndent(indent string) DumpOption {
+	return func(d *dumper) {
+		d.indent = indent
+	}
+}
+
+// DumpMaxDepth limits how many levels of nesting are printed. Deeper
+// containers are shown by their summary only. Zero means no limit.
+func DumpMaxDepth(depth int) DumpOption {
+	return func(d *dumper) {
+		d.maxDepth = depth
+	}
+}
+
+// DumpMaxItems limits how many elements of each container are printed.
+// Zero means no limit.
+func DumpMaxItems(n int) DumpOption {
+	return func(d *dumper) {
+		d.maxItems = n
+	}
+}
+
+// DumpLocale formats numbers, sizes and dates for readers of the locale l.
+func DumpLocale(l Locale) DumpOption {
+	return func(d *dumper) {
+		d.locale = l
+	}
+}
+
+// Dump writes a human-readable, type-annotated tree of the hydrated value v
+// to w, for example:
+//
+//	Object(2) {
+//	  created: Date(2024-01-02T00:00:00Z)
+//	  tags: Set(2) [
+//	    "a"
+//	    "b"
+//	  ]
+//	}
+//
+// Containers reached a second time are printed as a reference to the path
+// where they were first shown, so shared and cyclic values are visible.
+func Dump(v interface{}, w io.Writer, opts ...DumpOption) error {
+	bw := bufio.NewWriter(w)
+	d := &dumper{
+		w:      bw,
+		indent: "  ",
+		seen:   make(map[uintptr]string),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.dump("", v, 0)
+	d.w.WriteByte('\n')
+	return bw.Flush()
+}
+
+type dumper struct {
+	w        *bufio.Writer
+	indent   string
+	maxDepth int
+	maxItems int
+	locale   Locale
+	seen     map[uintptr]string
+}
+
+func (d *dumper) dump(path string, v interface{}, depth int) {
+	if obj, ok := v.(NullObject); ok {
+		v = map[string]interface{}(obj)
+	}
+	if id, ok := containerID(v); ok {
+		if first, seen := d.seen[id]; seen {
+			fmt.Fprintf(d.w, "%s <ref %s>", containerSummary(v), displayPath(first))
+			return
+		}
+		d.seen[id] = path
+	}
+
+	switch value := v.(type) {
+	case []interface{}:
+		d.container(containerSummary(v), "[", "]", depth, len(value), func(i int) {
+			d.dump(indexPath(path, i), value[i], depth+1)
+		})
+	case *Set:
+		items := value.Values()
+		d.container(containerSummary(v), "[", "]", depth, len(items), func(i int) {
+			d.dump(indexPath(path, i), items[i], depth+1)
+		})
+	case map[string]interface{}:
+		keys := sortedKeys(value)
+		d.container(containerSummary(v), "{", "}", depth, len(keys), func(i int) {
+			d.w.WriteString(dumpKey(keys[i]) + ": ")
+			d.dump(keyPath(path, keys[i]), value[keys[i]], depth+1)
+		})
+	case *SampledArray:
+		d.container(containerSummary(v), "[", "]", depth, len(value.Values), func(i int) {
+			fmt.Fprintf(d.w, "%d: ", value.Indices[i])
+			d.dump(indexPath(path, value.Indices[i]), value.Values[i], depth+1)
+		})
+	case *OrderedMap:
+		entries := value.entries
+		d.container(containerSummary(v), "{", "}", depth, len(entries), func(i int) {
+			d.w.WriteString(dumpScalar(entries[i].Key, d.locale) + " => ")
+			d.dump(mapKeyPath(path, entries[i].Key), entries[i].Value, depth+1)
+		})
+	default:
+		d.w.WriteString(dumpScalar(v, d.locale))
+	}
+}
+
+func (d *dumper) container(summary, open, close string, depth, n int, item func(int)) {
+	d.w.WriteString(summary)
+	if n == 0 || (d.maxDepth > 0 && depth >= d.maxDepth) {
+		return
+	}
+	d.w.WriteString(" " + open + "\n")
+	shown := n
+	if d.maxItems > 0 && shown > d.maxItems {
+		shown = d.maxItems
+	}
+	prefix := strings.Repeat(d.indent, depth+1)
+	for i := 0; i < shown; i++ {
+		d.w.WriteString(prefix)
+		item(i)
+		d.w.WriteByte('\n')
+	}
+	if shown < n {
+		fmt.Fprintf(d.w, "%s… %d more\n", prefix, n-shown)
+	}
+	d.w.WriteString(strings.Repeat(d.indent, depth) + close)
+}
+
+func containerSummary(v interface{}) string {
+	switch value := v.(type) {
+	case []interface{}:
+		return fmt.Sprintf("Array(%d)", len(value))
+	case *Set:
+		return fmt.Sprintf("Set(%d)", value.Len())
+	case map[string]interface{}:
+		return fmt.Sprintf("Object(%d)", len(value))
+	case *OrderedMap:
+		return fmt.Sprintf("Map(%d)", value.Len())
+	case *SampledArray:
+		return fmt.Sprintf("Array(%d, sampled %d)", value.Length, len(value.Values))
+	}
+	return ""
+}
+
+func dumpKey(key string) string {
+	if isIdentifier(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func dumpScalar(v interface{}, l Locale) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(value)
+	case UTF16String:
+		return "UTF16String(" + strconv.Quote(value.String()) + ")"
+	case float64:
+		return l.FormatNumber(value)
+	case bool:
+		return strconv.FormatBool(value)
+	case time.Time:
+		return "Date(" + l.FormatTime(value) + ")"
+	case []byte:
+		return "Binary(" + l.FormatSize(float64(len(value))) + ")"
+	case *LazyRef:
+		return "LazyRef(" + strconv.Itoa(value.Index) + ")"
+	case *Truncated:
+		return dumpScalar(value.Value, l) + "… (" + l.FormatSize(float64(value.Length)) + ")"
+	case *Wrapped:
+		return value.Kind + "(" + dumpScalar(value.Value, l) + ")"
+	}
+	if digits, ok := bigIntDigits(v); ok {
+		return digits + "n"
+	}
+	if source, flags, ok := regExpSource(v); ok {
+		return "/" + source + "/" + flags
+	}
+	if tag, ok := typedArrayTag(v); ok {
+		return fmt.Sprintf("%s(%d)", tag, reflect.ValueOf(v).Len())
+	}
+	if summary := containerSummary(v); summary != "" {
+		return summary
+	}
+	return fmt.Sprintf("%T(%v)", v, v)
+}
+
+func formatSize(n int) string {
+	return Locale{}.FormatSize(float64(n))
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,53 @@
+package rehydrate_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestDump(t *testing.T) {
+	input := `[{"created":1,"tags":2,"prices":5,"blob":8,"self":0,"list":9},` +
+		`["Date","2024-01-02T00:00:00Z"],["Set",3,4],"a","b",["Map",6,7],"EUR",12,` +
+		`["Uint8Array","AAECAw=="],[3,4,3]]`
+	v, err := rehydrate.Parse(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := rehydrate.Dump(v, &b); err != nil {
+		t.Fatal(err)
+	}
+	want := `Object(6) {
+  blob: Binary(4B)
+  created: Date(2024-01-02T00:00:00Z)
+  list: Array(3) [
+    "a"
+    "b"
+    "a"
+  ]
+  prices: Map(1) {
+    "EUR" => 12
+  }
+  self: Object(6) <ref (root)>
+  tags: Set(2) [
+    "a"
+    "b"
+  ]
+}
+`
+	if b.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	list := v.(map[string]interface{})["list"]
+	if err := rehydrate.Dump(list, &b, rehydrate.DumpMaxItems(2)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Array(3) [\n  \"a\"\n  \"b\"\n  … 1 more\n]\n"; b.String() != want {
+		t.Fatalf("got %q, want %q", b.String(), want)
+	}
+}
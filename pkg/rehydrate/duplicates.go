@@ -0,0 +1,150 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// DuplicateKeyPolicy controls how objects that repeat a key are hydrated.
+// JSON allows duplicate keys and encoding/json keeps the last value, which
+// hides what is usually a bug in the serializer that produced the payload.
+type DuplicateKeyPolicy int
+
+const (
+	// KeepLastDuplicateKey silently keeps the last value, like
+	// encoding/json. It is the default.
+	KeepLastDuplicateKey DuplicateKeyPolicy = iota
+	// RejectDuplicateKeys fails the parse with ErrInvalidInput.
+	RejectDuplicateKeys
+	// ReportDuplicateKeys keeps the last value and records every duplicate
+	// in the report given to WithDuplicateKeyReport.
+	ReportDuplicateKeys
+)
+
+// DuplicateKey is a key that occurs more than once in an object of the value
+// table.
+type DuplicateKey struct {
+	// Index is the value-table index of the object.
+	Index int    `json:"index"`
+	Key   string `json:"key"`
+	// Count is the number of times the key occurs.
+	Count int `json:"count"`
+}
+
+// WithDuplicateKeys sets the policy for objects that repeat a key. Detecting
+// duplicates requires decoding every object a second time at the token
+// level, so the check is off by default.
+func WithDuplicateKeys(policy DuplicateKeyPolicy) Option {
+	return func(o *options) {
+		o.duplicateKeys = policy
+	}
+}
+
+// WithDuplicateKeyReport selects ReportDuplicateKeys and appends the
+// duplicates found by the parse to report, ordered by index and then by the
+// first occurrence of each key.
+func WithDuplicateKeyReport(report *[]DuplicateKey) Option {
+	return func(o *options) {
+		o.duplicateKeys = ReportDuplicateKeys
+		o.duplicateReport = report
+	}
+}
+
+// checkDuplicateKeys applies the duplicate key policy to the plain and
+// null-prototype objects of serialized.
+func checkDuplicateKeys(serialized string, o *options) error {
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return err
+	}
+	for i, entry := range raw {
+		if err := checkEntryDuplicates(i, entry, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEntryDuplicates applies the duplicate key policy to the entry at
+// index i.
+func checkEntryDuplicates(i int, entry json.RawMessage, o *options) error {
+	keys, err := entryKeys(entry)
+	if err != nil {
+		return fmt.Errorf("%w: index %d: %w", ErrInvalidInput, i, err)
+	}
+	for _, dup := range duplicateKeys(i, keys) {
+		if o.duplicateKeys == RejectDuplicateKeys {
+			return fmt.Errorf("%w: duplicate key %q in object at index %d", ErrInvalidInput, dup.Key, i)
+		}
+		if o.duplicateReport != nil {
+			*o.duplicateReport = append(*o.duplicateReport, dup)
+		}
+	}
+	return nil
+}
+
+// entryKeys returns the keys of an object entry in source order: the member
+// names of a JSON object, or the keys of a ["null", key, ref, ...] entry.
+func entryKeys(entry json.RawMessage) ([]string, error) {
+	trimmed := bytes.TrimSpace(entry)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	switch trimmed[0] {
+	case '{':
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		var keys []string
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			keys = append(keys, tok.(string))
+		}
+		return keys, nil
+
+	case '[':
+		var items []interface{}
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+		if len(items) == 0 || items[0] != TagNull.String() {
+			return nil, nil
+		}
+		var keys []string
+		for i := 1; i < len(items); i += 2 {
+			if key, ok := items[i].(string); ok {
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	}
+	return nil, nil
+}
+
+func duplicateKeys(index int, keys []string) []DuplicateKey {
+	if len(keys) < 2 {
+		return nil
+	}
+	counts := make(map[string]int, len(keys))
+	for _, key := range keys {
+		counts[key]++
+	}
+	var dups []DuplicateKey
+	for _, key := range keys {
+		if n := counts[key]; n > 1 {
+			dups = append(dups, DuplicateKey{Index: index, Key: key, Count: n})
+			counts[key] = 0
+		}
+	}
+	return dups
+}
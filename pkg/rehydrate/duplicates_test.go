@@ -0,0 +1,37 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	input := `[{"a":1,"b":2,"a":3,"a":4},"x",["null","k",1,"k",3],"y","z"]`
+
+	v, err := rehydrate.ParseWithOptions(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := v.(map[string]interface{})["a"]; a != "z" {
+		t.Errorf("expected the last value to win by default, got %v", a)
+	}
+
+	if _, err := rehydrate.ParseWithOptions(input, rehydrate.WithDuplicateKeys(rehydrate.RejectDuplicateKeys)); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+
+	var report []rehydrate.DuplicateKey
+	if _, err := rehydrate.ParseWithOptions(input, rehydrate.WithDuplicateKeyReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	want := []rehydrate.DuplicateKey{
+		{Index: 0, Key: "a", Count: 3},
+		{Index: 2, Key: "k", Count: 2},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("got %+v, want %+v", report, want)
+	}
+}
@@ -0,0 +1,68 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ParseEmbedded hydrates a payload wrapped in a JSON envelope, such as an API
+// response carrying it in a field. path locates the field as a JSON Pointer,
+// e.g. "/data/payload", or in the syntax of Path.Dotted, e.g.
+// "results[0].state". The field may
+// hold the value table itself or a string containing the serialized payload.
+//
+// It returns the decoded envelope, in which the field is replaced by the
+// hydrated value, together with the hydrated value.
+func ParseEmbedded(doc []byte, path string, opts ...Option) (envelope, value interface{}, err error) {
+	if err := json.Unmarshal(doc, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+
+	segments := splitPath(path)
+	var parent interface{}
+	field := envelope
+	for _, segment := range segments {
+		parent = field
+		switch container := field.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if field, ok = container[segment]; !ok {
+				return nil, nil, fmt.Errorf("%w: no value at %s", ErrInvalidInput, displayPath(path))
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(container) {
+				return nil, nil, fmt.Errorf("%w: no value at %s", ErrInvalidInput, displayPath(path))
+			}
+			field = container[i]
+		default:
+			return nil, nil, fmt.Errorf("%w: no value at %s", ErrInvalidInput, displayPath(path))
+		}
+	}
+
+	switch payload := field.(type) {
+	case string:
+		value, err = ParseWithOptions(payload, opts...)
+	case []interface{}:
+		value, err = ParseValues(payload, nil, opts...)
+	default:
+		return nil, nil, fmt.Errorf("%w: %s is not a payload", ErrInvalidInput, displayPath(path))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(segments) == 0 {
+		return value, value, nil
+	}
+	last := segments[len(segments)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[last] = value
+	case []interface{}:
+		i, _ := strconv.Atoi(last)
+		container[i] = value
+	}
+	return envelope, value, nil
+}
@@ -0,0 +1,40 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParseEmbedded(t *testing.T) {
+	doc := []byte(`{"status":"ok","data":{"results":[{"state":[{"tags":1},["Set",2],"a"]}]}}`)
+	envelope, value, err := rehydrate.ParseEmbedded(doc, "data.results[0].state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, ok := value.(map[string]interface{})["tags"].(*rehydrate.Set)
+	if !ok || !set.Has("a") {
+		t.Fatalf("unexpected value %#v", value)
+	}
+	results := envelope.(map[string]interface{})["data"].(map[string]interface{})["results"].([]interface{})
+	if !reflect.DeepEqual(results[0].(map[string]interface{})["state"], value) {
+		t.Errorf("envelope does not hold the hydrated value: %#v", envelope)
+	}
+	if envelope.(map[string]interface{})["status"] != "ok" {
+		t.Errorf("unexpected envelope %#v", envelope)
+	}
+
+	// Payloads may also be embedded as strings.
+	_, value, err = rehydrate.ParseEmbedded([]byte(`{"payload":"[{\"n\":1},5]"}`), `["payload"]`)
+	if err != nil || !reflect.DeepEqual(value, map[string]interface{}{"n": 5.0}) {
+		t.Errorf("string payload: got %#v, %v", value, err)
+	}
+
+	for _, path := range []string{"data.missing", "data.results[3]", "status.x", "status"} {
+		if _, _, err := rehydrate.ParseEmbedded(doc, path); !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("%s: got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package rehydrate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors describing the category of a failure. Errors returned by
+// this package wrap one of them, so callers can branch with errors.Is.
+var (
+	ErrInvalidInput  = errors.New("invalid input")
+	ErrUnknownType   = errors.New("unknown type")
+	ErrLimitExceeded = errors.New("limit exceeded")
+	ErrBadReference  = errors.New("bad reference")
+
+	// ErrBudgetExceeded is returned when hydration runs longer than the
+	// budget set with WithTimeBudget. It wraps ErrLimitExceeded.
+	ErrBudgetExceeded = fmt.Errorf("%w: time budget", ErrLimitExceeded)
+
+	// ErrSecretFound is returned when WithRejectedSecrets is in use and the
+	// secret scanner reports a string. It wraps ErrInvalidInput.
+	ErrSecretFound = fmt.Errorf("%w: secret found", ErrInvalidInput)
+)
+
+// TypeError reports a failure while hydrating a tagged value such as
+// ["Date", ...] or a value handled by a reviver.
+type TypeError struct {
+	Tag   string
+	Index int
+	Err   error
+}
+
+func typeError(tag string, index int, err error) *TypeError {
+	return &TypeError{Tag: tag, Index: index, Err: err}
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%s at index %d: %v", e.Tag, e.Index, e.Err)
+}
+
+func (e *TypeError) Unwrap() error {
+	return e.Err
+}
+
+// FormatError reports that a payload matched the structure of Format but
+// failed to decode, which points to corruption rather than a format mismatch.
+// See ParseAny.
+type FormatError struct {
+	Format Format
+	Err    error
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Format, e.Err)
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnsupportedSentinel reports a negative index that is not one of the
+// sentinels known to this package, such as one introduced by a newer version
+// of devalue. It wraps ErrUnknownType.
+type ErrUnsupportedSentinel struct {
+	Value int
+}
+
+func (e *ErrUnsupportedSentinel) Error() string {
+	return fmt.Sprintf("unsupported sentinel %d: the payload may come from a newer devalue, try upgrading rehydrate_go", e.Value)
+}
+
+func (e *ErrUnsupportedSentinel) Unwrap() error {
+	return ErrUnknownType
+}
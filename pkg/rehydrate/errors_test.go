@@ -0,0 +1,73 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParseErrorCategories(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"malformed json", `[`, rehydrate.ErrInvalidInput},
+		{"empty table", `[]`, rehydrate.ErrInvalidInput},
+		{"unknown tag", `[["Widget",1],1]`, rehydrate.ErrUnknownType},
+		{"out of range", `[[5]]`, rehydrate.ErrBadReference},
+		{"non-index reference", `[{"a":true}]`, rehydrate.ErrBadReference},
+		{"fractional reference", `[{"a":1.5},1]`, rehydrate.ErrBadReference},
+		// 2^32 and 2^63 wrap to 0 when truncated to a 32-bit or 64-bit int,
+		// which would turn the reference into a self-cycle.
+		{"32-bit overflow", `[[4294967296]]`, rehydrate.ErrBadReference},
+		{"64-bit overflow", `[{"a":9223372036854775808}]`, rehydrate.ErrBadReference},
+		{"overflowing string index", `[["Map","99999999999999999999",1],"v"]`, rehydrate.ErrBadReference},
+		{"short Date", `[["Date"]]`, rehydrate.ErrInvalidInput},
+		{"odd Map", `[["Map",1],"k"]`, rehydrate.ErrInvalidInput},
+		{"unknown sentinel", `[{"a":-7}]`, rehydrate.ErrUnknownType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rehydrate.Parse(tt.input, nil)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTypeError(t *testing.T) {
+	_, err := rehydrate.Parse(`[["Date","yesterday"]]`, nil)
+	var typeErr *rehydrate.TypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected *TypeError, got %v", err)
+	}
+	if typeErr.Tag != "Date" || typeErr.Index != 0 {
+		t.Fatalf("unexpected TypeError %+v", typeErr)
+	}
+}
+
+func TestUnsupportedSentinel(t *testing.T) {
+	for _, input := range []string{`-7`, `[[1,-9],"x"]`, `[["Set",-8]]`} {
+		_, err := rehydrate.Parse(input, nil)
+		var sentinelErr *rehydrate.ErrUnsupportedSentinel
+		if !errors.As(err, &sentinelErr) {
+			t.Fatalf("%s: expected *ErrUnsupportedSentinel, got %v", input, err)
+		}
+		if sentinelErr.Value > -7 {
+			t.Errorf("%s: unexpected value %d", input, sentinelErr.Value)
+		}
+	}
+
+	_, err := rehydrate.ParseWithOptions(`[[1,-7],"x"]`, rehydrate.WithStrictReferences())
+	var sentinelErr *rehydrate.ErrUnsupportedSentinel
+	if !errors.As(err, &sentinelErr) || sentinelErr.Value != -7 {
+		t.Errorf("strict references: got %v", err)
+	}
+	if _, err := rehydrate.ParseTree(`[{"a":-7}]`); !errors.Is(err, rehydrate.ErrUnknownType) {
+		t.Errorf("ParseTree: got %v", err)
+	}
+}
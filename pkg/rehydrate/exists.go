@@ -0,0 +1,164 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Kind is the kind of value found at a path by TypeAt.
+type Kind int
+
+const (
+	// KindMissing: nothing is at the path, or it is an array hole.
+	KindMissing Kind = iota
+	// KindUndefined: the value is undefined.
+	KindUndefined
+	KindNull
+	KindBoolean
+	// KindNumber: a number, including NaN, the infinities and -0.
+	KindNumber
+	KindString
+	KindArray
+	KindObject
+	// KindTagged: an entry such as ["Date", ...] or ["Map", ...], or a
+	// custom tag handled by a reviver. ParseTree gives access to the tag.
+	KindTagged
+)
+
+var kindNames = [...]string{
+	KindMissing:   "missing",
+	KindUndefined: "undefined",
+	KindNull:      "null",
+	KindBoolean:   "boolean",
+	KindNumber:    "number",
+	KindString:    "string",
+	KindArray:     "array",
+	KindObject:    "object",
+	KindTagged:    "tagged",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return ""
+	}
+	return kindNames[k]
+}
+
+// MarshalText renders the kind by name.
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Exists reports whether a value, possibly undefined, is at path in
+// serialized. Paths are JSON Pointers, like those of Search results, or use
+// the syntax of Path.Dotted. See TypeAt.
+func Exists(serialized, path string) (bool, error) {
+	kind, err := TypeAt(serialized, path)
+	return kind != KindMissing, err
+}
+
+// TypeAt returns the kind of the value at path in serialized, or KindMissing
+// if there is none. It decodes only the value-table entries along the path,
+// without revivers or hydration, for cheap routing decisions on payloads
+// that may never be hydrated. It fails only if the payload is not valid or a
+// reference along the path is out of range.
+func TypeAt(serialized, path string) (Kind, error) {
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		// A standalone payload is a single sentinel.
+		var sentinel float64
+		if json.Unmarshal([]byte(serialized), &sentinel) != nil {
+			return KindMissing, err
+		}
+		ref, err := toInt(sentinel)
+		if err != nil || len(splitPath(path)) > 0 {
+			return KindMissing, err
+		}
+		return sentinelKind(ref), nil
+	}
+	if len(raw) == 0 {
+		return KindMissing, ErrInvalidInput
+	}
+
+	t := &Tree{Nodes: make([]*Node, len(raw))}
+	node := func(ref NodeRef) (*Node, error) {
+		if int(ref) >= len(raw) {
+			return nil, fmt.Errorf("%w: index %d out of range", ErrBadReference, ref)
+		}
+		if t.Nodes[ref] == nil {
+			var value interface{}
+			if err := json.Unmarshal(raw[ref], &value); err != nil {
+				return nil, fmt.Errorf("%w: index %d: %w", ErrInvalidInput, ref, err)
+			}
+			n, err := newNode(int(ref), value, raw[ref])
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", ref, err)
+			}
+			t.Nodes[ref] = n
+		}
+		return t.Nodes[ref], nil
+	}
+
+	ref := NodeRef(0)
+	for _, segment := range splitPath(path) {
+		if ref.IsSentinel() {
+			return KindMissing, nil
+		}
+		n, err := node(ref)
+		if err != nil {
+			return KindMissing, err
+		}
+		if n.Tag == TagMap.String() {
+			// Map keys are matched by their string form.
+			for i := 0; i < len(n.Args); i += 2 {
+				if !n.Args[i].IsSentinel() {
+					if _, err := node(n.Args[i]); err != nil {
+						return KindMissing, err
+					}
+				}
+			}
+		}
+		var ok bool
+		if ref, ok = t.Child(n, segment); !ok {
+			return KindMissing, nil
+		}
+	}
+	if ref.IsSentinel() {
+		return sentinelKind(int(ref)), nil
+	}
+	n, err := node(ref)
+	if err != nil {
+		return KindMissing, err
+	}
+	switch n.Kind {
+	case NodeArray:
+		return KindArray, nil
+	case NodeObject:
+		return KindObject, nil
+	case NodeTagged:
+		if n.Tag == TagNull.String() {
+			return KindObject, nil
+		}
+		return KindTagged, nil
+	}
+	switch n.Value.(type) {
+	case nil:
+		return KindNull, nil
+	case bool:
+		return KindBoolean, nil
+	case float64:
+		return KindNumber, nil
+	default:
+		return KindString, nil
+	}
+}
+
+func sentinelKind(ref int) Kind {
+	switch ref {
+	case UNDEFINED:
+		return KindUndefined
+	case NAN, POSITIVE_INFINITY, NEGATIVE_INFINITY, NEGATIVE_ZERO:
+		return KindNumber
+	}
+	return KindMissing
+}
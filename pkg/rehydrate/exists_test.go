@@ -0,0 +1,58 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestTypeAt(t *testing.T) {
+	payload := `[{"user":1,"tags":4,"gone":-1,"score":-3,"seen":6,"prefs":8},` +
+		`{"name":2,"admin":3},"Ada",false,[2,-2,5],null,["Date","2024-01-01T00:00:00.000Z"],` +
+		`"theme",["Map",7,3],["null","k",2]]`
+	for path, want := range map[string]rehydrate.Kind{
+		"":             rehydrate.KindObject,
+		"user":         rehydrate.KindObject,
+		"user.name":    rehydrate.KindString,
+		"user.admin":   rehydrate.KindBoolean,
+		"tags":         rehydrate.KindArray,
+		"tags[0]":      rehydrate.KindString,
+		"tags[1]":      rehydrate.KindMissing,
+		"tags[2]":      rehydrate.KindNull,
+		"tags[3]":      rehydrate.KindMissing,
+		"gone":         rehydrate.KindUndefined,
+		"score":        rehydrate.KindNumber,
+		"seen":         rehydrate.KindTagged,
+		"prefs":        rehydrate.KindTagged,
+		"prefs.theme":  rehydrate.KindBoolean,
+		"user.email":   rehydrate.KindMissing,
+		"gone.x":       rehydrate.KindMissing,
+		"/user/name":   rehydrate.KindString,
+		"/tags/2":      rehydrate.KindNull,
+		"/prefs/theme": rehydrate.KindBoolean,
+	} {
+		got, err := rehydrate.TypeAt(payload, path)
+		if err != nil || got != want {
+			t.Errorf("%q: got %v, %v, want %v", path, got, err, want)
+		}
+	}
+
+	if ok, err := rehydrate.Exists(payload, "gone"); !ok || err != nil {
+		t.Errorf("undefined value: got %v, %v", ok, err)
+	}
+	if ok, err := rehydrate.Exists(payload, "tags[1]"); ok || err != nil {
+		t.Errorf("hole: got %v, %v", ok, err)
+	}
+	if kind, err := rehydrate.TypeAt(`-3`, ""); kind != rehydrate.KindNumber || err != nil {
+		t.Errorf("standalone: got %v, %v", kind, err)
+	}
+
+	// Entries off the path are never decoded.
+	if ok, err := rehydrate.Exists(`[{"a":1,"b":2},"x",[99]]`, "a"); !ok || err != nil {
+		t.Errorf("got %v, %v", ok, err)
+	}
+	if _, err := rehydrate.TypeAt(`[{"a":5}]`, "a"); !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("got %v, want ErrBadReference", err)
+	}
+}
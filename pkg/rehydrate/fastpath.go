@@ -0,0 +1,97 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// fastPathMaxSize is the size, in bytes, of the largest payload hydrated by
+// the fast path. Most payloads are smaller and tag free.
+const fastPathMaxSize = 4 << 10
+
+// fastPath reports whether the options leave plain values untouched, so
+// that tag-free payloads may skip the full engine.
+func (o *options) fastPath() bool {
+	return len(o.keyTransforms) == 0 && o.reservedKeys == PreserveReservedKeys &&
+		o.sampleSize <= 0 && o.maxString <= 0 && o.maxDepth <= 0 &&
+		!o.strictRefs && !o.trackPaths()
+}
+
+// hydrateFast hydrates a value table in which every entry reachable from the
+// root is an untagged value referenced at most once, without the
+// bookkeeping the full engine needs for shared values, cycles, tags and
+// paths. It reports false as soon as the table needs the full engine, which
+// then also reports any error.
+func hydrateFast(values []interface{}) (interface{}, bool) {
+	f := fastHydrator{values: values, seen: make([]bool, len(values))}
+	return f.hydrate(0)
+}
+
+type fastHydrator struct {
+	values []interface{}
+	seen   []bool
+}
+
+func (f *fastHydrator) hydrate(index int) (interface{}, bool) {
+	if f.seen[index] {
+		return nil, false
+	}
+	f.seen[index] = true
+
+	switch v := f.values[index].(type) {
+	case string, nil, bool, float64, json.Number, int64, UTF16String:
+		return v, true
+	case []interface{}:
+		if len(v) > 0 {
+			if _, tagged := v[0].(string); tagged {
+				return nil, false
+			}
+		}
+		arr := make([]interface{}, len(v))
+		for i, item := range v {
+			elem, hole, ok := f.ref(item)
+			if !ok {
+				return nil, false
+			}
+			if !hole {
+				arr[i] = elem
+			}
+		}
+		return arr, true
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			elem, hole, ok := f.ref(val)
+			if !ok || hole {
+				return nil, false
+			}
+			obj[key] = elem
+		}
+		return obj, true
+	}
+	return nil, false
+}
+
+// ref hydrates the value a reference points to. hole reports an array hole.
+func (f *fastHydrator) ref(v interface{}) (value interface{}, hole, ok bool) {
+	n, isNumber := v.(float64)
+	if !isNumber || n != math.Trunc(n) || n < NEGATIVE_ZERO || n >= float64(len(f.values)) {
+		return nil, false, false
+	}
+	switch int(n) {
+	case UNDEFINED:
+		return nil, false, true
+	case HOLE:
+		return nil, true, true
+	case NAN:
+		return math.NaN(), false, true
+	case POSITIVE_INFINITY:
+		return math.Inf(1), false, true
+	case NEGATIVE_INFINITY:
+		return math.Inf(-1), false, true
+	case NEGATIVE_ZERO:
+		return math.Copysign(0, -1), false, true
+	}
+	value, ok = f.hydrate(int(n))
+	return value, false, ok
+}
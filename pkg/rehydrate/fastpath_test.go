@@ -0,0 +1,64 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// TestFastPath checks that small payloads hydrate the same whether or not
+// they qualify for the fast path, which WithStrictReferences disables.
+func TestFastPath(t *testing.T) {
+	for _, payload := range []string{
+		`[{"a":1,"b":2,"c":-1,"d":-4},"x",[3,-2,4],true,null]`,
+		`[[1,2],{"k":3},"y",-6]`,
+		`[{"shared":1,"again":1},{"v":2},"z"]`,
+		`[{"self":0}]`,
+		`[{"when":1},["Date","2024-01-01T00:00:00.000Z"]]`,
+		`["only"]`,
+		`[{"big":1},"` + strings.Repeat("x", 5000) + `"]`,
+	} {
+		fast, err := rehydrate.ParseWithOptions(payload)
+		if err != nil {
+			t.Fatalf("%s: %v", payload, err)
+		}
+		full, err := rehydrate.ParseWithOptions(payload, rehydrate.WithStrictReferences())
+		if err != nil {
+			t.Fatalf("%s: %v", payload, err)
+		}
+		if payload == `[{"self":0}]` {
+			if root := fast.(map[string]interface{}); reflect.ValueOf(root["self"]).Pointer() != reflect.ValueOf(root).Pointer() {
+				t.Errorf("cycle not preserved")
+			}
+			continue
+		}
+		if !reflect.DeepEqual(fast, full) {
+			t.Errorf("%s: got %#v, want %#v", payload, fast, full)
+		}
+	}
+
+	v, err := rehydrate.ParseWithOptions(`[[-6]]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z := v.([]interface{})[0].(float64); z != 0 || !math.Signbit(z) {
+		t.Errorf("got %v, want -0", z)
+	}
+
+	// Errors are reported by the full engine.
+	for payload, want := range map[string]error{
+		`[{"a":5}]`:     rehydrate.ErrBadReference,
+		`[{"a":-2}]`:    rehydrate.ErrBadReference,
+		`[[-9]]`:        rehydrate.ErrUnknownType,
+		`[{"a":1},5.5]`: nil,
+	} {
+		_, err := rehydrate.ParseWithOptions(payload)
+		if want != nil && !errors.Is(err, want) || want == nil && err != nil {
+			t.Errorf("%s: got %v, want %v", payload, err, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ParseFlatted decodes a payload produced by the flatted library. Its value
+// table holds the root first; strings inside objects and arrays are the
+// indices of other entries, and string entries are the literal strings.
+// Shared and cyclic values hydrate to shared Go values.
+func ParseFlatted(data []byte) (interface{}, error) {
+	var values []interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: empty flatted table", ErrInvalidInput)
+	}
+	f := &flattedDecoder{values: values, hydrated: make(map[int]interface{})}
+	return f.entry(0)
+}
+
+type flattedDecoder struct {
+	values   []interface{}
+	hydrated map[int]interface{}
+}
+
+func (f *flattedDecoder) entry(index int) (interface{}, error) {
+	if v, ok := f.hydrated[index]; ok {
+		return v, nil
+	}
+	switch v := f.values[index].(type) {
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		f.hydrated[index] = arr
+		for i, item := range v {
+			hydrated, err := f.item(item)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = hydrated
+		}
+		return arr, nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		f.hydrated[index] = obj
+		for key, item := range v {
+			hydrated, err := f.item(item)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = hydrated
+		}
+		return obj, nil
+	default:
+		return v, nil
+	}
+}
+
+// item hydrates a value found inside an object or array.
+func (f *flattedDecoder) item(v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 || index >= len(f.values) {
+		return nil, fmt.Errorf("%w: invalid flatted reference %q", ErrBadReference, s)
+	}
+	return f.entry(index)
+}
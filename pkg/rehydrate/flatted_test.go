@@ -0,0 +1,33 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParseFlatted(t *testing.T) {
+	v, err := rehydrate.ParseFlatted([]byte(`[{"name":"1","n":3,"self":"0","list":"2"},"ann",["1",true]]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	if root["name"] != "ann" || root["n"] != 3.0 {
+		t.Errorf("unexpected result %v", root)
+	}
+	if !reflect.DeepEqual(root["list"], []interface{}{"ann", true}) {
+		t.Errorf("unexpected list %v", root["list"])
+	}
+	if reflect.ValueOf(root["self"]).Pointer() != reflect.ValueOf(root).Pointer() {
+		t.Error("expected a cycle through the root")
+	}
+
+	if _, err := rehydrate.ParseFlatted([]byte(`[{"a":"9"}]`)); !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("expected ErrBadReference, got %v", err)
+	}
+	if _, err := rehydrate.ParseFlatted([]byte(`[]`)); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package rehydrate
+
+import (
+	"time"
+)
+
+// FlattenOption configures Flatten.
+type FlattenOption func(*flattenOptions)
+
+type flattenOptions struct {
+	parse []Option
+	empty bool
+}
+
+// FlattenParseOptions sets the options used to hydrate the payload, for
+// example the revivers needed for its custom tags.
+func FlattenParseOptions(opts ...Option) FlattenOption {
+	return func(o *flattenOptions) {
+		o.parse = append(o.parse, opts...)
+	}
+}
+
+// FlattenEmptyContainers adds a nil row for every empty object, array, Set
+// and Map, so their presence is not lost.
+func FlattenEmptyContainers() FlattenOption {
+	return func(o *flattenOptions) {
+		o.empty = true
+	}
+}
+
+// Flatten hydrates serialized and returns its scalar values keyed by path,
+// e.g. "/user/tags/0", ready to be loaded into an analytical database as
+// rows. Arrays and Sets are indexed and Maps are keyed as in Search. Values
+// are nil, bool, float64, string, time.Time for Dates, the decimal string of
+// BigInts, the source of RegExps and []byte or ArrayBuffer for binary data.
+// Typed arrays are indexed like arrays, their elements flattened to float64,
+// float32 for Float32Arrays or the digits of BigInts. Truncated values are
+// replaced by their prefix.
+// Objects and arrays shared by several parents are flattened once, at the
+// first path they are reached by.
+func Flatten(serialized string, opts ...FlattenOption) (map[string]interface{}, error) {
+	o := &flattenOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	v, err := ParseWithOptions(serialized, o.parse...)
+	if err != nil {
+		return nil, err
+	}
+
+	return flattenValue(v, o.empty), nil
+}
+
+// flattenValue returns the scalar values below v keyed by path, as Flatten
+// does; empty adds rows for empty containers.
+func flattenValue(v interface{}, empty bool) map[string]interface{} {
+	rows := make(map[string]interface{})
+	w := &walker{
+		visit: func(path string, v interface{}) bool {
+			switch value := v.(type) {
+			case []interface{}, map[string]interface{}, *Set, *OrderedMap, *SampledArray:
+				if empty && isEmptyContainer(value) {
+					rows[path] = nil
+				}
+				return true
+			case time.Time:
+				rows[path] = value.UTC()
+			case UTF16String:
+				rows[path] = value.String()
+			case *Truncated:
+				rows[path] = value.Value
+			default:
+				if items, ok := typedArrayElements(value); ok {
+					if empty && len(items) == 0 {
+						rows[path] = nil
+					}
+					for i, item := range items {
+						rows[indexPath(path, i)] = item
+					}
+				} else if digits, ok := bigIntDigits(value); ok {
+					rows[path] = digits
+				} else if source, _, ok := regExpSource(value); ok {
+					rows[path] = source
+				} else {
+					rows[path] = value
+				}
+			}
+			return false
+		},
+	}
+	w.walk("", v)
+	return rows
+}
+
+func isEmptyContainer(v interface{}) bool {
+	switch value := v.(type) {
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	case *Set:
+		return value.Len() == 0
+	case *OrderedMap:
+		return value.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestFlatten(t *testing.T) {
+	input := `[{"user":1,"when":5,"n":6,"none":7},{"name":2,"tags":3,"scores":4},"ada",["Set",2,8],["Map",2,9],` +
+		`["Date","2024-01-02T03:04:05Z"],["BigInt","12345678901234567890"],[],"b",1.5]`
+
+	rows, err := rehydrate.Flatten(input, rehydrate.FlattenEmptyContainers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		`/user/name`:       "ada",
+		`/user/tags/0`:     "ada",
+		`/user/tags/1`:     "b",
+		`/user/scores/ada`: 1.5,
+		`/when`:            time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		`/n`:               "12345678901234567890",
+		`/none`:            nil,
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %v\nwant %v", rows, want)
+	}
+
+	rows, err = rehydrate.Flatten(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rows["/none"]; ok {
+		t.Error("empty array flattened without FlattenEmptyContainers")
+	}
+
+	// Typed arrays are indexed like arrays; binary data stays whole.
+	rows, err = rehydrate.Flatten(`[{"i":1,"f":2,"b":3,"e":4},["Int16Array","AQD+/w=="],["Float32Array","AADAPw=="],["ArrayBuffer","AQI="],["Int32Array",""]]`,
+		rehydrate.FlattenEmptyContainers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]interface{}{
+		"/i/0": 1.0,
+		"/i/1": -2.0,
+		"/f/0": float32(1.5),
+		"/b":   rehydrate.ArrayBuffer{1, 2},
+		"/e":   nil,
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("typed arrays: got %v\nwant %v", rows, want)
+	}
+}
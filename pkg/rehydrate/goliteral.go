@@ -0,0 +1,284 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoLiteral renders the hydrated value v as a Go expression that evaluates
+// to an equal value, for freezing real payloads into table-driven test
+// fixtures, for example:
+//
+//	map[string]interface{}{
+//		"created": time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC),
+//		"tags":    []interface{}{"a", "b"},
+//	}
+//
+// Numbers are written as float64 constants, or in the type WithNumberMode
+// selects, and NaN, the infinities and -0 as calls to the math package.
+// Dates are written in UTC. BigInts and RegExps use math/big and regexp,
+// and the types of this package, such as
+// *Set or *OrderedMap, are qualified with "rehydrate."; the file holding
+// the expression must import what it uses. Object keys are sorted. A value
+// shared by several parents is written out at each of them; cyclic values
+// and types other than those Parse produces cannot be rendered, and the
+// error wraps ErrInvalidInput.
+func GoLiteral(v interface{}) (string, error) {
+	g := &goLiteral{active: make(map[uintptr]bool)}
+	if err := g.value("", v, 0); err != nil {
+		return "", err
+	}
+	// Formatting aligns the values of map literals.
+	fset := token.NewFileSet()
+	expr, err := parser.ParseExprFrom(fset, "", g.b.String(), 0)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := format.Node(&b, fset, expr); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+type goLiteral struct {
+	b strings.Builder
+	// active holds the containers being written, to detect cycles.
+	active map[uintptr]bool
+}
+
+func (g *goLiteral) value(path string, v interface{}, depth int) error {
+	if id, ok := containerID(v); ok {
+		if g.active[id] {
+			return fmt.Errorf("%w: cyclic value at %s", ErrInvalidInput, displayPath(path))
+		}
+		g.active[id] = true
+		defer delete(g.active, id)
+	}
+
+	switch value := v.(type) {
+	case nil:
+		g.b.WriteString("nil")
+	case bool:
+		g.b.WriteString(strconv.FormatBool(value))
+	case string:
+		g.b.WriteString(strconv.Quote(value))
+	case float64:
+		g.b.WriteString(goFloat(value))
+	case int64:
+		g.b.WriteString("int64(" + strconv.FormatInt(value, 10) + ")")
+	case json.Number:
+		g.b.WriteString("json.Number(" + strconv.Quote(string(value)) + ")")
+	case time.Time:
+		t := value.UTC()
+		fmt.Fprintf(&g.b, "time.Date(%d, time.%s, %d, %d, %d, %d, %d, time.UTC)",
+			t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond())
+	case []byte:
+		g.b.WriteString("[]byte(" + strconv.Quote(string(value)) + ")")
+	case ArrayBuffer:
+		g.b.WriteString("rehydrate.ArrayBuffer(" + strconv.Quote(string(value)) + ")")
+	case []int8, []int16, []uint16, []int32, []uint32, []int64, []uint64:
+		fmt.Fprintf(&g.b, "%#v", value)
+	case []float32:
+		g.b.WriteString("[]float32{")
+		for i, f := range value {
+			if i > 0 {
+				g.b.WriteString(", ")
+			}
+			if s := goFloat(float64(f)); strings.HasPrefix(s, "math.") {
+				g.b.WriteString("float32(" + s + ")")
+			} else {
+				g.b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+			}
+		}
+		g.b.WriteByte('}')
+	case []float64:
+		g.b.WriteString("[]float64{")
+		for i, f := range value {
+			if i > 0 {
+				g.b.WriteString(", ")
+			}
+			g.b.WriteString(goFloat(f))
+		}
+		g.b.WriteByte('}')
+	case UTF16String:
+		g.b.WriteString("rehydrate.UTF16String{")
+		for i, u := range value {
+			if i > 0 {
+				g.b.WriteString(", ")
+			}
+			fmt.Fprintf(&g.b, "0x%04x", u)
+		}
+		g.b.WriteByte('}')
+	case []interface{}:
+		return g.items(path, "[]interface{}", value, depth)
+	case map[string]interface{}:
+		keys := sortedKeys(value)
+		return g.composite("map[string]interface{}", len(keys), depth, func(i int) error {
+			g.b.WriteString(strconv.Quote(keys[i]) + ": ")
+			return g.value(keyPath(path, keys[i]), value[keys[i]], depth+1)
+		})
+	case *Set:
+		return g.builder("*rehydrate.Set", "s", "rehydrate.NewSet()", value.Len(), depth, func(i int) error {
+			g.b.WriteString("s.Add(")
+			if err := g.value(indexPath(path, i), value.Values()[i], depth+1); err != nil {
+				return err
+			}
+			g.b.WriteByte(')')
+			return nil
+		})
+	case *OrderedMap:
+		entries := value.Entries()
+		return g.builder("*rehydrate.OrderedMap", "m", "rehydrate.NewOrderedMap()", len(entries), depth, func(i int) error {
+			g.b.WriteString("m.Set(")
+			if err := g.value(path, entries[i].Key, depth+1); err != nil {
+				return err
+			}
+			g.b.WriteString(", ")
+			if err := g.value(mapKeyPath(path, entries[i].Key), entries[i].Value, depth+1); err != nil {
+				return err
+			}
+			g.b.WriteByte(')')
+			return nil
+		})
+	case *Tagged:
+		g.b.WriteString("&rehydrate.Tagged{Tag: " + strconv.Quote(value.Tag) + ", Args: ")
+		if err := g.items(path, "[]interface{}", value.Args, depth); err != nil {
+			return err
+		}
+		g.b.WriteByte('}')
+	case *Wrapped:
+		g.b.WriteString("&rehydrate.Wrapped{Kind: " + strconv.Quote(value.Kind) + ", Value: ")
+		if err := g.value(path, value.Value, depth); err != nil {
+			return err
+		}
+		g.b.WriteByte('}')
+	case *LazyRef:
+		fmt.Fprintf(&g.b, "&rehydrate.LazyRef{Index: %d}", value.Index)
+	case *Truncated:
+		g.b.WriteString("&rehydrate.Truncated{Value: ")
+		if err := g.value(path, value.Value, depth); err != nil {
+			return err
+		}
+		fmt.Fprintf(&g.b, ", Length: %d}", value.Length)
+	case *SampledArray:
+		fmt.Fprintf(&g.b, "&rehydrate.SampledArray{Length: %d, Indices: %#v, Values: ", value.Length, value.Indices)
+		if err := g.items(path, "[]interface{}", value.Values, depth); err != nil {
+			return err
+		}
+		g.b.WriteByte('}')
+	default:
+		if digits, ok := bigIntDigits(v); ok {
+			g.bigInt(digits)
+			return nil
+		}
+		if digits, ok := bigIntSliceDigits(v); ok {
+			g.b.WriteString("[]*big.Int{")
+			for i, d := range digits {
+				if i > 0 {
+					g.b.WriteString(", ")
+				}
+				g.bigInt(d)
+			}
+			g.b.WriteByte('}')
+			return nil
+		}
+		if expr, ok := regExpLiteral(v); ok {
+			g.b.WriteString(expr)
+			return nil
+		}
+		return fmt.Errorf("%w: cannot render %T at %s", ErrInvalidInput, v, displayPath(path))
+	}
+	return nil
+}
+
+// items writes a composite literal of type typ holding items.
+func (g *goLiteral) items(path, typ string, items []interface{}, depth int) error {
+	return g.composite(typ, len(items), depth, func(i int) error {
+		return g.value(indexPath(path, i), items[i], depth+1)
+	})
+}
+
+// composite writes a composite literal of type typ with n elements, one per
+// line, calling elem to write each of them.
+func (g *goLiteral) composite(typ string, n, depth int, elem func(i int) error) error {
+	g.b.WriteString(typ + "{")
+	if n == 0 {
+		g.b.WriteByte('}')
+		return nil
+	}
+	indent := strings.Repeat("\t", depth+1)
+	for i := 0; i < n; i++ {
+		g.b.WriteString("\n" + indent)
+		if err := elem(i); err != nil {
+			return err
+		}
+		g.b.WriteByte(',')
+	}
+	g.b.WriteString("\n" + strings.Repeat("\t", depth) + "}")
+	return nil
+}
+
+// builder writes a function literal that fills the container returned by
+// constructor, stored in the variable name, with one call per line written
+// by stmt.
+func (g *goLiteral) builder(typ, name, constructor string, n, depth int, stmt func(i int) error) error {
+	if n == 0 {
+		g.b.WriteString(constructor)
+		return nil
+	}
+	indent := strings.Repeat("\t", depth+1)
+	g.b.WriteString("func() " + typ + " {\n" + indent + name + " := " + constructor)
+	for i := 0; i < n; i++ {
+		g.b.WriteString("\n" + indent)
+		if err := stmt(i); err != nil {
+			return err
+		}
+	}
+	g.b.WriteString("\n" + indent + "return " + name + "\n" + strings.Repeat("\t", depth) + "}()")
+	return nil
+}
+
+// goFloat writes f as an expression of type float64 in an interface{}
+// context.
+func (g *goLiteral) bigInt(digits string) {
+	if _, err := strconv.ParseInt(digits, 10, 64); err == nil {
+		g.b.WriteString("big.NewInt(" + digits + ")")
+	} else {
+		g.b.WriteString(`func() *big.Int { n, _ := new(big.Int).SetString("` + digits + `", 10); return n }()`)
+	}
+}
+
+func goFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		return "math.Inf(-1)"
+	case f == 0 && math.Signbit(f):
+		return "math.Copysign(0, -1)"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// goRawString quotes s as a raw string literal where possible, which keeps
+// regular expressions readable.
+func goRawString(s string) string {
+	if strings.ContainsAny(s, "`\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,85 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestGoLiteral(t *testing.T) {
+	v, err := rehydrate.Parse(`[{"created":1,"tags":2,"price":5,"ratio":6,"empty":7},`+
+		`["Date","2024-01-02T03:04:05.000Z"],["Set",3,4],"a","b",12,[-3,-6],[]]`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rehydrate.GoLiteral(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `map[string]interface{}{
+	"created": time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC),
+	"empty":   []interface{}{},
+	"price":   12.0,
+	"ratio": []interface{}{
+		math.NaN(),
+		math.Copysign(0, -1),
+	},
+	"tags": func() *rehydrate.Set {
+		s := rehydrate.NewSet()
+		s.Add("a")
+		s.Add("b")
+		return s
+	}(),
+}`
+	if got != want {
+		t.Errorf("GoLiteral =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGoLiteralScalars(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`["x\n"]`, `"x\n"`},
+		{`[1.5]`, `1.5`},
+		{`[null]`, `nil`},
+		{`[["Uint8Array","aGk="]]`, `[]byte("hi")`},
+		{`[["Int16Array","AQD+/w=="]]`, `[]int16{1, -2}`},
+		{`[["Float32Array","AADAfw=="]]`, `[]float32{float32(math.NaN())}`},
+	}
+	for _, tt := range tests {
+		v, err := rehydrate.Parse(tt.in, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rehydrate.GoLiteral(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GoLiteral(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	v, err := rehydrate.ParseWithOptions(`[[1,2],42,0.5]`, rehydrate.WithNumberMode(rehydrate.NumberInt64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rehydrate.GoLiteral(v); err != nil || got != "[]interface{}{\n\tint64(42),\n\t0.5,\n}" {
+		t.Errorf("GoLiteral = %s, %v", got, err)
+	}
+}
+
+func TestGoLiteralErrors(t *testing.T) {
+	cyclic, err := rehydrate.Parse(`[{"self":0}]`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{cyclic, struct{}{}} {
+		if _, err := rehydrate.GoLiteral(v); !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("GoLiteral(%T) error = %v, want ErrInvalidInput", v, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package rehydrate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TypeHandler hydrates the tagged entries of one type, such as
+// ["Date", "2024-01-02T00:00:00.000Z"]. Unlike a reviver, which receives the
+// hydrated value of the first argument, a handler sees the whole entry and
+// decides which of its arguments are references to hydrate, so it can
+// replace the built-in hydration of types such as Map or RegExp, whose
+// arguments are inline data or several references.
+//
+// Handlers are registered with WithTypeHandler or Registry.RegisterType.
+// Revivers take precedence over them, and they take precedence over the
+// built-in types. Errors not already a *TypeError are wrapped in one.
+type TypeHandler interface {
+	HydrateType(e *TypedEntry) (interface{}, error)
+}
+
+// TypeHandlerFunc adapts a function to the TypeHandler interface.
+type TypeHandlerFunc func(e *TypedEntry) (interface{}, error)
+
+// HydrateType calls f(e).
+func (f TypeHandlerFunc) HydrateType(e *TypedEntry) (interface{}, error) {
+	return f(e)
+}
+
+// TypedEntry is the tagged value-table entry a TypeHandler hydrates.
+type TypedEntry struct {
+	Tag string
+	// Index is the position of the entry in the value table.
+	Index int
+	// Args are the elements following the tag, as decoded by encoding/json:
+	// references are float64.
+	Args []interface{}
+
+	h     *hydrator
+	entry []interface{}
+}
+
+// Hydrate hydrates the value Args[i] refers to.
+func (e *TypedEntry) Hydrate(i int) (interface{}, error) {
+	if i < 0 || i >= len(e.Args) {
+		return nil, fmt.Errorf("%w: %s has no argument %d", ErrInvalidInput, e.Tag, i)
+	}
+	ref, err := toInt(e.Args[i])
+	if err != nil {
+		return nil, err
+	}
+	return e.h.hydrateInPlace(ref)
+}
+
+// Store makes v the value of references back to the entry found while its
+// arguments are hydrated. Handlers building containers call it before
+// hydrating the elements, so cyclic values resolve; the value the handler
+// returns replaces v.
+func (e *TypedEntry) Store(v interface{}) {
+	e.h.store(e.Index, v)
+}
+
+// WithTypeHandler hydrates the entries tagged tag with handler, replacing
+// the built-in hydration of the type, if any, and a handler registered for
+// it in the Registry given to WithRegistry.
+func WithTypeHandler(tag string, handler TypeHandler) Option {
+	return func(o *options) {
+		if o.typeHandlers == nil {
+			o.typeHandlers = make(map[string]TypeHandler)
+		}
+		o.typeHandlers[tag] = handler
+	}
+}
+
+func (o *options) typeHandler(tag string) (TypeHandler, bool) {
+	if handler, ok := o.typeHandlers[tag]; ok {
+		return handler, true
+	}
+	handler, ok := o.registryTypes[tag]
+	return handler, ok
+}
+
+// DefaultTypeHandler returns the built-in hydration of tag, for handlers
+// that wrap it, such as one adjusting the hydrated Dates. It reports false
+// for tags the package does not know.
+func DefaultTypeHandler(tag string) (TypeHandler, bool) {
+	if _, ok := ParseTag(tag); !ok {
+		return nil, false
+	}
+	return TypeHandlerFunc(func(e *TypedEntry) (interface{}, error) {
+		return e.h.hydrateBuiltin(e.Index, e.Tag, e.entry)
+	}), true
+}
+
+// hydrateHandled hydrates a tagged entry with handler.
+func (h *hydrator) hydrateHandled(handler TypeHandler, index int, typeStr string, arr []interface{}) (interface{}, error) {
+	v, err := handler.HydrateType(&TypedEntry{Tag: typeStr, Index: index, Args: arr[1:], h: h, entry: arr})
+	if err != nil {
+		var typeErr *TypeError
+		if errors.As(err, &typeErr) {
+			return nil, err
+		}
+		return nil, typeError(typeStr, index, err)
+	}
+	h.store(index, v)
+	return v, nil
+}
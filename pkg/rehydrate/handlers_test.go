@@ -0,0 +1,137 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+type point struct{ X, Y float64 }
+
+func TestWithTypeHandler(t *testing.T) {
+	pointHandler := rehydrate.TypeHandlerFunc(func(e *rehydrate.TypedEntry) (interface{}, error) {
+		if len(e.Args) != 2 {
+			return nil, fmt.Errorf("%w: want 2 coordinates", rehydrate.ErrInvalidInput)
+		}
+		x, ok1 := e.Args[0].(float64)
+		y, ok2 := e.Args[1].(float64)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%w: coordinates must be numbers", rehydrate.ErrInvalidInput)
+		}
+		return point{x, y}, nil
+	})
+	v, err := rehydrate.ParseWithOptions(`[{"at":1},["Point",3,4]]`, rehydrate.WithTypeHandler("Point", pointHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at := v.(map[string]interface{})["at"]; at != (point{3, 4}) {
+		t.Errorf("got %#v", at)
+	}
+
+	_, err = rehydrate.ParseWithOptions(`[["Point",3]]`, rehydrate.WithTypeHandler("Point", pointHandler))
+	var typeErr *rehydrate.TypeError
+	if !errors.As(err, &typeErr) || typeErr.Tag != "Point" || !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected a TypeError, got %v", err)
+	}
+}
+
+func TestTypeHandlerOverridesBuiltin(t *testing.T) {
+	// Sets hydrate to their size; handlers run under both engines.
+	size := rehydrate.TypeHandlerFunc(func(e *rehydrate.TypedEntry) (interface{}, error) {
+		return len(e.Args), nil
+	})
+	for _, opts := range [][]rehydrate.Option{
+		{rehydrate.WithTypeHandler("Set", size)},
+		{rehydrate.WithTypeHandler("Set", size), rehydrate.WithAudit(func(rehydrate.AuditEvent) {})},
+	} {
+		v, err := rehydrate.ParseWithOptions(`[[1],["Set",2,2],"a"]`, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, []interface{}{2}) {
+			t.Errorf("got %#v", v)
+		}
+	}
+
+	dates, ok := rehydrate.DefaultTypeHandler("Date")
+	if !ok {
+		t.Fatal("no default handler for Date")
+	}
+	unix := rehydrate.TypeHandlerFunc(func(e *rehydrate.TypedEntry) (interface{}, error) {
+		v, err := dates.HydrateType(e)
+		if err != nil {
+			return nil, err
+		}
+		return v.(time.Time).Unix(), nil
+	})
+	v, err := rehydrate.ParseWithOptions(`[["Date","2024-01-02T00:00:00.000Z"]]`, rehydrate.WithTypeHandler("Date", unix))
+	if err != nil || v != int64(1704153600) {
+		t.Errorf("got %v, %v", v, err)
+	}
+	if _, ok := rehydrate.DefaultTypeHandler("Point"); ok {
+		t.Error("expected no default handler for an unknown tag")
+	}
+}
+
+func TestTypeHandlerCycle(t *testing.T) {
+	// A pair holding references, where the second refers back to the pair.
+	pair := rehydrate.TypeHandlerFunc(func(e *rehydrate.TypedEntry) (interface{}, error) {
+		p := make([]interface{}, len(e.Args))
+		e.Store(p)
+		for i := range e.Args {
+			v, err := e.Hydrate(i)
+			if err != nil {
+				return nil, err
+			}
+			p[i] = v
+		}
+		return p, nil
+	})
+	v, err := rehydrate.ParseWithOptions(`[["Pair",1,0],"a"]`, rehydrate.WithTypeHandler("Pair", pair))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := v.([]interface{})
+	if p[0] != "a" {
+		t.Errorf("got %v", p[0])
+	}
+	if inner, ok := p[1].([]interface{}); !ok || &inner[0] != &p[0] {
+		t.Errorf("expected the pair to contain itself, got %T", p[1])
+	}
+
+	_, err = rehydrate.ParseWithOptions(`[["Pair",5]]`, rehydrate.WithTypeHandler("Pair", pair))
+	if !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("expected ErrBadReference, got %v", err)
+	}
+}
+
+func TestRegistryTypes(t *testing.T) {
+	registry := rehydrate.NewRegistry(nil)
+	registry.RegisterType("Tag", rehydrate.TypeHandlerFunc(func(*rehydrate.TypedEntry) (interface{}, error) {
+		return "registry", nil
+	}))
+	input := `[["Tag"]]`
+
+	got, err := rehydrate.ParseWithOptions(input, rehydrate.WithRegistry(registry))
+	if err != nil || got != "registry" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	got, err = rehydrate.ParseWithOptions(input,
+		rehydrate.WithRegistry(registry),
+		rehydrate.WithTypeHandler("Tag", rehydrate.TypeHandlerFunc(func(*rehydrate.TypedEntry) (interface{}, error) {
+			return "call", nil
+		})),
+	)
+	if err != nil || got != "call" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	registry.UnregisterType("Tag")
+	if _, err := rehydrate.ParseWithOptions(input, rehydrate.WithRegistry(registry)); !errors.Is(err, rehydrate.ErrUnknownType) {
+		t.Errorf("expected ErrUnknownType, got %v", err)
+	}
+}
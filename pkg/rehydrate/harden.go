@@ -0,0 +1,128 @@
+package rehydrate
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Limits applied by Harden.
+const (
+	hardenedTimeBudget = time.Second
+	hardenedMaxString  = 1 << 20
+	hardenedMaxBinary  = 1 << 20
+	hardenedMaxDepth   = 1000
+)
+
+// Harden combines the options that protect against hostile payloads, for
+// parsing input from untrusted sources:
+//
+//   - references must be in-range integers (WithStrictReferences)
+//   - RegExp values are rejected (WithDeniedTags)
+//   - reserved keys such as __proto__ are dropped and duplicate keys rejected
+//   - values are nested at most 1000 levels deep
+//   - hydration stops after one second
+//   - strings and binary data over 1MB are truncated
+//   - reviver results are validated and Map keys must have a string form
+//   - panics, including those of revivers, are returned as errors
+//     (WithPanicRecovery)
+//
+// Options given after Harden override its settings, e.g. a longer
+// WithTimeBudget.
+func Harden() Option {
+	return func(o *options) {
+		for _, opt := range []Option{
+			WithStrictReferences(),
+			WithDeniedTags(TagRegExp.String()),
+			WithReservedKeys(DropReservedKeys),
+			WithDuplicateKeys(RejectDuplicateKeys),
+			WithTimeBudget(hardenedTimeBudget),
+			WithMaxStringLength(hardenedMaxString),
+			WithMaxBinaryInline(hardenedMaxBinary),
+			WithReviverValidation(),
+			WithStrictMapKeys(),
+			WithPanicRecovery(),
+		} {
+			opt(o)
+		}
+		o.maxDepth = hardenedMaxDepth
+	}
+}
+
+// WithStrictMode rejects payloads the parser otherwise tolerates, without
+// the limits of Harden:
+//
+//   - references must be in-range integers (WithStrictReferences)
+//   - objects must not repeat a key (WithDuplicateKeys)
+//   - Map keys must have a string form when rendered (WithStrictMapKeys)
+func WithStrictMode() Option {
+	return func(o *options) {
+		for _, opt := range []Option{
+			WithStrictReferences(),
+			WithDuplicateKeys(RejectDuplicateKeys),
+			WithStrictMapKeys(),
+		} {
+			opt(o)
+		}
+	}
+}
+
+// WithPanicRecovery turns a panic during hydration, for example in a
+// reviver, into an error wrapping ErrInvalidInput instead of crashing the
+// program.
+func WithPanicRecovery() Option {
+	return func(o *options) {
+		o.recoverPanics = true
+	}
+}
+
+// WithStrictReferences rejects payloads whose references are not integer
+// JSON numbers within the value table, such as the numeric strings the
+// parser otherwise accepts, and array holes outside of arrays. The whole
+// table is checked before hydration, including entries not reachable from
+// the root. Failures wrap ErrBadReference.
+func WithStrictReferences() Option {
+	return func(o *options) {
+		o.strictRefs = true
+	}
+}
+
+// WithDeniedTags makes values with any of the given type tags fail with a
+// TypeError wrapping ErrInvalidInput, even if a reviver is registered for
+// them.
+func WithDeniedTags(tags ...string) Option {
+	return func(o *options) {
+		if o.deniedTags == nil {
+			o.deniedTags = make(map[string]bool)
+		}
+		for _, tag := range tags {
+			o.deniedTags[tag] = true
+		}
+	}
+}
+
+// checkReferences validates every reference of a value table for
+// WithStrictReferences.
+func checkReferences(values []interface{}) error {
+	for i, value := range values {
+		arr, isArray := value.([]interface{})
+		plainArray := isArray && (len(arr) == 0 || !isString(arr[0]))
+		for _, ref := range refPositions(value) {
+			n, ok := ref.(float64)
+			if ok && n < NEGATIVE_ZERO && n == math.Trunc(n) {
+				return fmt.Errorf("entry %d: %w", i, &ErrUnsupportedSentinel{Value: int(n)})
+			}
+			valid := ok && n == math.Trunc(n) && n >= NEGATIVE_ZERO && n < float64(len(values)) &&
+				(n != HOLE || plainArray)
+			if !valid {
+				return fmt.Errorf("%w: invalid reference %v in entry %d", ErrBadReference, ref, i)
+			}
+		}
+	}
+	return nil
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
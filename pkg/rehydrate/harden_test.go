@@ -0,0 +1,87 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestHarden(t *testing.T) {
+	v, err := rehydrate.ParseWithOptions(`[{"a":1,"__proto__":1},"x"]`, rehydrate.Harden())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := v.(map[string]interface{}); len(root) != 1 || root["a"] != "x" {
+		t.Errorf("unexpected result %v", root)
+	}
+
+	// A chain of 2000 nested arrays.
+	var b strings.Builder
+	b.WriteString("[")
+	for i := 0; i < 2000; i++ {
+		b.WriteString("[" + strconv.Itoa(i+1) + "],")
+	}
+	b.WriteString("1]")
+	deep := b.String()
+	if _, err := rehydrate.ParseWithOptions(deep); err != nil {
+		t.Fatalf("expected the payload to parse without Harden: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		opts    []rehydrate.Option
+		want    error
+	}{
+		{"string reference", `[{"a":"1"},"x"]`, nil, rehydrate.ErrBadReference},
+		{"fractional reference", `[{"a":1.5},"x"]`, nil, rehydrate.ErrBadReference},
+		{"unreachable bad reference", `[1,{"a":9}]`, nil, rehydrate.ErrBadReference},
+		{"hole in object", `[{"a":-2}]`, nil, rehydrate.ErrBadReference},
+		{"regexp", `[["RegExp","a+",""]]`, nil, rehydrate.ErrInvalidInput},
+		{"duplicate key", `[{"a":1,"a":1},"x"]`, nil, rehydrate.ErrInvalidInput},
+		{"deep nesting", deep, nil, rehydrate.ErrLimitExceeded},
+		{"panicking reviver", `[["Boom",1],2]`, []rehydrate.Option{rehydrate.WithRevivers(rehydrate.Revivers{
+			"Boom": func(interface{}) (interface{}, error) { panic("boom") },
+		})}, rehydrate.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		opts := append([]rehydrate.Option{rehydrate.Harden()}, tt.opts...)
+		if _, err := rehydrate.ParseWithOptions(tt.payload, opts...); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// Holes stay valid in arrays.
+	if _, err := rehydrate.ParseWithOptions(`[[1,-2],"x"]`, rehydrate.Harden()); err != nil {
+		t.Errorf("hole in array: %v", err)
+	}
+}
+
+func TestWithDeniedTags(t *testing.T) {
+	revivers := rehydrate.Revivers{"Secret": func(v interface{}) (interface{}, error) { return v, nil }}
+	_, err := rehydrate.ParseWithOptions(`[["Secret",1],"x"]`, rehydrate.WithRevivers(revivers), rehydrate.WithDeniedTags("Secret"))
+	var typeErr *rehydrate.TypeError
+	if !errors.As(err, &typeErr) || typeErr.Tag != "Secret" || !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected a TypeError for the denied tag, got %v", err)
+	}
+}
+
+func TestWithStrictMode(t *testing.T) {
+	for _, payload := range []string{
+		`[{"a":"1"},"x"]`,
+		`[{"a":1,"a":1},"x"]`,
+	} {
+		if _, err := rehydrate.ParseWithOptions(payload); err != nil {
+			t.Fatalf("%s: %v", payload, err)
+		}
+		if _, err := rehydrate.ParseWithOptions(payload, rehydrate.WithStrictMode()); err == nil {
+			t.Errorf("%s: expected strict mode to reject the payload", payload)
+		}
+	}
+	if _, err := rehydrate.ParseWithOptions(`[{"big":1},123456789]`, rehydrate.WithStrictMode()); err != nil {
+		t.Errorf("expected a well-formed payload to parse, got %v", err)
+	}
+}
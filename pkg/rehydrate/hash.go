@@ -0,0 +1,135 @@
+package rehydrate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Canonical rewrites a serialized payload into a canonical form, so that
+// payloads describing the same value graph encode identically regardless of
+// the serializer that produced them. Entries unreachable from the root are
+// dropped, equal primitives are merged into one entry, object keys are
+// sorted and entries are numbered in depth-first order from the root.
+// Sharing is preserved: two references to one object and two equal objects
+// remain distinct.
+func Canonical(serialized string) (string, error) {
+	values, err := unmarshalTable(serialized)
+	if err != nil {
+		return "", err
+	}
+	if values == nil {
+		return strings.TrimSpace(serialized), nil
+	}
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return "", err
+	}
+
+	mapping := make([]int, len(values))
+	for i := range mapping {
+		mapping[i] = -1
+	}
+	primitives := make(map[string]int)
+	var order []int
+	var entries []json.RawMessage
+
+	stack := []int{0}
+	for len(stack) > 0 {
+		index := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if mapping[index] >= 0 {
+			continue
+		}
+
+		children, err := childRefs(values[index])
+		if err != nil {
+			return "", fmt.Errorf("index %d: %w", index, err)
+		}
+		if children == nil {
+			if _, ok := values[index].([]interface{}); !ok {
+				if _, ok := values[index].(map[string]interface{}); !ok {
+					key, err := json.Marshal(values[index])
+					if err != nil {
+						return "", fmt.Errorf("index %d: %w", index, err)
+					}
+					if merged, ok := primitives[string(key)]; ok {
+						mapping[index] = merged
+						continue
+					}
+					primitives[string(key)] = len(entries)
+					mapping[index] = len(entries)
+					entries = append(entries, key)
+					order = append(order, -1)
+					continue
+				}
+			}
+		}
+
+		mapping[index] = len(entries)
+		entries = append(entries, nil)
+		order = append(order, index)
+		for i := len(children) - 1; i >= 0; i-- {
+			if children[i] >= len(values) {
+				return "", fmt.Errorf("%w: index %d out of range", ErrBadReference, children[i])
+			}
+			stack = append(stack, children[i])
+		}
+	}
+
+	remap := func(index int) (int, error) {
+		return mapping[index], nil
+	}
+	for i, index := range order {
+		if index < 0 {
+			continue
+		}
+		if obj, ok := values[index].(map[string]interface{}); ok {
+			entries[i], err = canonicalObject(obj, mapping)
+		} else {
+			entries[i], err = remapEntry(raw[index], remap)
+		}
+		if err != nil {
+			return "", fmt.Errorf("index %d: %w", index, err)
+		}
+	}
+	return string(joinRawTable(entries)), nil
+}
+
+func canonicalObject(obj map[string]interface{}, mapping []int) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range sortedKeys(obj) {
+		ref, err := toInt(obj[key])
+		if err != nil {
+			return nil, err
+		}
+		if ref >= 0 {
+			ref = mapping[ref]
+		}
+		quoted, _ := json.Marshal(key)
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(quoted)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(ref))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Hash returns the hex-encoded SHA-256 digest of the canonical form of a
+// serialized payload. Payloads with equal hashes hydrate to equal values.
+func Hash(serialized string) (string, error) {
+	canonical, err := Canonical(serialized)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:]), nil
+}
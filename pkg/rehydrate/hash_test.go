@@ -0,0 +1,46 @@
+package rehydrate_test
+
+import (
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{"sorted keys", `[{"b":1,"a":2},"x","y"]`, `[{"a":1,"b":2},"y","x"]`},
+		{"merged primitives", `[[1,2],"x","x"]`, `[[1,1],"x"]`},
+		{"unreachable entries", `[[2],"orphan",["Set",3],1.50]`, `[[1],["Set",2],1.5]`},
+		{"shared object", `[{"a":1,"b":1},{}]`, `[{"a":1,"b":1},{}]`},
+		{"sentinels", `[[-1,2,-2],"unused","x"]`, `[[-1,1,-2],"x"]`},
+		{"standalone", ` -1 `, `-1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rehydrate.Canonical(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	a, err := rehydrate.Hash(`[{"name":1,"tags":2},"Ada",["Set",3],"x"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := rehydrate.Hash(`[{"tags":3,"name":2},"unused","Ada",["Set",4],"x"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := rehydrate.Hash(`[{"name":1,"tags":2},"Bob",["Set",3],"x"]`)
+	if a != b || a == c || len(a) != 64 {
+		t.Errorf("unexpected hashes %s %s %s", a, b, c)
+	}
+}
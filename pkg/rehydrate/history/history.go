@@ -0,0 +1,168 @@
+// Package history keeps versioned snapshots of the payloads seen under a key,
+// such as a page URL, and hydrates them as of a point in time:
+//
+//	h := history.New(dedupe.NewMemoryStore(), history.KeepLast(100))
+//	h.Record("https://example.com/", payload)
+//	...
+//	v, err := h.ValueAsOf("https://example.com/", yesterday)
+//
+// Payload contents are stored once per distinct payload in a dedupe.Store;
+// the history itself only holds references and timestamps, in memory.
+package history
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/dedupe"
+)
+
+// ErrNoSnapshot is returned when a key has no snapshot at the requested time.
+var ErrNoSnapshot = errors.New("history: no snapshot")
+
+// Snapshot is one recorded version of the payload under Key.
+type Snapshot struct {
+	Key string
+	// Version counts the snapshots recorded for Key, starting at 1. It keeps
+	// increasing when older snapshots are removed by retention.
+	Version int
+	Time    time.Time
+	Ref     dedupe.Ref
+}
+
+// Option configures New.
+type Option func(*History)
+
+// KeepLast keeps at most n snapshots per key, dropping the oldest.
+func KeepLast(n int) Option {
+	return func(h *History) {
+		h.keepLast = n
+	}
+}
+
+// KeepFor drops snapshots older than d, except the latest one of each key,
+// which still describes the current value. Like KeepLast, it is applied to a
+// key whenever a snapshot is recorded for it.
+func KeepFor(d time.Duration) Option {
+	return func(h *History) {
+		h.keepFor = d
+	}
+}
+
+// WithClock sets the clock that timestamps snapshots and ages them. The
+// default is rehydrate.SystemClock.
+func WithClock(c rehydrate.Clock) Option {
+	return func(h *History) {
+		if c != nil {
+			h.clock = c
+		}
+	}
+}
+
+// History records snapshots per key. It is safe for concurrent use.
+type History struct {
+	payloads *dedupe.Dedupe
+	clock    rehydrate.Clock
+	keepLast int
+	keepFor  time.Duration
+
+	mu        sync.RWMutex
+	snapshots map[string][]Snapshot
+	versions  map[string]int
+}
+
+// New returns an empty History storing payloads in store. Without retention
+// options every snapshot is kept. Retention only forgets snapshots; their
+// payloads stay in store, which may share them with other keys.
+func New(store dedupe.Store, opts ...Option) *History {
+	h := &History{
+		payloads:  dedupe.New(store),
+		clock:     rehydrate.SystemClock,
+		snapshots: make(map[string][]Snapshot),
+		versions:  make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Record stores serialized as the current payload of key. If it is
+// equivalent to the latest snapshot, no snapshot is added and the latest one
+// is returned with changed set to false.
+func (h *History) Record(key, serialized string) (s Snapshot, changed bool, err error) {
+	ref, _, err := h.payloads.Put(serialized)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	now := h.clock.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	snaps := h.snapshots[key]
+	if n := len(snaps); n > 0 && snaps[n-1].Ref == ref {
+		return snaps[n-1], false, nil
+	}
+	h.versions[key]++
+	s = Snapshot{Key: key, Version: h.versions[key], Time: now, Ref: ref}
+	h.snapshots[key] = h.prune(append(snaps, s), now)
+	return s, true, nil
+}
+
+// prune applies the retention policy to the snapshots of one key.
+func (h *History) prune(snaps []Snapshot, now time.Time) []Snapshot {
+	if h.keepLast > 0 && len(snaps) > h.keepLast {
+		snaps = append([]Snapshot(nil), snaps[len(snaps)-h.keepLast:]...)
+	}
+	if h.keepFor > 0 {
+		cutoff := now.Add(-h.keepFor)
+		i := sort.Search(len(snaps)-1, func(i int) bool {
+			return !snaps[i].Time.Before(cutoff)
+		})
+		snaps = snaps[i:]
+	}
+	return snaps
+}
+
+// Snapshots returns the retained snapshots of key, oldest first.
+func (h *History) Snapshots(key string) []Snapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]Snapshot(nil), h.snapshots[key]...)
+}
+
+// AsOf returns the snapshot of key that was current at t: the latest one
+// recorded at or before t.
+func (h *History) AsOf(key string, t time.Time) (Snapshot, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	snaps := h.snapshots[key]
+	i := sort.Search(len(snaps), func(i int) bool {
+		return snaps[i].Time.After(t)
+	})
+	if i == 0 {
+		return Snapshot{}, ErrNoSnapshot
+	}
+	return snaps[i-1], nil
+}
+
+// Payload returns the canonical payload of s.
+func (h *History) Payload(s Snapshot) (string, error) {
+	return h.payloads.Get(s.Ref)
+}
+
+// ValueAsOf hydrates the payload of key that was current at t.
+func (h *History) ValueAsOf(key string, t time.Time, opts ...rehydrate.Option) (interface{}, error) {
+	s, err := h.AsOf(key, t)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := h.Payload(s)
+	if err != nil {
+		return nil, err
+	}
+	return rehydrate.ParseWithOptions(payload, opts...)
+}
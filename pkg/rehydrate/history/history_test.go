@@ -0,0 +1,79 @@
+package history_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/dedupe"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/history"
+)
+
+type manualClock struct{ now time.Time }
+
+func (c *manualClock) Now() time.Time { return c.now }
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &manualClock{now: start}
+	h := history.New(dedupe.NewMemoryStore(), history.WithClock(clock))
+
+	record := func(payload string) bool {
+		t.Helper()
+		_, changed, err := h.Record("page", payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clock.now = clock.now.Add(time.Hour)
+		return changed
+	}
+	record(`[{"title":1},"v1"]`)
+	if record(`[{"title":2},"unused","v1"]`) {
+		t.Error("an equivalent payload must not add a snapshot")
+	}
+	record(`[{"title":1},"v2"]`)
+
+	if snaps := h.Snapshots("page"); len(snaps) != 2 || snaps[1].Version != 2 {
+		t.Fatalf("unexpected snapshots %+v", snaps)
+	}
+
+	v, err := h.ValueAsOf("page", start.Add(90*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := v.(map[string]interface{})["title"]; title != "v1" {
+		t.Errorf("expected v1 after 90 minutes, got %v", title)
+	}
+	v, _ = h.ValueAsOf("page", start.Add(2*time.Hour))
+	if title := v.(map[string]interface{})["title"]; title != "v2" {
+		t.Errorf("expected v2 after two hours, got %v", title)
+	}
+	if _, err := h.AsOf("page", start.Add(-time.Second)); !errors.Is(err, history.ErrNoSnapshot) {
+		t.Errorf("expected ErrNoSnapshot, got %v", err)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	clock := &manualClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	h := history.New(dedupe.NewMemoryStore(), history.WithClock(clock),
+		history.KeepLast(3), history.KeepFor(90*time.Minute))
+
+	for _, payload := range []string{`["a"]`, `["b"]`, `["c"]`, `["d"]`, `["e"]`} {
+		if _, _, err := h.Record("k", payload); err != nil {
+			t.Fatal(err)
+		}
+		clock.now = clock.now.Add(time.Hour)
+	}
+	snaps := h.Snapshots("k")
+	if len(snaps) != 2 || snaps[0].Version != 4 || snaps[1].Version != 5 {
+		t.Fatalf("unexpected snapshots %+v", snaps)
+	}
+
+	// The latest snapshot survives KeepFor however old it is.
+	clock.now = clock.now.Add(24 * time.Hour)
+	h.Record("k", `["f"]`)
+	h.Record("other", `["x"]`)
+	if snaps := h.Snapshots("k"); len(snaps) != 1 || snaps[0].Version != 6 {
+		t.Fatalf("unexpected snapshots %+v", snaps)
+	}
+}
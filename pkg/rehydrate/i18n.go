@@ -0,0 +1,257 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// localeMapKeys are the object keys under which a message map holding a
+// single locale is recognised. Maps of several locales are recognised
+// under any key.
+var localeMapKeys = map[string]bool{
+	"i18n": true, "lang": true, "locale": true, "locales": true,
+	"messages": true, "translations": true,
+}
+
+// LocaleMessages is an i18n message map SplitLocales moved out of a payload.
+type LocaleMessages struct {
+	// Path is the path of the map in the payload, empty for the root.
+	Path string `json:"path"`
+	// Locales maps each locale, such as "en" or "pt-BR", to its messages,
+	// encoded as a JSON object.
+	Locales map[string]json.RawMessage `json:"locales"`
+}
+
+// FindLocales returns the paths of the i18n message maps in serialized, in
+// path order. A message map is an object whose keys are all locale tags,
+// such as en, pt-BR or zh_Hant, and whose values are objects holding only
+// strings, arrays and nested objects, the shape vue-i18n and similar
+// libraries use. An object with a single locale counts only when it is held
+// under a key such as messages or translations. Maps nested in a message
+// map are not reported.
+func FindLocales(serialized string) ([]string, error) {
+	values, err := unmarshalTable(serialized)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, m := range findLocaleMaps(values) {
+		paths = append(paths, m.path)
+	}
+	return paths, nil
+}
+
+// SplitLocales moves the message maps FindLocales reports out of
+// serialized, so consumers that do not render text need not process them
+// and the messages can be served one locale at a time. It returns the
+// payload with each map replaced by an empty object and the entries only
+// the maps referenced removed, as by Compact, together with the messages
+// of each map. A payload without message maps is returned unchanged.
+func SplitLocales(serialized string) (string, []LocaleMessages, error) {
+	values, err := unmarshalTable(serialized)
+	if err != nil {
+		return "", nil, err
+	}
+	maps := findLocaleMaps(values)
+	if len(maps) == 0 {
+		return serialized, nil, nil
+	}
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return "", nil, err
+	}
+
+	split := make([]LocaleMessages, len(maps))
+	for i, m := range maps {
+		split[i] = LocaleMessages{Path: m.path, Locales: make(map[string]json.RawMessage, len(m.refs))}
+		for locale, ref := range m.refs {
+			data, err := json.Marshal(messageValue(values, ref))
+			if err != nil {
+				return "", nil, err
+			}
+			split[i].Locales[locale] = data
+		}
+		raw[m.index] = json.RawMessage("{}")
+	}
+	out, _, err := Compact(string(joinRawTable(raw)))
+	if err != nil {
+		return "", nil, err
+	}
+	return out, split, nil
+}
+
+type localeMap struct {
+	index int
+	path  string
+	// refs maps each locale to the index of its messages.
+	refs map[string]int
+}
+
+// findLocaleMaps returns the message maps reachable from the root of the
+// value table, in path order.
+func findLocaleMaps(values []interface{}) []localeMap {
+	type item struct {
+		index     int
+		path, key string
+	}
+	var maps []localeMap
+	seen := make([]bool, len(values))
+	messages := make(map[int]bool)
+	stack := []item{{index: 0}}
+	if len(values) == 0 {
+		stack = nil
+	}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if it.index < 0 || seen[it.index] {
+			continue
+		}
+		seen[it.index] = true
+		if refs, ok := localeRefs(values, it.index, it.key, messages); ok {
+			maps = append(maps, localeMap{index: it.index, path: it.path, refs: refs})
+			continue
+		}
+
+		var children []item
+		if obj, ok := values[it.index].(map[string]interface{}); ok {
+			for _, key := range sortedKeys(obj) {
+				ref, err := toInt(obj[key])
+				if err == nil && ref < len(values) {
+					children = append(children, item{ref, keyPath(it.path, key), key})
+				}
+			}
+		} else {
+			for _, child := range tableChildren(values, it.index, it.path) {
+				children = append(children, item{index: child.index, path: child.path})
+			}
+		}
+		// Push in reverse so children are visited in order.
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
+	}
+	return maps
+}
+
+// localeRefs returns the messages of each locale if the entry at index,
+// held under key, is a message map. Results of isMessages are cached in
+// messages.
+func localeRefs(values []interface{}, index int, key string, messages map[int]bool) (map[string]int, bool) {
+	obj, ok := values[index].(map[string]interface{})
+	if !ok || len(obj) == 0 || (len(obj) == 1 && !localeMapKeys[strings.ToLower(key)]) {
+		return nil, false
+	}
+	refs := make(map[string]int, len(obj))
+	for locale, ref := range obj {
+		i, err := toInt(ref)
+		if err != nil || i < 0 || i >= len(values) || !isLocaleTag(locale) {
+			return nil, false
+		}
+		if m, ok := values[i].(map[string]interface{}); !ok || len(m) == 0 {
+			return nil, false
+		}
+		if !isMessages(values, i, messages) {
+			return nil, false
+		}
+		refs[locale] = i
+	}
+	return refs, true
+}
+
+// isMessages reports whether the entry at index holds only strings, plain
+// arrays and objects. Entries being checked are recorded as false in
+// messages, so cyclic values are rejected.
+func isMessages(values []interface{}, index int, messages map[int]bool) bool {
+	if ok, checked := messages[index]; checked {
+		return ok
+	}
+	messages[index] = false
+	var children []interface{}
+	switch v := values[index].(type) {
+	case string:
+		messages[index] = true
+		return true
+	case map[string]interface{}:
+		for _, ref := range v {
+			children = append(children, ref)
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			if _, tagged := v[0].(string); tagged {
+				return false
+			}
+		}
+		children = v
+	default:
+		return false
+	}
+	for _, ref := range children {
+		i, err := toInt(ref)
+		if err != nil || i < 0 || i >= len(values) || !isMessages(values, i, messages) {
+			return false
+		}
+	}
+	messages[index] = true
+	return true
+}
+
+// messageValue returns the JSON value of the messages at index, which
+// isMessages accepted.
+func messageValue(values []interface{}, index int) interface{} {
+	switch v := values[index].(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for key, ref := range v {
+			i, _ := toInt(ref)
+			obj[key] = messageValue(values, i)
+		}
+		return obj
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for j, ref := range v {
+			i, _ := toInt(ref)
+			arr[j] = messageValue(values, i)
+		}
+		return arr
+	}
+	return values[index]
+}
+
+// isLocaleTag reports whether s looks like a BCP 47 language tag: a
+// lower-case language of two or three letters, optionally followed by a
+// four-letter script and a region of two letters or three digits, separated
+// by hyphens or underscores.
+func isLocaleTag(s string) bool {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 || len(parts) > 3 || strings.Count(s, "-")+strings.Count(s, "_") != len(parts)-1 {
+		return false
+	}
+	if len(parts[0]) < 2 || len(parts[0]) > 3 || !isASCII(parts[0], 'a', 'z') {
+		return false
+	}
+	for i, part := range parts[1:] {
+		switch {
+		case len(part) == 4 && i == 0 && isLetters(part):
+		case len(part) == 2 && isLetters(part):
+		case len(part) == 3 && isASCII(part, '0', '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isLetters(s string) bool {
+	return isASCII(strings.ToLower(s), 'a', 'z')
+}
+
+// isASCII reports whether every byte of s lies between lo and hi.
+func isASCII(s string, lo, hi byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < lo || s[i] > hi {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+const i18nPayload = `[{"title":1,"i18n":2},"Home",{"en":3,"pt-BR":4},{"hello":5,"nav":6},{"hello":7,"nav":8},"Hello",[9],"Olá",[10],"Start","Início"]`
+
+func TestSplitLocales(t *testing.T) {
+	out, split, err := rehydrate.SplitLocales(i18nPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"title":1,"i18n":2},"Home",{}]`; out != want {
+		t.Errorf("payload = %s, want %s", out, want)
+	}
+	if len(split) != 1 || split[0].Path != "/i18n" {
+		t.Fatalf("split = %+v", split)
+	}
+	want := map[string]string{
+		"en":    `{"hello":"Hello","nav":["Start"]}`,
+		"pt-BR": `{"hello":"Olá","nav":["Início"]}`,
+	}
+	got := make(map[string]string)
+	for locale, data := range split[0].Locales {
+		got[locale] = string(data)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("locales = %v, want %v", got, want)
+	}
+
+	plain := `[{"a":1},"x"]`
+	if out, split, err := rehydrate.SplitLocales(plain); err != nil || out != plain || split != nil {
+		t.Errorf("SplitLocales(%s) = %s, %v, %v", plain, out, split, err)
+	}
+}
+
+func TestFindLocales(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    []string
+	}{
+		{i18nPayload, []string{"/i18n"}},
+		// A single locale is only recognised under a key naming messages.
+		{`[{"messages":1},{"fr":2},{"hi":3},"salut"]`, []string{"/messages"}},
+		{`[{"user":1},{"id":2},{"name":3},"Ada"]`, nil},
+		// Messages hold only strings, arrays and objects.
+		{`[{"en":1,"de":2},{"n":3},{"n":3},1]`, nil},
+		{`[{"en":1,"de":2},{"d":3},{"d":3},["Date","2024-01-02T00:00:00.000Z"]]`, nil},
+		{`[{"en":1,"de":2},{"self":1},{"a":3},"x"]`, nil},
+		{`[{"EN":1,"de":2},{"a":3},{"a":3},"x"]`, nil},
+		{`[{"a":1,"b":4},{"zh_Hant":2,"sr-Latn-RS":3},{"k":5},{"k":5},{"en":2,"es-419":3},"v"]`, []string{"/a", "/b"}},
+	}
+	for _, tt := range tests {
+		got, err := rehydrate.FindLocales(tt.payload)
+		if err != nil {
+			t.Errorf("FindLocales(%s): %v", tt.payload, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindLocales(%s) = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
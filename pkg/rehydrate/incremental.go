@@ -0,0 +1,162 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Rehydrator hydrates successive versions of a payload, such as the buffer
+// of an editor, re-hydrating only the value-table entries that changed since
+// the previous version and the entries referring to them. Unchanged entries
+// keep their hydrated values, so results of successive versions share them
+// and must not be modified.
+//
+// Options depending on the path of a value, such as WithPathPolicy,
+// WithCoercion, WithAudit or WithProvenance, only see the entries hydrated
+// by the current call. A Rehydrator is not safe for concurrent use.
+type Rehydrator struct {
+	opts *options
+
+	raw      []json.RawMessage
+	values   []interface{}
+	hydrated []interface{}
+	// computed marks the entries the previous version hydrated; the others
+	// were unreachable from its root and have no value to reuse.
+	computed []bool
+
+	rehydrated int
+}
+
+// NewRehydrator returns a Rehydrator hydrating payloads with opts.
+func NewRehydrator(opts ...Option) *Rehydrator {
+	return &Rehydrator{opts: newOptions(opts)}
+}
+
+// Update hydrates a new version of the payload. If it fails, the previous
+// version remains the base of the next update.
+func (r *Rehydrator) Update(serialized string) (result interface{}, err error) {
+	o := r.opts
+	if o.recoverPanics {
+		defer o.recoverPanic(&result, &err)
+	}
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil || len(raw) == 0 {
+		// Not a value table, such as a standalone sentinel: nothing to
+		// reuse.
+		r.raw, r.values, r.hydrated, r.computed = nil, nil, nil, nil
+		r.rehydrated = 0
+		return parse(serialized, o)
+	}
+
+	values := make([]interface{}, len(raw))
+	changed := make([]bool, len(raw))
+	for i, entry := range raw {
+		if i < len(r.raw) && bytes.Equal(entry, r.raw[i]) {
+			values[i] = r.values[i]
+			continue
+		}
+		changed[i] = true
+		if values[i], err = decodeEntry(entry, o); err != nil {
+			return nil, fmt.Errorf("%w: index %d: %w", ErrInvalidInput, i, err)
+		}
+	}
+	if o.duplicateKeys != KeepLastDuplicateKey {
+		if err := checkDuplicateKeys(serialized, o); err != nil {
+			return nil, err
+		}
+	}
+	if o.provenance != nil {
+		offsets, err := entryOffsets(serialized)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		o.provenance.reset(offsets)
+	}
+
+	dirty := dependents(values, changed)
+	h := newHydrator(o)
+	if o.strictRefs {
+		if err := checkReferences(values); err != nil {
+			return nil, err
+		}
+	}
+	h.values = values
+	h.hydrated = make([]interface{}, len(values))
+	h.computed = make([]bool, len(values))
+	for i := range values {
+		if !dirty[i] && i < len(r.computed) && r.computed[i] {
+			h.hydrated[i], h.computed[i] = r.hydrated[i], true
+		}
+	}
+	result, err = h.hydrateRoot(0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	r.rehydrated = 0
+	for i := range values {
+		if dirty[i] && h.computed[i] {
+			r.rehydrated++
+		}
+	}
+	r.raw, r.values, r.hydrated, r.computed = raw, values, h.hydrated, h.computed
+	return result, nil
+}
+
+// Rehydrated returns the number of entries hydrated by the last successful
+// call to Update.
+func (r *Rehydrator) Rehydrated() int {
+	return r.rehydrated
+}
+
+// decodeEntry decodes a single value-table entry.
+func decodeEntry(entry json.RawMessage, o *options) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(entry, &v); err != nil {
+		return nil, err
+	}
+	table := []interface{}{v}
+	if o.utf16Strings {
+		if err := restoreLoneSurrogates("["+string(entry)+"]", table); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyNumberMode("["+string(entry)+"]", table, o.numberMode); err != nil {
+		return nil, err
+	}
+	return table[0], nil
+}
+
+// dependents returns the entries that are changed or refer, directly or
+// through other entries, to a changed one.
+func dependents(values []interface{}, changed []bool) []bool {
+	parents := make([][]int, len(values))
+	for i, value := range values {
+		for _, ref := range refPositions(value) {
+			child, err := toInt(ref)
+			if err == nil && child >= 0 && child < len(values) {
+				parents[child] = append(parents[child], i)
+			}
+		}
+	}
+	dirty := make([]bool, len(values))
+	var queue []int
+	for i, c := range changed {
+		if c {
+			dirty[i] = true
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, parent := range parents[i] {
+			if !dirty[parent] {
+				dirty[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return dirty
+}
@@ -0,0 +1,77 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestRehydrator(t *testing.T) {
+	versions := []string{
+		`[{"title":1,"items":2,"meta":6},"Draft",[3,4],{"id":5},{"id":5},1,["Date","2024-01-01T00:00:00.000Z"]]`,
+		`[{"title":1,"items":2,"meta":6},"Final",[3,4],{"id":5},{"id":5},1,["Date","2024-01-01T00:00:00.000Z"]]`,
+		`[{"title":1,"items":2,"meta":6},"Final",[3,4],{"id":5},{"id":7},1,["Date","2024-01-01T00:00:00.000Z"],2]`,
+	}
+	r := rehydrate.NewRehydrator()
+	for i, payload := range versions {
+		got, err := r.Update(payload)
+		if err != nil {
+			t.Fatalf("version %d: %v", i, err)
+		}
+		want, err := rehydrate.Parse(payload, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: got %#v, want %#v", i, got, want)
+		}
+	}
+	// The last version changed the entry of items[1] and added its new
+	// id: the new id, items[1], items and the root are re-hydrated.
+	if n := r.Rehydrated(); n != 4 {
+		t.Errorf("rehydrated %d entries, want 4", n)
+	}
+
+	// A failed update keeps the previous version as the base.
+	if _, err := r.Update(`[{"a":9}]`); !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Fatalf("got %v, want a reference error", err)
+	}
+	got, err := r.Update(versions[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := r.Rehydrated(); n != 0 {
+		t.Errorf("rehydrated %d entries of an unchanged payload", n)
+	}
+	if got.(map[string]interface{})["title"] != "Final" {
+		t.Errorf("got %#v", got)
+	}
+
+	// Standalone payloads are hydrated in full.
+	if v, err := r.Update(`-1`); err != nil || v != nil {
+		t.Errorf("standalone: got %#v, %v", v, err)
+	}
+}
+
+func TestRehydratorUnreachableEntry(t *testing.T) {
+	// "orphan" is unchanged but was unreachable in the first version, so it
+	// has no hydrated value to reuse once the second refers to it.
+	r := rehydrate.NewRehydrator()
+	if _, err := r.Update(`[{"a":1},"x","orphan"]`); err != nil {
+		t.Fatal(err)
+	}
+	payload := `[{"a":1,"b":2},"x","orphan"]`
+	got, err := r.Update(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := rehydrate.Parse(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,334 @@
+package rehydrate
+
+import "fmt"
+
+// iterative reports whether the hydrator can use hydrateIterative. Per-path
+// hooks (path policies, audit, coercion, provenance and secret scanning),
+// array sampling and the V1 compatibility mode are only implemented by the
+// recursive engine, so with them the nesting depth of payloads is limited to
+// WithMaxDepth levels and at most defaultRecursiveDepth; deeper
+// payloads fail with ErrLimitExceeded. The same limit applies below entries
+// hydrated by a TypeHandler, which are hydrated recursively.
+func (o *options) iterative() bool {
+	return !o.trackPaths() && o.sampleSize <= 0 && o.compat != V1
+}
+
+type frameKind int
+
+const (
+	frameArray frameKind = iota
+	frameObject
+	frameNullObject
+	frameSet
+	frameMap
+	frameReviver
+)
+
+// hydrateFrame is an entry of the work stack of hydrateIterative: a
+// container, or a tagged value handled by a reviver, waiting for its
+// children to be hydrated.
+type hydrateFrame struct {
+	kind  frameKind
+	index int
+	// entry is the table entry of arrays and tagged values, obj and keys
+	// those of objects.
+	entry []interface{}
+	obj   map[string]interface{}
+	keys  []string
+	// pos is the position of the next child in entry or keys.
+	pos    int
+	result interface{}
+
+	// slot is the array position, key the object key or hydrated Map key
+	// and ref the Set element or Map key reference the child being
+	// hydrated belongs to. ready is set once a Map key is hydrated and
+	// once the argument of a reviver is requested.
+	slot  int
+	key   interface{}
+	ref   int
+	ready bool
+
+	tag     string
+	reviver ReviverFunc
+}
+
+// hydrateIterative hydrates the entry at index like hydrate, but keeps the
+// containers being hydrated on an explicit work stack instead of the call
+// stack, so the nesting depth of payloads is bounded only by memory.
+func (h *hydrator) hydrateIterative(index int) (interface{}, error) {
+	v, f, err := h.begin(index, 0)
+	if f == nil {
+		return v, err
+	}
+	stack := []*hydrateFrame{f}
+	// reviving holds the entries of the reviver frames on the stack.
+	reviving := make(map[int]bool)
+	if f.kind == frameReviver {
+		reviving[f.index] = true
+	}
+	for {
+		top := stack[len(stack)-1]
+		child, more, err := h.next(top)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			v, err := h.finish(top)
+			if err != nil {
+				return nil, err
+			}
+			stack = stack[:len(stack)-1]
+			delete(reviving, top.index)
+			if len(stack) == 0 {
+				return v, nil
+			}
+			h.deliver(stack[len(stack)-1], v)
+			continue
+		}
+
+		v, f, err := h.begin(child, len(stack))
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			h.deliver(top, v)
+			continue
+		}
+		if f.kind == frameReviver {
+			if reviving[f.index] {
+				return nil, fmt.Errorf("%w: argument of the %s at index %d refers back to it", ErrBadReference, f.tag, f.index)
+			}
+			reviving[f.index] = true
+		}
+		stack = append(stack, f)
+	}
+}
+
+// begin starts hydrating the entry at index, nested depth levels below the
+// root. It returns the value of entries without children, and otherwise a
+// frame for the work stack; containers are stored before their children
+// are hydrated, so references back to them resolve.
+func (h *hydrator) begin(index, depth int) (interface{}, *hydrateFrame, error) {
+	if v, done, err := h.lookup(index, false); done {
+		return v, nil, err
+	}
+	if err := h.checkDepth(depth); err != nil {
+		return nil, nil, err
+	}
+	value := h.values[index]
+	if v, ok := h.scalar(index, value); ok {
+		return v, nil, nil
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		if len(v) > 0 {
+			if typeStr, ok := v[0].(string); ok {
+				return h.beginTagged(index, typeStr, v)
+			}
+		}
+		arr := make([]interface{}, len(v))
+		h.store(index, arr)
+		return nil, &hydrateFrame{kind: frameArray, index: index, entry: v, result: arr}, nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{})
+		h.store(index, obj)
+		return nil, &hydrateFrame{kind: frameObject, index: index, obj: v, keys: h.objectKeys(v), result: obj}, nil
+	}
+	return nil, nil, fmt.Errorf("%w: unknown value type at index %d", ErrInvalidInput, index)
+}
+
+// beginTagged starts hydrating a tagged entry. Tags without children, and
+// those handled by a TypeHandler, are hydrated at once.
+func (h *hydrator) beginTagged(index int, typeStr string, arr []interface{}) (interface{}, *hydrateFrame, error) {
+	if h.opts.deniedTags[typeStr] {
+		return nil, nil, typeError(typeStr, index, fmt.Errorf("%w: denied tag", ErrInvalidInput))
+	}
+	if reviver, exists := h.opts.reviver(typeStr); exists {
+		if len(arr) < 2 {
+			return nil, nil, typeError(typeStr, index, ErrInvalidInput)
+		}
+		return nil, &hydrateFrame{kind: frameReviver, index: index, entry: arr, tag: typeStr, reviver: reviver}, nil
+	}
+	if handler, ok := h.opts.typeHandler(typeStr); ok {
+		// Handlers hydrate their arguments with the recursive engine.
+		v, err := h.hydrateHandled(handler, index, typeStr, arr)
+		return v, nil, err
+	}
+
+	f := &hydrateFrame{index: index, entry: arr, pos: 1}
+	tag, _ := ParseTag(typeStr)
+	switch tag {
+	case TagSet:
+		f.kind, f.result = frameSet, NewSet()
+		if h.opts.setAsSlice {
+			f.result = make([]interface{}, len(arr)-1)
+		}
+	case TagMap:
+		if len(arr)%2 != 1 {
+			return nil, nil, typeError(typeStr, index, fmt.Errorf("%w: odd number of Map entries", ErrInvalidInput))
+		}
+		f.kind, f.result = frameMap, NewOrderedMap()
+	case TagNull:
+		if len(arr)%2 != 1 {
+			return nil, nil, typeError(typeStr, index, fmt.Errorf("%w: odd number of object entries", ErrInvalidInput))
+		}
+		f.kind, f.tag, f.result = frameNullObject, typeStr, h.opts.nullObject(make(map[string]interface{}))
+	default:
+		v, err := h.hydrateTagged(index, typeStr, arr)
+		return v, nil, err
+	}
+	h.store(index, f.result)
+	return nil, f, nil
+}
+
+// next returns the reference of the next child of f to hydrate, or false
+// once all children are hydrated.
+func (h *hydrator) next(f *hydrateFrame) (int, bool, error) {
+	switch f.kind {
+	case frameArray:
+		for f.pos < len(f.entry) {
+			f.slot = f.pos
+			f.pos++
+			ref, err := toInt(f.entry[f.slot])
+			if err != nil {
+				return 0, false, err
+			}
+			if ref != HOLE {
+				return ref, true, nil
+			}
+		}
+
+	case frameObject:
+		obj := f.result.(map[string]interface{})
+		for f.pos < len(f.keys) {
+			raw := f.keys[f.pos]
+			f.pos++
+			key, keep := h.objectKey(raw)
+			if !keep {
+				continue
+			}
+			if _, dup := obj[key]; dup {
+				continue
+			}
+			ref, err := toInt(f.obj[raw])
+			if err != nil {
+				return 0, false, err
+			}
+			f.key = key
+			return ref, true, nil
+		}
+
+	case frameNullObject:
+		for ; f.pos < len(f.entry); f.pos += 2 {
+			key, ok := f.entry[f.pos].(string)
+			if !ok {
+				return 0, false, typeError(f.tag, f.index, fmt.Errorf("%w: invalid key in null object", ErrInvalidInput))
+			}
+			key, keep := h.objectKey(key)
+			if !keep {
+				continue
+			}
+			ref, err := toInt(f.entry[f.pos+1])
+			if err != nil {
+				return 0, false, err
+			}
+			f.key = key
+			f.pos += 2
+			return ref, true, nil
+		}
+
+	case frameSet:
+		if f.pos < len(f.entry) {
+			ref, err := toInt(f.entry[f.pos])
+			if err != nil {
+				return 0, false, err
+			}
+			f.ref = ref
+			f.pos++
+			return ref, true, nil
+		}
+
+	case frameMap:
+		if f.pos < len(f.entry) {
+			if f.ready {
+				ref, err := toInt(f.entry[f.pos+1])
+				return ref, err == nil, err
+			}
+			keyRef, err := toInt(f.entry[f.pos])
+			if err != nil {
+				return 0, false, err
+			}
+			if _, err := toInt(f.entry[f.pos+1]); err != nil {
+				return 0, false, err
+			}
+			f.ref = keyRef
+			return keyRef, true, nil
+		}
+
+	case frameReviver:
+		if !f.ready {
+			f.ready = true
+			ref, err := toInt(f.entry[1])
+			return ref, err == nil, err
+		}
+	}
+	return 0, false, nil
+}
+
+// deliver stores v, the hydrated child requested by the last call to next,
+// in f.
+func (h *hydrator) deliver(f *hydrateFrame, v interface{}) {
+	switch f.kind {
+	case frameArray:
+		f.result.([]interface{})[f.slot] = v
+	case frameObject, frameNullObject:
+		objectOf(f.result)[f.key.(string)] = v
+	case frameSet:
+		if items, ok := f.result.([]interface{}); ok {
+			items[f.pos-2] = v
+			return
+		}
+		f.result.(*Set).add(v, f.ref)
+	case frameMap:
+		if !f.ready {
+			f.key, f.ready = v, true
+			return
+		}
+		f.result.(*OrderedMap).set(f.key, f.ref, v)
+		f.pos += 2
+		f.ready = false
+	case frameReviver:
+		f.result = v
+	}
+}
+
+// finish returns the value of f once its children are hydrated, calling
+// the reviver of revived values.
+func (h *hydrator) finish(f *hydrateFrame) (interface{}, error) {
+	if f.kind != frameReviver {
+		return f.result, nil
+	}
+	in := f.result
+	if h.opts.resolveRevived {
+		if h.reviving == nil {
+			h.reviving = make(map[int]bool)
+		}
+		h.reviving[f.index] = true
+	}
+	res, err := h.reviveChecked(f.reviver, f.tag, f.index, in)
+	if h.opts.resolveRevived {
+		if err == nil {
+			res, err = h.resolveRevived(in, res)
+		}
+		delete(h.reviving, f.index)
+	}
+	if err == nil {
+		err = h.validateRevived(f.tag, f.index, in, res)
+	}
+	if err != nil {
+		return nil, typeError(f.tag, f.index, err)
+	}
+	h.store(f.index, res)
+	return res, nil
+}
@@ -0,0 +1,172 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// nestedPayload returns a payload of arrays, objects, Sets and Maps nested
+// depth levels deep.
+func nestedPayload(depth int) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 0; i < depth; i++ {
+		next := strconv.Itoa(i + 1)
+		switch i % 4 {
+		case 0:
+			b.WriteString("[" + next + "]")
+		case 1:
+			b.WriteString(`{"a":` + next + "}")
+		case 2:
+			b.WriteString(`["Set",` + next + "]")
+		case 3:
+			b.WriteString(`["Map",` + next + "," + next + "]")
+		}
+		b.WriteByte(',')
+	}
+	b.WriteString(`"leaf"]`)
+	return b.String()
+}
+
+func TestDeepPayload(t *testing.T) {
+	const depth = 1_000_000
+	v, err := rehydrate.Parse(nestedPayload(depth), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < depth; i++ {
+		switch value := v.(type) {
+		case []interface{}:
+			v = value[0]
+		case map[string]interface{}:
+			v = value["a"]
+		case *rehydrate.Set:
+			v = value.Values()[0]
+		case *rehydrate.OrderedMap:
+			v = value.Entries()[0].Value
+		default:
+			t.Fatalf("level %d: got %T", i, v)
+		}
+	}
+	if v != "leaf" {
+		t.Errorf("got %v, want the leaf", v)
+	}
+
+	_, err = rehydrate.ParseWithOptions(nestedPayload(depth), rehydrate.WithMaxDepth(100))
+	if !errors.Is(err, rehydrate.ErrLimitExceeded) {
+		t.Errorf("WithMaxDepth: got %v, want ErrLimitExceeded", err)
+	}
+	if _, err := rehydrate.ParseWithOptions(nestedPayload(99), rehydrate.WithMaxDepth(100)); err != nil {
+		t.Errorf("WithMaxDepth: %v", err)
+	}
+}
+
+func TestDeepPayloadLimits(t *testing.T) {
+	payload := nestedPayload(200_000)
+	_, err := rehydrate.RehydrateWith(payload, nil)
+	if !errors.Is(err, rehydrate.ErrLimitExceeded) || len(err.Error()) > 200 {
+		t.Errorf("RehydrateWith: got %.200v, want ErrLimitExceeded", err)
+	}
+	// Options tracking paths hydrate recursively, within a fixed limit.
+	coerce := rehydrate.WithCoercion(func(string, interface{}) (interface{}, bool) { return nil, false })
+	if _, err := rehydrate.ParseWithOptions(payload, coerce); !errors.Is(err, rehydrate.ErrLimitExceeded) {
+		t.Errorf("WithCoercion: got %v, want ErrLimitExceeded", err)
+	}
+	if _, err := rehydrate.ParseWithOptions(nestedPayload(9_000), coerce); err != nil {
+		t.Errorf("WithCoercion: %v", err)
+	}
+}
+
+func TestIterativeMatchesRecursive(t *testing.T) {
+	payload := `[{"list":1,"set":4,"map":5,"box":7,"null":9,"self":0,"date":10},` +
+		`[2,-2,3,-4],"a",1.5,["Set",2,3],["Map",2,6,1,3],{"k":2},["Box",6],` +
+		`["Box",8],["null","x",3],["Date","2024-01-02T00:00:00.000Z"]]`
+	box := rehydrate.WithRevivers(rehydrate.Revivers{
+		"Box": func(v interface{}) (interface{}, error) { return []interface{}{v}, nil },
+	})
+
+	iterative, err := rehydrate.ParseWithOptions(payload, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A coercion makes the hydrator track paths, which selects the
+	// recursive engine.
+	keep := rehydrate.WithCoercion(func(string, interface{}) (interface{}, bool) { return nil, false })
+	recursive, err := rehydrate.ParseWithOptions(payload, box, keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(iterative, recursive) {
+		t.Error("the engines hydrate the payload differently")
+	}
+}
+
+func TestIterativeReviverCycle(t *testing.T) {
+	box := rehydrate.Revivers{
+		"Box": func(v interface{}) (interface{}, error) { return v, nil },
+	}
+	_, err := rehydrate.Parse(`[["Box",1],[0]]`, box)
+	if !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("got %v, want ErrBadReference", err)
+	}
+}
+
+// recursiveEngineOptions select the recursive engine.
+var recursiveEngineOptions = map[string]rehydrate.Option{
+	"V1":       rehydrate.WithCompatibility(rehydrate.V1),
+	"sampling": rehydrate.WithArraySampling(1),
+	"coercion": rehydrate.WithCoercion(func(string, interface{}) (interface{}, bool) { return nil, false }),
+}
+
+func TestRecursiveReviverCycle(t *testing.T) {
+	for name, opt := range recursiveEngineOptions {
+		for _, payload := range []string{`[["Reactive",0]]`, `[["Reactive",1],[0]]`} {
+			_, err := rehydrate.ParseWithOptions(payload, rehydrate.WithRevivers(rehydrate.DefaultNuxtRevivers()), opt)
+			if !errors.Is(err, rehydrate.ErrBadReference) {
+				t.Errorf("%s: %s: got %v, want ErrBadReference", name, payload, err)
+			}
+		}
+	}
+}
+
+func TestRecursiveDeepPayload(t *testing.T) {
+	deep := nestedPayload(1_000_000)
+	for name, opt := range recursiveEngineOptions {
+		if _, err := rehydrate.ParseWithOptions(deep, opt); !errors.Is(err, rehydrate.ErrLimitExceeded) {
+			t.Errorf("%s: got %v, want ErrLimitExceeded", name, err)
+		}
+		// V1 hydrates Sets to Go maps, which cannot hold nested containers,
+		// so only arrays are nested below the limit.
+		if _, err := rehydrate.ParseWithOptions(nestedArrays(1000), opt); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestRecursiveDepthAboveLimit(t *testing.T) {
+	// A larger WithMaxDepth does not raise the limit of the recursive engine,
+	// which would otherwise overflow the goroutine stack.
+	opts := []rehydrate.Option{rehydrate.WithMaxDepth(1 << 30), rehydrate.WithArraySampling(10)}
+	if _, err := rehydrate.ParseWithOptions(nestedPayload(1_000_000), opts...); !errors.Is(err, rehydrate.ErrLimitExceeded) {
+		t.Errorf("got %v, want ErrLimitExceeded", err)
+	}
+	if _, err := rehydrate.ParseWithOptions(nestedPayload(9_000), opts...); err != nil {
+		t.Error(err)
+	}
+}
+
+// nestedArrays returns a payload of arrays nested depth levels deep.
+func nestedArrays(depth int) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 0; i < depth; i++ {
+		b.WriteString("[" + strconv.Itoa(i+1) + "],")
+	}
+	b.WriteString(`"leaf"]`)
+	return b.String()
+}
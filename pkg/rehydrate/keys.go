@@ -0,0 +1,81 @@
+package rehydrate
+
+import (
+	"strings"
+	"unicode"
+)
+
+// KeyTransform rewrites an object key. See WithKeyTransform.
+type KeyTransform func(key string) string
+
+// WithKeyTransform renames the keys of hydrated objects, e.g. to the
+// snake_case names Go consumers expect. Transforms from repeated calls are
+// applied in order. The reserved key policy applies to the renamed keys. When
+// several keys of an object are renamed to the same key, the value of the
+// first of them in byte order is kept. Map keys are not affected.
+func WithKeyTransform(fns ...KeyTransform) Option {
+	return func(o *options) {
+		o.keyTransforms = append(o.keyTransforms, fns...)
+	}
+}
+
+// SnakeCase converts camelCase, PascalCase and kebab-case keys to snake_case:
+// userID becomes user_id and HTTPServer becomes http_server.
+func SnakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if r == '-' {
+			b.WriteByte('_')
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// CamelCase converts snake_case and kebab-case keys to camelCase. Leading
+// separators are kept, so _id stays _id.
+func CamelCase(key string) string {
+	trimmed := strings.TrimLeft(key, "_-")
+	var b strings.Builder
+	b.WriteString(key[:len(key)-len(trimmed)])
+	upper := false
+	for _, r := range trimmed {
+		if r == '_' || r == '-' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// StripPrefix returns a transform removing prefix from keys that start with
+// it and are longer than it.
+func StripPrefix(prefix string) KeyTransform {
+	return func(key string) string {
+		if len(key) > len(prefix) && strings.HasPrefix(key, prefix) {
+			return key[len(prefix):]
+		}
+		return key
+	}
+}
+
+func (o *options) transformKey(key string) string {
+	for _, fn := range o.keyTransforms {
+		key = fn(key)
+	}
+	return key
+}
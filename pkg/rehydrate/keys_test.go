@@ -0,0 +1,52 @@
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestKeyCase(t *testing.T) {
+	snake := map[string]string{
+		"userId":       "user_id",
+		"userID":       "user_id",
+		"HTTPServer":   "http_server",
+		"address2Line": "address2_line",
+		"kebab-case":   "kebab_case",
+		"already_done": "already_done",
+	}
+	for in, want := range snake {
+		if got := rehydrate.SnakeCase(in); got != want {
+			t.Errorf("SnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+	camel := map[string]string{
+		"user_id":    "userId",
+		"kebab-case": "kebabCase",
+		"_id":        "_id",
+		"plain":      "plain",
+	}
+	for in, want := range camel {
+		if got := rehydrate.CamelCase(in); got != want {
+			t.Errorf("CamelCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWithKeyTransform(t *testing.T) {
+	input := `[{"__typename":1,"userName":2,"user_name":3,"nested":4},"User","first","second",{"createdAt":1}]`
+	v, err := rehydrate.ParseWithOptions(input,
+		rehydrate.WithKeyTransform(rehydrate.StripPrefix("__"), rehydrate.SnakeCase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"typename":  "User",
+		"user_name": "first",
+		"nested":    map[string]interface{}{"created_at": "User"},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %v, want %v", v, want)
+	}
+}
@@ -0,0 +1,84 @@
+package rehydrate
+
+import "time"
+
+// Limits groups the size, depth and type limits applied during hydration, so
+// a platform can define its policy once, as one of the presets or its own
+// value, and apply it with WithLimits. Zero values disable the corresponding
+// limit.
+type Limits struct {
+	// TimeBudget is the limit of WithTimeBudget.
+	TimeBudget time.Duration
+	// MaxDepth is the limit of WithMaxDepth.
+	MaxDepth int
+	// MaxStringLength is the limit of WithMaxStringLength.
+	MaxStringLength int
+	// MaxBinaryInline is the limit of WithMaxBinaryInline.
+	MaxBinaryInline int
+	// DeniedTags are the tags rejected as with WithDeniedTags.
+	DeniedTags []string
+}
+
+// Limit presets. Programs may adjust them at start-up to change the policy
+// of every caller selecting them.
+var (
+	// LimitsWeb suits payloads received from browsers and other untrusted
+	// clients: small strings and binary data, shallow nesting, a short time
+	// budget and no RegExps.
+	LimitsWeb = Limits{
+		TimeBudget:      time.Second,
+		MaxDepth:        256,
+		MaxStringLength: 64 << 10,
+		MaxBinaryInline: 64 << 10,
+		DeniedTags:      []string{TagRegExp.String()},
+	}
+	// LimitsInternal suits payloads exchanged between trusted services,
+	// bounding only pathological sizes.
+	LimitsInternal = Limits{
+		TimeBudget:      10 * time.Second,
+		MaxDepth:        hardenedMaxDepth,
+		MaxStringLength: 16 << 20,
+		MaxBinaryInline: 16 << 20,
+	}
+	// LimitsForensic keeps payloads intact for investigation, only bounding
+	// the time spent on them.
+	LimitsForensic = Limits{
+		TimeBudget: time.Minute,
+	}
+)
+
+// WithLimits applies every limit of l, replacing those set by earlier
+// options, including the denied tags. Options given after it override
+// single limits, e.g.
+//
+//	rehydrate.ParseWithOptions(payload,
+//		rehydrate.WithLimits(rehydrate.LimitsWeb),
+//		rehydrate.WithMaxStringLength(1<<20))
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.timeBudget = l.TimeBudget
+		o.maxDepth = l.MaxDepth
+		o.maxString = l.MaxStringLength
+		o.maxBinary = l.MaxBinaryInline
+		o.deniedTags = nil
+		WithDeniedTags(l.DeniedTags...)(o)
+	}
+}
+
+// WithMaxDepth fails hydration with an error wrapping ErrLimitExceeded once
+// values are nested more than n levels below the root, as a safety valve
+// against payloads built to exhaust memory. Zero means no limit: Parse and
+// ParseWithOptions then hydrate payloads nested as deep as memory allows.
+// Some paths are limited to 10000 levels regardless, and fail beyond them
+// with ErrLimitExceeded too:
+//
+//   - hydrating with options that track paths (WithPathPolicy, WithAudit,
+//     WithCoercion, WithProvenance and WithSecretScanner), WithArraySampling or
+//     V1 compatibility, which use a recursive engine;
+//   - hydrating the values below an entry handled by a TypeHandler;
+//   - rendering JSON, as with RehydrateWith, which encoding/json limits.
+func WithMaxDepth(n int) Option {
+	return func(o *options) {
+		o.maxDepth = n
+	}
+}
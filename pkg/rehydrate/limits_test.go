@@ -0,0 +1,44 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestWithLimits(t *testing.T) {
+	long := strings.Repeat("x", 100<<10)
+	payload := `[{"text":1,"pattern":2},"` + long + `",["RegExp","a+",""]]`
+
+	if _, err := rehydrate.ParseWithOptions(payload, rehydrate.WithLimits(rehydrate.LimitsWeb)); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("web: got %v, want a denied RegExp", err)
+	}
+
+	// A later group replaces the earlier one entirely.
+	v, err := rehydrate.ParseWithOptions(payload,
+		rehydrate.WithLimits(rehydrate.LimitsWeb), rehydrate.WithLimits(rehydrate.LimitsForensic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := v.(map[string]interface{})["text"]; text != long {
+		t.Errorf("forensic: got a %T", text)
+	}
+
+	// Single options override the group.
+	web := rehydrate.LimitsWeb
+	web.DeniedTags = nil
+	v, err = rehydrate.ParseWithOptions(payload, rehydrate.WithLimits(web), rehydrate.WithMaxStringLength(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr, ok := v.(map[string]interface{})["text"].(*rehydrate.Truncated); !ok || tr.Length != len(long) || tr.Value != long[:10] {
+		t.Errorf("override: got %#v", v.(map[string]interface{})["text"])
+	}
+
+	deep := `[[1],[2],[3],[4],[5],"x"]`
+	if _, err := rehydrate.ParseWithOptions(deep, rehydrate.WithLimits(rehydrate.Limits{MaxDepth: 3})); !errors.Is(err, rehydrate.ErrLimitExceeded) {
+		t.Errorf("depth: got %v, want ErrLimitExceeded", err)
+	}
+}
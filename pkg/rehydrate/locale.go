@@ -0,0 +1,120 @@
+package rehydrate
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale formats the numbers, byte sizes and timestamps of reports such as
+// Dump for readers of a language and region. The zero value formats them as
+// Dump does by default: in JavaScript number syntax, with "." as decimal
+// separator and with RFC 3339 timestamps.
+type Locale struct {
+	// Tag is the BCP 47 tag the locale was parsed from.
+	Tag string
+
+	decimal    string
+	group      string
+	dateLayout string
+}
+
+// locales holds the separators and date layouts of the supported languages,
+// keyed by BCP 47 tag. Layouts are numeric, as the time package only knows
+// English month names.
+var locales = map[string]Locale{
+	"en":    {decimal: ".", group: ",", dateLayout: "1/2/2006, 3:04:05 PM MST"},
+	"en-GB": {decimal: ".", group: ",", dateLayout: "02/01/2006, 15:04:05 MST"},
+	"de":    {decimal: ",", group: ".", dateLayout: "02.01.2006, 15:04:05 MST"},
+	"es":    {decimal: ",", group: ".", dateLayout: "2/1/2006, 15:04:05 MST"},
+	"fr":    {decimal: ",", group: "\u202f", dateLayout: "02/01/2006 15:04:05 MST"},
+	"it":    {decimal: ",", group: ".", dateLayout: "2/1/2006, 15:04:05 MST"},
+	"ja":    {decimal: ".", group: ",", dateLayout: "2006/01/02 15:04:05 MST"},
+	"nl":    {decimal: ",", group: ".", dateLayout: "2-1-2006, 15:04:05 MST"},
+	"pl":    {decimal: ",", group: "\u00a0", dateLayout: "2.01.2006, 15:04:05 MST"},
+	"pt":    {decimal: ",", group: ".", dateLayout: "02/01/2006, 15:04:05 MST"},
+	"zh":    {decimal: ".", group: ",", dateLayout: "2006/1/2 15:04:05 MST"},
+}
+
+// ParseLocale returns the locale for a BCP 47 tag such as "de" or "en-GB". A
+// tag whose region is not known falls back to its language; unknown
+// languages are an error.
+func ParseLocale(tag string) (Locale, error) {
+	parts := strings.Split(strings.ReplaceAll(tag, "_", "-"), "-")
+	lang := strings.ToLower(parts[0])
+	if len(parts) > 1 {
+		if l, ok := locales[lang+"-"+strings.ToUpper(parts[len(parts)-1])]; ok {
+			l.Tag = tag
+			return l, nil
+		}
+	}
+	l, ok := locales[lang]
+	if !ok {
+		return Locale{}, fmt.Errorf("unsupported locale %q", tag)
+	}
+	l.Tag = tag
+	return l, nil
+}
+
+// FormatNumber formats f with the locale's decimal separator and digit
+// grouping. NaN, infinities and numbers JavaScript writes in exponent
+// notation are written as JavaScript does.
+func (l Locale) FormatNumber(f float64) string {
+	abs := math.Abs(f)
+	if l.decimal == "" || math.IsNaN(f) || math.IsInf(f, 0) || f == 0 || abs >= 1e21 || abs < 1e-6 {
+		return formatJSNumber(f)
+	}
+	s := strconv.FormatFloat(abs, 'f', -1, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if f < 0 {
+		b.WriteByte('-')
+	}
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteRune(c)
+	}
+	if frac != "" {
+		b.WriteString(l.decimal + frac)
+	}
+	return b.String()
+}
+
+// FormatSize formats a number of bytes in B, KB or MB.
+func (l Locale) FormatSize(n float64) string {
+	var s string
+	switch {
+	case n >= 1<<20:
+		s = fmt.Sprintf("%.1fMB", n/(1<<20))
+	case n >= 1<<10:
+		s = fmt.Sprintf("%.1fKB", n/(1<<10))
+	default:
+		return fmt.Sprintf("%.0fB", n)
+	}
+	if l.decimal != "" {
+		s = strings.Replace(s, ".", l.decimal, 1)
+	}
+	return s
+}
+
+// FormatPercent formats the ratio r as a percentage with one decimal, such
+// as 12.5%.
+func (l Locale) FormatPercent(r float64) string {
+	s := strconv.FormatFloat(r*100, 'f', 1, 64) + "%"
+	if l.decimal != "" {
+		s = strings.Replace(s, ".", l.decimal, 1)
+	}
+	return s
+}
+
+// FormatTime formats t in the locale's date and time layout.
+func (l Locale) FormatTime(t time.Time) string {
+	if l.dateLayout == "" {
+		return t.Format(time.RFC3339Nano)
+	}
+	return t.Format(l.dateLayout)
+}
@@ -0,0 +1,72 @@
+package rehydrate_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParseLocale(t *testing.T) {
+	for tag, want := range map[string]string{
+		"de":    "1.234.567,5",
+		"de-AT": "1.234.567,5",
+		"en_US": "1,234,567.5",
+		"fr-FR": "1\u202f234\u202f567,5",
+	} {
+		l, err := rehydrate.ParseLocale(tag)
+		if err != nil {
+			t.Fatalf("%s: %v", tag, err)
+		}
+		if got := l.FormatNumber(1234567.5); got != want || l.Tag != tag {
+			t.Errorf("%s: got %q (tag %q), want %q", tag, got, l.Tag, want)
+		}
+	}
+	if _, err := rehydrate.ParseLocale("xx"); err == nil {
+		t.Error("expected an error for an unknown language")
+	}
+}
+
+func TestLocaleFormat(t *testing.T) {
+	de, _ := rehydrate.ParseLocale("de")
+	for _, tt := range []struct {
+		f         float64
+		de, plain string
+	}{
+		{-1234.25, "-1.234,25", "-1234.25"},
+		{999, "999", "999"},
+		{0.5, "0,5", "0.5"},
+		{1e21, "1e+21", "1e+21"},
+		{math.Inf(-1), "-Infinity", "-Infinity"},
+	} {
+		if got := de.FormatNumber(tt.f); got != tt.de {
+			t.Errorf("de %v: got %q, want %q", tt.f, got, tt.de)
+		}
+		if got := (rehydrate.Locale{}).FormatNumber(tt.f); got != tt.plain {
+			t.Errorf("default %v: got %q, want %q", tt.f, got, tt.plain)
+		}
+	}
+
+	if got := de.FormatSize(1536); got != "1,5KB" {
+		t.Errorf("size: got %q", got)
+	}
+	if got := de.FormatPercent(0.125); got != "12,5%" {
+		t.Errorf("percent: got %q", got)
+	}
+	date := time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC)
+	if got := de.FormatTime(date); got != "09.03.2024, 14:05:00 UTC" {
+		t.Errorf("time: got %q", got)
+	}
+
+	var buf bytes.Buffer
+	v := map[string]interface{}{"n": 1234.5, "at": date}
+	if err := rehydrate.Dump(v, &buf, rehydrate.DumpLocale(de)); err != nil {
+		t.Fatal(err)
+	}
+	want := "Object(2) {\n  at: Date(09.03.2024, 14:05:00 UTC)\n  n: 1.234,5\n}\n"
+	if buf.String() != want {
+		t.Errorf("dump: got %q, want %q", buf.String(), want)
+	}
+}
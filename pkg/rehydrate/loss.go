@@ -0,0 +1,272 @@
+package rehydrate
+
+// LossKind describes how information is lost when a value is rendered as
+// plain JSON.
+type LossKind int
+
+const (
+	// LossUndefined: undefined became null.
+	LossUndefined LossKind = iota
+	// LossHole: an array hole became null.
+	LossHole
+	// LossNegativeZero: -0 became 0.
+	LossNegativeZero
+	// LossSet: a Set became an array.
+	LossSet
+	// LossMap: a Map became an object.
+	LossMap
+	// LossMapKey: a Map key that is not a string was stringified.
+	LossMapKey
+	// LossDate: a Date became an ISO 8601 string.
+	LossDate
+	// LossBigInt: a BigInt became a JSON number, which most parsers read
+	// as a float.
+	LossBigInt
+	// LossRegExp: a RegExp became its source string, dropping its flags.
+	LossRegExp
+	// LossBinary: a typed array or ArrayBuffer became a base64 string or an
+	// array of numbers, dropping its element type.
+	LossBinary
+	// LossBoxed: a boxed primitive became the primitive.
+	LossBoxed
+	// LossShared: a value referenced from several places is repeated, so
+	// the references are no longer identical.
+	LossShared
+)
+
+var lossKindNames = [...]string{
+	LossUndefined:    "undefined",
+	LossHole:         "hole",
+	LossNegativeZero: "negative-zero",
+	LossSet:          "set",
+	LossMap:          "map",
+	LossMapKey:       "map-key",
+	LossDate:         "date",
+	LossBigInt:       "bigint",
+	LossRegExp:       "regexp",
+	LossBinary:       "binary",
+	LossBoxed:        "boxed",
+	LossShared:       "shared",
+}
+
+func (k LossKind) String() string {
+	if k < 0 || int(k) >= len(lossKindNames) {
+		return ""
+	}
+	return lossKindNames[k]
+}
+
+// MarshalText renders the kind by name.
+func (k LossKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Loss is a single place where rendering as plain JSON dropped information.
+type Loss struct {
+	// Path is the path of the value, empty for the root.
+	Path string   `json:"path"`
+	Kind LossKind `json:"kind"`
+	// Detail adds context, such as the type of a stringified Map key or the
+	// first path of a shared value.
+	Detail string `json:"detail,omitempty"`
+}
+
+// LossReport lists the information dropped by Rehydrate and RehydrateWith.
+// See WithLossReport.
+type LossReport struct {
+	Losses []Loss `json:"losses"`
+}
+
+// Lossless reports whether nothing was dropped.
+func (r *LossReport) Lossless() bool {
+	return len(r.Losses) == 0
+}
+
+// WithLossReport makes RehydrateWith fill in report with every path where
+// converting the payload to JSON drops type information, so callers can
+// decide whether plain JSON is acceptable for it. Losses the output avoids,
+// such as those of Sets and Dates with WithAnnotatedOutput, are not
+// reported, and paths use the keys of the output, after key transforms.
+// Values produced by revivers and type handlers are opaque and are not
+// inspected. It has no effect on Parse.
+func WithLossReport(report *LossReport) Option {
+	return func(o *options) {
+		o.lossReport = report
+	}
+}
+
+// reportLosses walks the value table of a payload that was hydrated
+// successfully and records the losses of its JSON rendering with o.
+func reportLosses(serialized string, report *LossReport, o *options) error {
+	report.Losses = nil
+	values, err := unmarshalTable(serialized)
+	if err != nil || values == nil {
+		return err
+	}
+	add := func(path string, kind LossKind, detail string) {
+		if !o.preserves(kind) {
+			report.add(path, kind, detail)
+		}
+	}
+
+	first := make(map[int]string, len(values))
+	var visit func(index int, path string)
+	visit = func(index int, path string) {
+		if seen, ok := first[index]; ok {
+			switch values[index].(type) {
+			case []interface{}, map[string]interface{}:
+				add(path, LossShared, displayPath(seen))
+			}
+			return
+		}
+		first[index] = path
+
+		if arr, ok := values[index].([]interface{}); ok && len(arr) > 0 {
+			if typeStr, ok := arr[0].(string); ok {
+				tag, _ := ParseTag(typeStr)
+				_, revived := o.reviver(typeStr)
+				if _, handled := o.typeHandler(typeStr); revived || handled {
+					tag = TagInvalid
+				}
+				switch {
+				case tag == TagSet:
+					add(path, LossSet, "")
+				case tag == TagMap:
+					add(path, LossMap, "")
+					for i := 1; i+1 < len(arr); i += 2 {
+						k, err := toInt(arr[i])
+						if err != nil || k < 0 || k >= len(values) {
+							continue
+						}
+						if _, ok := values[k].(string); !ok {
+							add(mapKeyPath(path, values[k]), LossMapKey, jsTypeName(values[k]))
+						}
+					}
+				case tag == TagDate:
+					add(path, LossDate, "")
+				case tag == TagBigInt:
+					add(path, LossBigInt, "")
+				case tag == TagRegExp && len(arr) > 2:
+					if flags, _ := arr[2].(string); flags != "" {
+						add(path, LossRegExp, "flags "+flags)
+					}
+				case tag == TagObject:
+					add(path, LossBoxed, "")
+				case tag.IsBinary() && !o.preservesBinary(tag):
+					report.add(path, LossBinary, typeStr)
+				}
+			}
+		}
+
+		for _, child := range lossChildren(values, index, path, o) {
+			switch child.index {
+			case UNDEFINED:
+				add(child.path, LossUndefined, "")
+			case HOLE:
+				add(child.path, LossHole, "")
+			case NEGATIVE_ZERO:
+				add(child.path, LossNegativeZero, "")
+			default:
+				if child.index >= 0 {
+					visit(child.index, child.path)
+				}
+			}
+		}
+	}
+	visit(0, "")
+	return nil
+}
+
+// preserves reports whether the output RehydrateWith renders with o keeps
+// the information whose loss is of the given kind. Only annotated output
+// keeps any, and only for values hydrated to the types it annotates.
+func (o *options) preserves(kind LossKind) bool {
+	if !o.annotated {
+		return false
+	}
+	switch kind {
+	case LossSet:
+		return !o.setAsSlice && o.compat != V1
+	case LossMap, LossMapKey:
+		return o.compat != V1
+	case LossDate, LossBigInt, LossRegExp, LossNegativeZero, LossShared:
+		return true
+	case LossBoxed:
+		return o.wrapPrimitives
+	}
+	return false
+}
+
+// preservesBinary reports whether the output keeps the type of binary data
+// tagged tag: annotated output does for the typed slices and ArrayBuffers
+// typed arrays hydrate to, while Uint8ClampedArrays and raw typed arrays
+// become Uint8Arrays.
+func (o *options) preservesBinary(tag Tag) bool {
+	if !o.annotated || tag == TagUint8ClampedArray {
+		return false
+	}
+	return tag == TagUint8Array || !o.keepRawTypedArrays()
+}
+
+// lossChildren returns the children of the value-table entry at index like
+// tableChildren, with the object keys of the output: transformed, and
+// without the keys the reserved key policy drops.
+func lossChildren(values []interface{}, index int, path string, o *options) []tableChild {
+	var keys []interface{}
+	switch v := values[index].(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			keys = append(keys, key, v[key])
+		}
+	case []interface{}:
+		if len(v) == 0 || v[0] != TagNull.String() {
+			return tableChildren(values, index, path)
+		}
+		if _, revived := o.reviver(TagNull.String()); revived {
+			return tableChildren(values, index, path)
+		}
+		keys = v[1:]
+	default:
+		return tableChildren(values, index, path)
+	}
+	var out []tableChild
+	for i := 0; i+1 < len(keys); i += 2 {
+		raw, ok := keys[i].(string)
+		if !ok {
+			continue
+		}
+		key, keep := o.objectKey(raw)
+		ref, err := toInt(keys[i+1])
+		if !keep || err != nil || ref < NEGATIVE_ZERO || ref >= len(values) {
+			continue
+		}
+		out = append(out, tableChild{ref, keyPath(path, key)})
+	}
+	return out
+}
+
+func (r *LossReport) add(path string, kind LossKind, detail string) {
+	r.Losses = append(r.Losses, Loss{Path: path, Kind: kind, Detail: detail})
+}
+
+// jsTypeName names the JavaScript type of a raw value-table entry.
+func jsTypeName(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		if len(v) > 0 {
+			if typeStr, ok := v[0].(string); ok {
+				return typeStr
+			}
+		}
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,87 @@
+package rehydrate_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestLossReport(t *testing.T) {
+	input := `[{"tags":1,"prices":3,"when":6,"missing":-1,"list":7,"again":7,"re":8,"zero":-6,"n":9},` +
+		`["Set",2],"a",["Map",4,2,5,2],"EUR",1,["Date","2024-01-01T00:00:00Z"],[2,-2],["RegExp","x","gi"],["BigInt","1"]]`
+
+	var report rehydrate.LossReport
+	if _, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithLossReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	want := []rehydrate.Loss{
+		{Path: "/again/1", Kind: rehydrate.LossHole},
+		{Path: "/list", Kind: rehydrate.LossShared, Detail: "/again"},
+		{Path: "/missing", Kind: rehydrate.LossUndefined},
+		{Path: "/n", Kind: rehydrate.LossBigInt},
+		{Path: "/prices", Kind: rehydrate.LossMap},
+		{Path: "/prices/1", Kind: rehydrate.LossMapKey, Detail: "number"},
+		{Path: "/re", Kind: rehydrate.LossRegExp, Detail: "flags gi"},
+		{Path: "/tags", Kind: rehydrate.LossSet},
+		{Path: "/when", Kind: rehydrate.LossDate},
+		{Path: "/zero", Kind: rehydrate.LossNegativeZero},
+	}
+	if !reflect.DeepEqual(report.Losses, want) {
+		t.Errorf("got %+v\nwant %+v", report.Losses, want)
+	}
+
+	data, err := json.Marshal(report.Losses[1])
+	if err != nil || string(data) != `{"path":"/list","kind":"shared","detail":"/again"}` {
+		t.Errorf("unexpected JSON %s (%v)", data, err)
+	}
+
+	if _, err := rehydrate.RehydrateWith(`[{"a":1},"x"]`, nil, rehydrate.WithLossReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Lossless() {
+		t.Errorf("expected no losses, got %+v", report.Losses)
+	}
+}
+
+func TestLossReportOptions(t *testing.T) {
+	input := `[{"tags":1,"prices":3,"when":6,"list":7,"again":7,"re":8,"zero":-6,"n":9,"samples":10},` +
+		`["Set",2],"a",["Map",4,2,5,2],"EUR",1,["Date","2024-01-01T00:00:00Z"],[2],["RegExp","x","gi"],["BigInt","1"],` +
+		`["Int16Array","AQACAA=="]]`
+
+	var report rehydrate.LossReport
+	if _, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithAnnotatedOutput(), rehydrate.WithLossReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Lossless() {
+		t.Errorf("annotated output: expected no losses, got %+v", report.Losses)
+	}
+
+	// Sets hydrated to slices are arrays in annotated output too, and paths
+	// use the transformed keys.
+	if _, err := rehydrate.RehydrateWith(`[{"myTags":1,"boxed":3},["Set",2],"a",["Object",2]]`, nil,
+		rehydrate.WithAnnotatedOutput(), rehydrate.WithSetAsSlice(),
+		rehydrate.WithKeyTransform(rehydrate.SnakeCase), rehydrate.WithLossReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	want := []rehydrate.Loss{
+		{Path: "/boxed", Kind: rehydrate.LossBoxed},
+		{Path: "/my_tags", Kind: rehydrate.LossSet},
+	}
+	if !reflect.DeepEqual(report.Losses, want) {
+		t.Errorf("got %+v\nwant %+v", report.Losses, want)
+	}
+
+	// Entries hydrated by a type handler are opaque.
+	handler := rehydrate.TypeHandlerFunc(func(e *rehydrate.TypedEntry) (interface{}, error) {
+		return "date", nil
+	})
+	if _, err := rehydrate.RehydrateWith(`[["Date","2024-01-01T00:00:00Z"]]`, nil,
+		rehydrate.WithTypeHandler("Date", handler), rehydrate.WithLossReport(&report)); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Lossless() {
+		t.Errorf("type handler: expected no losses, got %+v", report.Losses)
+	}
+}
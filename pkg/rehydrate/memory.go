@@ -0,0 +1,38 @@
+package rehydrate
+
+// Weights of the memory estimate: bytes per byte of input, covering the
+// input itself, the decoded value table and the hydrated strings, and bytes
+// per value, covering the interfaces, maps and slices holding it.
+const (
+	memoryPerByte  = 3
+	memoryPerValue = 128
+)
+
+// EstimateMemory approximates the memory taken while hydrating serialized,
+// from its size and the number of values it holds, in a single pass that
+// does not decode it. It is meant for admission control, such as reserving
+// room in a pipeline.MemoryBudget before parsing, and errs on the high side
+// for typical payloads. Values shared by several parents are hydrated once,
+// so the estimate holds for payloads designed to amplify as well.
+func EstimateMemory(serialized string) int64 {
+	values := 1
+	inString, escaped := false, false
+	for i := 0; i < len(serialized); i++ {
+		c := serialized[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',' || c == ':':
+			values++
+		}
+	}
+	return int64(len(serialized))*memoryPerByte + int64(values)*memoryPerValue
+}
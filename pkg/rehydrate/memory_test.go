@@ -0,0 +1,42 @@
+package rehydrate_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestEstimateMemory(t *testing.T) {
+	// Separators inside strings are not values.
+	if a, b := rehydrate.EstimateMemory(`["a,b:c"]`), rehydrate.EstimateMemory(`["abcde"]`); a != b {
+		t.Errorf("strings: %d != %d", a, b)
+	}
+	if a, b := rehydrate.EstimateMemory(`[[1,2],1,2]`), rehydrate.EstimateMemory(`[[1222],12]`); a <= b {
+		t.Errorf("more values should cost more: %d <= %d", a, b)
+	}
+
+	// The estimate bounds the memory actually allocated.
+	var sb strings.Builder
+	sb.WriteString(`[[`)
+	for i := 1; i <= 20000; i++ {
+		if i > 1 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`1`)
+	}
+	sb.WriteString(`],{"key":2},"value"]`)
+	payload := sb.String()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	if _, err := rehydrate.Parse(payload, nil); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := int64(after.TotalAlloc - before.TotalAlloc); allocated > rehydrate.EstimateMemory(payload) {
+		t.Errorf("allocated %d bytes, estimated %d", allocated, rehydrate.EstimateMemory(payload))
+	}
+}
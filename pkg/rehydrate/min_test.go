@@ -0,0 +1,53 @@
+//go:build rehydrate_min
+
+package rehydrate_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestMinimalBuild(t *testing.T) {
+	payload := `[{"n":1,"re":2},["BigInt","-0042"],["RegExp","a+","gi"]]`
+	v, err := rehydrate.ParseWithOptions(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	if n := root["n"]; !reflect.DeepEqual(n, &rehydrate.Tagged{Tag: "BigInt", Args: []interface{}{"-42"}}) {
+		t.Errorf("BigInt: got %#v", n)
+	}
+	if re := root["re"]; !reflect.DeepEqual(re, &rehydrate.Tagged{Tag: "RegExp", Args: []interface{}{"a+", "gi"}}) {
+		t.Errorf("RegExp: got %#v", re)
+	}
+
+	out, err := json.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"n":-42,"re":"a+"}` {
+		t.Errorf("got %s", out)
+	}
+	if s, _ := rehydrate.MapKeyString(root["n"]); s != "-42" {
+		t.Errorf("got Map key %q", s)
+	}
+
+	if s, err := rehydrate.Stringify(root, nil); err != nil || s != `[{"n":1,"re":2},["BigInt","-42"],["RegExp","a+","gi"]]` {
+		t.Errorf("Stringify = %s, %v", s, err)
+	}
+	if s, err := rehydrate.GoLiteral(root["n"]); err != nil || s != `&rehydrate.Tagged{Tag: "BigInt", Args: []interface{}{
+	"-42",
+}}` {
+		t.Errorf("GoLiteral = %s, %v", s, err)
+	}
+
+	if _, err := rehydrate.ParseWithOptions(`[["BigInt","4x2"]]`); err == nil {
+		t.Error("expected an error for invalid BigInt digits")
+	}
+	if _, err := rehydrate.Search(payload, "a+", rehydrate.SearchRegexp()); err == nil {
+		t.Error("expected regexp search to fail")
+	}
+}
@@ -0,0 +1,38 @@
+package rehydrate
+
+import "sort"
+
+// MissingTags returns the sorted custom type tags used by serialized that
+// have no reviver in the configuration given by opts, such as WithRevivers or
+// WithRegistry, so a service can report every reviver it needs at once
+// instead of discovering them one failed Parse at a time. Built-in tags are
+// never reported. It returns nil if the payload cannot be decoded.
+func MissingTags(serialized string, opts ...Option) []string {
+	values, err := unmarshalTable(serialized)
+	if err != nil {
+		return nil
+	}
+	o := newOptions(opts)
+
+	seen := make(map[string]bool)
+	var missing []string
+	for _, value := range values {
+		arr, ok := value.([]interface{})
+		if !ok || len(arr) == 0 {
+			continue
+		}
+		typeStr, ok := arr[0].(string)
+		if !ok || seen[typeStr] {
+			continue
+		}
+		seen[typeStr] = true
+		if _, builtin := ParseTag(typeStr); builtin {
+			continue
+		}
+		if _, ok := o.reviver(typeStr); !ok {
+			missing = append(missing, typeStr)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,27 @@
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestMissingTags(t *testing.T) {
+	input := `[{"a":1,"b":3,"c":5,"d":7},["Widget",2],"x",["Reactive",4],"y",["Gadget",6],"z",["Set",2],["Widget",2]]`
+
+	if got, want := rehydrate.MissingTags(input), []string{"Gadget", "Reactive", "Widget"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	registry := rehydrate.NewRegistry(nil)
+	registry.Register("Gadget", func(v interface{}) (interface{}, error) { return v, nil })
+	got := rehydrate.MissingTags(input, rehydrate.WithRevivers(rehydrate.DefaultNuxtRevivers()), rehydrate.WithRegistry(registry))
+	if want := []string{"Widget"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := rehydrate.MissingTags(`not json`); got != nil {
+		t.Errorf("got %v for invalid input", got)
+	}
+}
@@ -0,0 +1,79 @@
+package rehydrate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// LineError reports a failure on one line of an NDJSON stream.
+type LineError struct {
+	// Line is the 1-based line number.
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Line is the result of hydrating one line of an NDJSON stream. Err is a
+// *LineError if the line failed, in which case Value is nil.
+type Line struct {
+	// Number is the 1-based line number.
+	Number int
+	Value  interface{}
+	Err    error
+}
+
+// WithReloadedOptions makes ParseNDJSON call fn before every line and apply
+// the options it returns after the others, so long-running streams pick up
+// configuration changes, such as a reloaded config file, without restarting.
+// Other functions ignore it.
+func WithReloadedOptions(fn func() []Option) Option {
+	return func(o *options) {
+		o.reload = fn
+	}
+}
+
+// ParseNDJSON reads one serialized payload per line from r and yields the
+// hydrated value of each. Blank lines are skipped. A line that fails to
+// hydrate yields an error for that line only and reading continues; an error
+// reading r is yielded as a final Line. Lines may be of any length.
+func ParseNDJSON(r io.Reader, opts ...Option) iter.Seq[Line] {
+	return func(yield func(Line) bool) {
+		o := newOptions(opts)
+		reload := o.reload
+		br := bufio.NewReader(r)
+		for n := 1; ; n++ {
+			data, err := br.ReadBytes('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				yield(Line{Number: n, Err: &LineError{Line: n, Err: err}})
+				return
+			}
+			if line := bytes.TrimSpace(data); len(line) > 0 {
+				if reload != nil {
+					o = newOptions(append(opts[:len(opts):len(opts)], reload()...))
+				}
+				v, perr := parse(string(line), o)
+				result := Line{Number: n, Value: v}
+				if perr != nil {
+					result = Line{Number: n, Err: &LineError{Line: n, Err: perr}}
+				}
+				if !yield(result) {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParseNDJSON(t *testing.T) {
+	input := "[{\"a\":1},\"x\"]\n\n[[\"Widget\",0]]\r\n[\"last\"]"
+	var lines []rehydrate.Line
+	for line := range rehydrate.ParseNDJSON(strings.NewReader(input)) {
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %+v", lines)
+	}
+	if lines[0].Number != 1 || lines[0].Value.(map[string]interface{})["a"] != "x" {
+		t.Errorf("unexpected first line %+v", lines[0])
+	}
+	var lineErr *rehydrate.LineError
+	if !errors.As(lines[1].Err, &lineErr) || lineErr.Line != 3 || !errors.Is(lines[1].Err, rehydrate.ErrUnknownType) {
+		t.Errorf("unexpected error %v", lines[1].Err)
+	}
+	if lines[2].Number != 4 || lines[2].Value != "last" {
+		t.Errorf("unexpected last line %+v", lines[2])
+	}
+
+	// Stopping early must not read further.
+	for range rehydrate.ParseNDJSON(strings.NewReader(input)) {
+		break
+	}
+
+	var last rehydrate.Line
+	for line := range rehydrate.ParseNDJSON(iotest.TimeoutReader(strings.NewReader("[1]\n[2]\n"))) {
+		last = line
+	}
+	if last.Err == nil {
+		t.Error("expected the read error to be reported")
+	}
+}
+
+func TestParseNDJSONReloadedOptions(t *testing.T) {
+	calls := 0
+	reload := func() []rehydrate.Option {
+		calls++
+		if calls < 2 {
+			return nil
+		}
+		return []rehydrate.Option{rehydrate.WithRevivers(rehydrate.Revivers{
+			"Widget": func(interface{}) (interface{}, error) { return "widget", nil },
+		})}
+	}
+	input := "[[\"Widget\",1],2]\n[[\"Widget\",1],2]\n"
+	var lines []rehydrate.Line
+	for line := range rehydrate.ParseNDJSON(strings.NewReader(input), rehydrate.WithReloadedOptions(reload)) {
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 || calls != 2 {
+		t.Fatalf("expected 2 lines and 2 reloads, got %+v after %d", lines, calls)
+	}
+	if !errors.Is(lines[0].Err, rehydrate.ErrUnknownType) {
+		t.Errorf("expected ErrUnknownType before the reload, got %v", lines[0].Err)
+	}
+	if lines[1].Value != "widget" {
+		t.Errorf("expected the reloaded reviver to apply, got %#v (%v)", lines[1].Value, lines[1].Err)
+	}
+}
@@ -0,0 +1,25 @@
+package rehydrate
+
+// NullObject is the hydrated form of an object created without a prototype,
+// as Object.create(null) does, when WithNullObjects is in use. Stringify
+// writes it back as a ["null", ...] entry, so such objects survive a round
+// trip; JSON, annotated and dump output render it as a plain object.
+type NullObject map[string]interface{}
+
+// WithNullObjects hydrates ["null", ...] entries to NullObject instead of
+// map[string]interface{}, for consumers that serialize values back into
+// payloads and must keep null prototypes.
+func WithNullObjects() Option {
+	return func(o *options) {
+		o.nullObjects = true
+	}
+}
+
+// nullObject returns obj as the value a ["null", ...] entry hydrates to. Both
+// share the same storage, so obj may still be filled afterwards.
+func (o *options) nullObject(obj map[string]interface{}) interface{} {
+	if o.nullObjects {
+		return NullObject(obj)
+	}
+	return obj
+}
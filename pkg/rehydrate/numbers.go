@@ -0,0 +1,63 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// NumberMode controls the Go type numbers hydrate to. encoding/json decodes
+// every number to float64, which silently rounds integers beyond 2^53 such
+// as the IDs of many databases.
+type NumberMode int
+
+const (
+	// NumberFloat64 hydrates numbers to float64. It is the default.
+	NumberFloat64 NumberMode = iota
+	// NumberJSON hydrates numbers to json.Number holding their literal text,
+	// which encoding/json re-emits unchanged.
+	NumberJSON
+	// NumberInt64 hydrates integers within the range of int64 to int64 and
+	// other numbers to float64.
+	NumberInt64
+)
+
+// WithNumberMode sets the Go type numbers hydrate to. NaN, the infinities
+// and -0 have no JSON literal and always hydrate to float64, as do the
+// values of boxed Numbers. Like WithUTF16Strings, the mode needs the
+// serialized text and has no effect on ParseValues.
+func WithNumberMode(mode NumberMode) Option {
+	return func(o *options) {
+		o.numberMode = mode
+	}
+}
+
+// applyNumberMode replaces the number entries of values with their form in
+// mode, read from the literal text of serialized.
+func applyNumberMode(serialized string, values []interface{}, mode NumberMode) error {
+	if mode == NumberFloat64 {
+		return nil
+	}
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return err
+	}
+	for i, entry := range raw {
+		if _, ok := values[i].(float64); !ok {
+			continue
+		}
+		num := json.Number(bytes.TrimSpace(entry))
+		switch mode {
+		case NumberJSON:
+			values[i] = num
+		case NumberInt64:
+			if n, err := strconv.ParseInt(num.String(), 10, 64); err == nil {
+				values[i] = n
+			}
+		default:
+			return fmt.Errorf("%w: unknown number mode %d", ErrInvalidInput, mode)
+		}
+	}
+	return nil
+}
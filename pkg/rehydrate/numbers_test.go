@@ -0,0 +1,56 @@
+package rehydrate_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestWithNumberMode(t *testing.T) {
+	// 9007199254740993 is 2^53+1, which float64 rounds down.
+	payload := `[{"id":1,"ratio":2,"nan":-3,"big":3},9007199254740993,0.5,12345678901234567890]`
+	tests := []struct {
+		mode rehydrate.NumberMode
+		want map[string]interface{}
+	}{
+		{rehydrate.NumberFloat64, map[string]interface{}{"id": 9007199254740992.0, "ratio": 0.5, "big": 12345678901234567890.0}},
+		{rehydrate.NumberJSON, map[string]interface{}{"id": json.Number("9007199254740993"), "ratio": json.Number("0.5"), "big": json.Number("12345678901234567890")}},
+		{rehydrate.NumberInt64, map[string]interface{}{"id": int64(9007199254740993), "ratio": 0.5, "big": 12345678901234567890.0}},
+	}
+	for _, tt := range tests {
+		v, err := rehydrate.ParseWithOptions(payload, rehydrate.WithNumberMode(tt.mode))
+		if err != nil {
+			t.Fatalf("mode %d: %v", tt.mode, err)
+		}
+		obj := v.(map[string]interface{})
+		if _, ok := obj["nan"].(float64); !ok {
+			t.Errorf("mode %d: NaN hydrated to %T", tt.mode, obj["nan"])
+		}
+		delete(obj, "nan")
+		if !reflect.DeepEqual(obj, tt.want) {
+			t.Errorf("mode %d: got %#v", tt.mode, obj)
+		}
+	}
+
+	v, err := rehydrate.ParseWithOptions(`[[1,1],9007199254740993]`, rehydrate.WithNumberMode(rehydrate.NumberJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(v)
+	if err != nil || string(out) != `[9007199254740993,9007199254740993]` {
+		t.Errorf("got %s, %v", out, err)
+	}
+}
+
+func TestWithNumberModeIncremental(t *testing.T) {
+	r := rehydrate.NewRehydrator(rehydrate.WithNumberMode(rehydrate.NumberInt64))
+	got, err := r.Update(`[[1],42]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []interface{}{int64(42)}) {
+		t.Errorf("got %#v", got)
+	}
+}
@@ -0,0 +1,221 @@
+package rehydrate
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+)
+
+// Option configures parsing and rendering behaviour.
+type Option func(*options)
+
+type options struct {
+	prefix string
+	indent string
+
+	revivers Revivers
+	registry Revivers
+	reducers Reducers
+
+	typeHandlers  map[string]TypeHandler
+	registryTypes map[string]TypeHandler
+
+	strictMapKeys bool
+	utf16Strings  bool
+	numberMode    NumberMode
+	setAsSlice    bool
+
+	reservedKeys      ReservedKeyPolicy
+	reservedKeyPrefix string
+
+	duplicateKeys   DuplicateKeyPolicy
+	duplicateReport *[]DuplicateKey
+
+	timeBudget time.Duration
+
+	lossReport *LossReport
+	annotated  bool
+
+	stringPolicy    StringPolicy
+	sanitizedReport *[]SanitizedString
+
+	keyTransforms []KeyTransform
+
+	sampleSize int
+	sampling   SamplingStrategy
+
+	maxString int
+	maxBinary int
+
+	binaryValidators map[string][]BinaryValidator
+	binaryCodecs     map[string]BinaryCodec
+	byteOrder        binary.ByteOrder
+	rawTypedArrays   bool
+
+	pathPolicies []pathPolicy
+
+	audit      func(AuditEvent)
+	coerce     Coercion
+	provenance *Provenance
+
+	secretScanner SecretScanner
+	secretReport  *[]SecretFinding
+	rejectSecrets bool
+
+	validateRevivers bool
+	contract         ReviverContract
+	tagContracts     map[string]ReviverContract
+	opaqueTags       map[string]bool
+	resolveRevived   bool
+	clock            Clock
+	compat           Compatibility
+
+	maxDepth      int
+	strictRefs    bool
+	deniedTags    map[string]bool
+	recoverPanics bool
+
+	wrapPrimitives bool
+	nullObjects    bool
+
+	reload func() []Option
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		indent:            "  ",
+		revivers:          Revivers{},
+		reservedKeyPrefix: "_",
+		clock:             SystemClock,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) marshal(v interface{}) ([]byte, error) {
+	if o.prefix == "" && o.indent == "" {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, o.prefix, o.indent)
+}
+
+// WithRevivers adds revivers for custom type tags. It may be given several
+// times; later revivers replace earlier ones registered for the same tag.
+// The map is copied, so the caller may reuse it afterwards.
+func WithRevivers(revivers map[string]ReviverFunc) Option {
+	return func(o *options) {
+		for tag, reviver := range revivers {
+			o.revivers[tag] = reviver
+		}
+	}
+}
+
+// WithRegistry uses the revivers and type handlers of r as defaults.
+// Revivers given with WithRevivers and handlers given with WithTypeHandler
+// take precedence over them. The registry is read once, when the call
+// starts, so concurrent updates never affect a parse in progress.
+func WithRegistry(r *Registry) Option {
+	return func(o *options) {
+		o.registry = r.load()
+		o.registryTypes = r.loadTypes()
+	}
+}
+
+func (o *options) reviver(tag string) (ReviverFunc, bool) {
+	if reviver, ok := o.revivers[tag]; ok {
+		return reviver, true
+	}
+	reviver, ok := o.registry[tag]
+	return reviver, ok
+}
+
+// WithIndent sets the prefix and indent used when rendering JSON output. An
+// empty prefix and indent produce compact output.
+func WithIndent(prefix, indent string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+		o.indent = indent
+	}
+}
+
+// WithStrictMapKeys makes rendering fail when a Map key has no string form
+// (an object, array or set) instead of falling back to fmt's %v format, and
+// when two keys share a string form, such as 1 and "1", instead of keeping
+// the value of the last one.
+func WithStrictMapKeys() Option {
+	return func(o *options) {
+		o.strictMapKeys = true
+	}
+}
+
+// WithUTF16Strings preserves strings containing lone surrogates, which
+// encoding/json would otherwise replace with U+FFFD. Such strings hydrate to
+// UTF16String and are re-emitted with their original escape sequences.
+func WithUTF16Strings() Option {
+	return func(o *options) {
+		o.utf16Strings = true
+	}
+}
+
+// WithSetAsSlice hydrates Sets to []interface{} holding their elements in
+// insertion order instead of *Set, for consumers that only iterate them.
+// Elements are not deduplicated, so a malformed payload listing an element
+// twice yields it twice.
+func WithSetAsSlice() Option {
+	return func(o *options) {
+		o.setAsSlice = true
+	}
+}
+
+// ReservedKeyPolicy controls how object keys that have special meaning in
+// JavaScript, such as __proto__ and constructor, are hydrated. The package
+// itself never treats these keys specially; the policy exists to protect
+// consumers that re-emit hydrated objects into JavaScript.
+type ReservedKeyPolicy int
+
+const (
+	// PreserveReservedKeys keeps reserved keys unchanged.
+	PreserveReservedKeys ReservedKeyPolicy = iota
+	// DropReservedKeys removes reserved keys and their values.
+	DropReservedKeys
+	// PrefixReservedKeys renames reserved keys by prepending a prefix.
+	PrefixReservedKeys
+)
+
+// WithReservedKeys sets the policy applied to reserved object keys in both
+// plain and null-prototype objects.
+func WithReservedKeys(policy ReservedKeyPolicy) Option {
+	return func(o *options) {
+		o.reservedKeys = policy
+	}
+}
+
+// WithReservedKeyPrefix renames reserved object keys by prepending prefix.
+func WithReservedKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.reservedKeys = PrefixReservedKeys
+		o.reservedKeyPrefix = prefix
+	}
+}
+
+func isReservedKey(key string) bool {
+	switch key {
+	case "__proto__", "constructor", "prototype":
+		return true
+	}
+	return false
+}
+
+// WithTimeBudget aborts hydration, or serialization with
+// StringifyWithOptions, with ErrBudgetExceeded once it has run for longer
+// than d. The budget is only checked every few hundred values, so it
+// is a soft cap that may be overrun slightly; it bounds the time spent on
+// pathological payloads independently of any context deadline. A budget of
+// zero or less disables the check.
+func WithTimeBudget(d time.Duration) Option {
+	return func(o *options) {
+		o.timeBudget = d
+	}
+}
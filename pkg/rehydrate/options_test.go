@@ -0,0 +1,95 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestReservedKeys(t *testing.T) {
+	input := `[{"__proto__":1,"constructor":2,"name":3},{"polluted":4},"fn","x",true]`
+	tests := []struct {
+		name string
+		opt  rehydrate.Option
+		want map[string]interface{}
+	}{
+		{"preserve", rehydrate.WithReservedKeys(rehydrate.PreserveReservedKeys), map[string]interface{}{
+			"__proto__":   map[string]interface{}{"polluted": true},
+			"constructor": "fn",
+			"name":        "x",
+		}},
+		{"drop", rehydrate.WithReservedKeys(rehydrate.DropReservedKeys), map[string]interface{}{
+			"name": "x",
+		}},
+		{"prefix", rehydrate.WithReservedKeyPrefix("$"), map[string]interface{}{
+			"$__proto__":   map[string]interface{}{"polluted": true},
+			"$constructor": "fn",
+			"name":         "x",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rehydrate.ParseWithOptions(input, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	got, err := rehydrate.ParseWithOptions(`[["null","__proto__",1],"v"]`, rehydrate.WithReservedKeys(rehydrate.DropReservedKeys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.(map[string]interface{})) != 0 {
+		t.Fatalf("reserved key kept in null-prototype object: %v", got)
+	}
+}
+
+func TestTimeBudget(t *testing.T) {
+	refs := make([]string, 10000)
+	for i := range refs {
+		refs[i] = fmt.Sprint(i + 1)
+	}
+	input := "[[" + strings.Join(refs, ",") + "]" + strings.Repeat(`,"x"`, len(refs)) + "]"
+
+	_, err := rehydrate.ParseWithOptions(input, rehydrate.WithTimeBudget(time.Nanosecond))
+	if !errors.Is(err, rehydrate.ErrBudgetExceeded) || !errors.Is(err, rehydrate.ErrLimitExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if _, err := rehydrate.ParseWithOptions(input, rehydrate.WithTimeBudget(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithSetAsSlice(t *testing.T) {
+	payload := `[{"tags":1,"self":2},["Set",3,4],["Set",2],"a","b"]`
+	for _, opts := range [][]rehydrate.Option{
+		{rehydrate.WithSetAsSlice()},
+		// Paths select the recursive engine.
+		{rehydrate.WithSetAsSlice(), rehydrate.WithAudit(func(rehydrate.AuditEvent) {})},
+	} {
+		v, err := rehydrate.ParseWithOptions(payload, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := v.(map[string]interface{})
+		if tags := root["tags"]; !reflect.DeepEqual(tags, []interface{}{"a", "b"}) {
+			t.Errorf("got %#v", tags)
+		}
+		self, ok := root["self"].([]interface{})
+		if !ok || len(self) != 1 {
+			t.Fatalf("got %T", root["self"])
+		}
+		if inner, ok := self[0].([]interface{}); !ok || &inner[0] != &self[0] {
+			t.Errorf("expected the Set to contain itself, got %T", self[0])
+		}
+	}
+}
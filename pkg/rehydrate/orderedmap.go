@@ -0,0 +1,256 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MapEntry is a single key/value pair of an OrderedMap.
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
+	// KeyRef is the value-table index the key was hydrated from, or -1 for
+	// entries added with Set.
+	KeyRef int
+}
+
+// OrderedMap is the hydrated form of a JavaScript Map. It keeps entries in
+// insertion order and preserves the original type of every key, so numeric
+// keys stay float64 instead of being stringified.
+//
+// Keys that are objects, arrays, dates or other non-comparable values are
+// matched by identity: hydrated entries are keyed by the value-table index of
+// their key, so two entries keyed by the same referenced object collapse into
+// one, while equal-looking but distinct objects stay separate.
+type OrderedMap struct {
+	entries []MapEntry
+	index   map[interface{}]int
+}
+
+type refKey int
+
+type nanKey struct{}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{index: make(map[interface{}]int)}
+}
+
+// Len returns the number of entries in the map.
+func (m *OrderedMap) Len() int {
+	return len(m.entries)
+}
+
+// Get returns the value stored under key. Non-comparable keys are looked up
+// by identity, so only the exact object used as a key will match.
+func (m *OrderedMap) Get(key interface{}) (interface{}, bool) {
+	if k, ok := indexKey(key); ok {
+		i, found := m.index[k]
+		if !found {
+			return nil, false
+		}
+		return m.entries[i].Value, true
+	}
+	for _, e := range m.entries {
+		if sameIdentity(e.Key, key) {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// GetRef returns the value whose key was hydrated from value-table index ref.
+func (m *OrderedMap) GetRef(ref int) (interface{}, bool) {
+	for _, e := range m.entries {
+		if e.KeyRef == ref && ref >= 0 {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set stores value under key, keeping the position of an existing entry.
+func (m *OrderedMap) Set(key, value interface{}) {
+	m.set(key, -1, value)
+}
+
+func (m *OrderedMap) set(key interface{}, ref int, value interface{}) {
+	if m.index == nil {
+		m.index = make(map[interface{}]int)
+	}
+	k, ok := indexKey(key)
+	if !ok {
+		if ref < 0 {
+			for i, e := range m.entries {
+				if sameIdentity(e.Key, key) {
+					m.entries[i].Value = value
+					return
+				}
+			}
+			m.entries = append(m.entries, MapEntry{Key: key, Value: value, KeyRef: ref})
+			return
+		}
+		k = refKey(ref)
+	}
+	if i, found := m.index[k]; found {
+		m.entries[i].Value = value
+		return
+	}
+	m.index[k] = len(m.entries)
+	m.entries = append(m.entries, MapEntry{Key: key, Value: value, KeyRef: ref})
+}
+
+// Keys returns the keys in insertion order.
+func (m *OrderedMap) Keys() []interface{} {
+	keys := make([]interface{}, len(m.entries))
+	for i, e := range m.entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// Entries returns the entries in insertion order.
+func (m *OrderedMap) Entries() []MapEntry {
+	return append([]MapEntry(nil), m.entries...)
+}
+
+// indexKey returns the Go map key used to look up key, following
+// JavaScript's SameValueZero semantics for numbers. It reports false when
+// the key is not comparable and has to be matched by identity instead.
+func indexKey(key interface{}) (interface{}, bool) {
+	if key == nil {
+		return nil, true
+	}
+	if f, ok := key.(float64); ok {
+		if math.IsNaN(f) {
+			return nanKey{}, true
+		}
+		if f == 0 {
+			return 0.0, true
+		}
+		return f, true
+	}
+	if _, ok := key.(time.Time); ok {
+		// Dates are objects in JavaScript, so two equal dates are distinct
+		// keys.
+		return nil, false
+	}
+	if !reflect.TypeOf(key).Comparable() {
+		return nil, false
+	}
+	return key, true
+}
+
+func sameIdentity(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() != vb.Kind() || va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Map, reflect.Pointer:
+		return va.Pointer() == vb.Pointer()
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len() && va.Len() > 0
+	}
+	return false
+}
+
+// MarshalJSON encodes the map as a JSON object in insertion order. Keys are
+// converted the way JavaScript's String() would, so 1000000 becomes
+// "1000000" rather than "1e+06". Keys whose string forms collide, such as 1
+// and "1", produce a single member, as Object.fromEntries would: it keeps
+// the position of the first such entry and the value of the last.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	keys := make([]string, 0, len(m.entries))
+	values := make([]interface{}, 0, len(m.entries))
+	seen := make(map[string]int, len(m.entries))
+	for _, e := range m.entries {
+		key := jsonMapKey(e.Key)
+		if i, dup := seen[key]; dup {
+			values[i] = e.Value
+			continue
+		}
+		seen[key] = len(keys)
+		keys = append(keys, key)
+		values = append(values, e.Value)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonMapKey returns the member name MarshalJSON uses for key, falling back
+// to fmt's %v format for keys without a string form.
+func jsonMapKey(key interface{}) string {
+	if s, ok := MapKeyString(key); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", key)
+}
+
+// MapKeyString converts a hydrated Map key to the string JavaScript would use
+// for it. It reports false for keys without a meaningful string form, such
+// as objects, arrays and sets.
+func MapKeyString(key interface{}) (string, bool) {
+	switch k := key.(type) {
+	case string:
+		return k, true
+	case UTF16String:
+		return k.String(), true
+	case float64:
+		return formatJSNumber(k), true
+	case bool:
+		return strconv.FormatBool(k), true
+	case nil:
+		return "null", true
+	case time.Time:
+		return k.Format(time.RFC3339Nano), true
+	}
+	return bigIntDigits(key)
+}
+
+func formatJSNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == 0:
+		return "0"
+	}
+	abs := math.Abs(f)
+	if abs >= 1e21 || abs < 1e-6 {
+		s := strconv.FormatFloat(f, 'e', -1, 64)
+		// Go pads the exponent to two digits; JavaScript does not.
+		mantissa, exp, _ := strings.Cut(s, "e")
+		sign, digits := exp[:1], strings.TrimLeft(exp[1:], "0")
+		return mantissa + "e" + sign + digits
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
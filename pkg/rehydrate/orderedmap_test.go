@@ -0,0 +1,121 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestMapKeepsNumericKeys(t *testing.T) {
+	input := `[["Map",1,2,3,4],1000000,"a",0.5,"b"]`
+	result, err := rehydrate.Parse(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := result.(*rehydrate.OrderedMap)
+	if !ok {
+		t.Fatalf("expected *OrderedMap, got %T", result)
+	}
+	if v, ok := m.Get(1000000.0); !ok || v != "a" {
+		t.Fatalf("numeric key lost: %v %v", v, ok)
+	}
+
+	out, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithIndent("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"1000000":"a","0.5":"b"}`; out != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+}
+
+func TestMapKeyString(t *testing.T) {
+	tests := map[float64]string{
+		1e21:   "1e+21",
+		1.5e-7: "1.5e-7",
+		-42:    "-42",
+		0.1:    "0.1",
+	}
+	for in, want := range tests {
+		if got, _ := rehydrate.MapKeyString(in); got != want {
+			t.Errorf("MapKeyString(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStrictMapKeys(t *testing.T) {
+	input := `[["Map",1,2],["RegExp","a+",""],"x"]`
+	_, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithStrictMapKeys())
+	if !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestMapKeyCollisions(t *testing.T) {
+	input := `[["Map",1,2,3,4,5,6],1,"a","1","b",2,"c"]`
+	out, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithIndent("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"1":"b","2":"c"}`; out != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+	_, err = rehydrate.RehydrateWith(input, nil, rehydrate.WithStrictMapKeys())
+	if !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput under WithStrictMapKeys, got %v", err)
+	}
+}
+
+func TestMapDateKeysByReference(t *testing.T) {
+	// Entries 1 and 3 share the date at index 1; entry 5 is an equal but
+	// distinct date.
+	input := `[["Map",1,2,1,3,4,5],["Date","2024-01-01T00:00:00.000Z"],"first","second",["Date","2024-01-01T00:00:00.000Z"],"third"]`
+	result, err := rehydrate.Parse(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := result.(*rehydrate.OrderedMap)
+	if m.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", m.Len())
+	}
+	if v, _ := m.GetRef(4); v != "third" {
+		t.Errorf("GetRef(4) = %v, want third", v)
+	}
+}
+
+func TestMapObjectKeysByIdentity(t *testing.T) {
+	// Entries 1 and 3 share the key at index 1; entry 5 uses an equal but
+	// distinct object.
+	input := `[["Map",1,2,1,3,4,5],{"id":6},"first","second",{"id":6},"third",7]`
+	result, err := rehydrate.Parse(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := result.(*rehydrate.OrderedMap)
+	if m.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", m.Len())
+	}
+	if v, _ := m.GetRef(1); v != "second" {
+		t.Fatalf("GetRef(1) = %v, want second", v)
+	}
+	if v, _ := m.GetRef(4); v != "third" {
+		t.Fatalf("GetRef(4) = %v, want third", v)
+	}
+	key := m.Keys()[0]
+	if v, ok := m.Get(key); !ok || v != "second" {
+		t.Fatalf("Get by identity = %v %v", v, ok)
+	}
+}
+
+func TestSetWithObjectElements(t *testing.T) {
+	input := `[["Set",1,1,2],[],[]]`
+	result, err := rehydrate.Parse(input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := result.(*rehydrate.Set)
+	if set.Len() != 2 {
+		t.Fatalf("expected 2 elements, got %d", set.Len())
+	}
+}
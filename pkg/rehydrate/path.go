@@ -0,0 +1,263 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path addresses a value inside a hydrated value, starting from the root.
+// Its string form is an RFC 6901 JSON Pointer, such as
+// /data/items/0/first name, as used by Search results and reports; Dotted
+// renders it in a JavaScript-like syntax instead. The empty Path is the root.
+type Path []PathSegment
+
+// PathSegment is a step of a Path: an object key or a Map key, or the
+// position of an array or Set element. Map keys that are not strings are
+// addressed by their string form, see MapKeyString.
+type PathSegment struct {
+	// Key is the object or Map key, empty for positions.
+	Key string
+	// Index is the position, or -1 for keys.
+	Index int
+}
+
+// KeySegment returns the segment addressing key.
+func KeySegment(key string) PathSegment {
+	return PathSegment{Key: key, Index: -1}
+}
+
+// IndexSegment returns the segment addressing position i.
+func IndexSegment(i int) PathSegment {
+	return PathSegment{Index: i}
+}
+
+// IsIndex reports whether s is a position.
+func (s PathSegment) IsIndex() bool {
+	return s.Index >= 0
+}
+
+func (s PathSegment) name() string {
+	if s.IsIndex() {
+		return strconv.Itoa(s.Index)
+	}
+	return s.Key
+}
+
+// ParsePath parses path as a JSON Pointer if it starts with "/", and in the
+// syntax of Dotted otherwise. Every function of this package taking a path
+// accepts both forms.
+func ParsePath(path string) (Path, error) {
+	if strings.HasPrefix(path, "/") {
+		return ParsePointer(path)
+	}
+	p := Path{}
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			if i == len(path) || path[i] == '.' || path[i] == '[' {
+				return nil, fmt.Errorf("%w: empty key in path %q", ErrInvalidInput, path)
+			}
+		case '[':
+			end := closingBracket(path, i)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated [ in path %q", ErrInvalidInput, path)
+			}
+			inner := path[i+1 : end]
+			if unquoted, err := strconv.Unquote(inner); err == nil {
+				p = append(p, KeySegment(unquoted))
+			} else if n, ok := pathIndex(inner); ok {
+				p = append(p, IndexSegment(n))
+			} else {
+				// The string form of a Map key, such as [true].
+				p = append(p, KeySegment(inner))
+			}
+			i = end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			p = append(p, KeySegment(path[i:i+end]))
+			i += end
+		}
+	}
+	return p, nil
+}
+
+// closingBracket returns the position of the ] closing the [ at start,
+// skipping over a quoted key.
+func closingBracket(path string, start int) int {
+	i := start + 1
+	if i < len(path) && path[i] == '"' {
+		for i++; i < len(path) && path[i] != '"'; i++ {
+			if path[i] == '\\' {
+				i++
+			}
+		}
+		i++
+	}
+	if i > len(path) {
+		return -1
+	}
+	if end := strings.IndexByte(path[i:], ']'); end >= 0 {
+		return i + end
+	}
+	return -1
+}
+
+// ParsePointer parses an RFC 6901 JSON Pointer such as /data/items/0.
+// Reference tokens that are array indices address positions; the others,
+// including "-", address keys.
+func ParsePointer(pointer string) (Path, error) {
+	if pointer == "" {
+		return Path{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: JSON Pointer %q does not start with /", ErrInvalidInput, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	p := make(Path, 0, len(tokens))
+	for _, token := range tokens {
+		key, err := unescapePointerToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("%w: JSON Pointer %q: %w", ErrInvalidInput, pointer, err)
+		}
+		if n, ok := pathIndex(key); ok {
+			p = append(p, IndexSegment(n))
+		} else {
+			p = append(p, KeySegment(key))
+		}
+	}
+	return p, nil
+}
+
+// ToPointer converts path, in the syntax of Path.Dotted, to a JSON Pointer.
+func ToPointer(path string) (string, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	return p.Pointer(), nil
+}
+
+// ToDotted converts path, a JSON Pointer, to the syntax of Path.Dotted.
+func ToDotted(path string) (string, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return "", err
+	}
+	return p.Dotted(), nil
+}
+
+// String renders p as a JSON Pointer, like Pointer.
+func (p Path) String() string {
+	return p.Pointer()
+}
+
+// Pointer renders p as an RFC 6901 JSON Pointer.
+func (p Path) Pointer() string {
+	var b strings.Builder
+	for _, s := range p {
+		b.WriteByte('/')
+		b.WriteString(pointerEscaper.Replace(s.name()))
+	}
+	return b.String()
+}
+
+// Dotted renders p in a JavaScript-like syntax: keys that are identifiers
+// follow a dot, other keys and positions are bracketed, as in
+// data.items[0]["first name"].
+func (p Path) Dotted() string {
+	var path string
+	for _, s := range p {
+		switch {
+		case s.IsIndex():
+			path += "[" + strconv.Itoa(s.Index) + "]"
+		case isIdentifier(s.Key):
+			if path != "" {
+				path += "."
+			}
+			path += s.Key
+		default:
+			quoted, _ := json.Marshal(s.Key)
+			path += "[" + string(quoted) + "]"
+		}
+	}
+	return path
+}
+
+// MarshalText renders p with String.
+func (p Path) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText parses text with ParsePath.
+func (p *Path) UnmarshalText(text []byte) error {
+	parsed, err := ParsePath(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// segments returns the keys and positions of p as strings, as matched by
+// Tree.Child and path policies.
+func (p Path) segments() []string {
+	segments := make([]string, len(p))
+	for i, s := range p {
+		segments[i] = s.name()
+	}
+	return segments
+}
+
+// pointerPath converts path to a JSON Pointer if it is in the syntax of
+// Dotted.
+func pointerPath(path string) (string, error) {
+	if path == "" || strings.HasPrefix(path, "/") {
+		return path, nil
+	}
+	return ToPointer(path)
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func unescapePointerToken(token string) (string, error) {
+	if !strings.Contains(token, "~") {
+		return token, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(token); i++ {
+		if token[i] != '~' {
+			b.WriteByte(token[i])
+			continue
+		}
+		if i+1 == len(token) || (token[i+1] != '0' && token[i+1] != '1') {
+			return "", fmt.Errorf("invalid escape at byte %d", i)
+		}
+		if token[i+1] == '0' {
+			b.WriteByte('~')
+		} else {
+			b.WriteByte('/')
+		}
+		i++
+	}
+	return b.String(), nil
+}
+
+// pathIndex parses s as an array index: digits without a leading zero.
+func pathIndex(s string) (int, bool) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
@@ -0,0 +1,99 @@
+package rehydrate_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParsePath(t *testing.T) {
+	want := rehydrate.Path{
+		rehydrate.KeySegment("data"),
+		rehydrate.KeySegment("a/b~c"),
+		rehydrate.IndexSegment(0),
+		rehydrate.KeySegment("first name"),
+		rehydrate.KeySegment("x]y"),
+	}
+	for _, path := range []string{
+		`data["a/b~c"][0]["first name"]["x]y"]`,
+		`/data/a~1b~0c/0/first name/x]y`,
+	} {
+		got, err := rehydrate.ParsePath(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v", path, got)
+		}
+	}
+	if s := want.String(); s != `/data/a~1b~0c/0/first name/x]y` {
+		t.Errorf("String() = %s", s)
+	}
+	if s := want.Dotted(); s != `data["a/b~c"][0]["first name"]["x]y"]` {
+		t.Errorf("Dotted() = %s", s)
+	}
+	if s := want.Pointer(); s != `/data/a~1b~0c/0/first name/x]y` {
+		t.Errorf("Pointer() = %s", s)
+	}
+
+	for path, pointer := range map[string]string{
+		"":                  "",
+		"items[3].price":    "/items/3/price",
+		`scores[1000000]`:   "/scores/1000000",
+		`flags[true]`:       "/flags/true",
+		`users["007"].name`: "/users/007/name",
+	} {
+		if got, err := rehydrate.ToPointer(path); err != nil || got != pointer {
+			t.Errorf("ToPointer(%q) = %q, %v, want %q", path, got, err, pointer)
+		}
+	}
+	for pointer, path := range map[string]string{
+		"":                 "",
+		"/items/3/price":   "items[3].price",
+		"/users/007":       `users["007"]`,
+		"/a~1b/first name": `["a/b"]["first name"]`,
+	} {
+		if got, err := rehydrate.ToDotted(pointer); err != nil || got != path {
+			t.Errorf("ToDotted(%q) = %q, %v, want %q", pointer, got, err, path)
+		}
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, path := range []string{"a..b", "a.", `a["b"`, "/a~2", "/a~"} {
+		if _, err := rehydrate.ParsePath(path); !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("%q: expected ErrInvalidInput, got %v", path, err)
+		}
+	}
+	if _, err := rehydrate.ParsePointer("a/b"); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestPathText(t *testing.T) {
+	var v struct {
+		Path rehydrate.Path `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(`{"path":"/items/0/name"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(v)
+	if err != nil || string(out) != `{"path":"/items/0/name"}` {
+		t.Errorf("got %s, %v", out, err)
+	}
+}
+
+func TestPathPolicyPointer(t *testing.T) {
+	v, err := rehydrate.ParseWithOptions(`[{"data":1},{"secret":2,"open":3},"x","y"]`,
+		rehydrate.WithPathPolicy("/data/secret", rehydrate.PolicySkip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := v.(map[string]interface{})["data"].(map[string]interface{})
+	if _, ok := data["secret"].(*rehydrate.LazyRef); !ok || data["open"] != "y" {
+		t.Errorf("got %#v", data)
+	}
+}
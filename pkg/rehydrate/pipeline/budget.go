@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// ErrOverBudget is returned for a payload whose estimated memory does not
+// fit in a MemoryBudget. It wraps rehydrate.ErrLimitExceeded.
+var ErrOverBudget = fmt.Errorf("%w: memory budget", rehydrate.ErrLimitExceeded)
+
+// MemoryBudget bounds the memory held by payloads in flight, as estimated by
+// rehydrate.EstimateMemory, so a burst of large payloads queues or is
+// rejected instead of exhausting the worker's memory. One budget may be
+// shared by several pipelines and servers. It is safe for concurrent use.
+type MemoryBudget struct {
+	limit int64
+
+	mu      sync.Mutex
+	used    int64
+	changed chan struct{} // closed and replaced on every release
+}
+
+// NewMemoryBudget returns a budget of limit bytes.
+func NewMemoryBudget(limit int64) *MemoryBudget {
+	return &MemoryBudget{limit: limit, changed: make(chan struct{})}
+}
+
+// Limit returns the size of the budget.
+func (b *MemoryBudget) Limit() int64 {
+	return b.limit
+}
+
+// InUse returns the number of bytes currently reserved.
+func (b *MemoryBudget) InUse() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// TryAcquire reserves n bytes if they are available and reports whether it
+// did.
+func (b *MemoryBudget) TryAcquire(n int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used+n > b.limit {
+		return false
+	}
+	b.used += n
+	return true
+}
+
+// Acquire reserves n bytes, waiting until enough are released or ctx is
+// done. It fails immediately with ErrOverBudget if n exceeds the whole
+// budget. Waiters are not served in order, so a large reservation may wait
+// while smaller ones proceed.
+func (b *MemoryBudget) Acquire(ctx context.Context, n int64) error {
+	if n > b.limit {
+		return fmt.Errorf("%w: %d bytes estimated, %d allowed", ErrOverBudget, n, b.limit)
+	}
+	for {
+		b.mu.Lock()
+		if b.used+n <= b.limit {
+			b.used += n
+			b.mu.Unlock()
+			return nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrOverBudget, ctx.Err())
+		}
+	}
+}
+
+// Release returns n bytes reserved by Acquire or TryAcquire.
+func (b *MemoryBudget) Release(n int64) {
+	b.mu.Lock()
+	b.used -= n
+	close(b.changed)
+	b.changed = make(chan struct{})
+	b.mu.Unlock()
+}
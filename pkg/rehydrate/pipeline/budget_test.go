@@ -0,0 +1,85 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	b := pipeline.NewMemoryBudget(100)
+	if !b.TryAcquire(60) || b.TryAcquire(60) || b.InUse() != 60 {
+		t.Fatalf("unexpected reservations, %d in use", b.InUse())
+	}
+	if err := b.Acquire(context.Background(), 101); !errors.Is(err, pipeline.ErrOverBudget) ||
+		!errors.Is(err, rehydrate.ErrLimitExceeded) {
+		t.Errorf("oversized: got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Acquire(ctx, 60); !errors.Is(err, pipeline.ErrOverBudget) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout: got %v", err)
+	}
+
+	acquired := make(chan error)
+	go func() { acquired <- b.Acquire(context.Background(), 60) }()
+	time.Sleep(5 * time.Millisecond)
+	b.Release(60)
+	if err := <-acquired; err != nil || b.InUse() != 60 {
+		t.Errorf("queued: got %v, %d in use", err, b.InUse())
+	}
+}
+
+func TestRunMemoryBudget(t *testing.T) {
+	ctx := context.Background()
+	msgs := messages(20)
+	msgs = append(msgs, pipeline.Message{Payload: `["` + strings.Repeat("x", 1000) + `"]`, Meta: "large"})
+	limit := 2*rehydrate.EstimateMemory(msgs[0].Payload) + 1
+	b := pipeline.NewMemoryBudget(limit)
+
+	var inFlight, peak atomic.Int64
+	transform := func(_ context.Context, _ pipeline.Message, v interface{}) (interface{}, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		return v, nil
+	}
+
+	check := func(name string, results <-chan pipeline.Result) {
+		t.Helper()
+		n := 0
+		for r := range results {
+			n++
+			if r.Message.Meta == "large" {
+				if !errors.Is(r.Err, pipeline.ErrOverBudget) {
+					t.Errorf("%s: large payload: got %v", name, r.Err)
+				}
+			} else if r.Err != nil {
+				t.Errorf("%s: %v", name, r.Err)
+			}
+		}
+		if n != len(msgs) || peak.Load() > 2 || b.InUse() != 0 {
+			t.Errorf("%s: %d results, peak %d, %d bytes in use", name, n, peak.Load(), b.InUse())
+		}
+		peak.Store(0)
+	}
+
+	check("Run", pipeline.Run(ctx, pipeline.FromSeq(ctx, slices.Values(msgs)),
+		pipeline.WithWorkers(8), pipeline.WithMemoryBudget(b), pipeline.WithTransform(transform)))
+
+	p, err := pipeline.NewBuilder().Parse(8).Transform(transform, 8).MemoryBudget(b).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Builder", p.Run(ctx, pipeline.FromSeq(ctx, slices.Values(msgs))))
+}
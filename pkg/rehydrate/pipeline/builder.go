@@ -0,0 +1,342 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// Stage identifies a step of a pipeline declared with a Builder. Stages run
+// in the order of their values, whatever the order they were declared in.
+type Stage int
+
+const (
+	StageExtract Stage = iota
+	StageParse
+	StageTransform
+	StageValidate
+	StageEncode
+)
+
+var stageNames = [...]string{
+	StageExtract:   "extract",
+	StageParse:     "parse",
+	StageTransform: "transform",
+	StageValidate:  "validate",
+	StageEncode:    "encode",
+}
+
+func (s Stage) String() string {
+	if s < 0 || int(s) >= len(stageNames) {
+		return ""
+	}
+	return stageNames[s]
+}
+
+// StageError reports that a message failed in a stage of a Builder pipeline.
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// ExtractFunc returns the payload carried by a message, for example the
+// content of a script element of an HTML page.
+type ExtractFunc func(ctx context.Context, msg Message) (string, error)
+
+// ValidateFunc checks a hydrated and transformed value.
+type ValidateFunc func(ctx context.Context, msg Message, v interface{}) error
+
+// EncodeFunc renders a validated value. Its output becomes the Encoded field
+// of the Result.
+type EncodeFunc func(ctx context.Context, msg Message, v interface{}) ([]byte, error)
+
+// ErrorHandler handles a failure routed to it with Builder.OnError. It
+// returns nil to drop the message, or the error to report in its Result,
+// typically err itself.
+type ErrorHandler func(ctx context.Context, msg Message, err *StageError) error
+
+// Builder declares a pipeline stage by stage. Each stage runs with its own
+// number of workers, so a slow encoder can be given more than a fast
+// validator. A message failing in a stage skips the remaining ones.
+type Builder struct {
+	stages []stage
+	buffer int
+	budget *MemoryBudget
+	routes []route
+	err    error
+}
+
+type stage struct {
+	kind    Stage
+	workers int
+	run     func(ctx context.Context, it *item) error
+}
+
+type route struct {
+	match   func(error) bool
+	handler ErrorHandler
+}
+
+// item is a message on its way through the stages.
+type item struct {
+	msg     Message
+	payload string
+	value   interface{}
+	encoded []byte
+	err     *StageError
+	// reserved is the memory reserved for the message in budget.
+	budget   *MemoryBudget
+	reserved int64
+}
+
+func (it *item) release() {
+	if it.reserved > 0 {
+		it.budget.Release(it.reserved)
+		it.reserved = 0
+	}
+}
+
+// NewBuilder returns a Builder whose pipeline only parses messages, with
+// four workers.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) add(kind Stage, workers int, run func(ctx context.Context, it *item) error) *Builder {
+	if kind != StageTransform && kind != StageValidate {
+		for _, s := range b.stages {
+			if s.kind == kind && b.err == nil {
+				b.err = fmt.Errorf("pipeline: more than one %s stage", kind)
+			}
+		}
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	b.stages = append(b.stages, stage{kind: kind, workers: workers, run: run})
+	return b
+}
+
+// Extract adds a stage deriving the payload to hydrate from each message.
+// Without it, Message.Payload is hydrated.
+func (b *Builder) Extract(fn ExtractFunc, workers int) *Builder {
+	return b.add(StageExtract, workers, func(ctx context.Context, it *item) (err error) {
+		it.payload, err = fn(ctx, it.msg)
+		return err
+	})
+}
+
+// Parse sets the number of workers hydrating payloads and the options they
+// are hydrated with.
+func (b *Builder) Parse(workers int, opts ...rehydrate.Option) *Builder {
+	return b.add(StageParse, workers, parse(opts))
+}
+
+func parse(opts []rehydrate.Option) func(context.Context, *item) error {
+	return func(_ context.Context, it *item) (err error) {
+		it.value, err = rehydrate.ParseWithOptions(it.payload, opts...)
+		return err
+	}
+}
+
+// Transform adds a stage replacing each value by the result of fn. Several
+// transform stages run in the order they were added.
+func (b *Builder) Transform(fn TransformFunc, workers int) *Builder {
+	return b.add(StageTransform, workers, func(ctx context.Context, it *item) (err error) {
+		it.value, err = fn(ctx, it.msg, it.value)
+		return err
+	})
+}
+
+// Validate adds a stage checking each value. Several validate stages run in
+// the order they were added.
+func (b *Builder) Validate(fn ValidateFunc, workers int) *Builder {
+	return b.add(StageValidate, workers, func(ctx context.Context, it *item) error {
+		return fn(ctx, it.msg, it.value)
+	})
+}
+
+// Encode adds a final stage rendering each value.
+func (b *Builder) Encode(fn EncodeFunc, workers int) *Builder {
+	return b.add(StageEncode, workers, func(ctx context.Context, it *item) (err error) {
+		it.encoded, err = fn(ctx, it.msg, it.value)
+		return err
+	})
+}
+
+// Buffer sets the number of results that may wait to be received before the
+// stages block. The default is 0.
+func (b *Builder) Buffer(n int) *Builder {
+	if n >= 0 {
+		b.buffer = n
+	}
+	return b
+}
+
+// MemoryBudget makes every message reserve its estimated memory in b when it
+// enters the pipeline, waiting while the budget is exhausted, and release it
+// once it leaves the pipeline. The reservation is estimated from
+// Message.Payload. Messages too large for the whole budget fail in the
+// extract stage with ErrOverBudget.
+func (b *Builder) MemoryBudget(budget *MemoryBudget) *Builder {
+	b.budget = budget
+	return b
+}
+
+// OnError routes the failures wrapping target, as reported by errors.Is, to
+// handler. Routes are tried in the order they were added and the first
+// matching one applies; unrouted failures are reported in their Result.
+func (b *Builder) OnError(target error, handler ErrorHandler) *Builder {
+	return b.OnErrorFunc(func(err error) bool { return errors.Is(err, target) }, handler)
+}
+
+// OnErrorFunc is like OnError for the failures match reports true for, for
+// example to route by error type with errors.As or by Stage.
+func (b *Builder) OnErrorFunc(match func(error) bool, handler ErrorHandler) *Builder {
+	b.routes = append(b.routes, route{match: match, handler: handler})
+	return b
+}
+
+// Build returns the declared pipeline. It fails if the extract, parse or
+// encode stage was declared more than once.
+func (b *Builder) Build() (*Pipeline, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	stages := append([]stage(nil), b.stages...)
+	hasParse := false
+	for _, s := range stages {
+		hasParse = hasParse || s.kind == StageParse
+	}
+	if !hasParse {
+		stages = append(stages, stage{kind: StageParse, workers: 4, run: parse(nil)})
+	}
+	sort.SliceStable(stages, func(i, j int) bool { return stages[i].kind < stages[j].kind })
+	return &Pipeline{
+		stages: stages,
+		buffer: b.buffer,
+		budget: b.budget,
+		routes: append([]route(nil), b.routes...),
+	}, nil
+}
+
+// Pipeline is a chain of stages built by a Builder. It may be run any number
+// of times, concurrently.
+type Pipeline struct {
+	stages []stage
+	buffer int
+	budget *MemoryBudget
+	routes []route
+}
+
+// Run processes the messages read from in and returns the channel results are
+// emitted on, as soon as they are ready. The channel is closed once in is
+// closed and all messages have been processed, or once ctx is canceled.
+// Error handlers are called on a single goroutine.
+func (p *Pipeline) Run(ctx context.Context, in <-chan Message) <-chan Result {
+	src := make(chan *item)
+	go func() {
+		defer close(src)
+		for {
+			msg, ok := receive(ctx, in)
+			if !ok {
+				return
+			}
+			it := &item{msg: msg, payload: msg.Payload}
+			if p.budget != nil {
+				n := rehydrate.EstimateMemory(msg.Payload)
+				if err := p.budget.Acquire(ctx, n); err != nil {
+					it.err = &StageError{Stage: StageExtract, Err: err}
+				} else {
+					it.budget, it.reserved = p.budget, n
+				}
+			}
+			select {
+			case src <- it:
+			case <-ctx.Done():
+				it.release()
+				return
+			}
+		}
+	}()
+
+	var ch <-chan *item = src
+	for _, s := range p.stages {
+		ch = s.start(ctx, ch)
+	}
+	out := make(chan Result, p.buffer)
+	go p.collect(ctx, ch, out)
+	return out
+}
+
+func (s stage) start(ctx context.Context, in <-chan *item) <-chan *item {
+	next := make(chan *item)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range in {
+				if it.err == nil {
+					if err := s.run(ctx, it); err != nil {
+						it.err = &StageError{Stage: s.kind, Err: err}
+					}
+				}
+				select {
+				case next <- it:
+				case <-ctx.Done():
+					it.release()
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(next)
+	}()
+	return next
+}
+
+func (p *Pipeline) collect(ctx context.Context, in <-chan *item, out chan<- Result) {
+	defer close(out)
+	for it := range in {
+		it.release()
+		r := Result{Message: it.msg, Value: it.value, Encoded: it.encoded}
+		if it.err != nil {
+			err := p.route(ctx, it)
+			if err == nil {
+				continue
+			}
+			r = Result{Message: it.msg, Err: err}
+		}
+		select {
+		case out <- r:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// route returns the error to report for a failed item, or nil if a handler
+// dropped it.
+func (p *Pipeline) route(ctx context.Context, it *item) error {
+	for _, r := range p.routes {
+		if r.match(it.err) {
+			return r.handler(ctx, it.msg, it.err)
+		}
+	}
+	return it.err
+}
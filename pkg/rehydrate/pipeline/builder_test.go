@@ -0,0 +1,111 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+var errTooLarge = errors.New("too large")
+
+func TestBuilder(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu          sync.Mutex
+		quarantined []interface{}
+	)
+	p, err := pipeline.NewBuilder().
+		Encode(func(_ context.Context, _ pipeline.Message, v interface{}) ([]byte, error) {
+			return json.Marshal(v)
+		}, 2).
+		Extract(func(_ context.Context, msg pipeline.Message) (string, error) {
+			payload, ok := strings.CutPrefix(msg.Payload, "payload:")
+			if !ok {
+				return "", fmt.Errorf("no payload in %q", msg.Payload)
+			}
+			return payload, nil
+		}, 2).
+		Parse(3).
+		Transform(func(_ context.Context, _ pipeline.Message, v interface{}) (interface{}, error) {
+			return v.(map[string]interface{})["n"], nil
+		}, 2).
+		Validate(func(_ context.Context, _ pipeline.Message, v interface{}) error {
+			if v.(float64) > 10 {
+				return errTooLarge
+			}
+			return nil
+		}, 1).
+		OnError(errTooLarge, func(_ context.Context, msg pipeline.Message, err *pipeline.StageError) error {
+			if err.Stage != pipeline.StageValidate {
+				t.Errorf("unexpected stage %v", err.Stage)
+			}
+			mu.Lock()
+			quarantined = append(quarantined, msg.Meta)
+			mu.Unlock()
+			return nil
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []string{`payload:[{"n":1},5]`, `payload:[{"n":1},50]`, `junk`, `payload:[`}
+	msgs := make([]pipeline.Message, len(inputs))
+	for i, input := range inputs {
+		msgs[i] = pipeline.Message{Payload: input, Meta: i}
+	}
+	results := map[int]pipeline.Result{}
+	for r := range p.Run(ctx, pipeline.FromSeq(ctx, slices.Values(msgs))) {
+		results[r.Message.Meta.(int)] = r
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if r := results[0]; r.Err != nil || r.Value != 5.0 || string(r.Encoded) != "5" {
+		t.Errorf("unexpected result %+v", r)
+	}
+	var stageErr *pipeline.StageError
+	if r := results[2]; !errors.As(r.Err, &stageErr) || stageErr.Stage != pipeline.StageExtract {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if r := results[3]; !errors.As(r.Err, &stageErr) || stageErr.Stage != pipeline.StageParse ||
+		!errors.Is(r.Err, rehydrate.ErrInvalidInput) {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if len(quarantined) != 1 || quarantined[0] != 1 {
+		t.Errorf("unexpected quarantine %v", quarantined)
+	}
+}
+
+func TestBuilderDefaults(t *testing.T) {
+	ctx := context.Background()
+	p, err := pipeline.NewBuilder().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []float64
+	for r := range p.Run(ctx, pipeline.FromSeq(ctx, slices.Values(messages(5)))) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		got = append(got, r.Value.(map[string]interface{})["n"].(float64))
+	}
+	sort.Float64s(got)
+	if fmt.Sprint(got) != "[0 1 2 3 4]" {
+		t.Errorf("got %v", got)
+	}
+
+	if _, err := pipeline.NewBuilder().Parse(1).Parse(2).Build(); err == nil {
+		t.Error("expected an error for two parse stages")
+	}
+}
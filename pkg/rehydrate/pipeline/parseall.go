@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+)
+
+// Stats aggregates the messages processed by Run or ParseAll, for batch
+// jobs reporting throughput and failure rates. It is safe for concurrent
+// use and may be read while processing is under way.
+type Stats struct {
+	mu       sync.Mutex
+	messages int
+	failed   int
+	bytes    int64
+	busy     time.Duration
+}
+
+// WithStats records every processed message in s.
+func WithStats(s *Stats) Option {
+	return func(c *config) { c.stats = s }
+}
+
+func (s *Stats) record(r Result, busy time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages++
+	if r.Err != nil {
+		s.failed++
+	}
+	s.bytes += int64(len(r.Message.Payload))
+	s.busy += busy
+}
+
+// Messages returns the number of messages processed.
+func (s *Stats) Messages() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages
+}
+
+// Failed returns the number of messages whose Result has an error.
+func (s *Stats) Failed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failed
+}
+
+// Bytes returns the total size of the processed payloads.
+func (s *Stats) Bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bytes
+}
+
+// Busy returns the time spent processing messages, summed over workers.
+func (s *Stats) Busy() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.busy
+}
+
+// ParseAll hydrates inputs with a bounded pool of workers configured like
+// Run and yields each Result together with its error. The Meta of every
+// result's Message is the position of its input in inputs, which is also
+// the order results are yielded in with WithOrdered.
+//
+// Workers stop when the consumer stops iterating. If ctx is canceled,
+// results still in flight are dropped and the last pair yielded carries
+// ctx's error and a zero Result.
+func ParseAll(ctx context.Context, inputs iter.Seq[[]byte], opts ...Option) iter.Seq2[Result, error] {
+	return func(yield func(Result, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		msgs := func(yield func(Message) bool) {
+			i := 0
+			for input := range inputs {
+				if !yield(Message{Payload: string(input), Meta: i}) {
+					return
+				}
+				i++
+			}
+		}
+		for r := range Run(ctx, FromSeq(ctx, msgs), opts...) {
+			if !yield(r, r.Err) {
+				return
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			yield(Result{}, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+func payloads(n int) iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		for i := 0; i < n; i++ {
+			payload := fmt.Sprintf(`[{"n":1},%d]`, i)
+			if i == 3 {
+				payload = `[["Widget",0]]`
+			}
+			if !yield([]byte(payload)) {
+				return
+			}
+		}
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	var stats pipeline.Stats
+	next := 0
+	for r, err := range pipeline.ParseAll(context.Background(), payloads(30),
+		pipeline.WithWorkers(4), pipeline.WithOrdered(), pipeline.WithStats(&stats)) {
+		if r.Message.Meta != next {
+			t.Fatalf("got input %v, want %d", r.Message.Meta, next)
+		}
+		if next == 3 {
+			if !errors.Is(err, rehydrate.ErrUnknownType) {
+				t.Errorf("expected ErrUnknownType, got %v", err)
+			}
+		} else if err != nil || r.Value.(map[string]interface{})["n"] != float64(next) {
+			t.Errorf("input %d: got %v, %v", next, r.Value, err)
+		}
+		next++
+	}
+	if next != 30 || stats.Messages() != 30 || stats.Failed() != 1 || stats.Bytes() == 0 {
+		t.Errorf("got %d results, stats %d/%d/%d", next, stats.Messages(), stats.Failed(), stats.Bytes())
+	}
+}
+
+func TestParseAllStop(t *testing.T) {
+	// Stopping early must not leak or block the workers.
+	n := 0
+	for range pipeline.ParseAll(context.Background(), payloads(1000), pipeline.WithWorkers(8)) {
+		if n++; n == 5 {
+			break
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var last error
+	for _, err := range pipeline.ParseAll(ctx, payloads(1000), pipeline.WithWorkers(2)) {
+		cancel()
+		last = err
+	}
+	if !errors.Is(last, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", last)
+	}
+}
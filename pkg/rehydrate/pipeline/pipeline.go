@@ -0,0 +1,290 @@
+// Package pipeline hydrates a stream of payload messages concurrently, such as
+// those read from a Kafka topic or a job queue, and applies a transform to
+// each result.
+//
+// Run reads messages until its input is closed or the context is canceled
+// and emits one Result per message. At most Workers messages are hydrated at
+// a time and at most Buffer results wait to be received, so a slow consumer
+// slows down reading instead of growing memory without bound. Batch jobs
+// can use ParseAll instead, which iterates over the results for a sequence
+// of payloads.
+//
+// Flows with more steps than a transform can be declared with a Builder,
+// which chains extract, parse, transform, validate and encode stages, each
+// with its own number of workers, and routes failures by error:
+//
+//	p, err := pipeline.NewBuilder().
+//		Extract(fromHTML, 2).
+//		Parse(8, rehydrate.Harden()).
+//		Validate(checkSchema, 2).
+//		Encode(toJSON, 2).
+//		OnError(rehydrate.ErrLimitExceeded, quarantine).
+//		Build()
+//	results := p.Run(ctx, in)
+package pipeline
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// Message is a raw payload to hydrate. Meta is passed through to the Result
+// unchanged, for example to commit a queue offset once it was processed.
+type Message struct {
+	Payload string
+	Meta    interface{}
+}
+
+// Result is the outcome of processing a Message. Err is set if hydration or
+// the transform failed, in which case Value is nil.
+type Result struct {
+	Message Message
+	Value   interface{}
+	// Encoded is the output of the encode stage of a Builder pipeline.
+	Encoded []byte
+	Err     error
+}
+
+// TransformFunc is applied to every successfully hydrated value. Its result
+// becomes the Value of the Result.
+type TransformFunc func(ctx context.Context, msg Message, v interface{}) (interface{}, error)
+
+// Option configures Run.
+type Option func(*config)
+
+type config struct {
+	workers   int
+	buffer    int
+	ordered   bool
+	reorder   int
+	parseOpts []rehydrate.Option
+	transform TransformFunc
+	budget    *MemoryBudget
+	stats     *Stats
+}
+
+// WithWorkers sets the number of messages hydrated concurrently. The default
+// is 4.
+func WithWorkers(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.workers = n
+		}
+	}
+}
+
+// WithBuffer sets the number of results that may wait to be received before
+// workers block. The default is 0.
+func WithBuffer(n int) Option {
+	return func(c *config) {
+		if n >= 0 {
+			c.buffer = n
+		}
+	}
+}
+
+// WithOrdered makes Run emit results in the order the messages were read.
+// Without it, results are emitted as soon as they are ready.
+func WithOrdered() Option {
+	return func(c *config) { c.ordered = true }
+}
+
+// WithReorderBuffer sets the number of results WithOrdered may hold back
+// while an earlier message is still being hydrated, so that workers can move
+// on to later messages. Once it is full, reading waits for the earlier
+// result. The default is 0, which still lets every worker finish the message
+// it holds.
+func WithReorderBuffer(n int) Option {
+	return func(c *config) {
+		if n >= 0 {
+			c.reorder = n
+		}
+	}
+}
+
+// WithParseOptions sets the options messages are hydrated with.
+func WithParseOptions(opts ...rehydrate.Option) Option {
+	return func(c *config) { c.parseOpts = append(c.parseOpts, opts...) }
+}
+
+// WithTransform sets the transform applied to hydrated values.
+func WithTransform(fn TransformFunc) Option {
+	return func(c *config) { c.transform = fn }
+}
+
+// WithMemoryBudget makes every message reserve its estimated memory in b
+// before it is hydrated, waiting while the budget is exhausted. Messages too
+// large for the whole budget fail with ErrOverBudget.
+func WithMemoryBudget(b *MemoryBudget) Option {
+	return func(c *config) { c.budget = b }
+}
+
+// Run hydrates the messages read from in and returns the channel results are
+// emitted on. The channel is closed once in is closed and all messages have
+// been processed, or once ctx is canceled; messages still in flight when ctx
+// is canceled are dropped.
+func Run(ctx context.Context, in <-chan Message, opts ...Option) <-chan Result {
+	c := &config{workers: 4}
+	for _, opt := range opts {
+		opt(c)
+	}
+	out := make(chan Result, c.buffer)
+	if c.ordered {
+		go c.runOrdered(ctx, in, out)
+	} else {
+		go c.run(ctx, in, out)
+	}
+	return out
+}
+
+// FromSeq returns a channel yielding the messages of seq, for use with Run.
+// It stops early when ctx is canceled.
+func FromSeq(ctx context.Context, seq iter.Seq[Message]) <-chan Message {
+	ch := make(chan Message)
+	go func() {
+		defer close(ch)
+		for msg := range seq {
+			select {
+			case ch <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (c *config) process(ctx context.Context, msg Message) (r Result) {
+	if c.stats != nil {
+		start := time.Now()
+		defer func() { c.stats.record(r, time.Since(start)) }()
+	}
+	if c.budget != nil {
+		n := rehydrate.EstimateMemory(msg.Payload)
+		if err := c.budget.Acquire(ctx, n); err != nil {
+			return Result{Message: msg, Err: err}
+		}
+		defer c.budget.Release(n)
+	}
+	v, err := rehydrate.ParseWithOptions(msg.Payload, c.parseOpts...)
+	if err == nil && c.transform != nil {
+		v, err = c.transform(ctx, msg, v)
+	}
+	if err != nil {
+		return Result{Message: msg, Err: err}
+	}
+	return Result{Message: msg, Value: v}
+}
+
+func (c *config) run(ctx context.Context, in <-chan Message, out chan<- Result) {
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				msg, ok := receive(ctx, in)
+				if !ok {
+					return
+				}
+				select {
+				case out <- c.process(ctx, msg):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(out)
+}
+
+type sequenced struct {
+	seq    int
+	result Result
+}
+
+// runOrdered processes messages with c.workers workers but emits them in
+// input order. A slot is taken per message read and released when its result
+// is emitted, bounding the results held back waiting for an earlier one to
+// c.reorder beyond those the workers hold.
+func (c *config) runOrdered(ctx context.Context, in <-chan Message, out chan<- Result) {
+	defer close(out)
+	slots := make(chan struct{}, c.workers+c.reorder)
+	jobs := make(chan sequenced)
+	done := make(chan sequenced)
+
+	go func() {
+		defer close(jobs)
+		for seq := 0; ; seq++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			msg, ok := receive(ctx, in)
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- sequenced{seq, Result{Message: msg}}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				select {
+				case done <- sequenced{job.seq, c.process(ctx, job.result.Message)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	pending := make(map[int]Result)
+	next := 0
+	for r := range done {
+		pending[r.seq] = r.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				for range done {
+				}
+				return
+			}
+			delete(pending, next)
+			next++
+			<-slots
+		}
+	}
+}
+
+func receive(ctx context.Context, in <-chan Message) (Message, bool) {
+	select {
+	case msg, ok := <-in:
+		return msg, ok
+	case <-ctx.Done():
+		return Message{}, false
+	}
+}
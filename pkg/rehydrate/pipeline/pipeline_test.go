@@ -0,0 +1,122 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/pipeline"
+)
+
+func messages(n int) []pipeline.Message {
+	msgs := make([]pipeline.Message, n)
+	for i := range msgs {
+		msgs[i] = pipeline.Message{Payload: fmt.Sprintf(`[{"n":1},%d]`, i), Meta: i}
+	}
+	return msgs
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	msgs := append(messages(20), pipeline.Message{Payload: `[["Widget",0]]`, Meta: -1})
+
+	transform := func(ctx context.Context, msg pipeline.Message, v interface{}) (interface{}, error) {
+		return v.(map[string]interface{})["n"], nil
+	}
+	results := pipeline.Run(ctx, pipeline.FromSeq(ctx, slices.Values(msgs)),
+		pipeline.WithWorkers(3), pipeline.WithTransform(transform))
+
+	var got []int
+	for r := range results {
+		if r.Message.Meta == -1 {
+			if !errors.Is(r.Err, rehydrate.ErrUnknownType) {
+				t.Errorf("expected ErrUnknownType, got %v", r.Err)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if r.Value != float64(r.Message.Meta.(int)) {
+			t.Errorf("message %v: got %v", r.Message.Meta, r.Value)
+		}
+		got = append(got, r.Message.Meta.(int))
+	}
+	sort.Ints(got)
+	if len(got) != 20 || got[0] != 0 || got[19] != 19 {
+		t.Errorf("unexpected results %v", got)
+	}
+}
+
+func TestRunOrdered(t *testing.T) {
+	ctx := context.Background()
+	// Earlier messages take longer, so they finish last unless reordered.
+	transform := func(ctx context.Context, msg pipeline.Message, v interface{}) (interface{}, error) {
+		time.Sleep(time.Duration(10-msg.Meta.(int)) * time.Millisecond)
+		return v, nil
+	}
+	results := pipeline.Run(ctx, pipeline.FromSeq(ctx, slices.Values(messages(10))),
+		pipeline.WithWorkers(4), pipeline.WithOrdered(), pipeline.WithTransform(transform))
+
+	next := 0
+	for r := range results {
+		if r.Message.Meta != next {
+			t.Fatalf("got message %v, want %d", r.Message.Meta, next)
+		}
+		next++
+	}
+	if next != 10 {
+		t.Errorf("got %d results, want 10", next)
+	}
+}
+
+// TestRunOrderedWorkers checks that WithOrdered hydrates no more messages at
+// once than WithWorkers allows, however large the buffers.
+func TestRunOrderedWorkers(t *testing.T) {
+	ctx := context.Background()
+	var running, peak atomic.Int32
+	transform := func(ctx context.Context, msg pipeline.Message, v interface{}) (interface{}, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return v, nil
+	}
+	results := pipeline.Run(ctx, pipeline.FromSeq(ctx, slices.Values(messages(50))),
+		pipeline.WithWorkers(2), pipeline.WithBuffer(20), pipeline.WithReorderBuffer(20),
+		pipeline.WithOrdered(), pipeline.WithTransform(transform))
+
+	next := 0
+	for r := range results {
+		if r.Message.Meta != next {
+			t.Fatalf("got message %v, want %d", r.Message.Meta, next)
+		}
+		next++
+	}
+	if next != 50 {
+		t.Errorf("got %d results, want 50", next)
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d messages hydrated at once, want at most 2", p)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan pipeline.Message)
+	results := pipeline.Run(ctx, in, pipeline.WithOrdered())
+	cancel()
+	for range results {
+	}
+}
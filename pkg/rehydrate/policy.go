@@ -0,0 +1,259 @@
+package rehydrate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Policy controls how the values at the paths matching a WithPathPolicy glob
+// are hydrated.
+type Policy struct {
+	kind    policyKind
+	reviver ReviverFunc
+}
+
+type policyKind int
+
+const (
+	policyDefault policyKind = iota
+	policySkip
+	policyStrict
+	policyLenient
+	policyReviver
+)
+
+var (
+	// PolicySkip leaves matching values unhydrated, as *LazyRef values.
+	PolicySkip = Policy{kind: policySkip}
+	// PolicyStrict fails the parse on any error within matching values. It
+	// is the default and is useful to override a broader PolicyLenient rule.
+	PolicyStrict = Policy{kind: policyStrict}
+	// PolicyLenient replaces values that fail to hydrate with nil instead of
+	// failing the parse.
+	PolicyLenient = Policy{kind: policyLenient}
+)
+
+// PolicyReviver passes matching values to fn after hydrating them and uses
+// its result instead, as if a reviver had been registered for their path.
+func PolicyReviver(fn ReviverFunc) Policy {
+	return Policy{kind: policyReviver, reviver: fn}
+}
+
+// LazyRef stands in for a value skipped by PolicySkip. Revivers may also
+// return it to refer to another entry; see WithResolveRevived.
+type LazyRef struct {
+	// Index is the position of the value in the payload's value table.
+	Index int
+}
+
+// MarshalJSON renders the reference as {"lazyRef": index}.
+func (r *LazyRef) MarshalJSON() ([]byte, error) {
+	return []byte(`{"lazyRef":` + strconv.Itoa(r.Index) + `}`), nil
+}
+
+type pathPolicy struct {
+	glob   []string
+	policy Policy
+}
+
+// WithPathPolicy applies policy to the values whose path matches glob and to
+// everything below them, unless a deeper path matches another rule. Globs
+// are JSON Pointers or paths in the syntax of Path.Dotted, where * matches a
+// single key or index and ** matches any number of them:
+//
+//	rehydrate.WithPathPolicy("/data/analytics", rehydrate.PolicySkip)
+//	rehydrate.WithPathPolicy("/data/products/*", rehydrate.PolicyStrict)
+//	rehydrate.WithPathPolicy("/**/createdAt", rehydrate.PolicyReviver(parseDate))
+//
+// When several rules match the same path, the last one added wins. A value
+// shared by several paths is hydrated once, under the policy of the first
+// path it is reached by.
+func WithPathPolicy(glob string, policy Policy) Option {
+	return func(o *options) {
+		o.pathPolicies = append(o.pathPolicies, pathPolicy{glob: splitPath(glob), policy: policy})
+	}
+}
+
+// splitPath splits a path such as a.b["c d"][0], or a JSON Pointer such as
+// /a/b/c d/0, into its keys and indices.
+func splitPath(path string) []string {
+	if strings.HasPrefix(path, "/") {
+		if p, err := ParsePointer(path); err == nil {
+			return p.segments()
+		}
+	}
+	var segments []string
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := closingBracket(path, i)
+			if end < 0 {
+				end = len(path)
+			}
+			inner := path[i+1 : end]
+			if unquoted, err := strconv.Unquote(inner); err == nil {
+				inner = unquoted
+			}
+			segments = append(segments, inner)
+			i = end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segments = append(segments, path[i:i+end])
+			i += end
+		}
+	}
+	return segments
+}
+
+func matchSegments(glob []string, path []pathSegment) bool {
+	if len(glob) == 0 {
+		return len(path) == 0
+	}
+	if glob[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(glob[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || (glob[0] != "*" && glob[0] != path[0].name) {
+		return false
+	}
+	return matchSegments(glob[1:], path[1:])
+}
+
+func (o *options) matchPolicy(path []pathSegment) (Policy, bool) {
+	for i := len(o.pathPolicies) - 1; i >= 0; i-- {
+		if matchSegments(o.pathPolicies[i].glob, path) {
+			return o.pathPolicies[i].policy, true
+		}
+	}
+	return Policy{}, false
+}
+
+// pathSegment is one step of the path tracked by the hydrator.
+type pathSegment struct {
+	// name is the key or index matched against globs.
+	name string
+	// path is the full path up to and including this step.
+	path string
+}
+
+// currentPath returns the path of the value being hydrated.
+func (h *hydrator) currentPath() string {
+	if len(h.path) == 0 {
+		return ""
+	}
+	return h.path[len(h.path)-1].path
+}
+
+// hydrateRoot hydrates the root value.
+func (h *hydrator) hydrateRoot(index int, standalone bool) (v interface{}, err error) {
+	if standalone || !h.opts.iterative() {
+		v, err = h.hydrate(index, standalone)
+	} else {
+		v, err = h.hydrateIterative(index)
+	}
+	if err == nil && h.opts.coerce != nil {
+		v = h.coerce(index, v)
+	}
+	if err == nil && h.opts.provenance != nil {
+		h.opts.provenance.record("", index)
+	}
+	if err == nil && h.opts.secretScanner != nil {
+		err = h.scanSecret(index)
+	}
+	return v, err
+}
+
+// hydrateKey hydrates the value of an object key.
+func (h *hydrator) hydrateKey(index int, key string) (interface{}, error) {
+	if !h.opts.trackPaths() {
+		return h.hydrate(index, false)
+	}
+	return h.hydrateAt(index, pathSegment{key, keyPath(h.currentPath(), key)})
+}
+
+// hydrateElem hydrates the element at position i of an array or Set.
+func (h *hydrator) hydrateElem(index, i int) (interface{}, error) {
+	if !h.opts.trackPaths() {
+		return h.hydrate(index, false)
+	}
+	return h.hydrateAt(index, pathSegment{strconv.Itoa(i), indexPath(h.currentPath(), i)})
+}
+
+// hydrateMapValue hydrates the value stored under key in a Map.
+func (h *hydrator) hydrateMapValue(index int, key interface{}) (interface{}, error) {
+	if !h.opts.trackPaths() {
+		return h.hydrate(index, false)
+	}
+	name, ok := MapKeyString(key)
+	if !ok {
+		name = "?"
+	}
+	return h.hydrateAt(index, pathSegment{name, mapKeyPath(h.currentPath(), key)})
+}
+
+// hydrateInPlace hydrates a value that has no path of its own and is part of
+// the value being hydrated: the argument of a reviver or TypeHandler, or a
+// Map key. It is scanned for secrets and coerced at the path of that value,
+// whose path policies and provenance apply to it as a whole. Map keys are
+// not reported at the path of their entry, which would include the key.
+func (h *hydrator) hydrateInPlace(index int) (interface{}, error) {
+	v, err := h.hydrate(index, false)
+	if err != nil || !h.opts.trackPaths() {
+		return v, err
+	}
+	if h.opts.secretScanner != nil {
+		if err := h.scanSecret(index); err != nil {
+			return nil, err
+		}
+	}
+	if h.opts.coerce != nil {
+		v = h.coerce(index, v)
+	}
+	return v, nil
+}
+
+// hydrateAt hydrates a child value reached through segment, applying the
+// path policies.
+func (h *hydrator) hydrateAt(index int, segment pathSegment) (interface{}, error) {
+	h.path = append(h.path, segment)
+	defer func() { h.path = h.path[:len(h.path)-1] }()
+
+	policy := h.policy
+	if p, ok := h.opts.matchPolicy(h.path); ok {
+		policy = p
+	}
+	if policy.kind == policySkip && index >= 0 && index < len(h.values) && !h.computed[index] {
+		return &LazyRef{Index: index}, nil
+	}
+
+	parent := h.policy
+	h.policy = policy
+	v, err := h.hydrate(index, false)
+	h.policy = parent
+	if err == nil && h.opts.provenance != nil {
+		h.opts.provenance.record(segment.path, index)
+	}
+	if err == nil && h.opts.secretScanner != nil {
+		err = h.scanSecret(index)
+	}
+
+	if err == nil && h.opts.coerce != nil {
+		v = h.coerce(index, v)
+	}
+	if err == nil && policy.kind == policyReviver {
+		v, err = policy.reviver(v)
+	}
+	if err != nil && policy.kind == policyLenient {
+		return nil, nil
+	}
+	return v, err
+}
@@ -0,0 +1,67 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestPathPolicy(t *testing.T) {
+	input := `[{"data":1},{"analytics":2,"products":4,"extra":7},{"events":3},["Widget",9],[5,6],` +
+		`{"name":9,"when":8},["Widget",9],["Date","bad"],["Date","bad"],"ada"]`
+
+	if _, err := rehydrate.ParseWithOptions(input); err == nil {
+		t.Fatalf("expected the parse without policies to fail, got %v", err)
+	}
+
+	upper := func(v interface{}) (interface{}, error) {
+		m := v.(map[string]interface{})
+		if m["when"] != nil {
+			return nil, errors.New("when should have been dropped")
+		}
+		return strings.ToUpper(m["name"].(string)), nil
+	}
+	v, err := rehydrate.ParseWithOptions(input,
+		rehydrate.WithPathPolicy("data.**", rehydrate.PolicyLenient),
+		rehydrate.WithPathPolicy("data.analytics", rehydrate.PolicySkip),
+		rehydrate.WithPathPolicy("data.products[0]", rehydrate.PolicyReviver(upper)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := v.(map[string]interface{})["data"].(map[string]interface{})
+	want := map[string]interface{}{
+		"analytics": &rehydrate.LazyRef{Index: 2},
+		"products":  []interface{}{"ADA", nil},
+		"extra":     nil,
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got %#v\nwant %#v", data, want)
+	}
+
+	// A strict rule below a lenient one lets the failure escape to the
+	// nearest lenient ancestor.
+	v, err = rehydrate.ParseWithOptions(input,
+		rehydrate.WithPathPolicy("data.*", rehydrate.PolicyLenient),
+		rehydrate.WithPathPolicy("data.analytics", rehydrate.PolicySkip),
+		rehydrate.WithPathPolicy("data.products.*", rehydrate.PolicyStrict),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = v.(map[string]interface{})["data"].(map[string]interface{})
+	if data["products"] != nil {
+		t.Errorf("products = %v, want nil", data["products"])
+	}
+
+	_, err = rehydrate.ParseWithOptions(input,
+		rehydrate.WithPathPolicy("data.analytics", rehydrate.PolicySkip),
+		rehydrate.WithPathPolicy("data.products", rehydrate.PolicyStrict),
+	)
+	if err == nil {
+		t.Error("expected the strict parse to fail")
+	}
+}
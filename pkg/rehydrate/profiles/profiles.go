@@ -0,0 +1,146 @@
+// Package profiles selects revivers and parse options by host name, for
+// tools that process payloads from many sites, each with its own custom tags:
+//
+//	p := profiles.New()
+//	p.Add("*", profiles.Profile{Options: []rehydrate.Option{rehydrate.WithTimeBudget(time.Second)}})
+//	p.Add("*.example.com", profiles.Profile{Revivers: exampleRevivers})
+//	p.Add("shop.example.com", profiles.Profile{Revivers: shopRevivers})
+//
+//	v, err := rehydrate.ParseWithOptions(payload, p.For("shop.example.com").ParseOptions()...)
+//
+// Every profile whose pattern matches a host applies, from the least to the
+// most specific, so a site profile only needs to describe how the site
+// differs from the broader ones.
+package profiles
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// Profile is the configuration used for payloads from matching hosts.
+type Profile struct {
+	// Name identifies the profile in logs. For merges it lists the names
+	// of the applied profiles.
+	Name     string
+	Revivers rehydrate.Revivers
+	// Options are passed to the parse after the revivers, for example
+	// limits and key transforms.
+	Options []rehydrate.Option
+}
+
+// ParseOptions returns the options that apply the profile.
+func (p Profile) ParseOptions() []rehydrate.Option {
+	return append([]rehydrate.Option{rehydrate.WithRevivers(p.Revivers)}, p.Options...)
+}
+
+// Profiles maps host patterns to profiles. It is safe for concurrent use, so
+// profiles can be added while payloads are processed.
+type Profiles struct {
+	mu    sync.RWMutex
+	rules []rule
+}
+
+type rule struct {
+	pattern string
+	profile Profile
+}
+
+// New returns an empty set of profiles.
+func New() *Profiles {
+	return &Profiles{}
+}
+
+// Add registers profile for hosts matching pattern, replacing any profile
+// registered for the same pattern. A pattern is a host name, *.domain to
+// match every subdomain of domain, or * to match every host.
+func (p *Profiles) Add(pattern string, profile Profile) error {
+	pattern = strings.ToLower(pattern)
+	if pattern == "" || strings.Contains(strings.TrimPrefix(pattern, "*"), "*") ||
+		(strings.HasPrefix(pattern, "*") && pattern != "*" && !strings.HasPrefix(pattern, "*.")) {
+		return fmt.Errorf("profiles: invalid pattern %q", pattern)
+	}
+	if profile.Name == "" {
+		profile.Name = pattern
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, r := range p.rules {
+		if r.pattern == pattern {
+			p.rules[i].profile = profile
+			return nil
+		}
+	}
+	p.rules = append(p.rules, rule{pattern, profile})
+	sort.SliceStable(p.rules, func(i, j int) bool {
+		return specificity(p.rules[i].pattern) < specificity(p.rules[j].pattern)
+	})
+	return nil
+}
+
+// specificity orders patterns from * through wildcard domains, shortest
+// first, to exact host names.
+func specificity(pattern string) int {
+	switch {
+	case pattern == "*":
+		return 0
+	case strings.HasPrefix(pattern, "*."):
+		return len(pattern)
+	}
+	return 1 << 16
+}
+
+func (r rule) matches(host string) bool {
+	switch {
+	case r.pattern == "*":
+		return true
+	case strings.HasPrefix(r.pattern, "*."):
+		return strings.HasSuffix(host, r.pattern[1:])
+	}
+	return host == r.pattern
+}
+
+// For returns the merge of every profile matching host, which may include a
+// port. Revivers of more specific profiles replace those registered for the
+// same tag by broader ones, and options are applied from the broadest
+// profile to the most specific. A host matching nothing gets an empty
+// profile.
+func (p *Profiles) For(host string) Profile {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	merged := Profile{Revivers: rehydrate.Revivers{}}
+	var names []string
+	for _, r := range p.rules {
+		if !r.matches(host) {
+			continue
+		}
+		names = append(names, r.profile.Name)
+		for tag, fn := range r.profile.Revivers {
+			merged.Revivers[tag] = fn
+		}
+		merged.Options = append(merged.Options, r.profile.Options...)
+	}
+	merged.Name = strings.Join(names, "+")
+	return merged
+}
+
+// ForURL is like For for the host of rawURL.
+func (p *Profiles) ForURL(rawURL string) (Profile, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Profile{}, err
+	}
+	return p.For(u.Host), nil
+}
@@ -0,0 +1,55 @@
+package profiles_test
+
+import (
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/profiles"
+)
+
+func constant(v interface{}) rehydrate.ReviverFunc {
+	return func(interface{}) (interface{}, error) { return v, nil }
+}
+
+func TestProfiles(t *testing.T) {
+	p := profiles.New()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(p.Add("shop.example.com", profiles.Profile{Revivers: rehydrate.Revivers{"Price": constant("shop")}}))
+	must(p.Add("*.example.com", profiles.Profile{Revivers: rehydrate.Revivers{"Price": constant("example"), "Ref": constant("ref")}}))
+	must(p.Add("*", profiles.Profile{Name: "base", Options: []rehydrate.Option{rehydrate.WithKeyTransform(rehydrate.SnakeCase)}}))
+
+	shop := p.For("Shop.Example.com:443")
+	if shop.Name != "base+*.example.com+shop.example.com" {
+		t.Errorf("unexpected profile name %q", shop.Name)
+	}
+	v, err := rehydrate.ParseWithOptions(`[{"unitPrice":1,"ref":2},["Price",3],["Ref",3],0]`, shop.ParseOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	if root["unit_price"] != "shop" || root["ref"] != "ref" {
+		t.Errorf("unexpected result %v", root)
+	}
+
+	other, err := p.ForURL("https://blog.example.com/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price, _ := other.Revivers["Price"](nil); price != "example" {
+		t.Errorf("unexpected Price reviver result %v", price)
+	}
+	if len(p.For("example.org").Revivers) != 0 {
+		t.Error("expected no revivers for an unmatched host")
+	}
+
+	for _, bad := range []string{"", "shop.*.com", "*example.com", "**"} {
+		if err := p.Add(bad, profiles.Profile{}); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
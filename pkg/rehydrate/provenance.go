@@ -0,0 +1,87 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Origin locates a hydrated value in the serialized payload.
+type Origin struct {
+	// Index is the value-table entry the value was hydrated from, or one of
+	// the negative sentinels such as UNDEFINED.
+	Index int `json:"index"`
+	// Start and End are the byte offsets of the entry in the payload, or -1
+	// for sentinels and for tables given to ParseValues.
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Provenance records where the values of a parse come from, so error
+// reports and diffs can point back to exact positions in the raw payload.
+// See WithProvenance.
+type Provenance struct {
+	offsets [][2]int
+	origins map[string]Origin
+}
+
+// WithProvenance records the origin of every value reached by a key or index
+// in p, replacing what p held. Tracking costs an entry per path, so it is
+// meant for debugging rather than for every parse.
+func WithProvenance(p *Provenance) Option {
+	return func(o *options) {
+		o.provenance = p
+	}
+}
+
+// Of returns the origin of the value at path, a JSON Pointer or a path in
+// the syntax of Path.Dotted. It reports false for paths that were not
+// hydrated.
+func (p *Provenance) Of(path string) (Origin, bool) {
+	pointer, err := pointerPath(path)
+	if err != nil {
+		return Origin{}, false
+	}
+	origin, ok := p.origins[pointer]
+	return origin, ok
+}
+
+// Len returns the number of paths recorded.
+func (p *Provenance) Len() int {
+	return len(p.origins)
+}
+
+func (p *Provenance) reset(offsets [][2]int) {
+	p.offsets = offsets
+	p.origins = make(map[string]Origin)
+}
+
+func (p *Provenance) record(path string, index int) {
+	origin := Origin{Index: index, Start: -1, End: -1}
+	if index >= 0 && index < len(p.offsets) {
+		origin.Start, origin.End = p.offsets[index][0], p.offsets[index][1]
+	}
+	p.origins[path] = origin
+}
+
+// entryOffsets returns the byte offsets of the entries of a value table.
+func entryOffsets(serialized string) ([][2]int, error) {
+	dec := json.NewDecoder(strings.NewReader(serialized))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var offsets [][2]int
+	for dec.More() {
+		start := int(dec.InputOffset())
+		// InputOffset points before the separator and whitespace preceding
+		// the entry.
+		rest := bytes.TrimLeft([]byte(serialized[start:]), ", \t\r\n")
+		start = len(serialized) - len(rest)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, [2]int{start, int(dec.InputOffset())})
+	}
+	return offsets, nil
+}
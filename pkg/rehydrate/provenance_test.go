@@ -0,0 +1,47 @@
+package rehydrate_test
+
+import (
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestWithProvenance(t *testing.T) {
+	payload := `[{"user":1,"tags":3,"gone":-1}, {"name":2},
+	"Ada",[2,2]]`
+
+	var p rehydrate.Provenance
+	if _, err := rehydrate.ParseWithOptions(payload, rehydrate.WithProvenance(&p)); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"":          `{"user":1,"tags":3,"gone":-1}`,
+		"user":      `{"name":2}`,
+		"user.name": `"Ada"`,
+		"tags":      `[2,2]`,
+		"tags[1]":   `"Ada"`,
+	} {
+		origin, ok := p.Of(path)
+		if !ok {
+			t.Errorf("%q: no origin", path)
+			continue
+		}
+		if got := payload[origin.Start:origin.End]; got != want {
+			t.Errorf("%q: got %s at index %d, want %s", path, got, origin.Index, want)
+		}
+	}
+	if origin, ok := p.Of("gone"); !ok || origin.Index != rehydrate.UNDEFINED || origin.Start != -1 {
+		t.Errorf("sentinel: got %+v, %v", origin, ok)
+	}
+	if _, ok := p.Of("missing"); ok || p.Len() != 7 {
+		t.Errorf("unexpected origins, %d recorded", p.Len())
+	}
+
+	// A later parse replaces the recorded origins.
+	if _, err := rehydrate.ParseValues([]interface{}{[]interface{}{1.0}, "x"}, nil, rehydrate.WithProvenance(&p)); err != nil {
+		t.Fatal(err)
+	}
+	if origin, ok := p.Of("[0]"); !ok || origin.Index != 1 || origin.Start != -1 || p.Len() != 2 {
+		t.Errorf("ParseValues: got %+v, %d recorded", origin, p.Len())
+	}
+}
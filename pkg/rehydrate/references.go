@@ -0,0 +1,194 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// References returns the reference graph of a serialized payload's value
+// table. Each key is the index of a value that is referenced by another entry
+// and maps to the sorted indices of the entries referencing it. Values
+// appearing under more than one referrer are shared; indices missing from the
+// map (other than the root 0) are never referenced.
+func References(serialized string) (map[int][]int, error) {
+	values, err := unmarshalTable(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[int][]int)
+	for i, value := range values {
+		children, err := childRefs(value)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		seen := make(map[int]bool, len(children))
+		for _, child := range children {
+			if child >= len(values) {
+				return nil, fmt.Errorf("%w: index %d out of range", ErrBadReference, child)
+			}
+			if seen[child] {
+				continue
+			}
+			seen[child] = true
+			refs[child] = append(refs[child], i)
+		}
+	}
+	for _, from := range refs {
+		sort.Ints(from)
+	}
+	return refs, nil
+}
+
+// unmarshalTable decodes the value table of a serialized payload. A payload
+// consisting of a single sentinel number yields an empty table.
+func unmarshalTable(serialized string) ([]interface{}, error) {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(serialized), &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if _, ok := parsed.(float64); ok {
+		return nil, nil
+	}
+	values, ok := parsed.([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, ErrInvalidInput
+	}
+	return values, nil
+}
+
+// childRefs returns the value-table indices referenced by a raw table entry,
+// in the order they appear. Sentinels such as HOLE or UNDEFINED are omitted.
+func childRefs(value interface{}) ([]int, error) {
+	positions := refPositions(value)
+	if positions == nil {
+		return nil, nil
+	}
+	refs := make([]int, 0, len(positions))
+	for _, p := range positions {
+		index, err := toInt(p)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 {
+			continue
+		}
+		refs = append(refs, index)
+	}
+	return refs, nil
+}
+
+// refPositions returns the raw references held by a table entry, in the
+// order childRefs reports them.
+func refPositions(value interface{}) []interface{} {
+	var positions []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		typeStr, tagged := "", false
+		if len(v) > 0 {
+			typeStr, tagged = v[0].(string)
+		}
+		for _, slot := range refSlots(typeStr, tagged, len(v)) {
+			positions = append(positions, v[slot])
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			positions = append(positions, v[key])
+		}
+	}
+	return positions
+}
+
+// refSlots returns the positions within an array entry of the given length
+// that hold value-table references. tagged reports whether the first element
+// is a type tag.
+func refSlots(typeStr string, tagged bool, length int) []int {
+	var slots []int
+	if !tagged {
+		for i := 0; i < length; i++ {
+			slots = append(slots, i)
+		}
+		return slots
+	}
+
+	tag, builtin := ParseTag(typeStr)
+	switch {
+	case !builtin:
+		if length > 1 {
+			slots = append(slots, 1)
+		}
+	case tag == TagSet, tag == TagMap:
+		for i := 1; i < length; i++ {
+			slots = append(slots, i)
+		}
+	case tag == TagNull:
+		for i := 2; i < length; i += 2 {
+			slots = append(slots, i)
+		}
+	}
+	return slots
+}
+
+type tableChild struct {
+	index int
+	path  string
+}
+
+// tableChildren returns the references held by the value-table entry at index
+// together with their paths, given the entry's own path. Sentinels are
+// included as negative indices; references that are not indices or are out
+// of range are skipped.
+func tableChildren(values []interface{}, index int, path string) []tableChild {
+	var out []tableChild
+	add := func(ref interface{}, childPath string) {
+		i, err := toInt(ref)
+		if err != nil || i < NEGATIVE_ZERO || i >= len(values) {
+			return
+		}
+		out = append(out, tableChild{i, childPath})
+	}
+
+	switch v := values[index].(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			add(v[key], keyPath(path, key))
+		}
+	case []interface{}:
+		typeStr, tagged := "", false
+		if len(v) > 0 {
+			typeStr, tagged = v[0].(string)
+		}
+		tag, builtin := ParseTag(typeStr)
+		switch {
+		case !tagged:
+			for i, item := range v {
+				add(item, indexPath(path, i))
+			}
+		case tag == TagSet && builtin:
+			for i := 1; i < len(v); i++ {
+				add(v[i], indexPath(path, i-1))
+			}
+		case tag == TagMap && builtin:
+			for i := 1; i+1 < len(v); i += 2 {
+				entryPath := path + "[?]"
+				if k, err := toInt(v[i]); err == nil && k >= 0 && k < len(values) {
+					entryPath = mapKeyPath(path, values[k])
+				}
+				add(v[i], entryPath+"#key")
+				add(v[i+1], entryPath)
+			}
+		case tag == TagNull && builtin:
+			for i := 1; i+1 < len(v); i += 2 {
+				if key, ok := v[i].(string); ok {
+					add(v[i+1], keyPath(path, key))
+				}
+			}
+		default:
+			for _, slot := range refSlots(typeStr, tagged, len(v)) {
+				add(v[slot], path)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,24 @@
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestReferences(t *testing.T) {
+	input := `[{"a":1,"b":2,"c":-1},[3,-2,3],["Set",3],"shared",["Date","2024-01-02T00:00:00Z"]]`
+	refs, err := rehydrate.References(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int][]int{
+		1: {0},
+		2: {0},
+		3: {1, 2},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Fatalf("got %v, want %v", refs, want)
+	}
+}
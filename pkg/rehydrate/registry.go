@@ -0,0 +1,114 @@
+package rehydrate
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds a set of default revivers and type handlers shared between
+// calls, typically configured once at start-up and passed to every parse
+// with WithRegistry.
+//
+// A Registry is safe for concurrent use. Updates copy the underlying map
+// instead of mutating it, so parses that already started keep the snapshot
+// they were given.
+type Registry struct {
+	mu       sync.Mutex
+	revivers atomic.Pointer[Revivers]
+	types    atomic.Pointer[map[string]TypeHandler]
+}
+
+// NewRegistry returns a registry pre-populated with a copy of revivers.
+func NewRegistry(revivers Revivers) *Registry {
+	r := &Registry{}
+	snapshot := make(Revivers, len(revivers))
+	for tag, reviver := range revivers {
+		snapshot[tag] = reviver
+	}
+	r.revivers.Store(&snapshot)
+	return r
+}
+
+// Register adds or replaces the reviver for tag.
+func (r *Registry) Register(tag string, reviver ReviverFunc) {
+	r.update(func(revivers Revivers) {
+		revivers[tag] = reviver
+	})
+}
+
+// Unregister removes the reviver for tag.
+func (r *Registry) Unregister(tag string) {
+	r.update(func(revivers Revivers) {
+		delete(revivers, tag)
+	})
+}
+
+// Lookup returns the reviver registered for tag.
+func (r *Registry) Lookup(tag string) (ReviverFunc, bool) {
+	reviver, ok := r.load()[tag]
+	return reviver, ok
+}
+
+// Revivers returns a copy of the registered revivers.
+func (r *Registry) Revivers() Revivers {
+	current := r.load()
+	revivers := make(Revivers, len(current))
+	for tag, reviver := range current {
+		revivers[tag] = reviver
+	}
+	return revivers
+}
+
+// RegisterType adds or replaces the type handler for tag.
+func (r *Registry) RegisterType(tag string, handler TypeHandler) {
+	r.updateTypes(func(types map[string]TypeHandler) {
+		types[tag] = handler
+	})
+}
+
+// UnregisterType removes the type handler for tag.
+func (r *Registry) UnregisterType(tag string) {
+	r.updateTypes(func(types map[string]TypeHandler) {
+		delete(types, tag)
+	})
+}
+
+func (r *Registry) load() Revivers {
+	if r == nil {
+		return nil
+	}
+	if p := r.revivers.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+func (r *Registry) update(fn func(Revivers)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := r.Revivers()
+	fn(next)
+	r.revivers.Store(&next)
+}
+
+func (r *Registry) loadTypes() map[string]TypeHandler {
+	if r == nil {
+		return nil
+	}
+	if p := r.types.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+func (r *Registry) updateTypes(fn func(map[string]TypeHandler)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.loadTypes()
+	next := make(map[string]TypeHandler, len(current)+1)
+	for tag, handler := range current {
+		next[tag] = handler
+	}
+	fn(next)
+	r.types.Store(&next)
+}
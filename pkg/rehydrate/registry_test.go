@@ -0,0 +1,56 @@
+package rehydrate_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestRegistryOverlay(t *testing.T) {
+	registry := rehydrate.NewRegistry(rehydrate.Revivers{
+		"Tag": func(interface{}) (interface{}, error) { return "registry", nil },
+	})
+	input := `[["Tag",1],0]`
+
+	got, err := rehydrate.ParseWithOptions(input, rehydrate.WithRegistry(registry))
+	if err != nil || got != "registry" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+
+	got, err = rehydrate.ParseWithOptions(input,
+		rehydrate.WithRegistry(registry),
+		rehydrate.WithRevivers(rehydrate.Revivers{
+			"Tag": func(interface{}) (interface{}, error) { return "call", nil },
+		}),
+	)
+	if err != nil || got != "call" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+}
+
+func TestRegistryConcurrentUse(t *testing.T) {
+	registry := rehydrate.NewRegistry(nil)
+	registry.Register("Tag", func(v interface{}) (interface{}, error) { return v, nil })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			registry.Register(fmt.Sprintf("Extra%d", i), func(v interface{}) (interface{}, error) { return v, nil })
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := rehydrate.ParseWithOptions(`[["Tag",1],"x"]`, rehydrate.WithRegistry(registry)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(registry.Revivers()) != 9 {
+		t.Fatalf("expected 9 revivers, got %d", len(registry.Revivers()))
+	}
+}
@@ -1,14 +1,13 @@
 package rehydrate
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
-	"math/big"
-	"regexp"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,341 +22,789 @@ const (
 
 type ReviverFunc func(interface{}) (interface{}, error)
 
+// Parse hydrates a serialized payload, consulting revivers for custom type
+// tags. The revivers map is copied when the call starts and is not retained.
 func Parse(serialized string, revivers map[string]ReviverFunc) (interface{}, error) {
+	return ParseWithOptions(serialized, WithRevivers(revivers))
+}
+
+// ParseWithOptions hydrates a serialized payload using the behaviour
+// configured by opts.
+func ParseWithOptions(serialized string, opts ...Option) (interface{}, error) {
+	return parse(serialized, newOptions(opts))
+}
+
+// ParseValues hydrates a value table that was already decoded, for example
+// as part of a larger JSON document, saving a round trip through its
+// serialized form. values must hold what encoding/json decodes into an
+// interface{}. Options that inspect the serialized text, such as
+// WithUTF16Strings or WithDuplicateKeys, have no effect.
+func ParseValues(values []interface{}, revivers Revivers, opts ...Option) (result interface{}, err error) {
+	o := newOptions(append([]Option{WithRevivers(revivers)}, opts...))
+	if o.recoverPanics {
+		defer o.recoverPanic(&result, &err)
+	}
+	if len(values) == 0 {
+		return nil, ErrInvalidInput
+	}
+	if o.provenance != nil {
+		o.provenance.reset(nil)
+	}
+	return newHydrator(o).hydrateTable(values)
+}
+
+// recoverPanic turns a panic during a parse into an error, for
+// WithPanicRecovery.
+func (o *options) recoverPanic(result *interface{}, err *error) {
+	if r := recover(); r != nil {
+		*result, *err = nil, fmt.Errorf("%w: recovered from panic: %v", ErrInvalidInput, r)
+	}
+}
+
+func parse(serialized string, o *options) (result interface{}, err error) {
+	if o.recoverPanics {
+		defer o.recoverPanic(&result, &err)
+	}
+	h := newHydrator(o)
+
 	var parsed interface{}
 	if err := json.Unmarshal([]byte(serialized), &parsed); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if o.provenance != nil {
+		offsets, err := entryOffsets(serialized)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		o.provenance.reset(offsets)
 	}
-
-	var hydrate func(index int, standalone bool, values []interface{}, computed []bool, revivers map[string]ReviverFunc) (interface{}, error)
 
 	if num, ok := parsed.(float64); ok {
-		return hydrate(int(num), true, nil, nil, revivers)
+		index, err := toInt(num)
+		if err != nil {
+			return nil, err
+		}
+		return h.hydrateRoot(index, true)
 	}
 
 	values, ok := parsed.([]interface{})
 	if !ok || len(values) == 0 {
-		return nil, errors.New("invalid input")
+		return nil, ErrInvalidInput
 	}
 
-	hydrated := make([]interface{}, len(values))
-	computed := make([]bool, len(values))
+	if o.utf16Strings {
+		if err := restoreLoneSurrogates(serialized, values); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyNumberMode(serialized, values, o.numberMode); err != nil {
+		return nil, err
+	}
+	if o.duplicateKeys != KeepLastDuplicateKey {
+		if err := checkDuplicateKeys(serialized, o); err != nil {
+			return nil, err
+		}
+	}
+	if len(serialized) <= fastPathMaxSize && o.fastPath() {
+		if v, ok := hydrateFast(values); ok {
+			return v, nil
+		}
+	}
+	return h.hydrateTable(values)
+}
 
-	hydrate = func(index int, standalone bool, values []interface{}, computed []bool, revivers map[string]ReviverFunc) (interface{}, error) {
-		switch index {
-		case UNDEFINED:
-			return nil, nil
-		case NAN:
-			return math.NaN(), nil
-		case POSITIVE_INFINITY:
-			return math.Inf(1), nil
-		case NEGATIVE_INFINITY:
-			return math.Inf(-1), nil
-		case NEGATIVE_ZERO:
-			return math.Copysign(0, -1), nil
+func newHydrator(o *options) *hydrator {
+	h := &hydrator{opts: o}
+	if o.timeBudget > 0 {
+		h.deadline = o.clock.Now().Add(o.timeBudget)
+	}
+	return h
+}
+
+// hydrateTable hydrates the root of a decoded value table.
+func (h *hydrator) hydrateTable(values []interface{}) (interface{}, error) {
+	if h.opts.strictRefs {
+		if err := checkReferences(values); err != nil {
+			return nil, err
 		}
+	}
+	h.values = values
+	h.hydrated = make([]interface{}, len(values))
+	h.computed = make([]bool, len(values))
+	return h.hydrateRoot(0, false)
+}
+
+type hydrator struct {
+	values   []interface{}
+	hydrated []interface{}
+	computed []bool
+	opts     *options
+
+	deadline time.Time
+	steps    int
+	depth    int
+
+	// path and policy track the position and effective policy while
+	// WithPathPolicy rules, an audit hook or a coercion are in use.
+	path   []pathSegment
+	policy Policy
+
+	// reviving holds the entries whose reviver is running while
+	// WithResolveRevived is in use.
+	reviving map[int]bool
+	// reviverArgs holds the entries whose reviver argument hydrate is
+	// hydrating, so arguments referring back to their entry fail.
+	reviverArgs map[int]bool
+}
+
+// budgetCheckInterval is the number of values hydrated between checks of the
+// time budget, to keep the cost of reading the clock negligible.
+const budgetCheckInterval = 256
 
-		if standalone {
-			return nil, errors.New("invalid input")
+func (h *hydrator) checkBudget() error {
+	if h.deadline.IsZero() {
+		return nil
+	}
+	h.steps++
+	if h.steps%budgetCheckInterval == 0 && h.opts.clock.Now().After(h.deadline) {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+func (h *hydrator) hydrate(index int, standalone bool) (interface{}, error) {
+	if v, done, err := h.lookup(index, standalone); done {
+		return v, err
+	}
+	if limit := h.recursiveDepth(); h.depth >= limit {
+		return nil, fmt.Errorf("%w: values nested more than %d levels deep", ErrLimitExceeded, limit)
+	}
+	h.depth++
+	defer func() { h.depth-- }()
+
+	value := h.values[index]
+	if v, ok := h.scalar(index, value); ok {
+		return v, nil
+	}
+
+	if arr, ok := value.([]interface{}); ok {
+		if len(arr) > 0 {
+			if typeStr, ok := arr[0].(string); ok {
+				return h.hydrateTagged(index, typeStr, arr)
+			}
+		}
+		if n := h.opts.sampleSize; n > 0 && len(arr) > n {
+			return h.hydrateSample(index, arr)
 		}
+		arrResult := make([]interface{}, len(arr))
+		h.store(index, arrResult)
+		for i, item := range arr {
+			itemIndex, err := toInt(item)
+			if err != nil {
+				return nil, err
+			}
+			if itemIndex == HOLE {
+				continue
+			}
+			elem, err := h.hydrateElem(itemIndex, i)
+			if err != nil {
+				return nil, err
+			}
+			arrResult[i] = elem
+		}
+		return arrResult, nil
+	}
 
-		if computed[index] {
-			return hydrated[index], nil
+	if obj, ok := value.(map[string]interface{}); ok {
+		result := make(map[string]interface{})
+		h.store(index, result)
+		for _, key := range h.objectKeys(obj) {
+			val := obj[key]
+			key, keep := h.objectKey(key)
+			if !keep {
+				continue
+			}
+			if _, dup := result[key]; dup {
+				continue
+			}
+			valIndex, err := toInt(val)
+			if err != nil {
+				return nil, err
+			}
+			hVal, err := h.hydrateKey(valIndex, key)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = hVal
 		}
+		return result, nil
+	}
 
-		value := values[index]
+	return nil, fmt.Errorf("%w: unknown value type at index %d", ErrInvalidInput, index)
+}
 
-		switch v := value.(type) {
-		case nil, bool, float64, string:
-			hydrated[index] = v
-			computed[index] = true
-			return v, nil
+// lookup resolves references that need no hydration: sentinels, invalid
+// references and entries hydrated before. It reports false if the entry at
+// index remains to be hydrated.
+func (h *hydrator) lookup(index int, standalone bool) (interface{}, bool, error) {
+	switch index {
+	case UNDEFINED:
+		return nil, true, nil
+	case NAN:
+		return math.NaN(), true, nil
+	case POSITIVE_INFINITY:
+		return math.Inf(1), true, nil
+	case NEGATIVE_INFINITY:
+		return math.Inf(-1), true, nil
+	case NEGATIVE_ZERO:
+		return math.Copysign(0, -1), true, nil
+	}
+	if index < NEGATIVE_ZERO {
+		return nil, true, &ErrUnsupportedSentinel{Value: index}
+	}
+
+	if standalone {
+		return nil, true, ErrInvalidInput
+	}
+
+	if index < 0 || index >= len(h.values) {
+		return nil, true, fmt.Errorf("%w: index %d out of range", ErrBadReference, index)
+	}
+
+	if h.computed[index] {
+		return h.hydrated[index], true, nil
+	}
+	if err := h.checkBudget(); err != nil {
+		return nil, true, err
+	}
+	return nil, false, nil
+}
+
+// defaultRecursiveDepth bounds the nesting the recursive engine hydrates,
+// whatever WithMaxDepth allows, so deep payloads fail with an error
+// instead of exhausting the goroutine stack, which cannot be recovered. It
+// matches the nesting encoding/json accepts.
+const defaultRecursiveDepth = 10000
+
+// errOutputTooDeep reports a value nested deeper than encoding/json renders.
+var errOutputTooDeep = fmt.Errorf("%w: output nested more than %d levels deep, the limit of encoding/json", ErrLimitExceeded, defaultRecursiveDepth)
+
+// recursiveDepth returns the nesting limit of hydrate, which WithMaxDepth
+// may lower but not raise beyond defaultRecursiveDepth.
+func (h *hydrator) recursiveDepth() int {
+	if h.opts.maxDepth > 0 {
+		return min(h.opts.maxDepth, defaultRecursiveDepth)
+	}
+	return defaultRecursiveDepth
+}
+
+// checkDepth fails once the value being hydrated is nested depth levels
+// below the root and depth reaches the limit set by WithMaxDepth.
+func (h *hydrator) checkDepth(depth int) error {
+	if h.opts.maxDepth > 0 && depth >= h.opts.maxDepth {
+		return fmt.Errorf("%w: values nested more than %d levels deep", ErrLimitExceeded, h.opts.maxDepth)
+	}
+	return nil
+}
+
+// scalar hydrates the entry at index if value, its content, is a scalar.
+func (h *hydrator) scalar(index int, value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		s := h.opts.truncateString(v)
+		h.store(index, s)
+		return s, true
+	case nil, bool, float64, json.Number, int64, UTF16String:
+		h.store(index, v)
+		return v, true
+	}
+	return nil, false
+}
+
+func (h *hydrator) hydrateTagged(index int, typeStr string, arr []interface{}) (interface{}, error) {
+	if h.opts.deniedTags[typeStr] {
+		return nil, typeError(typeStr, index, fmt.Errorf("%w: denied tag", ErrInvalidInput))
+	}
+	if reviver, exists := h.opts.reviver(typeStr); exists {
+		if len(arr) < 2 {
+			return nil, typeError(typeStr, index, ErrInvalidInput)
+		}
+		argIndex, err := toInt(arr[1])
+		if err != nil {
+			return nil, err
+		}
+		if h.reviverArgs[index] {
+			return nil, fmt.Errorf("%w: argument of the %s at index %d refers back to it", ErrBadReference, typeStr, index)
+		}
+		if h.reviverArgs == nil {
+			h.reviverArgs = make(map[int]bool)
+		}
+		h.reviverArgs[index] = true
+		innerVal, err := h.hydrateInPlace(argIndex)
+		delete(h.reviverArgs, index)
+		if err != nil {
+			return nil, err
+		}
+		if h.opts.resolveRevived {
+			if h.reviving == nil {
+				h.reviving = make(map[int]bool)
+			}
+			h.reviving[index] = true
+		}
+		res, err := h.reviveChecked(reviver, typeStr, index, innerVal)
+		if h.opts.resolveRevived {
+			if err == nil {
+				res, err = h.resolveRevived(innerVal, res)
+			}
+			delete(h.reviving, index)
+		}
+		if err == nil {
+			err = h.validateRevived(typeStr, index, innerVal, res)
+		}
+		if err != nil {
+			return nil, typeError(typeStr, index, err)
+		}
+		h.store(index, res)
+		return res, nil
+	}
+	if handler, ok := h.opts.typeHandler(typeStr); ok {
+		return h.hydrateHandled(handler, index, typeStr, arr)
+	}
+	return h.hydrateBuiltin(index, typeStr, arr)
+}
+
+// hydrateBuiltin hydrates a tagged entry of one of the built-in types.
+func (h *hydrator) hydrateBuiltin(index int, typeStr string, arr []interface{}) (interface{}, error) {
+	tag, _ := ParseTag(typeStr)
+	switch tag {
+	case TagDate:
+		if len(arr) < 2 {
+			return nil, typeError(typeStr, index, ErrInvalidInput)
+		}
+		dateStr, ok := arr[1].(string)
+		if !ok {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: invalid Date format", ErrInvalidInput))
 		}
+		t, err := time.Parse(time.RFC3339, dateStr)
+		if err != nil {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: %w", ErrInvalidInput, err))
+		}
+		h.store(index, t)
+		return t, nil
 
-		if arr, ok := value.([]interface{}); ok {
-			if len(arr) > 0 {
-				if typeStr, ok := arr[0].(string); ok {
-					if revivers != nil {
-						if reviver, exists := revivers[typeStr]; exists {
-							innerVal, err := hydrate(getInt(arr, 1), false, values, computed, revivers)
-							if err != nil {
-								return nil, err
-							}
-							res, err := reviver(innerVal)
-							if err != nil {
-								return nil, err
-							}
-							hydrated[index] = res
-							computed[index] = true
-							return res, nil
-						}
-					}
-
-					switch typeStr {
-					case "Date":
-						dateStr, ok := arr[1].(string)
-						if !ok {
-							return nil, errors.New("invalid Date format")
-						}
-						t, err := time.Parse(time.RFC3339, dateStr)
-						if err != nil {
-							return nil, err
-						}
-						hydrated[index] = t
-						computed[index] = true
-						return t, nil
-
-					case "Set":
-						set := make(map[interface{}]struct{})
-						hydrated[index] = set
-						computed[index] = true
-						for i := 1; i < len(arr); i++ {
-							elemIndex, err := toInt(arr[i])
-							if err != nil {
-								return nil, err
-							}
-							elem, err := hydrate(elemIndex, false, values, computed, revivers)
-							if err != nil {
-								return nil, err
-							}
-							set[elem] = struct{}{}
-						}
-						return set, nil
-
-					case "Map":
-						m := make(map[interface{}]interface{})
-						hydrated[index] = m
-						computed[index] = true
-						for i := 1; i < len(arr); i += 2 {
-							keyIndex, err := toInt(arr[i])
-							if err != nil {
-								return nil, err
-							}
-							valIndex, err := toInt(arr[i+1])
-							if err != nil {
-								return nil, err
-							}
-							key, err := hydrate(keyIndex, false, values, computed, revivers)
-							if err != nil {
-								return nil, err
-							}
-							val, err := hydrate(valIndex, false, values, computed, revivers)
-							if err != nil {
-								return nil, err
-							}
-							m[key] = val
-						}
-						return m, nil
-
-					case "RegExp":
-						pattern, ok1 := arr[1].(string)
-						_, ok2 := arr[2].(string)
-						if !ok1 || !ok2 {
-							return nil, errors.New("invalid RegExp format")
-						}
-						re, err := regexp.Compile(pattern)
-						if err != nil {
-							return nil, err
-						}
-						hydrated[index] = re
-						computed[index] = true
-						return re, nil
-
-					case "Object":
-						hydrated[index] = arr[1]
-						computed[index] = true
-						return arr[1], nil
-
-					case "BigInt":
-						bigStr, ok := arr[1].(string)
-						if !ok {
-							return nil, errors.New("invalid BigInt format")
-						}
-						bigInt := new(big.Int)
-						_, ok = bigInt.SetString(bigStr, 10)
-						if !ok {
-							return nil, errors.New("failed to parse BigInt")
-						}
-						hydrated[index] = bigInt
-						computed[index] = true
-						return bigInt, nil
-
-					case "null":
-						obj := make(map[string]interface{})
-						hydrated[index] = obj
-						computed[index] = true
-						for i := 1; i < len(arr); i += 2 {
-							key, ok := arr[i].(string)
-							if !ok {
-								return nil, errors.New("invalid key in null object")
-							}
-							valIndex, err := toInt(arr[i+1])
-							if err != nil {
-								return nil, err
-							}
-							val, err := hydrate(valIndex, false, values, computed, revivers)
-							if err != nil {
-								return nil, err
-							}
-							obj[key] = val
-						}
-						return obj, nil
-
-					case "Int8Array", "Uint8Array", "Uint8ClampedArray",
-						"Int16Array", "Uint16Array", "Int32Array", "Uint32Array",
-						"Float32Array", "Float64Array", "BigInt64Array", "BigUint64Array":
-						b64, ok := arr[1].(string)
-						if !ok {
-							return nil, errors.New("invalid typed array format")
-						}
-						data, err := base64.StdEncoding.DecodeString(b64)
-						if err != nil {
-							return nil, err
-						}
-						hydrated[index] = data
-						computed[index] = true
-						return data, nil
-
-					case "ArrayBuffer":
-						b64, ok := arr[1].(string)
-						if !ok {
-							return nil, errors.New("invalid ArrayBuffer format")
-						}
-						data, err := base64.StdEncoding.DecodeString(b64)
-						if err != nil {
-							return nil, err
-						}
-						hydrated[index] = data
-						computed[index] = true
-						return data, nil
-
-					default:
-						return nil, fmt.Errorf("unknown type %s", typeStr)
-					}
-				}
+	case TagSet:
+		if h.opts.compat == V1 {
+			return h.hydrateSetV1(index, typeStr, arr)
+		}
+		if h.opts.setAsSlice {
+			return h.hydrateSetSlice(index, arr)
+		}
+		set := NewSet()
+		h.store(index, set)
+		for i := 1; i < len(arr); i++ {
+			elemIndex, err := toInt(arr[i])
+			if err != nil {
+				return nil, err
 			}
-			arrResult := make([]interface{}, len(arr))
-			hydrated[index] = arrResult
-			computed[index] = true
-			for i, item := range arr {
-				if num, err := toInt(item); err == nil && num == HOLE {
-					continue
-				}
-				itemIndex, err := toInt(item)
-				if err != nil {
-					return nil, err
-				}
-				elem, err := hydrate(itemIndex, false, values, computed, revivers)
-				if err != nil {
-					return nil, err
-				}
-				arrResult[i] = elem
+			elem, err := h.hydrateElem(elemIndex, i-1)
+			if err != nil {
+				return nil, err
 			}
-			return arrResult, nil
+			set.add(elem, elemIndex)
 		}
+		return set, nil
 
-		if obj, ok := value.(map[string]interface{}); ok {
-			result := make(map[string]interface{})
-			hydrated[index] = result
-			computed[index] = true
-			for key, val := range obj {
-				valIndex, err := toInt(val)
-				if err != nil {
-					return nil, err
-				}
-				hVal, err := hydrate(valIndex, false, values, computed, revivers)
-				if err != nil {
-					return nil, err
-				}
-				result[key] = hVal
+	case TagMap:
+		if h.opts.compat == V1 {
+			return h.hydrateMapV1(index, typeStr, arr)
+		}
+		if len(arr)%2 != 1 {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: odd number of Map entries", ErrInvalidInput))
+		}
+		m := NewOrderedMap()
+		h.store(index, m)
+		for i := 1; i < len(arr); i += 2 {
+			keyIndex, err := toInt(arr[i])
+			if err != nil {
+				return nil, err
+			}
+			valIndex, err := toInt(arr[i+1])
+			if err != nil {
+				return nil, err
 			}
-			return result, nil
+			key, err := h.hydrateInPlace(keyIndex)
+			if err != nil {
+				return nil, err
+			}
+			val, err := h.hydrateMapValue(valIndex, key)
+			if err != nil {
+				return nil, err
+			}
+			m.set(key, keyIndex, val)
 		}
+		return m, nil
 
-		return nil, errors.New("unknown value type")
+	case TagRegExp:
+		if len(arr) < 3 {
+			return nil, typeError(typeStr, index, ErrInvalidInput)
+		}
+		pattern, ok1 := arr[1].(string)
+		flags, ok2 := arr[2].(string)
+		if !ok1 || !ok2 {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: invalid RegExp format", ErrInvalidInput))
+		}
+		re, err := compileRegExp(pattern, flags)
+		if err != nil {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: %w", ErrInvalidInput, err))
+		}
+		h.store(index, re)
+		return re, nil
+
+	case TagObject:
+		return h.hydrateObject(index, typeStr, arr)
+
+	case TagBigInt:
+		if len(arr) < 2 {
+			return nil, typeError(typeStr, index, ErrInvalidInput)
+		}
+		bigStr, ok := arr[1].(string)
+		if !ok {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: invalid BigInt format", ErrInvalidInput))
+		}
+		bigInt, ok := parseBigInt(bigStr)
+		if !ok {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: failed to parse BigInt", ErrInvalidInput))
+		}
+		h.store(index, bigInt)
+		return bigInt, nil
+
+	case TagNull:
+		if len(arr)%2 != 1 {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: odd number of object entries", ErrInvalidInput))
+		}
+		obj := make(map[string]interface{})
+		h.store(index, h.opts.nullObject(obj))
+		for i := 1; i < len(arr); i += 2 {
+			key, ok := arr[i].(string)
+			if !ok {
+				return nil, typeError(typeStr, index, fmt.Errorf("%w: invalid key in null object", ErrInvalidInput))
+			}
+			key, keep := h.objectKey(key)
+			if !keep {
+				continue
+			}
+			valIndex, err := toInt(arr[i+1])
+			if err != nil {
+				return nil, err
+			}
+			val, err := h.hydrateKey(valIndex, key)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = val
+		}
+		return h.opts.nullObject(obj), nil
+
+	case TagInt8Array, TagUint8Array, TagUint8ClampedArray,
+		TagInt16Array, TagUint16Array, TagInt32Array, TagUint32Array,
+		TagFloat32Array, TagFloat64Array, TagBigInt64Array, TagBigUint64Array,
+		TagArrayBuffer:
+		if len(arr) < 2 {
+			return nil, typeError(typeStr, index, ErrInvalidInput)
+		}
+		b64, ok := arr[1].(string)
+		if !ok {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: invalid %s format", ErrInvalidInput, typeStr))
+		}
+		data, err := h.opts.decodeBinary(typeStr, b64)
+		if err != nil {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: %w", ErrInvalidInput, err))
+		}
+		switch value := data.(type) {
+		case []byte:
+			data, err = h.opts.typedArray(tag, value)
+		case *Truncated:
+			// Decode the prefix too, so a truncated array holds the same
+			// type of elements as a whole one.
+			value.Value, err = h.opts.typedArray(tag, value.Value.([]byte))
+		}
+		if err != nil {
+			return nil, typeError(typeStr, index, fmt.Errorf("%w: %w", ErrInvalidInput, err))
+		}
+		h.store(index, data)
+		return data, nil
+
+	default:
+		return nil, typeError(typeStr, index, ErrUnknownType)
+	}
+}
+
+// objectKeys returns the keys of a raw object entry. They are sorted when keys
+// are transformed, so the value kept when two keys collide is deterministic.
+func (h *hydrator) objectKeys(obj map[string]interface{}) []string {
+	if len(h.opts.keyTransforms) > 0 {
+		return sortedKeys(obj)
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
 	}
+	return keys
+}
 
-	return hydrate(0, false, values, computed, revivers)
+// objectKey applies the key transforms and the reserved key policy to an
+// object key. It reports false when the key should be dropped.
+func (h *hydrator) objectKey(key string) (string, bool) {
+	return h.opts.objectKey(key)
 }
 
+func (o *options) objectKey(key string) (string, bool) {
+	key = o.transformKey(key)
+	if !isReservedKey(key) {
+		return key, true
+	}
+	switch o.reservedKeys {
+	case DropReservedKeys:
+		return "", false
+	case PrefixReservedKeys:
+		return o.reservedKeyPrefix + key, true
+	default:
+		return key, true
+	}
+}
+
+func (h *hydrator) store(index int, v interface{}) {
+	h.hydrated[index] = v
+	h.computed[index] = true
+}
+
+// toInt converts a value-table reference to an index. References are parsed
+// as int64 and range checked, so oversized or fractional indices from buggy
+// serializers fail with ErrBadReference instead of wrapping silently on
+// 32-bit platforms.
 func toInt(v interface{}) (int, error) {
+	var index int64
 	switch num := v.(type) {
 	case float64:
-		return int(num), nil
+		if num != math.Trunc(num) || num < math.MinInt64 || num >= math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %v is not an index", ErrBadReference, num)
+		}
+		index = int64(num)
 	case int:
 		return num, nil
+	case int64:
+		index = num
 	case string:
-		i, err := strconv.Atoi(num)
+		i, err := strconv.ParseInt(num, 10, 64)
 		if err != nil {
-			return 0, err
+			return 0, fmt.Errorf("%w: %w", ErrBadReference, err)
 		}
-		return i, nil
+		index = i
 	default:
-		return 0, errors.New("unable to convert to int")
+		return 0, fmt.Errorf("%w: %v is not an index", ErrBadReference, v)
 	}
-}
-
-func getInt(arr []interface{}, i int) int {
-	val, _ := toInt(arr[i])
-	return val
+	if index < math.MinInt || index > math.MaxInt {
+		return 0, fmt.Errorf("%w: index %d out of range", ErrBadReference, index)
+	}
+	return int(index), nil
 }
 
 type Revivers map[string]ReviverFunc
 
+// ConvertUnsupportedTypes returns a copy of v in which values encoding/json
+// cannot represent directly, such as sets, are replaced by JSON-friendly
+// equivalents. v itself is not modified. Cyclic values, which JSON cannot
+// represent, convert to nil.
 func ConvertUnsupportedTypes(v interface{}) interface{} {
+	converted, _ := convertUnsupportedTypes(v, &options{})
+	return converted
+}
+
+// convertUnsupportedTypes is ConvertUnsupportedTypes, failing with an error
+// wrapping ErrInvalidInput for cyclic values.
+func convertUnsupportedTypes(v interface{}, o *options) (interface{}, error) {
+	c := &converter{opts: o, active: make(map[uintptr]bool)}
+	return c.convert(v)
+}
+
+type converter struct {
+	opts *options
+	// active holds the containers being converted, the ancestors of the
+	// current value.
+	active map[uintptr]bool
+}
+
+func (c *converter) convert(v interface{}) (interface{}, error) {
+	id, isContainer := containerID(v)
+	if m, ok := v.(map[interface{}]interface{}); ok && len(m) > 0 {
+		id, isContainer = reflect.ValueOf(m).Pointer(), true
+	}
+	if isContainer {
+		if c.active[id] {
+			return nil, fmt.Errorf("%w: cyclic value cannot be represented in JSON", ErrInvalidInput)
+		}
+		if len(c.active) >= defaultRecursiveDepth {
+			return nil, errOutputTooDeep
+		}
+		c.active[id] = true
+		defer delete(c.active, id)
+	}
+
+	o := c.opts
 	switch value := v.(type) {
 	case map[interface{}]struct{}:
 		arr := make([]interface{}, 0, len(value))
 		for key := range value {
-			arr = append(arr, ConvertUnsupportedTypes(key))
+			item, err := c.convert(key)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, item)
+		}
+		return arr, nil
+	case *Set:
+		arr := make([]interface{}, 0, value.Len())
+		for _, elem := range value.Values() {
+			item, err := c.convert(elem)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, item)
 		}
-		return arr
+		return arr, nil
 	case []interface{}:
+		arr := make([]interface{}, len(value))
 		for i, item := range value {
-			value[i] = ConvertUnsupportedTypes(item)
+			converted, err := c.convert(item)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = converted
+		}
+		return arr, nil
+	case map[string]interface{}, NullObject:
+		obj := objectOf(value)
+		m := make(map[string]interface{}, len(obj))
+		for k, item := range obj {
+			converted, err := c.convert(item)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = converted
 		}
-		return value
-	case map[string]interface{}:
-		for k, item := range value {
-			value[k] = ConvertUnsupportedTypes(item)
+		return m, nil
+	case *OrderedMap:
+		m := NewOrderedMap()
+		seen := make(map[string]bool, len(value.entries))
+		for _, e := range value.entries {
+			if o.strictMapKeys {
+				key, ok := MapKeyString(e.Key)
+				if !ok {
+					return nil, fmt.Errorf("%w: Map key of type %T has no string form", ErrInvalidInput, e.Key)
+				}
+				if seen[key] {
+					return nil, fmt.Errorf("%w: Map keys collide on %q", ErrInvalidInput, key)
+				}
+				seen[key] = true
+			}
+			converted, err := c.convert(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			m.set(e.Key, e.KeyRef, converted)
+		}
+		return m, nil
+	case *SampledArray:
+		values, err := c.convert(value.Values)
+		if err != nil {
+			return nil, err
 		}
-		return value
+		return &SampledArray{Length: value.Length, Indices: value.Indices, Values: values.([]interface{})}, nil
 	case map[interface{}]interface{}:
 		m := make(map[string]interface{})
 		for key, item := range value {
-			m[fmt.Sprintf("%v", key)] = ConvertUnsupportedTypes(item)
+			converted, err := c.convert(item)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprintf("%v", key)] = converted
 		}
-		return m
+		return m, nil
 	default:
-		return v
+		return v, nil
+	}
+}
+
+// DefaultNuxtRevivers returns a fresh copy of the revivers used by Rehydrate
+// for Nuxt payloads. Callers may modify the returned map freely.
+func DefaultNuxtRevivers() Revivers {
+	passthrough := func(val interface{}) (interface{}, error) {
+		return val, nil
+	}
+	return Revivers{
+		"Reactive":        passthrough,
+		"Ref":             passthrough,
+		"EmptyRef":        passthrough,
+		"ShallowReactive": passthrough,
 	}
 }
 
 func Rehydrate(inputString string) (string, error) {
-	result, err := Parse(inputString, Revivers{
-		"Reactive": func(val interface{}) (interface{}, error) {
-			return val, nil
-		},
-		"Ref": func(val interface{}) (interface{}, error) {
-			return val, nil
-		},
-		"EmptyRef": func(val interface{}) (interface{}, error) {
-			return val, nil
-		},
-		"ShallowReactive": func(val interface{}) (interface{}, error) {
-			return val, nil
-		},
-	})
+	return RehydrateWith(inputString, nil)
+}
+
+// RehydrateWith is like Rehydrate but layers extra on top of the default Nuxt
+// revivers, letting callers add or replace individual tags. Payloads whose
+// value refers back to itself, such as [[0]], fail with an error wrapping
+// ErrInvalidInput, as JSON cannot represent cycles; values shared without a
+// cycle are written out at every place they appear.
+func RehydrateWith(inputString string, extra Revivers, opts ...Option) (string, error) {
+	o := newOptions(rehydrateOptions(extra, opts))
+
+	result, err := parse(inputString, o)
 	if err != nil {
 		return "", err
 	}
+	return o.render(inputString, result)
+}
 
-	fixedResult := ConvertUnsupportedTypes(result)
+// rehydrateOptions returns the options of RehydrateWith: the default Nuxt
+// revivers, then extra, then opts.
+func rehydrateOptions(extra Revivers, opts []Option) []Option {
+	return append([]Option{WithRevivers(DefaultNuxtRevivers()), WithRevivers(extra), WithReviverValidation()}, opts...)
+}
 
-	jsonOutput, err := json.MarshalIndent(fixedResult, "", "  ")
+// render converts result, hydrated from inputString, to the JSON output of
+// RehydrateWith.
+func (o *options) render(inputString string, result interface{}) (string, error) {
+	var fixedResult interface{}
+	var err error
+	if o.annotated {
+		fixedResult, err = annotate(result)
+	} else {
+		fixedResult, err = convertUnsupportedTypes(result, o)
+	}
+	if err != nil {
+		return "", err
+	}
+	fixedResult = sanitizeStrings(fixedResult, o)
+
+	jsonOutput, err := o.marshal(fixedResult)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) && strings.Contains(syntaxErr.Error(), "exceeded max depth") {
+		// encoding/json nests one error per level, so the original would
+		// be as deep as the value.
+		return "", errOutputTooDeep
+	}
 	if err != nil {
 		return "", err
 	}
 
+	if o.lossReport != nil {
+		if err := reportLosses(inputString, o.lossReport, o); err != nil {
+			return "", err
+		}
+	}
+
 	return string(jsonOutput), nil
 }
@@ -1,21 +1,92 @@
 package rehydrate_test
 
 import (
-	"os"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/testutil"
 )
 
-func TestRehydrateFromFile(t *testing.T) {
-	data, err := os.ReadFile("test.json")
+func TestRehydrateFixture(t *testing.T) {
+	payload := testutil.Fixture().
+		With("title", "Home").
+		With("items", []interface{}{1, testutil.Undefined, 2.5}).
+		WithDate("updated", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)).
+		WithSet("tags", "a", "b").
+		WithMap("counts", "x", 1).
+		WithRef("labels", "tags").
+		Payload()
+	testutil.RehydrateGolden(t, "fixture", payload, nil)
+}
+
+func TestRehydrateWithExtraReviver(t *testing.T) {
+	input := `[["Money",1],{"amount":2},42]`
+	out, err := rehydrate.RehydrateWith(input, rehydrate.Revivers{
+		"Money": func(val interface{}) (interface{}, error) {
+			return val.(map[string]interface{})["amount"], nil
+		},
+	}, rehydrate.WithIndent("", ""))
 	if err != nil {
 		t.Fatal(err)
 	}
-	out, err := rehydrate.Rehydrate(string(data))
+	if out != "42" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestRehydrateCycle(t *testing.T) {
+	for _, input := range []string{`[[0]]`, `[{"self":0}]`, `[["Set",1],[0]]`, `[["Map",1,0],"k"]`} {
+		if _, err := rehydrate.RehydrateWith(input, nil); !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("%s: got %v, want ErrInvalidInput", input, err)
+		}
+	}
+	// Values shared without a cycle are written out each time.
+	out, err := rehydrate.RehydrateWith(`[{"a":1,"b":1},[2],"x"]`, nil, rehydrate.WithIndent("", ""))
+	if err != nil || out != `{"a":["x"],"b":["x"]}` {
+		t.Errorf("shared: got %s, %v", out, err)
+	}
+}
+
+func TestDefaultNuxtRevivers(t *testing.T) {
+	revivers := rehydrate.DefaultNuxtRevivers()
+	for _, tag := range []string{"Reactive", "Ref", "EmptyRef", "ShallowReactive"} {
+		if _, ok := revivers[tag]; !ok {
+			t.Errorf("missing reviver for %s", tag)
+		}
+	}
+	delete(revivers, "Ref")
+	if _, ok := rehydrate.DefaultNuxtRevivers()["Ref"]; !ok {
+		t.Error("DefaultNuxtRevivers must return a fresh map")
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	var doc struct {
+		Page    string        `json:"page"`
+		Payload []interface{} `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(`{"page":"home","payload":[{"at":1,"n":-5},["Money",2],"10 EUR"]}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	revivers := rehydrate.Revivers{"Money": func(v interface{}) (interface{}, error) { return "money:" + v.(string), nil }}
+	v, err := rehydrate.ParseValues(doc.Payload, revivers)
 	if err != nil {
 		t.Fatal(err)
 	}
+	want := map[string]interface{}{"at": "money:10 EUR", "n": math.Inf(-1)}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
 
-	t.Log(out)
+	if _, err := rehydrate.ParseValues(nil, nil); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("empty table: got %v", err)
+	}
+	if _, err := rehydrate.ParseValues([]interface{}{[]interface{}{1.0, 7.0}, "x"}, nil, rehydrate.WithStrictReferences()); !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("strict references: got %v", err)
+	}
 }
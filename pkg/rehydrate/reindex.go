@@ -0,0 +1,69 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Reindex returns a copy of the value table values with every reference
+// shifted by offset, so the entries can be placed at position offset of a
+// larger table. Sentinels such as undefined and holes are left unchanged.
+// Reindex fails with ErrBadReference if a shifted reference would become
+// negative.
+func Reindex(values []json.RawMessage, offset int) ([]json.RawMessage, error) {
+	return RemapTable(values, func(index int) (int, error) {
+		if index+offset < 0 {
+			return 0, fmt.Errorf("%w: index %d shifted by %d", ErrBadReference, index, offset)
+		}
+		return index + offset, nil
+	})
+}
+
+// RemapTable returns a copy of values with every reference rewritten by
+// remap. It is the general form of Reindex, for compositions that reorder
+// or deduplicate entries. Entries keep their original encoding, including
+// object key order.
+func RemapTable(values []json.RawMessage, remap func(index int) (int, error)) ([]json.RawMessage, error) {
+	out := make([]json.RawMessage, len(values))
+	for i, entry := range values {
+		remapped, err := remapEntry(entry, remap)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		out[i] = remapped
+	}
+	return out, nil
+}
+
+// AppendTable appends the value table src to dst, reindexing its entries,
+// and returns the extended table along with the index of src's root in it.
+// Callers then point an entry of dst at that index, for example to assemble
+// per-widget payloads into a single page payload:
+//
+//	page := []json.RawMessage{nil}
+//	page, header, _ := rehydrate.AppendTable(page, headerTable)
+//	page, body, _ := rehydrate.AppendTable(page, bodyTable)
+//	page[0] = json.RawMessage(fmt.Sprintf(`{"header":%d,"body":%d}`, header, body))
+func AppendTable(dst, src []json.RawMessage) ([]json.RawMessage, int, error) {
+	root := len(dst)
+	if len(src) == 0 {
+		return dst, root, fmt.Errorf("%w: empty value table", ErrInvalidInput)
+	}
+	shifted, err := Reindex(src, root)
+	if err != nil {
+		return dst, root, err
+	}
+	return append(dst, shifted...), root, nil
+}
+
+// Table splits a serialized payload into its raw value-table entries, for use
+// with Reindex, RemapTable and AppendTable.
+func Table(serialized string) ([]json.RawMessage, error) {
+	return unmarshalRawTable(serialized)
+}
+
+// JoinTable serializes a value table produced by Table, Reindex, RemapTable
+// or AppendTable back into a payload.
+func JoinTable(values []json.RawMessage) string {
+	return string(joinRawTable(values))
+}
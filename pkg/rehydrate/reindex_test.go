@@ -0,0 +1,67 @@
+package rehydrate_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestReindex(t *testing.T) {
+	table, err := rehydrate.Table(`[{"b":1,"a":-1},["Set",2,-2],"x"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shifted, err := rehydrate.Reindex(table, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rehydrate.JoinTable(shifted), `[{"b":4,"a":-1},["Set",5,-2],"x"]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if _, err := rehydrate.Reindex(table, -2); !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("expected ErrBadReference, got %v", err)
+	}
+
+	table, err = rehydrate.Table(`[{"<b>":1},"x"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shifted, err = rehydrate.Reindex(table, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rehydrate.JoinTable(shifted), `[{"<b>":2},"x"]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestAppendTable(t *testing.T) {
+	header, _ := rehydrate.Table(`[{"title":1},"Home"]`)
+	body, _ := rehydrate.Table(`[["Set",1],"a"]`)
+
+	page := []json.RawMessage{nil}
+	page, h, err := rehydrate.AppendTable(page, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, b, err := rehydrate.AppendTable(page, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page[0] = json.RawMessage(fmt.Sprintf(`{"header":%d,"body":%d}`, h, b))
+
+	v, err := rehydrate.Parse(rehydrate.JoinTable(page), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	if title := root["header"].(map[string]interface{})["title"]; title != "Home" {
+		t.Errorf("unexpected title %v", title)
+	}
+	if !root["body"].(*rehydrate.Set).Has("a") {
+		t.Errorf("unexpected body %v", root["body"])
+	}
+}
@@ -0,0 +1,72 @@
+package rehydrate
+
+import "fmt"
+
+// WithResolveRevived resolves *LazyRef values found in reviver results, so a
+// reviver can build a composite result that refers to other entries of the
+// value table without hydrating them itself:
+//
+//	// ["Link", n] holds the index of the entry it links to as a number.
+//	"Link": func(v interface{}) (interface{}, error) {
+//		return map[string]interface{}{
+//			"target": &rehydrate.LazyRef{Index: int(v.(float64))},
+//		}, nil
+//	},
+//
+// References are resolved in slices, objects and Map values the reviver
+// returns, replacing them in place. The reviver's own input is not searched,
+// so values left unhydrated by PolicySkip stay lazy. A reference to an entry
+// whose reviver is still running fails with ErrBadReference.
+func WithResolveRevived() Option {
+	return func(o *options) {
+		o.resolveRevived = true
+	}
+}
+
+// resolveRevived resolves the references in res, the result of a reviver
+// that was called with in.
+func (h *hydrator) resolveRevived(in, res interface{}) (interface{}, error) {
+	seen := make(map[uintptr]bool)
+	if id, ok := containerID(in); ok {
+		seen[id] = true
+	}
+	return h.resolveRefs(res, seen)
+}
+
+func (h *hydrator) resolveRefs(v interface{}, seen map[uintptr]bool) (interface{}, error) {
+	if ref, ok := v.(*LazyRef); ok {
+		if h.reviving[ref.Index] {
+			return nil, fmt.Errorf("%w: reviver result refers to index %d, which is still being revived", ErrBadReference, ref.Index)
+		}
+		return h.hydrate(ref.Index, false)
+	}
+
+	id, ok := containerID(v)
+	if !ok || seen[id] {
+		return v, nil
+	}
+	seen[id] = true
+
+	var err error
+	switch value := v.(type) {
+	case []interface{}:
+		for i := range value {
+			if value[i], err = h.resolveRefs(value[i], seen); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		for key, item := range value {
+			if value[key], err = h.resolveRefs(item, seen); err != nil {
+				return nil, err
+			}
+		}
+	case *OrderedMap:
+		for i := range value.entries {
+			if value.entries[i].Value, err = h.resolveRefs(value.entries[i].Value, seen); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,60 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestResolveRevived(t *testing.T) {
+	input := `[{"pair":1,"user":4},["Pair",2],[3,4],"Ada",{"name":3}]`
+	pair := func(v interface{}) (interface{}, error) {
+		ids := v.([]interface{})
+		return map[string]interface{}{
+			"first":  &rehydrate.LazyRef{Index: 3},
+			"second": []interface{}{&rehydrate.LazyRef{Index: 4}, ids[0]},
+		}, nil
+	}
+
+	v, err := rehydrate.ParseWithOptions(input,
+		rehydrate.WithRevivers(rehydrate.Revivers{"Pair": pair}),
+		rehydrate.WithResolveRevived())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	got := root["pair"].(map[string]interface{})
+	if got["first"] != "Ada" {
+		t.Errorf("unexpected first %v", got["first"])
+	}
+	second := got["second"].([]interface{})
+	user, ok := second[0].(map[string]interface{})
+	if !ok || user["name"] != "Ada" || second[1] != "Ada" {
+		t.Errorf("unexpected second %v", second)
+	}
+	if root["user"].(map[string]interface{})["name"] != "Ada" {
+		t.Errorf("unexpected user %v", root["user"])
+	}
+
+	// Without the option the placeholders are kept.
+	v, err = rehydrate.ParseWithOptions(input, rehydrate.WithRevivers(rehydrate.Revivers{"Pair": pair}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(map[string]interface{})["pair"].(map[string]interface{})["first"].(*rehydrate.LazyRef); !ok {
+		t.Error("expected an unresolved LazyRef without WithResolveRevived")
+	}
+}
+
+func TestResolveRevivedSelfReference(t *testing.T) {
+	self := func(v interface{}) (interface{}, error) {
+		return []interface{}{&rehydrate.LazyRef{Index: 0}}, nil
+	}
+	_, err := rehydrate.ParseWithOptions(`[["Self",1],"x"]`,
+		rehydrate.WithRevivers(rehydrate.Revivers{"Self": self}),
+		rehydrate.WithResolveRevived())
+	if !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("expected ErrBadReference, got %v", err)
+	}
+}
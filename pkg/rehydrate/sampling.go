@@ -0,0 +1,111 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"sort"
+)
+
+// SamplingStrategy selects the elements kept by WithArraySampling.
+type SamplingStrategy int
+
+const (
+	// SampleFirst keeps the first n elements.
+	SampleFirst SamplingStrategy = iota
+	// SampleLast keeps the last n elements.
+	SampleLast
+	// SampleRandom keeps n elements chosen at random, in their original
+	// order.
+	SampleRandom
+)
+
+// SampledArray stands in for an array that was longer than the sample size
+// set with WithArraySampling. Only the sampled elements are hydrated.
+type SampledArray struct {
+	// Length is the length of the original array.
+	Length int
+	// Indices holds the original positions of Values, in ascending order.
+	Indices []int
+	// Values holds the hydrated sampled elements.
+	Values []interface{}
+}
+
+// MarshalJSON renders the sample as {"length": n, "indices": [...],
+// "values": [...]}.
+func (s *SampledArray) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Length  int           `json:"length"`
+		Indices []int         `json:"indices"`
+		Values  []interface{} `json:"values"`
+	}{s.Length, s.Indices, s.Values})
+}
+
+// WithArraySampling hydrates only n elements of arrays longer than n, which
+// become *SampledArray values recording the true length, for monitoring
+// payloads with enormous arrays where full hydration is unnecessary.
+// The first n elements are kept unless WithSamplingStrategy selects another
+// strategy. Sets, Maps and binary data are not sampled. A size of zero or
+// less disables sampling.
+func WithArraySampling(n int) Option {
+	return func(o *options) {
+		o.sampleSize = n
+	}
+}
+
+// WithSamplingStrategy selects the elements kept by WithArraySampling.
+func WithSamplingStrategy(s SamplingStrategy) Option {
+	return func(o *options) {
+		o.sampling = s
+	}
+}
+
+// sampleIndices returns the positions to keep of an array of the given
+// length, in ascending order.
+func (o *options) sampleIndices(length int) []int {
+	n := o.sampleSize
+	indices := make([]int, n)
+	switch o.sampling {
+	case SampleLast:
+		for i := range indices {
+			indices[i] = length - n + i
+		}
+	case SampleRandom:
+		// Reservoir sampling keeps the selection uniform over all positions.
+		for i := range indices {
+			indices[i] = i
+		}
+		for i := n; i < length; i++ {
+			if j := rand.IntN(i + 1); j < n {
+				indices[j] = i
+			}
+		}
+		sort.Ints(indices)
+	default:
+		for i := range indices {
+			indices[i] = i
+		}
+	}
+	return indices
+}
+
+// hydrateSample hydrates the sampled elements of the array entry at index.
+func (h *hydrator) hydrateSample(index int, arr []interface{}) (interface{}, error) {
+	sample := &SampledArray{Length: len(arr), Indices: h.opts.sampleIndices(len(arr))}
+	sample.Values = make([]interface{}, len(sample.Indices))
+	h.store(index, sample)
+	for i, pos := range sample.Indices {
+		itemIndex, err := toInt(arr[pos])
+		if err != nil {
+			return nil, err
+		}
+		if itemIndex == HOLE {
+			continue
+		}
+		elem, err := h.hydrateElem(itemIndex, pos)
+		if err != nil {
+			return nil, err
+		}
+		sample.Values[i] = elem
+	}
+	return sample, nil
+}
@@ -0,0 +1,62 @@
+package rehydrate_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestArraySampling(t *testing.T) {
+	refs := make([]string, 100)
+	values := make([]string, 100)
+	for i := range refs {
+		refs[i] = fmt.Sprint(i + 2)
+		values[i] = fmt.Sprint(i)
+	}
+	input := `[{"items":1},[` + strings.Join(refs, ",") + `],` + strings.Join(values, ",") + `]`
+
+	tests := []struct {
+		strategy rehydrate.SamplingStrategy
+		want     []int
+	}{
+		{rehydrate.SampleFirst, []int{0, 1, 2}},
+		{rehydrate.SampleLast, []int{97, 98, 99}},
+	}
+	for _, tt := range tests {
+		v, err := rehydrate.ParseWithOptions(input, rehydrate.WithArraySampling(3), rehydrate.WithSamplingStrategy(tt.strategy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sample := v.(map[string]interface{})["items"].(*rehydrate.SampledArray)
+		if sample.Length != 100 || !reflect.DeepEqual(sample.Indices, tt.want) {
+			t.Errorf("strategy %d: got length %d, indices %v", tt.strategy, sample.Length, sample.Indices)
+		}
+		for i, pos := range sample.Indices {
+			if sample.Values[i] != float64(pos) {
+				t.Errorf("strategy %d: value at %d = %v", tt.strategy, pos, sample.Values[i])
+			}
+		}
+	}
+
+	v, err := rehydrate.ParseWithOptions(input, rehydrate.WithArraySampling(5), rehydrate.WithSamplingStrategy(rehydrate.SampleRandom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := v.(map[string]interface{})["items"].(*rehydrate.SampledArray)
+	for i, pos := range sample.Indices {
+		if (i > 0 && pos <= sample.Indices[i-1]) || sample.Values[i] != float64(pos) {
+			t.Fatalf("random sample out of order or mismatched: %v %v", sample.Indices, sample.Values)
+		}
+	}
+
+	out, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithArraySampling(2), rehydrate.WithIndent("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"items":{"length":100,"indices":[0,1],"values":[0,1]}}`; out != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+}
@@ -0,0 +1,186 @@
+package rehydrate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// StringPolicy controls how RehydrateWith renders strings containing control
+// characters or invalid UTF-8, which strict JSON consumers may reject even
+// when escaped. Tabs, line feeds and carriage returns are left alone.
+// Invalid UTF-8 in the payload text is replaced with U+FFFD when it is
+// decoded, so it only reaches the output through revivers; lone surrogates
+// kept by WithUTF16Strings are treated as invalid too.
+type StringPolicy int
+
+const (
+	// KeepStrings renders strings as encoding/json does: control characters
+	// become \u escapes and invalid UTF-8 becomes U+FFFD. It is the default.
+	KeepStrings StringPolicy = iota
+	// EscapeStrings replaces control characters and lone surrogates by the
+	// visible text \u0007 and invalid bytes by \xff, so the original content
+	// stays readable.
+	EscapeStrings
+	// StripStrings removes control characters and invalid bytes.
+	StripStrings
+)
+
+// WithStringPolicy sets how strings with control characters or invalid UTF-8
+// are rendered. It applies to object keys and Map keys as well as values.
+func WithStringPolicy(policy StringPolicy) Option {
+	return func(o *options) {
+		o.stringPolicy = policy
+	}
+}
+
+// SanitizedString reports a string changed by WithStringPolicy.
+type SanitizedString struct {
+	// Path is the path of the string in the rendered output. For keys it is
+	// the path of the entry.
+	Path string `json:"path"`
+	// Key is set if the string is an object or Map key.
+	Key bool `json:"key,omitempty"`
+	// Changes is the number of characters and bytes escaped or removed.
+	Changes int `json:"changes"`
+}
+
+// WithSanitizedStringReport appends the strings changed by the policy set
+// with WithStringPolicy to report, sorted by path.
+func WithSanitizedStringReport(report *[]SanitizedString) Option {
+	return func(o *options) {
+		o.sanitizedReport = report
+	}
+}
+
+// sanitizeStrings applies the string policy in place to v, the output of
+// convertUnsupportedTypes or annotate, and returns the result.
+func sanitizeStrings(v interface{}, o *options) interface{} {
+	if o.stringPolicy == KeepStrings {
+		return v
+	}
+	s := &sanitizer{policy: o.stringPolicy}
+	v = s.value("", v)
+	if o.sanitizedReport != nil {
+		sort.Slice(s.report, func(i, j int) bool { return s.report[i].Path < s.report[j].Path })
+		*o.sanitizedReport = append(*o.sanitizedReport, s.report...)
+	}
+	return v
+}
+
+type sanitizer struct {
+	policy StringPolicy
+	report []SanitizedString
+}
+
+func (s *sanitizer) value(path string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return s.string(path, false, value)
+	case UTF16String:
+		return s.utf16(path, value)
+	case []interface{}:
+		for i, item := range value {
+			value[i] = s.value(indexPath(path, i), item)
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			clean := s.string(keyPath(path, key), true, key)
+			item := s.value(keyPath(path, clean), value[key])
+			if clean != key {
+				delete(value, key)
+			}
+			value[clean] = item
+		}
+	case *OrderedMap:
+		// Keys may change, so the map is rebuilt to keep its index valid.
+		m := NewOrderedMap()
+		for _, e := range value.entries {
+			entryPath := mapKeyPath(path, e.Key)
+			key := e.Key
+			if str, ok := key.(string); ok {
+				key = s.string(entryPath, true, str)
+			}
+			m.set(key, e.KeyRef, s.value(entryPath, e.Value))
+		}
+		return m
+	case *SampledArray:
+		for i, item := range value.Values {
+			value.Values[i] = s.value(indexPath(path, value.Indices[i]), item)
+		}
+	}
+	return v
+}
+
+func (s *sanitizer) string(path string, key bool, str string) string {
+	changes := 0
+	var b strings.Builder
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRuneInString(str[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			changes++
+			if s.policy == EscapeStrings {
+				fmt.Fprintf(&b, `\x%02x`, str[i])
+			}
+		case isControl(r):
+			changes++
+			if s.policy == EscapeStrings {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			}
+		default:
+			b.WriteString(str[i : i+size])
+		}
+		i += size
+	}
+	if changes == 0 {
+		return str
+	}
+	s.report = append(s.report, SanitizedString{Path: path, Key: key, Changes: changes})
+	return b.String()
+}
+
+// utf16 converts a string with lone surrogates to a sanitized string.
+func (s *sanitizer) utf16(path string, str UTF16String) interface{} {
+	var b strings.Builder
+	lone := 0
+	for i := 0; i < len(str); i++ {
+		u := rune(str[i])
+		switch {
+		case utf16.IsSurrogate(u) && i+1 < len(str) && utf16.DecodeRune(u, rune(str[i+1])) != utf8.RuneError:
+			b.WriteRune(utf16.DecodeRune(u, rune(str[i+1])))
+			i++
+		case utf16.IsSurrogate(u):
+			lone++
+			if s.policy == EscapeStrings {
+				fmt.Fprintf(&b, `\u%04x`, u)
+			}
+		default:
+			b.WriteRune(u)
+		}
+	}
+	before := len(s.report)
+	clean := s.string(path, false, b.String())
+	if lone > 0 {
+		if len(s.report) > before {
+			s.report[before].Changes += lone
+		} else {
+			s.report = append(s.report, SanitizedString{Path: path, Changes: lone})
+		}
+	}
+	return clean
+}
+
+// isControl reports whether r is a C0 or C1 control character other than a
+// tab, line feed or carriage return.
+func isControl(r rune) bool {
+	switch {
+	case r == '\t', r == '\n', r == '\r':
+		return false
+	case r < 0x20, r == 0x7f:
+		return true
+	}
+	return r >= 0x80 && r <= 0x9f
+}
@@ -0,0 +1,45 @@
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestWithStringPolicy(t *testing.T) {
+	payload := `[{"ok":1,"bad\u0000key":2,"tags":3,"m":5,"raw":7,"lone":9},"line\n\ttab","a\u0007b",[4],"\u0085x",["Map",6,1],"k\u0001",["Raw",8],"x","\ud800y"]`
+	revivers := rehydrate.Revivers{"Raw": func(interface{}) (interface{}, error) { return "\xffz", nil }}
+
+	var report []rehydrate.SanitizedString
+	out, err := rehydrate.RehydrateWith(payload, revivers, rehydrate.WithIndent("", ""), rehydrate.WithUTF16Strings(),
+		rehydrate.WithStringPolicy(rehydrate.EscapeStrings), rehydrate.WithSanitizedStringReport(&report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"bad\\u0000key":"a\\u0007b","lone":"\\ud800y","m":{"k\\u0001":"line\n\ttab"},"ok":"line\n\ttab","raw":"\\xffz","tags":["\\u0085x"]}`
+	if out != want {
+		t.Errorf("got  %s\nwant %s", out, want)
+	}
+	wantReport := []rehydrate.SanitizedString{
+		{Path: "/bad\x00key", Key: true, Changes: 1},
+		{Path: `/bad\u0000key`, Changes: 1},
+		{Path: "/lone", Changes: 1},
+		{Path: "/m/k\x01", Key: true, Changes: 1},
+		{Path: "/raw", Changes: 1},
+		{Path: "/tags/0", Changes: 1},
+	}
+	if !reflect.DeepEqual(report, wantReport) {
+		t.Errorf("got report %+v\nwant %+v", report, wantReport)
+	}
+
+	out, err = rehydrate.RehydrateWith(payload, revivers, rehydrate.WithIndent("", ""), rehydrate.WithUTF16Strings(),
+		rehydrate.WithStringPolicy(rehydrate.StripStrings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"badkey":"ab","lone":"y","m":{"k":"line\n\ttab"},"ok":"line\n\ttab","raw":"z","tags":["x"]}`
+	if out != want {
+		t.Errorf("got  %s\nwant %s", out, want)
+	}
+}
@@ -0,0 +1,129 @@
+package rehydrate
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Match is a string value found by Search.
+type Match struct {
+	// Path locates the value within the hydrated payload.
+	Path string
+	// Value is the matching string.
+	Value string
+	// Key reports whether the match is a Map key rather than a value.
+	Key bool
+}
+
+// SearchOption configures Search.
+type SearchOption func(*searchOptions)
+
+type searchOptions struct {
+	regexp     bool
+	ignoreCase bool
+	binary     bool
+	parse      []Option
+}
+
+// SearchRegexp treats the query as a regular expression.
+func SearchRegexp() SearchOption {
+	return func(o *searchOptions) {
+		o.regexp = true
+	}
+}
+
+// SearchIgnoreCase makes matching case-insensitive.
+func SearchIgnoreCase() SearchOption {
+	return func(o *searchOptions) {
+		o.ignoreCase = true
+	}
+}
+
+// SearchBinary also searches typed arrays and ArrayBuffers whose contents
+// are valid UTF-8 text, reading typed arrays with elements wider than a
+// byte in little-endian order.
+func SearchBinary() SearchOption {
+	return func(o *searchOptions) {
+		o.binary = true
+	}
+}
+
+// SearchParseOptions sets the options used to hydrate the payload, for
+// example the revivers needed for its custom tags.
+func SearchParseOptions(opts ...Option) SearchOption {
+	return func(o *searchOptions) {
+		o.parse = append(o.parse, opts...)
+	}
+}
+
+// Search hydrates serialized and returns every string value matching query,
+// including strings inside Sets, Maps and Map keys. Objects and arrays shared
+// by several parents are searched once, at the first path they are reached
+// by.
+func Search(serialized, query string, opts ...SearchOption) ([]Match, error) {
+	o := &searchOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	match, err := o.matcher(query)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := ParseWithOptions(serialized, o.parse...)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	w := &walker{
+		visit: func(path string, v interface{}) bool {
+			var s string
+			switch value := v.(type) {
+			case string:
+				s = value
+			case UTF16String:
+				s = value.String()
+			default:
+				if !o.binary {
+					return true
+				}
+				data, ok := binaryData(value)
+				if !ok || !utf8.Valid(data) {
+					return true
+				}
+				s = string(data)
+			}
+			if match(s) {
+				matches = append(matches, Match{Path: path, Value: s})
+			}
+			return true
+		},
+		visitKey: func(path string, key interface{}) {
+			if s, ok := key.(string); ok && match(s) {
+				matches = append(matches, Match{Path: path, Value: s, Key: true})
+			}
+		},
+	}
+	w.walk("", v)
+	return matches, nil
+}
+
+func (o *searchOptions) matcher(query string) (func(string) bool, error) {
+	if o.regexp {
+		if o.ignoreCase {
+			query = "(?i)" + query
+		}
+		return compileMatcher(query)
+	}
+	if o.ignoreCase {
+		lower := strings.ToLower(query)
+		return func(s string) bool {
+			return strings.Contains(strings.ToLower(s), lower)
+		}, nil
+	}
+	return func(s string) bool {
+		return strings.Contains(s, query)
+	}, nil
+}
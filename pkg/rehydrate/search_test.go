@@ -0,0 +1,49 @@
+//go:build !rehydrate_min
+
+package rehydrate_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestSearch(t *testing.T) {
+	input := `[{"title":1,"tags":2,"prices":4,"raw":6,"first name":1},` +
+		`"Hello World",["Set",3],"world tour",["Map",5,3],"world-key",["Uint8Array","d29ybGQ="]]`
+
+	matches, err := rehydrate.Search(input, "world", rehydrate.SearchIgnoreCase(), rehydrate.SearchBinary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rehydrate.Match{
+		{Path: `/first name`, Value: "Hello World"},
+		{Path: `/prices/world-key`, Value: "world-key", Key: true},
+		{Path: `/prices/world-key`, Value: "world tour"},
+		{Path: `/raw`, Value: "world"},
+		{Path: `/tags/0`, Value: "world tour"},
+		{Path: `/title`, Value: "Hello World"},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Fatalf("got %#v\nwant %#v", matches, want)
+	}
+
+	matches, err = rehydrate.Search(input, `^world\s`, rehydrate.SearchRegexp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 regexp matches, got %v", matches)
+	}
+
+	// Typed arrays are searched through their little-endian memory.
+	input = `[{"words":1},["Uint16Array","d29ybGRz"]]`
+	matches, err = rehydrate.Search(input, "world", rehydrate.SearchBinary())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []rehydrate.Match{{Path: "/words", Value: "worlds"}}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("typed array: got %#v, want %#v", matches, want)
+	}
+}
@@ -0,0 +1,209 @@
+package rehydrate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SecretFinding reports a credential found in a hydrated string.
+type SecretFinding struct {
+	// Path is the path of the string, empty for the root.
+	Path string `json:"path"`
+	// Kind names the credential format, such as "jwt" or "aws-access-key-id".
+	Kind string `json:"kind"`
+	// Start and End are the byte offsets of the credential in the string.
+	// The credential itself is not kept, so findings can be logged safely.
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SecretScanner inspects strings for credentials as they are hydrated. Scan
+// is called with the path and content of every string value; the Path of
+// the findings it returns is filled in by the hydrator.
+type SecretScanner interface {
+	Scan(path, s string) []SecretFinding
+}
+
+// SecretScannerFunc adapts a function to the SecretScanner interface.
+type SecretScannerFunc func(path, s string) []SecretFinding
+
+// Scan calls f.
+func (f SecretScannerFunc) Scan(path, s string) []SecretFinding {
+	return f(path, s)
+}
+
+// DefaultSecretScanner detects common credential formats by their shape:
+// JSON Web Tokens, AWS access key IDs, GitHub, Slack and Stripe tokens,
+// Google API keys and PEM private keys. Shape alone cannot tell live
+// credentials from test fixtures, and credentials in other formats are
+// missed.
+var DefaultSecretScanner SecretScanner = SecretScannerFunc(scanSecrets)
+
+// WithSecretScanner calls scanner for every string value as it is hydrated,
+// so payloads are checked for leaked credentials without a separate pass.
+// Strings shared by several values are scanned once per path. The arguments
+// of revivers and Map keys are scanned at the path of the value they belong
+// to; object keys are not scanned. Findings are collected with
+// WithSecretReport, or fail the parse with WithRejectedSecrets.
+func WithSecretScanner(scanner SecretScanner) Option {
+	return func(o *options) {
+		o.secretScanner = scanner
+	}
+}
+
+// WithSecretReport appends the findings of the scanner set with
+// WithSecretScanner to report, in the order the strings are hydrated.
+func WithSecretReport(report *[]SecretFinding) Option {
+	return func(o *options) {
+		o.secretReport = report
+	}
+}
+
+// WithRejectedSecrets fails the parse at the first string the scanner set
+// with WithSecretScanner reports, with an error wrapping ErrSecretFound.
+// Like other failures, strings below a path with PolicyLenient hydrate to
+// nil instead.
+func WithRejectedSecrets() Option {
+	return func(o *options) {
+		o.rejectSecrets = true
+	}
+}
+
+// scanSecret passes the entry at index to the secret scanner if it is a
+// string.
+func (h *hydrator) scanSecret(index int) error {
+	if index < 0 || index >= len(h.values) {
+		return nil
+	}
+	var s string
+	switch v := h.values[index].(type) {
+	case string:
+		s = v
+	case UTF16String:
+		s = v.String()
+	default:
+		return nil
+	}
+	path := h.currentPath()
+	findings := h.opts.secretScanner.Scan(path, s)
+	if len(findings) == 0 {
+		return nil
+	}
+	for i := range findings {
+		findings[i].Path = path
+	}
+	if h.opts.secretReport != nil {
+		*h.opts.secretReport = append(*h.opts.secretReport, findings...)
+	}
+	if h.opts.rejectSecrets {
+		return fmt.Errorf("%w: %s at %s", ErrSecretFound, findings[0].Kind, displayPath(path))
+	}
+	return nil
+}
+
+// secretPattern describes a token made of a fixed prefix followed by a body
+// of min to max bytes accepted by body; a max of zero means no limit.
+type secretPattern struct {
+	kind     string
+	prefixes []string
+	min, max int
+	body     func(byte) bool
+	valid    func(token string) bool
+}
+
+var secretPatterns = []secretPattern{
+	{kind: "jwt", prefixes: []string{"eyJ"}, min: 20, body: isJWTByte, valid: isJWT},
+	{kind: "aws-access-key-id", prefixes: []string{"AKIA", "ASIA"}, min: 16, max: 16, body: isUpperAlnum},
+	{kind: "github-token", prefixes: []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_"}, min: 36, max: 36, body: isAlnum},
+	{kind: "github-token", prefixes: []string{"github_pat_"}, min: 22, body: isTokenByte},
+	{kind: "slack-token", prefixes: []string{"xoxa-", "xoxb-", "xoxp-", "xoxr-", "xoxs-"}, min: 10, body: isTokenByte},
+	{kind: "stripe-key", prefixes: []string{"sk_live_", "rk_live_"}, min: 24, body: isAlnum},
+	{kind: "google-api-key", prefixes: []string{"AIza"}, min: 35, max: 35, body: isTokenByte},
+}
+
+// scanSecrets implements DefaultSecretScanner.
+func scanSecrets(_ string, s string) []SecretFinding {
+	var findings []SecretFinding
+	for _, p := range secretPatterns {
+		for _, prefix := range p.prefixes {
+			for from := 0; ; {
+				i := strings.Index(s[from:], prefix)
+				if i < 0 {
+					break
+				}
+				start := from + i
+				end := start + len(prefix)
+				for end < len(s) && p.body(s[end]) {
+					end++
+				}
+				from = end
+				n := end - start - len(prefix)
+				switch {
+				case start > 0 && isTokenByte(s[start-1]),
+					end < len(s) && isTokenByte(s[end]),
+					n < p.min, p.max > 0 && n > p.max,
+					p.valid != nil && !p.valid(s[start:end]):
+					continue
+				}
+				findings = append(findings, SecretFinding{Kind: p.kind, Start: start, End: end})
+			}
+		}
+	}
+	findings = append(findings, privateKeys(s)...)
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+// privateKeys finds PEM blocks holding private keys, from the BEGIN line to
+// the END line, or to the end of the BEGIN line if the block is cut short.
+func privateKeys(s string) []SecretFinding {
+	const begin, end = "-----BEGIN ", "-----END "
+	var findings []SecretFinding
+	for from := 0; ; {
+		i := strings.Index(s[from:], begin)
+		if i < 0 {
+			return findings
+		}
+		start := from + i
+		line := s[start:]
+		if n := strings.IndexByte(line, '\n'); n >= 0 {
+			line = line[:n]
+		}
+		from = start + len(line)
+		if !strings.HasSuffix(strings.TrimSpace(line), "PRIVATE KEY-----") {
+			continue
+		}
+		if j := strings.Index(s[from:], end); j >= 0 {
+			if k := strings.Index(s[from+j+len(end):], "-----"); k >= 0 {
+				from += j + len(end) + k + len("-----")
+			}
+		}
+		findings = append(findings, SecretFinding{Kind: "private-key", Start: start, End: from})
+	}
+}
+
+// isJWT reports whether token consists of a header and payload holding
+// base64url-encoded JSON objects and a possibly empty signature.
+func isJWT(token string) bool {
+	parts := strings.Split(token, ".")
+	return len(parts) == 3 && len(parts[0]) >= 10 && strings.HasPrefix(parts[1], "eyJ") && len(parts[1]) >= 10
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isUpperAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z'
+}
+
+// isTokenByte reports whether c may be part of a token, so tokens found in
+// longer words are not reported.
+func isTokenByte(c byte) bool {
+	return isAlnum(c) || c == '_' || c == '-'
+}
+
+func isJWTByte(c byte) bool {
+	return isTokenByte(c) || c == '.'
+}
@@ -0,0 +1,336 @@
+package rehydrate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RiskKind classifies a risky construct found by SecurityAudit.
+type RiskKind int
+
+const (
+	// RiskReservedKey: an object key with special meaning in JavaScript,
+	// such as __proto__, which enables prototype pollution when re-emitted.
+	RiskReservedKey RiskKind = iota
+	// RiskDuplicateKey: an object with the same key more than once, which
+	// parsers may resolve differently.
+	RiskDuplicateKey
+	// RiskRegExp: a regular expression, which consumers may run on other
+	// input.
+	RiskRegExp
+	// RiskCustomTag: a tag that only a reviver can hydrate, handing the
+	// value to application code.
+	RiskCustomTag
+	// RiskCycle: a value that contains itself.
+	RiskCycle
+	// RiskAmplification: shared values that expand to a hydrated tree far
+	// larger than the payload.
+	RiskAmplification
+	// RiskDeepNesting: values nested deeply enough to exhaust recursive
+	// consumers.
+	RiskDeepNesting
+	// RiskLargeString: a very long string.
+	RiskLargeString
+	// RiskLargeBinary: a very large typed array or ArrayBuffer.
+	RiskLargeBinary
+	// RiskLargeBigInt: a BigInt with very many digits, which is slow to
+	// parse and to compute with.
+	RiskLargeBigInt
+	// RiskStringReference: a reference written as a string rather than a
+	// number, which serializers never produce.
+	RiskStringReference
+	// RiskUnreachable: entries not reachable from the root, which may
+	// smuggle data past consumers that only inspect the hydrated value.
+	RiskUnreachable
+)
+
+var riskKindNames = [...]string{
+	RiskReservedKey:     "reserved-key",
+	RiskDuplicateKey:    "duplicate-key",
+	RiskRegExp:          "regexp",
+	RiskCustomTag:       "custom-tag",
+	RiskCycle:           "cycle",
+	RiskAmplification:   "amplification",
+	RiskDeepNesting:     "deep-nesting",
+	RiskLargeString:     "large-string",
+	RiskLargeBinary:     "large-binary",
+	RiskLargeBigInt:     "large-bigint",
+	RiskStringReference: "string-reference",
+	RiskUnreachable:     "unreachable",
+}
+
+func (k RiskKind) String() string {
+	if k < 0 || int(k) >= len(riskKindNames) {
+		return ""
+	}
+	return riskKindNames[k]
+}
+
+// MarshalText renders the kind by name.
+func (k RiskKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Risk is a single risky construct in a payload.
+type Risk struct {
+	Kind RiskKind `json:"kind"`
+	// Path is the path of the value, empty for the root or for entries
+	// not reachable from it.
+	Path string `json:"path"`
+	// Index is the position of the entry in the value table.
+	Index int `json:"index"`
+	// Detail adds context, such as the key or tag involved.
+	Detail string `json:"detail,omitempty"`
+}
+
+// SecurityReport lists the risky constructs of a payload. See SecurityAudit.
+type SecurityReport struct {
+	Risks []Risk `json:"risks"`
+}
+
+// Has reports whether the report contains a risk of kind k.
+func (r *SecurityReport) Has(k RiskKind) bool {
+	for _, risk := range r.Risks {
+		if risk.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *SecurityReport) add(kind RiskKind, path string, index int, detail string) {
+	r.Risks = append(r.Risks, Risk{Kind: kind, Path: path, Index: index, Detail: detail})
+}
+
+// Thresholds used by SecurityAudit.
+const (
+	auditMaxDepth         = 256
+	auditMaxString        = 1 << 20
+	auditMaxBinary        = 1 << 20
+	auditMaxBigIntDigits  = 1000
+	auditAmplification    = 10
+	auditAmplifiedMinimum = 10000
+)
+
+// SecurityAudit inspects a serialized payload without hydrating it and
+// reports the constructs that deserve scrutiny when the payload comes from an
+// untrusted source, for triaging new input sources before choosing options
+// such as Harden. Custom tags and RegExps are reported once per tag, with
+// their number of occurrences. The traversal is iterative, so deeply nested
+// payloads are reported rather than exhausting the stack. It fails only if
+// the payload is not a value table.
+func SecurityAudit(serialized string) (*SecurityReport, error) {
+	report := &SecurityReport{Risks: []Risk{}}
+	values, err := unmarshalTable(serialized)
+	if err != nil || values == nil {
+		return report, err
+	}
+
+	a := &securityAuditor{
+		report: report,
+		values: values,
+		paths:  make([]string, len(values)),
+		state:  make([]uint8, len(values)),
+		sizes:  make([]int, len(values)),
+		tags:   make(map[string]int),
+	}
+	a.traverse()
+
+	if err := a.duplicateKeys(serialized); err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(a.tagFirst))
+	for tag := range a.tagFirst {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		first := a.tagFirst[tag]
+		kind := RiskCustomTag
+		if tag == TagRegExp.String() {
+			kind = RiskRegExp
+		}
+		report.add(kind, a.paths[first], first, fmt.Sprintf("%s (%d occurrences)", tag, a.tags[tag]))
+	}
+	if total := a.sizes[0]; total >= auditAmplifiedMinimum && total >= auditAmplification*len(values) {
+		report.add(RiskAmplification, "", 0, fmt.Sprintf("%d values hydrate from %d entries", total, len(values)))
+	}
+	unreachable, first := 0, -1
+	for i, s := range a.state {
+		if s == 0 {
+			unreachable++
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if unreachable > 0 {
+		report.add(RiskUnreachable, "", first, fmt.Sprintf("%d entries", unreachable))
+	}
+	return report, nil
+}
+
+type securityAuditor struct {
+	report *SecurityReport
+	values []interface{}
+	paths  []string
+	state  []uint8 // 0 unvisited, 1 on the current path, 2 done
+	// sizes is the number of values each entry hydrates to, counting shared
+	// values once per reference and saturating at math.MaxInt.
+	sizes    []int
+	tags     map[string]int
+	tagFirst map[string]int
+	deep     bool
+}
+
+type auditFrame struct {
+	index int
+	path  string
+	depth int
+	exit  bool
+}
+
+func (a *securityAuditor) traverse() {
+	a.tagFirst = make(map[string]int)
+	stack := []auditFrame{{index: 0}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.exit {
+			a.state[f.index] = 2
+			a.sizes[f.index] = a.size(f.index)
+			continue
+		}
+		switch a.state[f.index] {
+		case 1:
+			a.report.add(RiskCycle, f.path, f.index, "refers to "+displayPath(a.paths[f.index]))
+			continue
+		case 2:
+			continue
+		}
+		a.state[f.index] = 1
+		a.paths[f.index] = f.path
+		if f.depth > auditMaxDepth && !a.deep {
+			a.deep = true
+			a.report.add(RiskDeepNesting, f.path, f.index, fmt.Sprintf("more than %d levels", auditMaxDepth))
+		}
+		a.inspect(f.index, f.path)
+
+		stack = append(stack, auditFrame{index: f.index, exit: true})
+		children := tableChildren(a.values, f.index, f.path)
+		for i := len(children) - 1; i >= 0; i-- {
+			c := children[i]
+			if c.index < 0 {
+				continue
+			}
+			if f.depth >= auditMaxDepth {
+				// Keep paths of deep values short, so their total size
+				// stays linear in the payload size.
+				c.path = f.path
+			}
+			stack = append(stack, auditFrame{index: c.index, path: c.path, depth: f.depth + 1})
+		}
+	}
+}
+
+// size returns the number of values the entry at index hydrates to, given
+// the sizes of its finished children. References back into the current path
+// count as a single value.
+func (a *securityAuditor) size(index int) int {
+	total := 1
+	for _, ref := range refPositions(a.values[index]) {
+		child, err := toInt(ref)
+		if err != nil || child < 0 || child >= len(a.values) {
+			continue
+		}
+		n := 1
+		if a.state[child] == 2 {
+			n = a.sizes[child]
+		}
+		if total > math.MaxInt-n {
+			return math.MaxInt
+		}
+		total += n
+	}
+	return total
+}
+
+// inspect reports the risks of the entry at index itself.
+func (a *securityAuditor) inspect(index int, path string) {
+	for _, ref := range refPositions(a.values[index]) {
+		if s, ok := ref.(string); ok {
+			a.report.add(RiskStringReference, path, index, s)
+			break
+		}
+	}
+
+	switch v := a.values[index].(type) {
+	case string:
+		if len(v) > auditMaxString {
+			a.report.add(RiskLargeString, path, index, formatSize(len(v)))
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			if isReservedKey(key) {
+				a.report.add(RiskReservedKey, keyPath(path, key), index, key)
+			}
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return
+		}
+		typeStr, tagged := v[0].(string)
+		if !tagged {
+			return
+		}
+		tag, builtin := ParseTag(typeStr)
+		switch {
+		case !builtin, tag == TagRegExp:
+			if a.tags[typeStr] == 0 {
+				a.tagFirst[typeStr] = index
+			}
+			a.tags[typeStr]++
+		case tag == TagNull:
+			for i := 1; i < len(v); i += 2 {
+				if key, ok := v[i].(string); ok && isReservedKey(key) {
+					a.report.add(RiskReservedKey, keyPath(path, key), index, key)
+				}
+			}
+		case tag == TagBigInt:
+			if s, ok := arrayString(v, 1); ok && len(s) > auditMaxBigIntDigits {
+				a.report.add(RiskLargeBigInt, path, index, fmt.Sprintf("%d digits", len(s)))
+			}
+		case tag.IsBinary():
+			if s, ok := arrayString(v, 1); ok && len(s)/4*3 > auditMaxBinary {
+				a.report.add(RiskLargeBinary, path, index, formatSize(len(s)/4*3))
+			}
+		}
+	}
+}
+
+// duplicateKeys reports the objects with duplicate keys.
+func (a *securityAuditor) duplicateKeys(serialized string) error {
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return err
+	}
+	for i, entry := range raw {
+		keys, err := entryKeys(entry)
+		if err != nil {
+			return fmt.Errorf("%w: index %d: %w", ErrInvalidInput, i, err)
+		}
+		for _, dup := range duplicateKeys(i, keys) {
+			a.report.add(RiskDuplicateKey, a.paths[i], i, fmt.Sprintf("%s (%d times)", dup.Key, dup.Count))
+		}
+	}
+	return nil
+}
+
+func arrayString(arr []interface{}, i int) (string, bool) {
+	if i >= len(arr) {
+		return "", false
+	}
+	s, ok := arr[i].(string)
+	return s, ok
+}
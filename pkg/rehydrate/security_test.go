@@ -0,0 +1,79 @@
+package rehydrate_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestSecurityAudit(t *testing.T) {
+	payload := `[{"__proto__":1,"re":2,"self":0,"money":3,"other":4,"s":"1","k":1,"k":1},"x",["RegExp","a+",""],["Money",1],["Money",1],{"hidden":1}]`
+	report, err := rehydrate.SecurityAudit(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[rehydrate.RiskKind]string{
+		rehydrate.RiskReservedKey:     "__proto__",
+		rehydrate.RiskCycle:           "refers to (root)",
+		rehydrate.RiskRegExp:          "RegExp (1 occurrences)",
+		rehydrate.RiskCustomTag:       "Money (2 occurrences)",
+		rehydrate.RiskStringReference: "1",
+		rehydrate.RiskDuplicateKey:    "k (2 times)",
+		rehydrate.RiskUnreachable:     "1 entries",
+	}
+	for _, risk := range report.Risks {
+		detail, ok := want[risk.Kind]
+		if !ok {
+			t.Errorf("unexpected risk %+v", risk)
+			continue
+		}
+		if risk.Detail != detail {
+			t.Errorf("%v: got detail %q, want %q", risk.Kind, risk.Detail, detail)
+		}
+		delete(want, risk.Kind)
+	}
+	for kind := range want {
+		t.Errorf("missing %v risk", kind)
+	}
+
+	clean, err := rehydrate.SecurityAudit(`[{"a":1,"b":2},"x",["Date","2024-01-01T00:00:00Z"]]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clean.Risks) != 0 {
+		t.Errorf("expected no risks, got %+v", clean.Risks)
+	}
+}
+
+func TestSecurityAuditAmplificationAndDepth(t *testing.T) {
+	// Each level references the next twice, doubling the hydrated size.
+	var b strings.Builder
+	b.WriteString("[")
+	for i := 1; i <= 20; i++ {
+		b.WriteString("[" + strconv.Itoa(i) + "," + strconv.Itoa(i) + "],")
+	}
+	b.WriteString(`"leaf"]`)
+	report, err := rehydrate.SecurityAudit(b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Has(rehydrate.RiskAmplification) || report.Has(rehydrate.RiskDeepNesting) {
+		t.Errorf("unexpected risks %+v", report.Risks)
+	}
+
+	b.Reset()
+	b.WriteString("[")
+	for i := 1; i <= 100000; i++ {
+		b.WriteString("[" + strconv.Itoa(i) + "],")
+	}
+	b.WriteString("0]")
+	report, err = rehydrate.SecurityAudit(b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Has(rehydrate.RiskDeepNesting) || report.Has(rehydrate.RiskAmplification) {
+		t.Errorf("unexpected risks %+v", report.Risks)
+	}
+}
@@ -0,0 +1,63 @@
+package rehydrate
+
+import "encoding/json"
+
+// Set is the hydrated form of a JavaScript Set. Elements keep their insertion
+// order and, like OrderedMap keys, non-comparable elements are tracked by
+// identity rather than hashed.
+type Set struct {
+	m OrderedMap
+}
+
+// NewSet returns an empty Set.
+func NewSet() *Set {
+	return &Set{}
+}
+
+// Len returns the number of elements in the set.
+func (s *Set) Len() int {
+	return s.m.Len()
+}
+
+// Add inserts v into the set.
+func (s *Set) Add(v interface{}) {
+	s.m.Set(v, nil)
+}
+
+func (s *Set) add(v interface{}, ref int) {
+	s.m.set(v, ref, nil)
+}
+
+// Has reports whether v is an element of the set.
+func (s *Set) Has(v interface{}) bool {
+	_, ok := s.m.Get(v)
+	return ok
+}
+
+// Values returns the elements in insertion order.
+func (s *Set) Values() []interface{} {
+	return s.m.Keys()
+}
+
+// MarshalJSON encodes the set as a JSON array.
+func (s *Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Values())
+}
+
+// hydrateSetSlice hydrates a Set entry to []interface{}, for WithSetAsSlice.
+func (h *hydrator) hydrateSetSlice(index int, arr []interface{}) (interface{}, error) {
+	items := make([]interface{}, len(arr)-1)
+	h.store(index, items)
+	for i := 1; i < len(arr); i++ {
+		elemIndex, err := toInt(arr[i])
+		if err != nil {
+			return nil, err
+		}
+		elem, err := h.hydrateElem(elemIndex, i-1)
+		if err != nil {
+			return nil, err
+		}
+		items[i-1] = elem
+	}
+	return items, nil
+}
@@ -0,0 +1,159 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Report attributes the serialized size of a payload to the values it
+// contains. See SizeReport.
+type Report struct {
+	// Bytes is the size of the serialized payload.
+	Bytes int `json:"bytes"`
+	// Unreachable is the size of value-table entries not reachable from the
+	// root.
+	Unreachable int `json:"unreachable"`
+	// Root is the attribution tree, starting at the payload root.
+	Root *SizeNode `json:"root"`
+}
+
+// SizeNode is the size attributed to a single value of a payload.
+type SizeNode struct {
+	Path string `json:"path"`
+	// Tag is the type tag of the value, if it has one.
+	Tag string `json:"tag,omitempty"`
+	// Own is the size of the value's own value-table entry.
+	Own int `json:"own"`
+	// Total is Own plus the attributed size of all children.
+	Total float64 `json:"total"`
+	// Attributed is the share of Total charged to the parent at Path. It
+	// equals Total unless the value is shared by Refs parents, in which case
+	// each of them is charged Total/Refs.
+	Attributed float64 `json:"attributed"`
+	// Refs is the number of references to the value in the payload.
+	Refs int `json:"refs"`
+	// Children is nil for shared values on every path but the first one they
+	// were reached by, to keep the tree linear in the payload size.
+	Children []*SizeNode `json:"children,omitempty"`
+}
+
+// SizeReport attributes the byte size of a serialized payload to the paths of
+// its values, so it is easy to see which parts of the state make it large.
+// Shared values are charged to each parent proportionally.
+func SizeReport(serialized string) (*Report, error) {
+	report := &Report{Bytes: len(serialized)}
+
+	values, err := unmarshalTable(serialized)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return report, nil
+	}
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]int, len(values))
+	for i, value := range values {
+		children, err := childRefs(value)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		for _, child := range children {
+			if child >= len(values) {
+				return nil, fmt.Errorf("%w: index %d out of range", ErrBadReference, child)
+			}
+			refs[child]++
+		}
+	}
+
+	s := &sizer{
+		values:   values,
+		raw:      raw,
+		refs:     refs,
+		totals:   make([]float64, len(values)),
+		state:    make([]uint8, len(values)),
+		expanded: make([]bool, len(values)),
+	}
+	s.total(0)
+	report.Root = s.node(0, "")
+
+	for i, entry := range raw {
+		if s.state[i] == 0 {
+			report.Unreachable += len(entry) + 1
+		}
+	}
+	return report, nil
+}
+
+type sizer struct {
+	values   []interface{}
+	raw      []json.RawMessage
+	refs     []int
+	totals   []float64
+	state    []uint8 // 0 unvisited, 1 in progress, 2 done
+	expanded []bool
+}
+
+func (s *sizer) own(index int) int {
+	// Count the separating comma so entries add up to the payload size.
+	return len(s.raw[index]) + 1
+}
+
+func (s *sizer) share(index int) float64 {
+	if n := s.refs[index]; n > 1 {
+		return s.totals[index] / float64(n)
+	}
+	return s.totals[index]
+}
+
+// total computes the attributed size of index, ignoring references back to
+// values still being computed, which only occur in cycles.
+// SizeReport validates all references before calling it.
+func (s *sizer) total(index int) float64 {
+	switch s.state[index] {
+	case 1:
+		return 0
+	case 2:
+		return s.share(index)
+	}
+	s.state[index] = 1
+
+	children, _ := childRefs(s.values[index])
+	sum := float64(s.own(index))
+	for _, child := range children {
+		sum += s.total(child)
+	}
+	s.totals[index] = sum
+	s.state[index] = 2
+	return s.share(index)
+}
+
+func (s *sizer) node(index int, path string) *SizeNode {
+	n := &SizeNode{
+		Path:       path,
+		Own:        s.own(index),
+		Total:      s.totals[index],
+		Attributed: s.share(index),
+		Refs:       s.refs[index],
+	}
+	if arr, ok := s.values[index].([]interface{}); ok && len(arr) > 0 {
+		n.Tag, _ = arr[0].(string)
+	}
+	if s.expanded[index] {
+		return n
+	}
+	s.expanded[index] = true
+
+	for _, child := range tableChildren(s.values, index, path) {
+		if child.index >= 0 {
+			n.Children = append(n.Children, s.node(child.index, child.path))
+		}
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		return n.Children[i].Attributed > n.Children[j].Attributed
+	})
+	return n
+}
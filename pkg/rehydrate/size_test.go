@@ -0,0 +1,36 @@
+package rehydrate_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestSizeReport(t *testing.T) {
+	input := `[{"a":1,"b":2},{"x":3},{"y":3},"a long shared string value"]`
+	report, err := rehydrate.SizeReport(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Bytes != len(input) {
+		t.Fatalf("Bytes = %d", report.Bytes)
+	}
+	root := report.Root
+	// Every entry is reachable, so the root accounts for all entries: the
+	// payload minus its brackets plus the comma counted for the last entry.
+	if want := float64(len(input) - 1); math.Abs(root.Total-want) > 1e-9 {
+		t.Fatalf("root total = %v, want %v", root.Total, want)
+	}
+	if len(root.Children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(root.Children))
+	}
+	a, b := root.Children[0], root.Children[1]
+	if a.Attributed != b.Attributed {
+		t.Fatalf("shared string should be split evenly: %v vs %v", a.Attributed, b.Attributed)
+	}
+	shared := a.Children[0]
+	if shared.Refs != 2 || shared.Attributed != shared.Total/2 {
+		t.Fatalf("unexpected shared node %+v", shared)
+	}
+}
@@ -0,0 +1,335 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Format identifies a serialization format Sniff can recognise.
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	FormatJSON
+	FormatDevalue
+	FormatSuperJSON
+	FormatFlatted
+	FormatTurboStream
+)
+
+var formatNames = [...]string{
+	FormatUnknown:     "unknown",
+	FormatJSON:        "json",
+	FormatDevalue:     "devalue",
+	FormatSuperJSON:   "superjson",
+	FormatFlatted:     "flatted",
+	FormatTurboStream: "turbo-stream",
+}
+
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return formatNames[FormatUnknown]
+	}
+	return formatNames[f]
+}
+
+// isTurboStreamChunk reports whether line starts like the promise and error
+// chunks following the first line of a turbo-stream, such as P1:[...].
+func isTurboStreamChunk(line []byte) bool {
+	if len(line) < 3 || line[0] != 'P' && line[0] != 'E' {
+		return false
+	}
+	i := 1
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		i++
+	}
+	return i > 1 && i < len(line) && line[i] == ':'
+}
+
+// Sniff guesses the format of data and returns it together with a confidence
+// between 0 and 1. Many documents are valid in more than one format, e.g.
+// [1] is both plain JSON and a devalue payload; Sniff then prefers the more
+// specific format when its structure is consistent, such as every reference
+// of a devalue table being in range. Data that is not JSON at all yields
+// FormatUnknown with confidence 0.
+//
+// Sniff and ParseAny disagree on tables with references out of range, such
+// as [{"a":5},"x"]: Sniff reports them as JSON, the format they are valid
+// in, while ParseAny decodes them as devalue and fails with ErrBadReference,
+// so corrupt payloads are not passed on as plain JSON. Use ParseAny to
+// decode and Sniff to label data, for example in logs.
+func Sniff(data []byte) (Format, float64) {
+	data = bytes.TrimSpace(data)
+	if lines := bytes.Split(data, []byte("\n")); len(lines) > 1 {
+		chunks := 0
+		for _, line := range lines[1:] {
+			if isTurboStreamChunk(bytes.TrimSpace(line)) {
+				chunks++
+			}
+		}
+		if chunks > 0 && json.Valid(lines[0]) {
+			return FormatTurboStream, 0.5 + 0.5*float64(chunks)/float64(len(lines)-1)
+		}
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return FormatUnknown, 0
+	}
+
+	switch v := parsed.(type) {
+	case map[string]interface{}:
+		if _, ok := v["json"]; ok {
+			meta, hasMeta := v["meta"].(map[string]interface{})
+			switch {
+			case hasMeta && (meta["values"] != nil || meta["referentialEqualities"] != nil):
+				return FormatSuperJSON, 0.95
+			case len(v) == 1:
+				return FormatSuperJSON, 0.6
+			}
+		}
+	case float64:
+		if v < 0 && v >= NEGATIVE_ZERO && v == float64(int(v)) {
+			return FormatDevalue, 0.6
+		}
+	case []interface{}:
+		if c := sniffFlatted(v); c > 0.5 {
+			return FormatFlatted, c
+		}
+		if c := sniffDevalue(v); c > 0.5 {
+			return FormatDevalue, c
+		}
+	}
+	return FormatJSON, 0.9
+}
+
+// sniffDevalue rates how likely values is a devalue value table.
+func sniffDevalue(values []interface{}) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	for _, value := range values {
+		arr, _ := value.([]interface{})
+		if len(arr) > 0 {
+			if _, tagged := arr[0].(string); !tagged {
+				// Untagged arrays must hold only indices and sentinels.
+				for _, item := range arr {
+					if !isTableIndex(item, len(values)) {
+						return 0
+					}
+				}
+			}
+		}
+		children, err := childRefs(value)
+		if err != nil {
+			return 0
+		}
+		for _, child := range children {
+			if child >= len(values) {
+				return 0
+			}
+		}
+		if obj, ok := value.(map[string]interface{}); ok {
+			for _, item := range obj {
+				if !isTableIndex(item, len(values)) {
+					return 0
+				}
+			}
+		}
+	}
+
+	switch root := values[0].(type) {
+	case map[string]interface{}:
+		return 0.9
+	case []interface{}:
+		if len(root) == 0 {
+			return 0.6
+		}
+		if typeStr, ok := root[0].(string); ok {
+			if _, builtin := ParseTag(typeStr); builtin {
+				return 0.95
+			}
+			if len(root) == 2 {
+				return 0.7
+			}
+			return 0
+		}
+		return 0.8
+	}
+	// A scalar root followed by more entries leaves them unreachable, which
+	// a serializer would not produce.
+	if len(values) == 1 {
+		return 0.6
+	}
+	return 0
+}
+
+// sniffFlatted rates how likely values is a flatted table, whose references
+// are numeric strings rather than numbers.
+func sniffFlatted(values []interface{}) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var refs, total int
+	count := func(item interface{}) {
+		switch s := item.(type) {
+		case string:
+			total++
+			if n, err := strconv.Atoi(s); err == nil && strconv.Itoa(n) == s && n >= 0 && n < len(values) {
+				refs++
+			}
+		case map[string]interface{}, []interface{}:
+		default:
+			total++
+		}
+	}
+	switch root := values[0].(type) {
+	case map[string]interface{}:
+		for _, item := range root {
+			count(item)
+		}
+	case []interface{}:
+		for _, item := range root {
+			count(item)
+		}
+	default:
+		return 0
+	}
+	if total == 0 || refs < total {
+		return 0
+	}
+	return 0.9
+}
+
+// devalueShaped reports whether values has the shape of a devalue table even
+// if some of its references are out of range: the root is a container, every
+// untagged array holds only index-shaped numbers and every object maps to
+// them. ParseAny uses it to report such tables as corrupt devalue rather than
+// returning them as plain JSON.
+func devalueShaped(values []interface{}) bool {
+	if len(values) == 0 {
+		return false
+	}
+	switch root := values[0].(type) {
+	case map[string]interface{}:
+	case []interface{}:
+		if len(root) > 0 {
+			if typeStr, ok := root[0].(string); ok {
+				if _, builtin := ParseTag(typeStr); !builtin && len(root) != 2 {
+					return false
+				}
+			}
+		}
+	default:
+		return false
+	}
+	for _, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			if len(v) > 0 {
+				if _, tagged := v[0].(string); tagged {
+					continue
+				}
+			}
+			for _, item := range v {
+				if !isIndexShaped(item) {
+					return false
+				}
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				if !isIndexShaped(item) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// isIndexShaped reports whether v is a whole number that could be an index or
+// a sentinel, regardless of the length of the table.
+func isIndexShaped(v interface{}) bool {
+	f, ok := v.(float64)
+	return ok && f >= NEGATIVE_ZERO && f == math.Trunc(f)
+}
+
+func isTableIndex(v interface{}, length int) bool {
+	if _, ok := v.(float64); !ok {
+		return false
+	}
+	n, err := toInt(v)
+	return err == nil && n >= NEGATIVE_ZERO && n < length
+}
+
+// ParseAuto sniffs the format of data and hydrates it accordingly.
+//
+// Deprecated: ParseAuto is ParseAny, which callers should use instead.
+func ParseAuto(data []byte, opts ...Option) (interface{}, Format, error) {
+	return ParseAny(data, opts...)
+}
+
+// ParseAny decodes data in the first format whose structure it matches,
+// trying devalue, superjson, flatted and plain JSON in that order, and returns
+// the value together with that format. Unlike catching the error of each
+// parser in turn, a payload that matches a format but fails to decode is not
+// retried as the next format: the failure is returned as a *FormatError, so
+// corrupt payloads are told apart from unrecognised ones, which fail with
+// ErrInvalidInput and FormatUnknown. In particular, an array whose entries
+// are all index-shaped or tagged is decoded as devalue, so a reference out of
+// range fails with ErrBadReference instead of passing as plain JSON. opts
+// apply to devalue and superjson.
+func ParseAny(data []byte, opts ...Option) (interface{}, Format, error) {
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, FormatUnknown, fmt.Errorf("%w: unrecognised format: %w", ErrInvalidInput, err)
+	}
+
+	devalue := func() (interface{}, error) { return ParseWithOptions(string(data), opts...) }
+	format, decode := FormatJSON, func() (interface{}, error) { return parsed, nil }
+	switch v := parsed.(type) {
+	case float64:
+		if v < 0 && v >= NEGATIVE_ZERO && v == float64(int(v)) {
+			format, decode = FormatDevalue, devalue
+		}
+	case []interface{}:
+		switch {
+		case sniffDevalue(v) > 0, devalueShaped(v):
+			format, decode = FormatDevalue, devalue
+		case sniffFlatted(v) > 0:
+			format, decode = FormatFlatted, func() (interface{}, error) { return ParseFlatted(data) }
+		}
+	case map[string]interface{}:
+		if _, ok := v["json"]; ok {
+			if _, hasMeta := v["meta"].(map[string]interface{}); hasMeta || len(v) == 1 {
+				format, decode = FormatSuperJSON, func() (interface{}, error) { return ParseSuperJSON(data, opts...) }
+			}
+		}
+	}
+
+	v, err := decode()
+	if err != nil {
+		return nil, format, &FormatError{Format: format, Err: err}
+	}
+	return v, format, nil
+}
+
+// RehydrateAny is like RehydrateWith, but accepts every format ParseAny
+// decodes and returns the format it detected. Losses are reported for
+// devalue payloads only, as other formats have no value table to inspect.
+func RehydrateAny(data []byte, extra Revivers, opts ...Option) (string, Format, error) {
+	all := rehydrateOptions(extra, opts)
+	v, format, err := ParseAny(data, all...)
+	if err != nil {
+		return "", format, err
+	}
+	o := newOptions(all)
+	if format != FormatDevalue {
+		o.lossReport = nil
+	}
+	out, err := o.render(string(data), v)
+	return out, format, err
+}
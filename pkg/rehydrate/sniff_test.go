@@ -0,0 +1,120 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		input string
+		want  rehydrate.Format
+	}{
+		{`[{"a":1,"b":2},"x",["Set",1]]`, rehydrate.FormatDevalue},
+		{`[["Date","2024-01-01T00:00:00Z"]]`, rehydrate.FormatDevalue},
+		{`[[1,2],"a","b"]`, rehydrate.FormatDevalue},
+		{`[1,2]`, rehydrate.FormatJSON},
+		{`-1`, rehydrate.FormatDevalue},
+		{`{"a":1}`, rehydrate.FormatJSON},
+		{`["a","b"]`, rehydrate.FormatJSON},
+		{`[{"a":1,"b":99}]`, rehydrate.FormatJSON},
+		{`[{"a":5},"x"]`, rehydrate.FormatJSON},
+		{`[[1.5,2]]`, rehydrate.FormatJSON},
+		{`{"json":{"a":1},"meta":{"values":{"a":["Date"]}}}`, rehydrate.FormatSuperJSON},
+		{`[{"a":"1","b":"2"},"x",{"c":"0"}]`, rehydrate.FormatFlatted},
+		{"[{\"a\":1},[\"P\",2]]\nP2:[3]\n", rehydrate.FormatTurboStream},
+		{`not json`, rehydrate.FormatUnknown},
+	}
+	for _, tt := range tests {
+		got, confidence := rehydrate.Sniff([]byte(tt.input))
+		if got != tt.want {
+			t.Errorf("Sniff(%s) = %v (%.2f), want %v", tt.input, got, confidence, tt.want)
+		}
+		if got != rehydrate.FormatUnknown && (confidence <= 0 || confidence > 1) {
+			t.Errorf("Sniff(%s) confidence %v out of range", tt.input, confidence)
+		}
+	}
+}
+
+func TestParseAuto(t *testing.T) {
+	v, format, err := rehydrate.ParseAuto([]byte(`[{"a":1},"x"]`))
+	if err != nil || format != rehydrate.FormatDevalue || !reflect.DeepEqual(v, map[string]interface{}{"a": "x"}) {
+		t.Errorf("devalue: %v %v %v", v, format, err)
+	}
+	v, format, err = rehydrate.ParseAuto([]byte(`{"a":"x"}`))
+	if err != nil || format != rehydrate.FormatJSON || !reflect.DeepEqual(v, map[string]interface{}{"a": "x"}) {
+		t.Errorf("json: %v %v %v", v, format, err)
+	}
+	v, format, err = rehydrate.ParseAuto([]byte(`{"json":1,"meta":{"values":{}}}`))
+	if err != nil || format != rehydrate.FormatSuperJSON || v != 1.0 {
+		t.Errorf("superjson: %v %v %v", v, format, err)
+	}
+}
+
+func TestRehydrateAny(t *testing.T) {
+	tests := []struct {
+		input, want string
+		format      rehydrate.Format
+	}{
+		{`[{"a":1},["Reactive",2],["Set",3],"x"]`, `{"a":["x"]}`, rehydrate.FormatDevalue},
+		{`{"json":{"d":"2024-01-02T00:00:00.000Z"},"meta":{"values":{"d":["Date"]}}}`, `{"d":"2024-01-02T00:00:00Z"}`, rehydrate.FormatSuperJSON},
+		{`[{"a":"1"},"x"]`, `{"a":"x"}`, rehydrate.FormatFlatted},
+		{`{"a":1}`, `{"a":1}`, rehydrate.FormatJSON},
+	}
+	for _, tt := range tests {
+		got, format, err := rehydrate.RehydrateAny([]byte(tt.input), nil, rehydrate.WithIndent("", ""))
+		if err != nil || got != tt.want || format != tt.format {
+			t.Errorf("RehydrateAny(%s) = %s, %v, %v; want %s, %v", tt.input, got, format, err, tt.want, tt.format)
+		}
+	}
+}
+
+func TestParseAny(t *testing.T) {
+	tests := []struct {
+		input  string
+		format rehydrate.Format
+		want   interface{}
+	}{
+		{`[{"a":1},"x"]`, rehydrate.FormatDevalue, map[string]interface{}{"a": "x"}},
+		{`{"json":{"a":1},"meta":{"values":{"a":["undefined"]}}}`, rehydrate.FormatSuperJSON, map[string]interface{}{"a": nil}},
+		{`[{"a":"1"},"x"]`, rehydrate.FormatFlatted, map[string]interface{}{"a": "x"}},
+		{`[1,2]`, rehydrate.FormatJSON, []interface{}{1.0, 2.0}},
+		{`{"a":1}`, rehydrate.FormatJSON, map[string]interface{}{"a": 1.0}},
+	}
+	for _, tt := range tests {
+		v, format, err := rehydrate.ParseAny([]byte(tt.input))
+		if err != nil {
+			t.Errorf("ParseAny(%s): %v", tt.input, err)
+			continue
+		}
+		if format != tt.format || !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("ParseAny(%s) = %v, %v; want %v, %v", tt.input, v, format, tt.want, tt.format)
+		}
+	}
+
+	// A devalue payload with a bad tag is corrupt, not plain JSON.
+	_, format, err := rehydrate.ParseAny([]byte(`[["Date","yesterday"]]`))
+	var formatErr *rehydrate.FormatError
+	if format != rehydrate.FormatDevalue || !errors.As(err, &formatErr) || !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("corrupt devalue: %v %v", format, err)
+	}
+	_, format, err = rehydrate.ParseAny([]byte(`{"json":1,"meta":{"values":["Date"]}}`))
+	if format != rehydrate.FormatSuperJSON || !errors.As(err, &formatErr) {
+		t.Errorf("corrupt superjson: %v %v", format, err)
+	}
+	// Tables shaped like devalue stay devalue when a reference is out of
+	// range, rather than passing as plain JSON.
+	for _, input := range []string{`[{"a":7}]`, `[["Set",1,9],"x"]`, `[[5]]`, `[{"a":5},"x"]`} {
+		_, format, err = rehydrate.ParseAny([]byte(input))
+		if format != rehydrate.FormatDevalue || !errors.As(err, &formatErr) || !errors.Is(err, rehydrate.ErrBadReference) {
+			t.Errorf("ParseAny(%s) = %v, %v; want devalue, bad reference", input, format, err)
+		}
+	}
+	_, format, err = rehydrate.ParseAny([]byte(`not json`))
+	if format != rehydrate.FormatUnknown || !errors.Is(err, rehydrate.ErrInvalidInput) || errors.As(err, &formatErr) {
+		t.Errorf("not json: %v %v", format, err)
+	}
+}
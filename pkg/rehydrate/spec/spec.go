@@ -0,0 +1,274 @@
+// Package spec describes the payload format as data: its sentinels, the
+// layout and arity of every built-in tag, and how references behave. The
+// description and a set of conformance fixtures are embedded as JSON, so
+// ports of the format to other languages can check themselves against the
+// same files, and Validate checks payloads against the description alone,
+// independently of the hydrator.
+package spec
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+//go:embed spec.json
+var specJSON []byte
+
+//go:embed fixtures.json
+var fixturesJSON []byte
+
+// Spec is the machine-readable description of the format.
+type Spec struct {
+	Version    int               `json:"version"`
+	Root       string            `json:"root"`
+	References string            `json:"references"`
+	Sentinels  []Sentinel        `json:"sentinels"`
+	Layouts    map[string]string `json:"layouts"`
+	Tags       []TagRule         `json:"tags"`
+	// CustomTag is the rule for tags not listed in Tags, which are handled
+	// by revivers.
+	CustomTag TagRule `json:"customTag"`
+}
+
+// Sentinel is a negative reference standing for a value that has no entry.
+type Sentinel struct {
+	Name    string `json:"name"`
+	Value   int    `json:"value"`
+	Meaning string `json:"meaning"`
+	// ArrayOnly sentinels may only appear as elements of plain arrays.
+	ArrayOnly bool `json:"arrayOnly,omitempty"`
+}
+
+// TagRule describes the arguments of a tagged entry ["Tag", args...].
+type TagRule struct {
+	Name string `json:"name,omitempty"`
+	// Layout is one of the keys of Spec.Layouts: inline, refs, pairs,
+	// entries or custom.
+	Layout string `json:"layout"`
+	// Args lists the JSON types of inline arguments: string or any. Inline
+	// tags need at least this many arguments.
+	Args []string `json:"args,omitempty"`
+	// Format constrains the first inline argument: date-time, integer or
+	// base64.
+	Format string `json:"format,omitempty"`
+}
+
+// Fixture is a conformance case.
+type Fixture struct {
+	Name    string `json:"name"`
+	Payload string `json:"payload"`
+	// Rule is the rule Validate reports as violated, empty for valid
+	// payloads.
+	Rule string `json:"rule,omitempty"`
+	// Error is the category of the error the reference hydrator returns
+	// without any revivers: invalid_input, bad_reference or unknown_type.
+	// It is empty if hydration succeeds.
+	Error   string `json:"error,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Load returns the embedded description.
+func Load() *Spec {
+	var s Spec
+	if err := json.Unmarshal(specJSON, &s); err != nil {
+		panic("spec: invalid spec.json: " + err.Error())
+	}
+	return &s
+}
+
+// Fixtures returns the embedded conformance cases.
+func Fixtures() []Fixture {
+	var fixtures []Fixture
+	if err := json.Unmarshal(fixturesJSON, &fixtures); err != nil {
+		panic("spec: invalid fixtures.json: " + err.Error())
+	}
+	return fixtures
+}
+
+// Tag returns the rule for the tag name, which is CustomTag for tags that
+// are not built in.
+func (s *Spec) Tag(name string) TagRule {
+	for _, rule := range s.Tags {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return s.CustomTag
+}
+
+// Violation is a place where a payload breaks a rule of the spec.
+type Violation struct {
+	// Index is the value-table index of the offending entry, or -1 for the
+	// payload as a whole.
+	Index int `json:"index"`
+	// Rule names the broken rule: root, reference-integer, reference-range,
+	// hole-position, arity, type, format or layout.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v Violation) Error() string {
+	if v.Index < 0 {
+		return v.Rule + ": " + v.Message
+	}
+	return fmt.Sprintf("index %d: %s: %s", v.Index, v.Rule, v.Message)
+}
+
+// Validate checks payload against the spec and returns every violation
+// found, or nil if the payload conforms.
+func (s *Spec) Validate(payload string) []Violation {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(payload), &parsed); err != nil {
+		return []Violation{{Index: -1, Rule: "root", Message: err.Error()}}
+	}
+	if f, ok := parsed.(float64); ok {
+		if !s.isSentinel(f, false) {
+			return []Violation{{Index: -1, Rule: "root", Message: fmt.Sprintf("%v is not a sentinel", f)}}
+		}
+		return nil
+	}
+	table, ok := parsed.([]interface{})
+	if !ok || len(table) == 0 {
+		return []Violation{{Index: -1, Rule: "root", Message: "the payload must be a non-empty array"}}
+	}
+
+	v := &validator{spec: s, size: len(table)}
+	for i, entry := range table {
+		v.index = i
+		v.entry(entry)
+	}
+	return v.violations
+}
+
+func (s *Spec) isSentinel(f float64, inArray bool) bool {
+	for _, sentinel := range s.Sentinels {
+		if float64(sentinel.Value) == f {
+			return inArray || !sentinel.ArrayOnly
+		}
+	}
+	return false
+}
+
+type validator struct {
+	spec       *Spec
+	size       int
+	index      int
+	violations []Violation
+}
+
+func (v *validator) fail(rule, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Index: v.index, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) ref(x interface{}, inArray bool) {
+	f, ok := x.(float64)
+	if !ok || f != math.Trunc(f) {
+		v.fail("reference-integer", "%v is not an integer reference", x)
+		return
+	}
+	if f >= 0 {
+		if f >= float64(v.size) {
+			v.fail("reference-range", "index %v out of range", f)
+		}
+		return
+	}
+	if v.spec.isSentinel(f, true) && !v.spec.isSentinel(f, inArray) {
+		v.fail("hole-position", "sentinel %v is only allowed in arrays", f)
+	} else if !v.spec.isSentinel(f, inArray) {
+		v.fail("reference-range", "%v is not a sentinel", f)
+	}
+}
+
+func (v *validator) entry(entry interface{}) {
+	switch e := entry.(type) {
+	case map[string]interface{}:
+		for _, x := range e {
+			v.ref(x, false)
+		}
+	case []interface{}:
+		tag, tagged := "", false
+		if len(e) > 0 {
+			tag, tagged = e[0].(string)
+		}
+		if !tagged {
+			for _, x := range e {
+				v.ref(x, true)
+			}
+			return
+		}
+		v.tagged(v.spec.Tag(tag), tag, e[1:])
+	}
+}
+
+func (v *validator) tagged(rule TagRule, tag string, args []interface{}) {
+	switch rule.Layout {
+	case "refs":
+		for _, x := range args {
+			v.ref(x, false)
+		}
+	case "pairs":
+		if len(args)%2 != 0 {
+			v.fail("arity", "%s needs an even number of arguments", tag)
+			return
+		}
+		for _, x := range args {
+			v.ref(x, false)
+		}
+	case "entries":
+		if len(args)%2 != 0 {
+			v.fail("arity", "%s needs an even number of arguments", tag)
+			return
+		}
+		for i := 0; i < len(args); i += 2 {
+			if _, ok := args[i].(string); !ok {
+				v.fail("type", "%s key %v is not a string", tag, args[i])
+			}
+			v.ref(args[i+1], false)
+		}
+	case "custom":
+		if len(args) < 1 {
+			v.fail("arity", "custom tag %s needs a value reference", tag)
+			return
+		}
+		v.ref(args[0], false)
+	case "inline":
+		if len(args) < len(rule.Args) {
+			v.fail("arity", "%s needs %d arguments, has %d", tag, len(rule.Args), len(args))
+			return
+		}
+		for i, typ := range rule.Args {
+			if _, ok := args[i].(string); typ == "string" && !ok {
+				v.fail("type", "%s argument %d must be a string", tag, i+1)
+				return
+			}
+		}
+		if rule.Format != "" {
+			if err := checkFormat(rule.Format, args[0].(string)); err != nil {
+				v.fail("format", "%s: %v", tag, err)
+			}
+		}
+	default:
+		v.fail("layout", "unknown layout %q for %s", rule.Layout, tag)
+	}
+}
+
+func checkFormat(format, s string) error {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err
+	case "integer":
+		if _, ok := new(big.Int).SetString(s, 10); !ok {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+	case "base64":
+		_, err := base64.StdEncoding.DecodeString(s)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package spec_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/spec"
+)
+
+func category(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, rehydrate.ErrUnknownType):
+		return "unknown_type"
+	case errors.Is(err, rehydrate.ErrBadReference):
+		return "bad_reference"
+	case errors.Is(err, rehydrate.ErrInvalidInput):
+		return "invalid_input"
+	}
+	return err.Error()
+}
+
+// TestFixtures checks both the validator and the hydrator against the
+// shared fixtures.
+func TestFixtures(t *testing.T) {
+	s := spec.Load()
+	for _, f := range spec.Fixtures() {
+		t.Run(f.Name, func(t *testing.T) {
+			violations := s.Validate(f.Payload)
+			switch {
+			case f.Rule == "" && violations != nil:
+				t.Errorf("unexpected violations %v", violations)
+			case f.Rule != "" && (len(violations) == 0 || violations[0].Rule != f.Rule):
+				t.Errorf("expected a %s violation, got %v", f.Rule, violations)
+			}
+
+			_, err := rehydrate.Parse(f.Payload, nil)
+			if got := category(err); got != f.Error {
+				t.Errorf("hydrator returned %q (%v), want %q", got, err, f.Error)
+			}
+		})
+	}
+}
+
+// TestTagsMatchPackage keeps the spec's tag list in sync with the built-in
+// tags of the package.
+func TestTagsMatchPackage(t *testing.T) {
+	s := spec.Load()
+	for _, rule := range s.Tags {
+		if _, ok := rehydrate.ParseTag(rule.Name); !ok {
+			t.Errorf("spec tag %s is not built in", rule.Name)
+		}
+		if _, ok := s.Layouts[rule.Layout]; !ok {
+			t.Errorf("tag %s has undocumented layout %s", rule.Name, rule.Layout)
+		}
+	}
+	for tag := rehydrate.TagDate; tag <= rehydrate.TagArrayBuffer; tag++ {
+		if s.Tag(tag.String()).Name != tag.String() {
+			t.Errorf("built-in tag %s is missing from the spec", tag)
+		}
+	}
+	sentinels := map[string]int{
+		"UNDEFINED": rehydrate.UNDEFINED, "HOLE": rehydrate.HOLE, "NAN": rehydrate.NAN,
+		"POSITIVE_INFINITY": rehydrate.POSITIVE_INFINITY, "NEGATIVE_INFINITY": rehydrate.NEGATIVE_INFINITY,
+		"NEGATIVE_ZERO": rehydrate.NEGATIVE_ZERO,
+	}
+	for _, sentinel := range s.Sentinels {
+		if sentinels[sentinel.Name] != sentinel.Value {
+			t.Errorf("sentinel %s is %d in the spec", sentinel.Name, sentinel.Value)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decoder reads payloads from an input stream, such as a multi-megabyte
+// _payload.json file, decoding the value table one entry at a time instead
+// of holding the serialized text in memory next to its decoded form.
+type Decoder struct {
+	dec  *json.Decoder
+	opts []Option
+}
+
+// NewDecoder returns a decoder reading from r and hydrating with opts. The
+// decoder buffers its input and may read past the end of a payload.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r), opts: opts}
+}
+
+// Decode reads the next payload from the input and hydrates it like Parse.
+// Payloads may follow each other in the input, separated by whitespace;
+// Decode returns io.EOF once there are none left. Options inspecting the
+// serialized text, such as WithUTF16Strings or WithDuplicateKeys, see each
+// entry as it is read; the offsets recorded by WithProvenance count from
+// the opening bracket of the payload.
+func (d *Decoder) Decode(revivers Revivers) (result interface{}, err error) {
+	o := newOptions(append([]Option{WithRevivers(revivers)}, d.opts...))
+	if o.recoverPanics {
+		defer o.recoverPanic(&result, &err)
+	}
+
+	tok, err := d.dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if num, ok := tok.(float64); ok {
+		index, err := toInt(num)
+		if err != nil {
+			return nil, err
+		}
+		if o.provenance != nil {
+			o.provenance.reset(nil)
+		}
+		return newHydrator(o).hydrateRoot(index, true)
+	}
+	if tok != json.Delim('[') {
+		return nil, fmt.Errorf("%w: payload is not a value table", ErrInvalidInput)
+	}
+
+	// Offsets are relative to the opening bracket of the payload.
+	base := int(d.dec.InputOffset()) - 1
+	var values []interface{}
+	var offsets [][2]int
+	for i := 0; d.dec.More(); i++ {
+		var entry json.RawMessage
+		if err := d.dec.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		v, err := decodeEntry(entry, o)
+		if err != nil {
+			return nil, fmt.Errorf("%w: index %d: %w", ErrInvalidInput, i, err)
+		}
+		if o.duplicateKeys != KeepLastDuplicateKey {
+			if err := checkEntryDuplicates(i, entry, o); err != nil {
+				return nil, err
+			}
+		}
+		if o.provenance != nil {
+			end := int(d.dec.InputOffset()) - base
+			offsets = append(offsets, [2]int{end - len(entry), end})
+		}
+		values = append(values, v)
+	}
+	if _, err := d.dec.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if len(values) == 0 {
+		return nil, ErrInvalidInput
+	}
+	if o.provenance != nil {
+		o.provenance.reset(offsets)
+	}
+	return newHydrator(o).hydrateTable(values)
+}
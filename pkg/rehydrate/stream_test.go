@@ -0,0 +1,75 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestDecoder(t *testing.T) {
+	input := `[{"when":1,"tags":2},["Date","2024-01-02T00:00:00.000Z"],["Set",3],"a"]
+	-1 [["Point",1],7]`
+	revivers := rehydrate.Revivers{"Point": func(v interface{}) (interface{}, error) { return v.(float64) * 2, nil }}
+	dec := rehydrate.NewDecoder(strings.NewReader(input))
+
+	first, err := dec.Decode(revivers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := rehydrate.Parse(input[:strings.IndexByte(input, '\n')], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("got %#v, want %#v", first, want)
+	}
+	if v, err := dec.Decode(revivers); v != nil || err != nil {
+		t.Errorf("standalone: got %v, %v", v, err)
+	}
+	if v, err := dec.Decode(revivers); v != 14.0 || err != nil {
+		t.Errorf("revived: got %v, %v", v, err)
+	}
+	if _, err := dec.Decode(revivers); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoderOptions(t *testing.T) {
+	payload := ` [ {"name":1} , "Ada"]`
+	var p rehydrate.Provenance
+	dec := rehydrate.NewDecoder(strings.NewReader(payload), rehydrate.WithProvenance(&p))
+	if _, err := dec.Decode(nil); err != nil {
+		t.Fatal(err)
+	}
+	origin, ok := p.Of("name")
+	if !ok {
+		t.Fatal("no origin")
+	}
+	// Offsets are relative to the start of the payload.
+	if got := payload[1:][origin.Start:origin.End]; got != `"Ada"` {
+		t.Errorf("got %s", got)
+	}
+
+	dec = rehydrate.NewDecoder(strings.NewReader(`[{"a":1,"a":1},0]`), rehydrate.WithDuplicateKeys(rehydrate.RejectDuplicateKeys))
+	if _, err := dec.Decode(nil); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput, got %v", err)
+	}
+
+	dec = rehydrate.NewDecoder(strings.NewReader(`[9007199254740993]`), rehydrate.WithNumberMode(rehydrate.NumberInt64))
+	if v, err := dec.Decode(nil); v != int64(9007199254740993) || err != nil {
+		t.Errorf("got %v, %v", v, err)
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	for _, input := range []string{`[]`, `{"a":1}`, `[1,`, `"x"`} {
+		dec := rehydrate.NewDecoder(strings.NewReader(input))
+		if _, err := dec.Decode(nil); !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,525 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReducerFunc converts values of a custom type for Stringify. It reports
+// false for values it does not handle; otherwise the value it returns is
+// serialized in place of v under the reducer's tag, for the reviver of the
+// same tag to turn back into the custom type. Reducers see every value,
+// including strings and numbers, before the built-in types are handled.
+type ReducerFunc func(v interface{}) (interface{}, bool)
+
+// Reducers maps custom type tags to their reducers.
+type Reducers map[string]ReducerFunc
+
+// WithReducers adds reducers for custom type tags, used by
+// StringifyWithOptions. Reducers are tried in the order of their tags.
+func WithReducers(reducers map[string]ReducerFunc) Option {
+	return func(o *options) {
+		if o.reducers == nil {
+			o.reducers = Reducers{}
+		}
+		for tag, reducer := range reducers {
+			o.reducers[tag] = reducer
+		}
+	}
+}
+
+// Stringify serializes v into a payload Parse hydrates back, consulting
+// reducers for custom types. See StringifyWithOptions.
+func Stringify(v interface{}, reducers map[string]ReducerFunc) (string, error) {
+	return StringifyWithOptions(v, WithReducers(reducers))
+}
+
+// StringifyWithOptions serializes v into the value-table format produced by
+// devalue, so Go programs can hand data to Nuxt and SvelteKit frontends.
+// Values are mapped as follows:
+//
+//   - nil, booleans, strings and numbers become primitives; NaN, the
+//     infinities and -0 become their sentinels
+//   - time.Time becomes a Date, *big.Int a BigInt, *RegExp a RegExp and
+//     *regexp.Regexp a RegExp without flags
+//   - *Set, *OrderedMap and *Wrapped become Sets, Maps and boxed primitives
+//   - []byte becomes a Uint8Array; []int8, []uint16, []int16, []uint32,
+//     []int32, []float32, []float64, []int64 and []uint64 become the typed
+//     arrays of the same element type, encoded with WithBinaryCodec codecs
+//   - slices and arrays become arrays, maps with string keys objects and
+//     other maps Maps; keys are written in sorted order
+//   - pointers are followed, and structs and other types are converted
+//     with encoding/json first
+//
+// Maps, slices and pointers referenced more than once, including cycles,
+// are written once and referenced by index, and so are equal primitives.
+// Values such as *Truncated or *LazyRef, which stand for data the payload
+// they came from held in full, cannot be serialized; neither can channels
+// or functions. The error wraps ErrInvalidInput.
+func StringifyWithOptions(v interface{}, opts ...Option) (string, error) {
+	o := newOptions(opts)
+	s := &stringifier{
+		opts:       o,
+		ids:        make(map[identity]int),
+		primitives: make(map[string]int),
+	}
+	if o.timeBudget > 0 {
+		s.deadline = o.clock.Now().Add(o.timeBudget)
+	}
+	for tag := range o.reducers {
+		s.tags = append(s.tags, tag)
+	}
+	sort.Strings(s.tags)
+
+	root, err := s.flatten("", v)
+	if err != nil {
+		return "", err
+	}
+	if root < 0 {
+		// A payload consisting only of a sentinel is the bare number.
+		return strconv.Itoa(root), nil
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, entry := range s.entries {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(entry)
+	}
+	b.WriteByte(']')
+	return b.String(), nil
+}
+
+type stringifier struct {
+	opts    *options
+	tags    []string
+	entries []string
+	// ids indexes the entries of containers and pointers by identity and
+	// primitives by their JSON literal.
+	ids        map[identity]int
+	primitives map[string]int
+
+	// deadline is when WithTimeBudget runs out, checked every
+	// budgetCheckInterval entries.
+	deadline time.Time
+}
+
+type identity struct {
+	t   reflect.Type
+	ptr uintptr
+	len int
+}
+
+// identityOf returns the identity of maps, non-empty slices and pointers.
+func identityOf(rv reflect.Value) (identity, bool) {
+	switch rv.Kind() {
+	case reflect.Map, reflect.Pointer:
+		if !rv.IsNil() {
+			return identity{t: rv.Type(), ptr: rv.Pointer()}, true
+		}
+	case reflect.Slice:
+		if rv.Len() > 0 {
+			return identity{t: rv.Type(), ptr: rv.Pointer(), len: rv.Len()}, true
+		}
+	}
+	return identity{}, false
+}
+
+func (s *stringifier) unsupported(path string, v interface{}) error {
+	return fmt.Errorf("%w: cannot stringify %T at %s", ErrInvalidInput, v, displayPath(path))
+}
+
+// flatten adds v to the value table and returns its index or sentinel.
+func (s *stringifier) flatten(path string, v interface{}) (int, error) {
+	if f, ok := toFloat(v); ok {
+		switch {
+		case math.IsNaN(f):
+			return NAN, nil
+		case math.IsInf(f, 1):
+			return POSITIVE_INFINITY, nil
+		case math.IsInf(f, -1):
+			return NEGATIVE_INFINITY, nil
+		case f == 0 && math.Signbit(f):
+			return NEGATIVE_ZERO, nil
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		v, rv = nil, reflect.Value{}
+	}
+	literal, isPrimitive, err := primitiveLiteral(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %w", ErrInvalidInput, displayPath(path), err)
+	}
+	id, hasID := identityOf(rv)
+	switch {
+	case isPrimitive:
+		if index, ok := s.primitives[literal]; ok {
+			return index, nil
+		}
+	case hasID:
+		if index, ok := s.ids[id]; ok {
+			return index, nil
+		}
+	}
+
+	index := len(s.entries)
+	if !s.deadline.IsZero() && index%budgetCheckInterval == 0 && s.opts.clock.Now().After(s.deadline) {
+		return 0, ErrBudgetExceeded
+	}
+	s.entries = append(s.entries, "")
+	switch {
+	case isPrimitive:
+		s.primitives[literal] = index
+	case hasID:
+		s.ids[id] = index
+	}
+
+	for _, tag := range s.tags {
+		reduced, ok := s.opts.reducers[tag](v)
+		if !ok {
+			continue
+		}
+		inner, err := s.flatten(path, reduced)
+		if err != nil {
+			return 0, err
+		}
+		s.entries[index] = "[" + quote(tag) + "," + strconv.Itoa(inner) + "]"
+		return index, nil
+	}
+
+	if isPrimitive {
+		s.entries[index] = literal
+		return index, nil
+	}
+	if rv.Kind() == reflect.Pointer && !isBuiltinPointer(v) {
+		// Pointers are followed, giving up the slot taken above; every
+		// reference to the pointer shares the entry of its target.
+		s.entries = s.entries[:index]
+		delete(s.ids, id)
+		target, err := s.flatten(path, rv.Elem().Interface())
+		if err == nil && target >= 0 {
+			s.ids[id] = target
+		}
+		return target, err
+	}
+	entry, err := s.entry(path, v, rv)
+	if err != nil {
+		return 0, err
+	}
+	s.entries[index] = entry
+	return index, nil
+}
+
+// entry returns the table entry of a value that is not a primitive.
+func (s *stringifier) entry(path string, v interface{}, rv reflect.Value) (string, error) {
+	switch value := v.(type) {
+	case *Tagged:
+		args := make([]string, 0, len(value.Args)+1)
+		args = append(args, quote(value.Tag))
+		for _, arg := range value.Args {
+			data, err := json.Marshal(arg)
+			if err != nil {
+				return "", err
+			}
+			args = append(args, string(data))
+		}
+		return "[" + strings.Join(args, ",") + "]", nil
+	case time.Time:
+		return tagged(TagDate.String(), quote(value.UTC().Format("2006-01-02T15:04:05.000Z"))), nil
+	case []byte:
+		return s.binary(TagUint8Array, value), nil
+	case *Wrapped:
+		return s.wrapped(path, value)
+	case *Set:
+		refs, err := s.flattenAll(path, value.Values())
+		if err != nil {
+			return "", err
+		}
+		return tagged(TagSet.String(), refs...), nil
+	case *OrderedMap:
+		var refs []string
+		for _, e := range value.Entries() {
+			key, err := s.flatten(path, e.Key)
+			if err != nil {
+				return "", err
+			}
+			keyString, _ := MapKeyString(e.Key)
+			val, err := s.flatten(keyPath(path, keyString), e.Value)
+			if err != nil {
+				return "", err
+			}
+			refs = append(refs, strconv.Itoa(key), strconv.Itoa(val))
+		}
+		return tagged(TagMap.String(), refs...), nil
+	case []interface{}:
+		refs, err := s.flattenAll(path, value)
+		if err != nil {
+			return "", err
+		}
+		return "[" + strings.Join(refs, ",") + "]", nil
+	case map[string]interface{}:
+		return s.object(path, sortedKeys(value), func(key string) interface{} { return value[key] })
+	case NullObject:
+		return s.nullObject(path, value)
+	case *Truncated, *LazyRef, *SampledArray:
+		return "", s.unsupported(path, v)
+	}
+	if digits, ok := bigIntDigits(v); ok {
+		return tagged(TagBigInt.String(), quote(digits)), nil
+	}
+	if source, flags, ok := regExpSource(v); ok {
+		return tagged(TagRegExp.String(), quote(source), quote(flags)), nil
+	}
+	if tag, data, ok := typedArray(v); ok {
+		return s.binary(tag, data), nil
+	}
+	if _, ok := v.(json.Marshaler); ok {
+		return s.viaJSON(path, v)
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+		refs, err := s.flattenAll(path, items)
+		if err != nil {
+			return "", err
+		}
+		return "[" + strings.Join(refs, ",") + "]", nil
+	case reflect.Map:
+		return s.reflectMap(path, rv)
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return "", s.unsupported(path, v)
+	}
+	return s.viaJSON(path, v)
+}
+
+func (s *stringifier) flattenAll(path string, items []interface{}) ([]string, error) {
+	refs := make([]string, len(items))
+	for i, item := range items {
+		ref, err := s.flatten(indexPath(path, i), item)
+		if err != nil {
+			return nil, err
+		}
+		refs[i] = strconv.Itoa(ref)
+	}
+	return refs, nil
+}
+
+func (s *stringifier) object(path string, keys []string, get func(string) interface{}) (string, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, key := range keys {
+		ref, err := s.flatten(keyPath(path, key), get(key))
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(quote(key))
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(ref))
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// nullObject writes a NullObject as a ["null", key, ref, ...] entry with its
+// keys in sorted order.
+func (s *stringifier) nullObject(path string, obj NullObject) (string, error) {
+	var refs []string
+	for _, key := range sortedKeys(obj) {
+		ref, err := s.flatten(keyPath(path, key), obj[key])
+		if err != nil {
+			return "", err
+		}
+		refs = append(refs, quote(key), strconv.Itoa(ref))
+	}
+	return tagged(TagNull.String(), refs...), nil
+}
+
+// reflectMap writes maps with string keys as objects and other maps as Maps,
+// ordering keys by their string form.
+func (s *stringifier) reflectMap(path string, rv reflect.Value) (string, error) {
+	keys := rv.MapKeys()
+	if rv.Type().Key().Kind() == reflect.String {
+		names := make([]string, len(keys))
+		byName := make(map[string]reflect.Value, len(keys))
+		for i, key := range keys {
+			names[i] = key.String()
+			byName[names[i]] = key
+		}
+		sort.Strings(names)
+		return s.object(path, names, func(name string) interface{} {
+			return rv.MapIndex(byName[name]).Interface()
+		})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	var refs []string
+	for _, key := range keys {
+		k, err := s.flatten(path, key.Interface())
+		if err != nil {
+			return "", err
+		}
+		val, err := s.flatten(keyPath(path, fmt.Sprint(key.Interface())), rv.MapIndex(key).Interface())
+		if err != nil {
+			return "", err
+		}
+		refs = append(refs, strconv.Itoa(k), strconv.Itoa(val))
+	}
+	return tagged(TagMap.String(), refs...), nil
+}
+
+func (s *stringifier) wrapped(path string, w *Wrapped) (string, error) {
+	if digits, ok := bigIntDigits(w.Value); ok && w.Kind == "BigInt" {
+		return tagged(TagObject.String(), quote(w.Kind), quote(digits)), nil
+	}
+	if literal := wrapLiteral(w.Value); literal != nil && literal.Kind == w.Kind {
+		if f, ok := w.Value.(float64); !ok || !math.IsNaN(f) && !math.IsInf(f, 0) {
+			text, _, _ := primitiveLiteral(w.Value)
+			return tagged(TagObject.String(), text), nil
+		}
+	}
+	return "", s.unsupported(path, w)
+}
+
+// viaJSON converts v with encoding/json and serializes the result.
+func (s *stringifier) viaJSON(path string, v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrInvalidInput, displayPath(path), err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrInvalidInput, displayPath(path), err)
+	}
+	rv := reflect.ValueOf(decoded)
+	if literal, ok, _ := primitiveLiteral(decoded); ok {
+		return literal, nil
+	}
+	return s.entry(path, decoded, rv)
+}
+
+func (s *stringifier) binary(tag Tag, data []byte) string {
+	return tagged(tag.String(), quote(s.opts.binaryCodec(tag.String()).Encode(data)))
+}
+
+// primitiveLiteral returns the JSON literal of a primitive value. Floats
+// must not be NaN or infinite.
+func primitiveLiteral(v interface{}) (string, bool, error) {
+	switch value := v.(type) {
+	case nil:
+		return "null", true, nil
+	case bool:
+		return strconv.FormatBool(value), true, nil
+	case string:
+		return quote(value), true, nil
+	case UTF16String:
+		data, err := value.MarshalJSON()
+		return string(data), true, err
+	case json.Number:
+		if _, err := strconv.ParseFloat(string(value), 64); err != nil {
+			return "", false, err
+		}
+		return string(value), true, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return fmt.Sprint(value), true, nil
+	case float32, float64:
+		f, _ := toFloat(value)
+		return formatJSNumber(f), true, nil
+	}
+	return "", false, nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch f := v.(type) {
+	case float64:
+		return f, true
+	case float32:
+		return float64(f), true
+	}
+	return 0, false
+}
+
+// isBuiltinPointer reports whether v is a pointer type the stringifier
+// writes as a whole rather than following it.
+func isBuiltinPointer(v interface{}) bool {
+	switch v.(type) {
+	case *Set, *OrderedMap, *Wrapped, *Tagged, *Truncated, *LazyRef, *SampledArray:
+		return true
+	}
+	if _, ok := bigIntDigits(v); ok {
+		return true
+	}
+	_, _, ok := regExpSource(v)
+	return ok
+}
+
+// typedArray returns the tag and little-endian contents of typed numeric
+// slices.
+func typedArray(v interface{}) (Tag, []byte, bool) {
+	tag, ok := typedArrayTag(v)
+	if !ok {
+		return 0, nil, false
+	}
+	var buf bytes.Buffer
+	// Writing a slice of fixed-size values cannot fail.
+	binary.Write(&buf, binary.LittleEndian, v)
+	return tag, buf.Bytes(), true
+}
+
+// typedArrayTag returns the tag of the typed array a typed numeric slice or
+// an ArrayBuffer stands for.
+func typedArrayTag(v interface{}) (Tag, bool) {
+	switch v.(type) {
+	case []int8:
+		return TagInt8Array, true
+	case []int16:
+		return TagInt16Array, true
+	case []uint16:
+		return TagUint16Array, true
+	case []int32:
+		return TagInt32Array, true
+	case []uint32:
+		return TagUint32Array, true
+	case []float32:
+		return TagFloat32Array, true
+	case []float64:
+		return TagFloat64Array, true
+	case []int64:
+		return TagBigInt64Array, true
+	case []uint64:
+		return TagBigUint64Array, true
+	case ArrayBuffer:
+		return TagArrayBuffer, true
+	}
+	return 0, false
+}
+
+func tagged(tag string, args ...string) string {
+	return "[" + strings.Join(append([]string{quote(tag)}, args...), ",") + "]"
+}
+
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
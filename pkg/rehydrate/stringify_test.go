@@ -0,0 +1,200 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestStringify(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want string
+	}{
+		{"string", "x", `["x"]`},
+		{"nan root", math.NaN(), `-3`},
+		{"object", map[string]interface{}{"b": 1, "a": "x"}, `[{"a":1,"b":2},"x",1]`},
+		{"sentinels", []interface{}{math.Inf(1), math.Inf(-1), math.Copysign(0, -1), nil}, `[[-4,-5,-6,1],null]`},
+		{"dedup", []interface{}{"x", "x", 1.0, 1}, `[[1,1,2,2],"x",1]`},
+		{"date", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), `[["Date","2024-01-02T03:04:05.000Z"]]`},
+		{"bytes", []byte("hi"), `[["Uint8Array","aGk="]]`},
+		{"float64 array", []float64{1}, `[["Float64Array","AAAAAAAA8D8="]]`},
+		{"empty set", rehydrate.NewSet(), `[["Set"]]`},
+		{"string map", map[string]int{"a": 1}, `[{"a":1},1]`},
+		{"int map", map[int]string{1: "a"}, `[["Map",1,2],1,"a"]`},
+		{"struct", struct {
+			Name string `json:"name"`
+		}{"x"}, `[{"name":1},"x"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rehydrate.Stringify(tt.in, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Stringify = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringifyRoundTrip(t *testing.T) {
+	set := rehydrate.NewSet()
+	set.Add("a")
+	m := rehydrate.NewOrderedMap()
+	m.Set(1.0, "one")
+	created := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	in := map[string]interface{}{
+		"created": created,
+		"tags":    set,
+		"names":   m,
+		"items":   []interface{}{1.0, "x", true},
+	}
+
+	payload, err := rehydrate.Stringify(in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := rehydrate.Parse(payload, nil)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", payload, err)
+	}
+	obj := v.(map[string]interface{})
+	if !obj["created"].(time.Time).Equal(created) {
+		t.Errorf("created = %v", obj["created"])
+	}
+	if !obj["tags"].(*rehydrate.Set).Has("a") {
+		t.Errorf("tags = %v", obj["tags"])
+	}
+	if name, _ := obj["names"].(*rehydrate.OrderedMap).Get(1.0); name != "one" {
+		t.Errorf("names[1] = %v", name)
+	}
+	if items := obj["items"].([]interface{}); len(items) != 3 || items[1] != "x" {
+		t.Errorf("items = %v", items)
+	}
+}
+
+func TestStringifyNullObjects(t *testing.T) {
+	// Array sampling makes hydration use the recursive engine instead of the
+	// iterative one.
+	for _, engine := range [][]rehydrate.Option{nil, {rehydrate.WithArraySampling(100)}} {
+		for _, payload := range []string{`[["null","a",0]]`, `[["null","a",1,"b",2],"x",[3],1]`} {
+			v, err := rehydrate.ParseWithOptions(payload, append(engine, rehydrate.WithNullObjects())...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := v.(rehydrate.NullObject); !ok {
+				t.Fatalf("Parse(%s) = %T, want NullObject", payload, v)
+			}
+			out, err := rehydrate.Stringify(v, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != payload {
+				t.Errorf("round trip of %s: got %s", payload, out)
+			}
+		}
+	}
+
+	v, err := rehydrate.Parse(`[["null","a",1],"x"]`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		t.Errorf("without WithNullObjects: got %T, want map[string]interface{}", v)
+	}
+}
+
+func TestStringifySharedAndCyclic(t *testing.T) {
+	shared := map[string]interface{}{"n": 1.0}
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+	in := map[string]interface{}{"a": shared, "b": shared, "c": cyclic}
+
+	payload, err := rehydrate.Stringify(in, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"a":1,"b":1,"c":3},{"n":2},1,{"self":3}]`; payload != want {
+		t.Errorf("Stringify = %s, want %s", payload, want)
+	}
+	v, err := rehydrate.Parse(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := v.(map[string]interface{})["c"].(map[string]interface{})
+	if c["self"].(map[string]interface{})["self"] == nil {
+		t.Error("cycle not restored")
+	}
+}
+
+type money struct {
+	Cents    int
+	Currency string
+}
+
+func TestStringifyReducers(t *testing.T) {
+	reducers := map[string]rehydrate.ReducerFunc{
+		"Money": func(v interface{}) (interface{}, bool) {
+			m, ok := v.(*money)
+			if !ok {
+				return nil, false
+			}
+			return []interface{}{m.Cents, m.Currency}, true
+		},
+	}
+	in := []interface{}{&money{150, "EUR"}}
+
+	payload, err := rehydrate.Stringify(in, reducers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[[1],["Money",2],[3,4],150,"EUR"]`; payload != want {
+		t.Errorf("Stringify = %s, want %s", payload, want)
+	}
+
+	v, err := rehydrate.ParseWithOptions(payload, rehydrate.WithRevivers(map[string]rehydrate.ReviverFunc{
+		"Money": func(v interface{}) (interface{}, error) {
+			args := v.([]interface{})
+			return &money{int(args[0].(float64)), args[1].(string)}, nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.([]interface{})[0].(*money); *got != (money{150, "EUR"}) {
+		t.Errorf("revived = %+v", got)
+	}
+}
+
+func TestStringifyUnsupported(t *testing.T) {
+	for _, in := range []interface{}{
+		&rehydrate.Truncated{Value: "x", Length: 10},
+		map[string]interface{}{"f": func() {}},
+		make(chan int),
+	} {
+		if _, err := rehydrate.Stringify(in, nil); !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("Stringify(%T) error = %v, want ErrInvalidInput", in, err)
+		}
+	}
+}
+
+func TestStringifyTimeBudget(t *testing.T) {
+	values := make([]interface{}, 10000)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+	_, err := rehydrate.StringifyWithOptions(values, rehydrate.WithTimeBudget(time.Nanosecond))
+	if !errors.Is(err, rehydrate.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if _, err := rehydrate.StringifyWithOptions(values, rehydrate.WithTimeBudget(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+}
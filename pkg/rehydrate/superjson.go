@@ -0,0 +1,425 @@
+package rehydrate
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSuperJSON decodes a superjson document, {"json": ..., "meta": ...},
+// applying its type annotations. Dates, BigInts, RegExps, Sets, Maps,
+// typed arrays, undefined and special numbers hydrate to the same Go types as
+// from devalue. Custom types are passed to the reviver registered for their
+// name, as given with WithRevivers or WithRegistry; class instances, errors
+// and URLs stay plain values. Referential equalities are restored except for
+// Set elements and Map keys, which keep equal but distinct copies.
+func ParseSuperJSON(data []byte, opts ...Option) (interface{}, error) {
+	var doc struct {
+		JSON interface{} `json:"json"`
+		Meta struct {
+			Values                interface{} `json:"values"`
+			ReferentialEqualities interface{} `json:"referentialEqualities"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	d := &superjsonDecoder{opts: newOptions(opts), root: doc.JSON}
+	if doc.Meta.Values != nil {
+		if err := d.applyTree(nil, doc.Meta.Values); err != nil {
+			return nil, err
+		}
+	}
+	if doc.Meta.ReferentialEqualities != nil {
+		if err := d.applyEqualities(doc.Meta.ReferentialEqualities); err != nil {
+			return nil, err
+		}
+	}
+	return d.root, nil
+}
+
+type superjsonDecoder struct {
+	opts *options
+	root interface{}
+}
+
+// applyTree applies an annotation tree to the value at path. A tree is an
+// annotation, [annotation], [annotation, children] or a children object whose
+// keys are escaped, dot-separated paths relative to path. Children are
+// applied first, while their parent still has its JSON form.
+func (d *superjsonDecoder) applyTree(path []string, tree interface{}) error {
+	var annotation interface{}
+	var children map[string]interface{}
+	switch t := tree.(type) {
+	case string:
+		annotation = t
+	case []interface{}:
+		if len(t) == 0 || len(t) > 2 {
+			return fmt.Errorf("%w: invalid superjson annotation at %s", ErrInvalidInput, superjsonPathString(path))
+		}
+		annotation = t[0]
+		if len(t) == 2 {
+			var ok bool
+			if children, ok = t[1].(map[string]interface{}); !ok {
+				return fmt.Errorf("%w: invalid superjson annotation at %s", ErrInvalidInput, superjsonPathString(path))
+			}
+		}
+	case map[string]interface{}:
+		children = t
+	default:
+		return fmt.Errorf("%w: invalid superjson annotation at %s", ErrInvalidInput, superjsonPathString(path))
+	}
+
+	keys := sortedKeys(children)
+	for _, key := range keys {
+		child := append(path[:len(path):len(path)], splitSuperJSONPath(key)...)
+		if err := d.applyTree(child, children[key]); err != nil {
+			return err
+		}
+	}
+	if annotation == nil {
+		return nil
+	}
+
+	v, ok := superjsonGet(d.root, path)
+	if !ok {
+		return fmt.Errorf("%w: superjson annotation for missing path %s", ErrInvalidInput, superjsonPathString(path))
+	}
+	converted, err := d.convert(annotation, v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", superjsonPathString(path), err)
+	}
+	superjsonSet(&d.root, path, converted)
+	return nil
+}
+
+// convert turns the JSON form v of a value annotated with annotation into
+// its hydrated form.
+func (d *superjsonDecoder) convert(annotation, v interface{}) (interface{}, error) {
+	if composite, ok := annotation.([]interface{}); ok {
+		if len(composite) != 2 {
+			return nil, fmt.Errorf("%w: invalid annotation %v", ErrInvalidInput, annotation)
+		}
+		kind, _ := composite[0].(string)
+		name, _ := composite[1].(string)
+		switch kind {
+		case "class", "symbol":
+			return v, nil
+		case "custom":
+			reviver, ok := d.opts.reviver(name)
+			if !ok {
+				return nil, typeError(name, -1, ErrUnknownType)
+			}
+			res, err := reviver(v)
+			if err != nil {
+				return nil, typeError(name, -1, err)
+			}
+			return res, nil
+		case "typed-array":
+			data, err := typedArrayBytes(name, v)
+			if err != nil || d.opts.keepRawTypedArrays() {
+				return data, err
+			}
+			tag, _ := ParseTag(name)
+			res, err := decodeTypedArray(tag, data, binary.LittleEndian)
+			if err != nil {
+				return nil, typeError(name, -1, fmt.Errorf("%w: %w", ErrInvalidInput, err))
+			}
+			return res, nil
+		}
+		return nil, fmt.Errorf("%w: annotation %v", ErrUnknownType, annotation)
+	}
+
+	kind, _ := annotation.(string)
+	s, isString := v.(string)
+	switch kind {
+	case "undefined":
+		return nil, nil
+	case "Error", "URL":
+		return v, nil
+	case "number":
+		switch s {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		case "-0":
+			return math.Copysign(0, -1), nil
+		}
+		return nil, fmt.Errorf("%w: invalid number %v", ErrInvalidInput, v)
+	case "bigint":
+		n, ok := parseBigInt(s)
+		if !isString || !ok {
+			return nil, fmt.Errorf("%w: invalid BigInt %v", ErrInvalidInput, v)
+		}
+		return n, nil
+	case "Date":
+		t, err := time.Parse(time.RFC3339, s)
+		if !isString || err != nil {
+			return nil, fmt.Errorf("%w: invalid Date %v", ErrInvalidInput, v)
+		}
+		return t, nil
+	case "regexp":
+		end := strings.LastIndex(s, "/")
+		if !isString || !strings.HasPrefix(s, "/") || end < 1 {
+			return nil, fmt.Errorf("%w: invalid RegExp %v", ErrInvalidInput, v)
+		}
+		re, err := compileRegExp(s[1:end], s[end+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		return re, nil
+	case "set":
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid Set %v", ErrInvalidInput, v)
+		}
+		set := NewSet()
+		for _, item := range items {
+			set.Add(item)
+		}
+		return set, nil
+	case "map":
+		entries, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid Map %v", ErrInvalidInput, v)
+		}
+		m := NewOrderedMap()
+		for _, entry := range entries {
+			pair, ok := entry.([]interface{})
+			if !ok || len(pair) != 2 {
+				return nil, fmt.Errorf("%w: invalid Map entry %v", ErrInvalidInput, entry)
+			}
+			m.Set(pair[0], pair[1])
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("%w: annotation %v", ErrUnknownType, annotation)
+}
+
+// typedArrayBytes encodes the elements of a typed array in little-endian
+// byte order, matching the buffers devalue payloads carry.
+func typedArrayBytes(name string, v interface{}) ([]byte, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid %s %v", ErrInvalidInput, name, v)
+	}
+	tag, _ := ParseTag(name)
+	size := tag.elementSize()
+	if size == 0 || tag == TagArrayBuffer {
+		return nil, typeError(name, -1, ErrUnknownType)
+	}
+
+	out := make([]byte, len(items)*size)
+	for i, item := range items {
+		n, ok := item.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid %s element %v", ErrInvalidInput, name, item)
+		}
+		b := out[i*size:]
+		switch tag {
+		case TagFloat32Array:
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(n)))
+		case TagFloat64Array:
+			binary.LittleEndian.PutUint64(b, math.Float64bits(n))
+		default:
+			bits := uint64(int64(n))
+			if n >= 0 {
+				bits = uint64(n)
+			}
+			for j := 0; j < size; j++ {
+				b[j] = byte(bits >> (8 * j))
+			}
+		}
+	}
+	return out, nil
+}
+
+// applyEqualities restores shared references. The annotation maps a path
+// to the other paths holding the same value; the form [rootPaths] or
+// [rootPaths, rest] lists the paths sharing the root first.
+func (d *superjsonDecoder) applyEqualities(annotation interface{}) error {
+	invalid := fmt.Errorf("%w: invalid superjson referential equalities", ErrInvalidInput)
+	var rest interface{} = annotation
+	if arr, ok := annotation.([]interface{}); ok {
+		if len(arr) == 0 || len(arr) > 2 {
+			return invalid
+		}
+		if err := d.share(nil, arr[0]); err != nil {
+			return err
+		}
+		rest = nil
+		if len(arr) == 2 {
+			rest = arr[1]
+		}
+	}
+	if rest == nil {
+		return nil
+	}
+	equalities, ok := rest.(map[string]interface{})
+	if !ok {
+		return invalid
+	}
+	for _, key := range sortedKeys(equalities) {
+		if err := d.share(splitSuperJSONPath(key), equalities[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *superjsonDecoder) share(from []string, targets interface{}) error {
+	paths, ok := targets.([]interface{})
+	if !ok {
+		return fmt.Errorf("%w: invalid superjson referential equalities", ErrInvalidInput)
+	}
+	v, ok := superjsonGet(d.root, from)
+	if !ok {
+		return fmt.Errorf("%w: referential equality for missing path %s", ErrInvalidInput, superjsonPathString(from))
+	}
+	for _, p := range paths {
+		s, ok := p.(string)
+		if !ok {
+			return fmt.Errorf("%w: invalid superjson path %v", ErrInvalidInput, p)
+		}
+		superjsonSet(&d.root, splitSuperJSONPath(s), v)
+	}
+	return nil
+}
+
+// mapEntrySlot addresses an entry of an OrderedMap while following a path:
+// the next segment is 0 for its key or 1 for its value.
+type mapEntrySlot struct {
+	m *OrderedMap
+	i int
+}
+
+// superjsonGet returns the value at path. Sets are indexed by position and
+// Maps by entry position followed by 0 or 1.
+func superjsonGet(v interface{}, path []string) (interface{}, bool) {
+	for _, segment := range path {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = c[segment]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			v = c[i]
+		case *Set:
+			values := c.Values()
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(values) {
+				return nil, false
+			}
+			v = values[i]
+		case *OrderedMap:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= c.Len() {
+				return nil, false
+			}
+			v = mapEntrySlot{c, i}
+		case mapEntrySlot:
+			switch segment {
+			case "0":
+				v = c.m.entries[c.i].Key
+			case "1":
+				v = c.m.entries[c.i].Value
+			default:
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	if _, ok := v.(mapEntrySlot); ok {
+		return nil, false
+	}
+	return v, true
+}
+
+// superjsonSet replaces the value at path, reporting whether it could. Set
+// elements and Map keys cannot be replaced.
+func superjsonSet(root *interface{}, path []string, value interface{}) bool {
+	if len(path) == 0 {
+		*root = value
+		return true
+	}
+	parent, ok := superjsonParent(*root, path[:len(path)-1])
+	if !ok {
+		return false
+	}
+	last := path[len(path)-1]
+	switch c := parent.(type) {
+	case map[string]interface{}:
+		c[last] = value
+		return true
+	case []interface{}:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(c) {
+			return false
+		}
+		c[i] = value
+		return true
+	case mapEntrySlot:
+		if last == "1" {
+			c.m.entries[c.i].Value = value
+			return true
+		}
+	}
+	return false
+}
+
+// superjsonParent is like superjsonGet but may return a mapEntrySlot.
+func superjsonParent(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	if m, ok := superjsonGet(v, path[:len(path)-1]); ok {
+		if om, ok := m.(*OrderedMap); ok {
+			i, err := strconv.Atoi(path[len(path)-1])
+			if err != nil || i < 0 || i >= om.Len() {
+				return nil, false
+			}
+			return mapEntrySlot{om, i}, true
+		}
+	}
+	return superjsonGet(v, path)
+}
+
+// splitSuperJSONPath splits a dot-separated superjson path, in which literal
+// dots and backslashes are escaped with a backslash.
+func splitSuperJSONPath(s string) []string {
+	var segments []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case s[i] == '.':
+			segments = append(segments, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(segments, b.String())
+}
+
+func superjsonPathString(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	return strings.Join(path, ".")
+}
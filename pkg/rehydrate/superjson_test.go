@@ -0,0 +1,133 @@
+//go:build !rehydrate_min
+
+package rehydrate_test
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParseSuperJSON(t *testing.T) {
+	input := `{
+		"json": {
+			"created": "2024-01-02T03:04:05.000Z",
+			"count": "12345678901234567890",
+			"pattern": "/a+b/gi",
+			"tags": ["x", "y"],
+			"prices": [["EUR", "1"], [2, "Infinity"]],
+			"missing": null,
+			"bytes": [1, 258],
+			"money": {"amount": 5},
+			"a.b": "NaN"
+		},
+		"meta": {
+			"values": {
+				"created": ["Date"],
+				"count": ["bigint"],
+				"pattern": ["regexp"],
+				"tags": ["set"],
+				"prices": ["map", {"0.1": ["bigint"], "1.1": ["number"]}],
+				"missing": ["undefined"],
+				"bytes": [["typed-array", "Uint16Array"]],
+				"money": [["custom", "Money"]],
+				"a\\.b": ["number"]
+			}
+		}
+	}`
+	money := func(v interface{}) (interface{}, error) {
+		return v.(map[string]interface{})["amount"], nil
+	}
+	v, err := rehydrate.ParseSuperJSON([]byte(input), rehydrate.WithRevivers(rehydrate.Revivers{"Money": money}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !root["created"].(time.Time).Equal(want) {
+		t.Errorf("created: got %v", root["created"])
+	}
+	if n, _ := new(big.Int).SetString("12345678901234567890", 10); root["count"].(*big.Int).Cmp(n) != 0 {
+		t.Errorf("count: got %v", root["count"])
+	}
+	if re := root["pattern"].(*rehydrate.RegExp); re.Source != "a+b" {
+		t.Errorf("pattern: got %v", re)
+	}
+	if tags := root["tags"].(*rehydrate.Set); !reflect.DeepEqual(tags.Values(), []interface{}{"x", "y"}) {
+		t.Errorf("tags: got %v", tags.Values())
+	}
+	prices := root["prices"].(*rehydrate.OrderedMap)
+	if eur, _ := prices.Get("EUR"); eur.(*big.Int).Int64() != 1 {
+		t.Errorf("prices[EUR]: got %v", eur)
+	}
+	if two, _ := prices.Get(2.0); !math.IsInf(two.(float64), 1) {
+		t.Errorf("prices[2]: got %v", two)
+	}
+	if root["missing"] != nil {
+		t.Errorf("missing: got %v", root["missing"])
+	}
+	if !reflect.DeepEqual(root["bytes"], []uint16{1, 258}) {
+		t.Errorf("bytes: got %v", root["bytes"])
+	}
+	if root["money"] != 5.0 {
+		t.Errorf("money: got %v", root["money"])
+	}
+	if !math.IsNaN(root["a.b"].(float64)) {
+		t.Errorf("a.b: got %v", root["a.b"])
+	}
+}
+
+func TestParseSuperJSONReferentialEqualities(t *testing.T) {
+	input := `{
+		"json": {"a": {"x": 1}, "b": {"x": 1}, "m": [["k", {"x": 1}]]},
+		"meta": {
+			"values": {"m": ["map"]},
+			"referentialEqualities": {"a": ["b", "m.0.1"]}
+		}
+	}`
+	v, err := rehydrate.ParseSuperJSON([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	a := root["a"].(map[string]interface{})
+	a["x"] = 2.0
+	if root["b"].(map[string]interface{})["x"] != 2.0 {
+		t.Error("expected a and b to be the same object")
+	}
+	if k, _ := root["m"].(*rehydrate.OrderedMap).Get("k"); k.(map[string]interface{})["x"] != 2.0 {
+		t.Error("expected the Map value to be the same object as a")
+	}
+
+	v, err = rehydrate.ParseSuperJSON([]byte(`{"json":{"self":null},"meta":{"referentialEqualities":[["self"]]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if self := v.(map[string]interface{}); reflect.ValueOf(self["self"]).Pointer() != reflect.ValueOf(self).Pointer() {
+		t.Error("expected a cycle through the root")
+	}
+}
+
+func TestParseSuperJSONErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{`{"json":"x","meta":{"values":["Date"]}}`, rehydrate.ErrInvalidInput},
+		{`{"json":1,"meta":{"values":["bigint"]}}`, rehydrate.ErrInvalidInput},
+		{`{"json":{},"meta":{"values":{"a":["Date"]}}}`, rehydrate.ErrInvalidInput},
+		{`{"json":{},"meta":{"values":[["custom","Money"]]}}`, rehydrate.ErrUnknownType},
+		{`{"json":{},"meta":{"values":["Temporal"]}}`, rehydrate.ErrUnknownType},
+		{`{"json":`, rehydrate.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		if _, err := rehydrate.ParseSuperJSON([]byte(tt.input)); !errors.Is(err, tt.want) {
+			t.Errorf("ParseSuperJSON(%s): got %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
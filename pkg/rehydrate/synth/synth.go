@@ -0,0 +1,321 @@
+// Package synth generates random devalue payloads of a given shape, for
+// benchmarking Parse and load-testing services that consume payloads:
+//
+//	g, err := synth.New(synth.Shape{Depth: 5, FanOut: 8, Sharing: 0.2, Seed: 1})
+//	...
+//	payload := g.Next()
+//
+// Generators with the same shape and seed produce the same payloads.
+package synth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// Value types that can appear in a Mix.
+const (
+	String    = "string"
+	Number    = "number"
+	Boolean   = "boolean"
+	Null      = "null"
+	Undefined = "undefined"
+	Object    = "object"
+	Array     = "array"
+	Date      = "date"
+	BigInt    = "bigint"
+	Binary    = "binary"
+	Set       = "set"
+	Map       = "map"
+)
+
+// DefaultMix is the type mix used when Shape.Mix is empty, loosely modelled
+// on the payloads of server-rendered pages.
+var DefaultMix = Mix{
+	String:    8,
+	Number:    6,
+	Boolean:   2,
+	Null:      1,
+	Undefined: 1,
+	Object:    4,
+	Array:     3,
+	Date:      2,
+	BigInt:    1,
+	Binary:    1,
+	Set:       1,
+	Map:       1,
+}
+
+// Mix maps value types to their relative weights.
+type Mix map[string]int
+
+// ParseMix parses a mix written as comma-separated type=weight pairs, such
+// as "string=3,object=1".
+func ParseMix(s string) (Mix, error) {
+	m := Mix{}
+	for _, pair := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("synth: invalid mix entry %q", pair)
+		}
+		n, err := strconv.Atoi(weight)
+		if err != nil {
+			return nil, fmt.Errorf("synth: invalid weight for %s: %w", name, err)
+		}
+		m[name] = n
+	}
+	return m, nil
+}
+
+var containers = map[string]bool{Object: true, Array: true, Set: true, Map: true}
+
+// Shape describes the payloads to generate. Zero values select defaults.
+type Shape struct {
+	// Depth is the maximum nesting of objects, arrays, Sets and Maps,
+	// counting the root object: with a depth of 1 the root holds only
+	// scalars. The default is 4.
+	Depth int
+	// FanOut is the maximum number of children of a container; each gets
+	// between one and FanOut. The default is 4.
+	FanOut int
+	// Mix weighs the value types; the default is DefaultMix.
+	Mix Mix
+	// BinarySize is the number of bytes of typed arrays and ArrayBuffers.
+	// The default is 64.
+	BinarySize int
+	// Sharing is the probability, between 0 and 1, that a value refers to an
+	// existing entry instead of a new one, as serializers do for repeated
+	// values. Shared values never create cycles.
+	Sharing float64
+	// Seed seeds the generator.
+	Seed uint64
+}
+
+// Generator produces payloads of a shape. It is not safe for concurrent
+// use.
+type Generator struct {
+	shape   Shape
+	rnd     *rand.Rand
+	types   []string
+	weights []int
+	total   int
+	scalars bool
+
+	entries []interface{}
+	heights []int
+	// done lists the entries that may be shared: those whose subtree is
+	// complete.
+	done []int
+}
+
+// New returns a Generator for shape, or an error if the shape is invalid.
+func New(shape Shape) (*Generator, error) {
+	if shape.Depth == 0 {
+		shape.Depth = 4
+	}
+	if shape.FanOut == 0 {
+		shape.FanOut = 4
+	}
+	if shape.BinarySize == 0 {
+		shape.BinarySize = 64
+	}
+	if len(shape.Mix) == 0 {
+		shape.Mix = DefaultMix
+	}
+	if shape.Depth < 0 || shape.FanOut < 0 || shape.BinarySize < 0 {
+		return nil, fmt.Errorf("synth: negative depth, fan-out or binary size")
+	}
+	if shape.Sharing < 0 || shape.Sharing > 1 {
+		return nil, fmt.Errorf("synth: sharing %v is not between 0 and 1", shape.Sharing)
+	}
+
+	g := &Generator{shape: shape, rnd: rand.New(rand.NewPCG(shape.Seed, shape.Seed))}
+	names := make([]string, 0, len(shape.Mix))
+	for name := range shape.Mix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		weight := shape.Mix[name]
+		if _, ok := DefaultMix[name]; !ok {
+			return nil, fmt.Errorf("synth: unknown value type %q", name)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("synth: negative weight for %s", name)
+		}
+		if weight == 0 {
+			continue
+		}
+		g.types = append(g.types, name)
+		g.weights = append(g.weights, weight)
+		g.total += weight
+		if !containers[name] {
+			g.scalars = true
+		}
+	}
+	if g.total == 0 {
+		return nil, fmt.Errorf("synth: the mix has no positive weight")
+	}
+	return g, nil
+}
+
+// Generate returns a single payload of shape.
+func Generate(shape Shape) (string, error) {
+	g, err := New(shape)
+	if err != nil {
+		return "", err
+	}
+	return g.Next(), nil
+}
+
+// Next returns a new payload. Its root is an object.
+func (g *Generator) Next() string {
+	g.entries, g.heights, g.done = g.entries[:0], g.heights[:0], g.done[:0]
+	g.value(Object, g.shape.Depth)
+	data, err := json.Marshal(g.entries)
+	if err != nil {
+		// Entries only hold strings, numbers, booleans and nil.
+		panic(err)
+	}
+	return string(data)
+}
+
+// ref returns a reference to a value allowed to nest depth more levels.
+func (g *Generator) ref(depth int) int {
+	if len(g.done) > 0 && g.rnd.Float64() < g.shape.Sharing {
+		if i := g.done[g.rnd.IntN(len(g.done))]; g.heights[i] <= depth {
+			return i
+		}
+	}
+	typ := g.pick(depth > 0)
+	if typ == Undefined {
+		return rehydrate.UNDEFINED
+	}
+	return g.value(typ, depth)
+}
+
+// pick draws a value type, a scalar one unless containers are allowed.
+func (g *Generator) pick(containersAllowed bool) string {
+	if !containersAllowed && !g.scalars {
+		return String
+	}
+	for {
+		n := g.rnd.IntN(g.total)
+		for i, w := range g.weights {
+			if n < w {
+				if containersAllowed || !containers[g.types[i]] {
+					return g.types[i]
+				}
+				break
+			}
+			n -= w
+		}
+	}
+}
+
+// value appends an entry of type typ and returns its index.
+func (g *Generator) value(typ string, depth int) int {
+	index := len(g.entries)
+	g.entries = append(g.entries, nil)
+	g.heights = append(g.heights, 0)
+
+	var entry interface{}
+	height := 0
+	child := func() int {
+		ref := g.ref(depth - 1)
+		if ref >= 0 && g.heights[ref]+1 > height {
+			height = g.heights[ref] + 1
+		}
+		return ref
+	}
+	switch typ {
+	case String:
+		entry = g.word()
+	case Number:
+		if g.rnd.IntN(2) == 0 {
+			entry = g.rnd.IntN(100000)
+		} else {
+			entry = float64(g.rnd.IntN(1000000)) / 100
+		}
+	case Boolean:
+		entry = g.rnd.IntN(2) == 0
+	case Null:
+		entry = nil
+	case Date:
+		t := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(g.rnd.Int64N(int64(5 * 365 * 24 * time.Hour))))
+		entry = []interface{}{rehydrate.TagDate.String(), t.Format("2006-01-02T15:04:05.000Z")}
+	case BigInt:
+		entry = []interface{}{rehydrate.TagBigInt.String(), strconv.FormatUint(g.rnd.Uint64(), 10) + strconv.Itoa(g.rnd.IntN(1000))}
+	case Binary:
+		data := make([]byte, g.shape.BinarySize)
+		for i := range data {
+			data[i] = byte(g.rnd.IntN(256))
+		}
+		entry = []interface{}{rehydrate.TagUint8Array.String(), base64.StdEncoding.EncodeToString(data)}
+	case Object:
+		obj := map[string]interface{}{}
+		for n := g.children(); len(obj) < n; {
+			key := g.word()
+			if _, ok := obj[key]; ok {
+				key += strconv.Itoa(len(obj))
+			}
+			if _, ok := obj[key]; !ok {
+				obj[key] = child()
+			}
+		}
+		entry = obj
+	case Array:
+		arr := []interface{}{}
+		for n := g.children(); len(arr) < n; {
+			arr = append(arr, child())
+		}
+		entry = arr
+	case Set:
+		set := []interface{}{rehydrate.TagSet.String()}
+		for n := g.children(); len(set) <= n; {
+			set = append(set, child())
+		}
+		entry = set
+	case Map:
+		m := []interface{}{rehydrate.TagMap.String()}
+		for n := g.children(); len(m) < 2*n+1; {
+			key := len(g.entries)
+			g.entries = append(g.entries, g.word())
+			g.heights = append(g.heights, 0)
+			g.done = append(g.done, key)
+			m = append(m, key, child())
+		}
+		entry = m
+	}
+	g.entries[index] = entry
+	g.heights[index] = height
+	g.done = append(g.done, index)
+	return index
+}
+
+func (g *Generator) children() int {
+	if g.shape.FanOut == 0 {
+		return 0
+	}
+	return 1 + g.rnd.IntN(g.shape.FanOut)
+}
+
+var syllables = []string{"ka", "lo", "mi", "ne", "ro", "ta", "vi", "su", "de", "pa", "zu", "en"}
+
+// word returns a short pronounceable string, so generated keys and strings
+// resemble those of real payloads.
+func (g *Generator) word() string {
+	var b strings.Builder
+	for n := 2 + g.rnd.IntN(3); n > 0; n-- {
+		b.WriteString(syllables[g.rnd.IntN(len(syllables))])
+	}
+	return b.String()
+}
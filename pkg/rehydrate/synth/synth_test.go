@@ -0,0 +1,85 @@
+package synth_test
+
+import (
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/synth"
+)
+
+func TestGenerate(t *testing.T) {
+	shape := synth.Shape{Depth: 5, FanOut: 6, Sharing: 0.3, Seed: 42}
+	g, err := synth.New(shape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		payload := g.Next()
+		tree, err := rehydrate.ParseTree(payload)
+		if err != nil {
+			t.Fatalf("payload %d: %v\n%s", i, err, payload)
+		}
+		if tree.Root().Kind != rehydrate.NodeObject {
+			t.Fatalf("payload %d: root is %v", i, tree.Root().Kind)
+		}
+		if depth := nesting(tree, tree.Root()); depth > shape.Depth {
+			t.Errorf("payload %d: nested %d levels deep", i, depth)
+		}
+		if _, err := rehydrate.ParseWithOptions(payload, rehydrate.WithStrictReferences()); err != nil {
+			t.Fatalf("payload %d: %v", i, err)
+		}
+	}
+
+	// The same seed produces the same payloads.
+	first, _ := synth.Generate(shape)
+	second, _ := synth.Generate(shape)
+	if first != second {
+		t.Error("payloads of the same seed differ")
+	}
+}
+
+func nesting(tree *rehydrate.Tree, n *rehydrate.Node) int {
+	if n.Kind == rehydrate.NodePrimitive || (n.Kind == rehydrate.NodeTagged && n.Tag != rehydrate.TagSet.String() && n.Tag != rehydrate.TagMap.String()) {
+		return 0
+	}
+	deepest := 0
+	for _, child := range tree.Children(n) {
+		if d := nesting(tree, child); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest + 1
+}
+
+func TestMix(t *testing.T) {
+	mix, err := synth.ParseMix("string=1,binary=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := synth.Generate(synth.Shape{Mix: mix, FanOut: 3, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := rehydrate.ParseTree(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, child := range tree.Children(tree.Root()) {
+		if _, ok := child.Value.(string); !ok {
+			t.Errorf("unexpected child %+v", child)
+		}
+	}
+
+	for _, input := range []string{"string", "string=x", "regexp=1"} {
+		mix, err := synth.ParseMix(input)
+		if err == nil {
+			_, err = synth.New(synth.Shape{Mix: mix})
+		}
+		if err == nil {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+	if _, err := synth.New(synth.Shape{Sharing: 2}); err == nil {
+		t.Errorf("sharing: got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package rehydrate
+
+// Tag identifies one of the built-in type tags of the payload format, the
+// string in the first position of entries such as ["Date", ...].
+type Tag int
+
+const (
+	TagInvalid Tag = iota
+	TagDate
+	TagSet
+	TagMap
+	TagRegExp
+	TagObject
+	TagBigInt
+	TagNull
+	TagInt8Array
+	TagUint8Array
+	TagUint8ClampedArray
+	TagInt16Array
+	TagUint16Array
+	TagInt32Array
+	TagUint32Array
+	TagFloat32Array
+	TagFloat64Array
+	TagBigInt64Array
+	TagBigUint64Array
+	TagArrayBuffer
+)
+
+var tagNames = [...]string{
+	TagInvalid:           "",
+	TagDate:              "Date",
+	TagSet:               "Set",
+	TagMap:               "Map",
+	TagRegExp:            "RegExp",
+	TagObject:            "Object",
+	TagBigInt:            "BigInt",
+	TagNull:              "null",
+	TagInt8Array:         "Int8Array",
+	TagUint8Array:        "Uint8Array",
+	TagUint8ClampedArray: "Uint8ClampedArray",
+	TagInt16Array:        "Int16Array",
+	TagUint16Array:       "Uint16Array",
+	TagInt32Array:        "Int32Array",
+	TagUint32Array:       "Uint32Array",
+	TagFloat32Array:      "Float32Array",
+	TagFloat64Array:      "Float64Array",
+	TagBigInt64Array:     "BigInt64Array",
+	TagBigUint64Array:    "BigUint64Array",
+	TagArrayBuffer:       "ArrayBuffer",
+}
+
+var tagsByName = func() map[string]Tag {
+	m := make(map[string]Tag, len(tagNames))
+	for tag, name := range tagNames {
+		if name != "" {
+			m[name] = Tag(tag)
+		}
+	}
+	return m
+}()
+
+// String returns the tag as it appears in payloads.
+func (t Tag) String() string {
+	if t < 0 || int(t) >= len(tagNames) {
+		return ""
+	}
+	return tagNames[t]
+}
+
+// ParseTag returns the built-in tag named s. It reports false for custom
+// tags, which are handled by revivers.
+func ParseTag(s string) (Tag, bool) {
+	tag, ok := tagsByName[s]
+	return tag, ok
+}
+
+// IsBinary reports whether the tag holds base64-encoded binary data, i.e. it
+// is a typed array or an ArrayBuffer.
+func (t Tag) IsBinary() bool {
+	return t >= TagInt8Array && t <= TagArrayBuffer
+}
+
+// elementSize returns the size in bytes of the elements of a typed array,
+// 1 for ArrayBuffers and 0 for tags that do not hold binary data.
+func (t Tag) elementSize() int {
+	switch t {
+	case TagInt8Array, TagUint8Array, TagUint8ClampedArray, TagArrayBuffer:
+		return 1
+	case TagInt16Array, TagUint16Array:
+		return 2
+	case TagInt32Array, TagUint32Array, TagFloat32Array:
+		return 4
+	case TagFloat64Array, TagBigInt64Array, TagBigUint64Array:
+		return 8
+	}
+	return 0
+}
+
+// Sentinel is one of the negative indices standing in for values that have
+// no entry in the value table.
+type Sentinel int
+
+const (
+	SentinelUndefined        Sentinel = UNDEFINED
+	SentinelHole             Sentinel = HOLE
+	SentinelNaN              Sentinel = NAN
+	SentinelPositiveInfinity Sentinel = POSITIVE_INFINITY
+	SentinelNegativeInfinity Sentinel = NEGATIVE_INFINITY
+	SentinelNegativeZero     Sentinel = NEGATIVE_ZERO
+)
+
+// String returns the JavaScript value the sentinel stands for.
+func (s Sentinel) String() string {
+	switch s {
+	case SentinelUndefined:
+		return "undefined"
+	case SentinelHole:
+		return "hole"
+	case SentinelNaN:
+		return "NaN"
+	case SentinelPositiveInfinity:
+		return "Infinity"
+	case SentinelNegativeInfinity:
+		return "-Infinity"
+	case SentinelNegativeZero:
+		return "-0"
+	}
+	return ""
+}
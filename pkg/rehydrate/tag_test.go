@@ -0,0 +1,25 @@
+package rehydrate_test
+
+import (
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParseTag(t *testing.T) {
+	for tag := rehydrate.TagDate; tag <= rehydrate.TagArrayBuffer; tag++ {
+		parsed, ok := rehydrate.ParseTag(tag.String())
+		if !ok || parsed != tag {
+			t.Errorf("ParseTag(%q) = %v, %v", tag.String(), parsed, ok)
+		}
+	}
+	if _, ok := rehydrate.ParseTag("Reactive"); ok {
+		t.Error("custom tags must not parse as built-in")
+	}
+	if !rehydrate.TagFloat32Array.IsBinary() || rehydrate.TagDate.IsBinary() {
+		t.Error("unexpected IsBinary result")
+	}
+	if rehydrate.SentinelHole.String() != "hole" {
+		t.Errorf("unexpected sentinel name %q", rehydrate.SentinelHole)
+	}
+}
@@ -0,0 +1,31 @@
+package rehydrate
+
+import (
+	"encoding/json"
+)
+
+// Tagged is a value whose tag the package hydrates without a Go type of its
+// own, keeping the inline data of its payload entry. Builds with the
+// rehydrate_min tag, which leave out regexp and math/big for TinyGo and
+// other constrained targets, hydrate BigInts as ["BigInt", digits] and
+// RegExps as ["RegExp", source, flags] Tagged values; other builds never
+// produce them.
+type Tagged struct {
+	Tag  string
+	Args []interface{}
+}
+
+// MarshalJSON renders BigInts as numbers and RegExps as their source, like
+// *big.Int and *regexp.Regexp, and other values as {"tag": ..., "args": ...}.
+func (t *Tagged) MarshalJSON() ([]byte, error) {
+	if digits, ok := bigIntDigits(t); ok {
+		return []byte(digits), nil
+	}
+	if source, _, ok := regExpSource(t); ok {
+		return json.Marshal(source)
+	}
+	return json.Marshal(struct {
+		Tag  string        `json:"tag"`
+		Args []interface{} `json:"args"`
+	}{t.Tag, t.Args})
+}
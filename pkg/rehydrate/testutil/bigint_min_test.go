@@ -0,0 +1,16 @@
+//go:build rehydrate_min
+
+package testutil_test
+
+import "github.com/necodeus/rehydrate_go/pkg/rehydrate"
+
+// bigIntString returns the digits of a hydrated BigInt, which the minimal
+// build hydrates to a *rehydrate.Tagged.
+func bigIntString(v interface{}) string {
+	t, ok := v.(*rehydrate.Tagged)
+	if !ok || t.Tag != rehydrate.TagBigInt.String() || len(t.Args) != 1 {
+		return ""
+	}
+	s, _ := t.Args[0].(string)
+	return s
+}
@@ -0,0 +1,14 @@
+//go:build !rehydrate_min
+
+package testutil_test
+
+import "math/big"
+
+// bigIntString returns the digits of a hydrated BigInt.
+func bigIntString(v interface{}) string {
+	n, ok := v.(*big.Int)
+	if !ok {
+		return ""
+	}
+	return n.String()
+}
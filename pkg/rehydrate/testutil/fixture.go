@@ -0,0 +1,285 @@
+// Package testutil builds devalue payloads for tests and compares rendered
+// output against golden files. It is meant for projects that write revivers
+// and want canonical fixtures without hand-assembling value tables:
+//
+//	payload := testutil.Fixture().
+//		With("name", "Ada").
+//		WithDate("created", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)).
+//		WithSet("tags", "a", "b").
+//		WithTag("price", "Money", map[string]interface{}{"amount": 42}).
+//		WithCycle().
+//		Payload()
+package testutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// Undefined encodes as JavaScript undefined wherever a value is accepted.
+var Undefined = undefined{}
+
+type undefined struct{}
+
+// Builder assembles a payload whose root is an object. Keys are emitted in
+// the order they were added; adding a key twice replaces its value. The
+// first invalid value is remembered and reported by Build.
+type Builder struct {
+	entries []interface{}
+	keys    []string
+	refs    map[string]int
+	err     error
+}
+
+// Fixture starts an empty payload.
+func Fixture() *Builder {
+	return &Builder{
+		entries: []interface{}{nil},
+		refs:    map[string]int{},
+	}
+}
+
+// With sets key to v, which may be any JSON value (nil, bool, string, a
+// number, []interface{}, map[string]interface{}) or one of time.Time,
+// *big.Int, *rehydrate.RegExp, *regexp.Regexp, []byte, rehydrate.ArrayBuffer,
+// *rehydrate.Set, *rehydrate.OrderedMap and Undefined, nested arbitrarily.
+// NaN, the infinities and negative zero are encoded as their sentinels.
+func (b *Builder) With(key string, v interface{}) *Builder {
+	return b.set(key, b.value(v))
+}
+
+// WithDate sets key to a Date.
+func (b *Builder) WithDate(key string, t time.Time) *Builder {
+	return b.With(key, t)
+}
+
+// WithBigInt sets key to a BigInt.
+func (b *Builder) WithBigInt(key string, n *big.Int) *Builder {
+	return b.With(key, n)
+}
+
+// WithUndefined sets key to undefined.
+func (b *Builder) WithUndefined(key string) *Builder {
+	return b.With(key, Undefined)
+}
+
+// WithSet sets key to a Set of items.
+func (b *Builder) WithSet(key string, items ...interface{}) *Builder {
+	entry := []interface{}{rehydrate.TagSet.String()}
+	for _, item := range items {
+		entry = append(entry, b.value(item))
+	}
+	return b.set(key, b.add(entry))
+}
+
+// WithMap sets key to a Map built from alternating keys and values.
+func (b *Builder) WithMap(key string, pairs ...interface{}) *Builder {
+	if len(pairs)%2 != 0 {
+		b.fail(fmt.Errorf("testutil: WithMap(%q): odd number of arguments", key))
+		return b
+	}
+	entry := []interface{}{rehydrate.TagMap.String()}
+	for _, item := range pairs {
+		entry = append(entry, b.value(item))
+	}
+	return b.set(key, b.add(entry))
+}
+
+// WithTag sets key to a custom tag wrapping v, the shape revivers receive.
+func (b *Builder) WithTag(key, tag string, v interface{}) *Builder {
+	return b.set(key, b.add([]interface{}{tag, b.value(v)}))
+}
+
+// WithRef sets key to the value already stored at other, so both keys share
+// one value-table entry.
+func (b *Builder) WithRef(key, other string) *Builder {
+	ref, ok := b.refs[other]
+	if !ok {
+		b.fail(fmt.Errorf("testutil: WithRef(%q): no key %q", key, other))
+		return b
+	}
+	return b.set(key, ref)
+}
+
+// WithCycle sets the key "self" to the root object.
+func (b *Builder) WithCycle() *Builder {
+	return b.set("self", 0)
+}
+
+// Build returns the payload, or the first error recorded while building it.
+func (b *Builder) Build() (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	entries := make([]json.RawMessage, len(b.entries))
+	for i, entry := range b.entries {
+		var data []byte
+		var err error
+		if i == 0 {
+			data, err = b.marshalRoot()
+		} else {
+			data, err = json.Marshal(entry)
+		}
+		if err != nil {
+			return "", err
+		}
+		entries[i] = data
+	}
+	return rehydrate.JoinTable(entries), nil
+}
+
+// Payload is like Build but panics on error, which suits table-driven
+// tests where fixtures are built once.
+func (b *Builder) Payload() string {
+	payload, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return payload
+}
+
+func (b *Builder) marshalRoot() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, key := range b.keys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		quoted, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, quoted...)
+		buf = append(buf, ':')
+		buf = strconv.AppendInt(buf, int64(b.refs[key]), 10)
+	}
+	return append(buf, '}'), nil
+}
+
+func (b *Builder) set(key string, ref int) *Builder {
+	if _, ok := b.refs[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.refs[key] = ref
+	return b
+}
+
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *Builder) add(entry interface{}) int {
+	b.entries = append(b.entries, entry)
+	return len(b.entries) - 1
+}
+
+// value encodes v and returns its reference, which is either a sentinel or
+// the index of a new entry.
+func (b *Builder) value(v interface{}) int {
+	switch value := v.(type) {
+	case undefined:
+		return rehydrate.UNDEFINED
+	case nil, bool, string:
+		return b.add(value)
+	case int:
+		return b.number(float64(value))
+	case int64:
+		return b.number(float64(value))
+	case float64:
+		return b.number(value)
+	case json.Number:
+		return b.add(value)
+	case time.Time:
+		return b.add([]interface{}{rehydrate.TagDate.String(), value.UTC().Format("2006-01-02T15:04:05.000Z")})
+	case *big.Int:
+		return b.add([]interface{}{rehydrate.TagBigInt.String(), value.String()})
+	case *regexp.Regexp:
+		source, flags := jsRegExp(value)
+		return b.add([]interface{}{rehydrate.TagRegExp.String(), source, flags})
+	case []byte:
+		return b.add([]interface{}{rehydrate.TagUint8Array.String(), base64.StdEncoding.EncodeToString(value)})
+	case rehydrate.ArrayBuffer:
+		return b.add([]interface{}{rehydrate.TagArrayBuffer.String(), base64.StdEncoding.EncodeToString(value)})
+	case []interface{}:
+		index := b.add(nil)
+		refs := make([]int, len(value))
+		for i, item := range value {
+			refs[i] = b.value(item)
+		}
+		b.entries[index] = refs
+		return index
+	case map[string]interface{}:
+		index := b.add(nil)
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		obj := make(map[string]int, len(value))
+		for _, key := range keys {
+			obj[key] = b.value(value[key])
+		}
+		b.entries[index] = obj
+		return index
+	case *rehydrate.Set:
+		index := b.add(nil)
+		entry := []interface{}{rehydrate.TagSet.String()}
+		for _, item := range value.Values() {
+			entry = append(entry, b.value(item))
+		}
+		b.entries[index] = entry
+		return index
+	case *rehydrate.OrderedMap:
+		index := b.add(nil)
+		entry := []interface{}{rehydrate.TagMap.String()}
+		for _, e := range value.Entries() {
+			entry = append(entry, b.value(e.Key), b.value(e.Value))
+		}
+		b.entries[index] = entry
+		return index
+	}
+	if entry, ok := regExpEntry(v); ok {
+		return b.add(entry)
+	}
+	b.fail(fmt.Errorf("testutil: cannot encode %T", v))
+	return rehydrate.UNDEFINED
+}
+
+// jsRegExp returns the JavaScript source and flags of re, moving a leading
+// (?ims) flag group into the flags.
+func jsRegExp(re *regexp.Regexp) (source, flags string) {
+	source = re.String()
+	if !strings.HasPrefix(source, "(?") {
+		return source, ""
+	}
+	end := strings.IndexByte(source, ')')
+	if end < 0 || strings.Trim(source[2:end], "ims") != "" || end == 2 {
+		return source, ""
+	}
+	return source[end+1:], source[2:end]
+}
+
+func (b *Builder) number(f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return rehydrate.NAN
+	case math.IsInf(f, 1):
+		return rehydrate.POSITIVE_INFINITY
+	case math.IsInf(f, -1):
+		return rehydrate.NEGATIVE_INFINITY
+	case f == 0 && math.Signbit(f):
+		return rehydrate.NEGATIVE_ZERO
+	}
+	return b.add(f)
+}
@@ -0,0 +1,68 @@
+package testutil_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/testutil"
+)
+
+func TestFixture(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	payload, err := testutil.Fixture().
+		With("name", "Ada").
+		With("scores", []interface{}{1, math.NaN(), testutil.Undefined}).
+		WithDate("created", created).
+		WithBigInt("id", big.NewInt(7)).
+		WithSet("tags", "a", "b").
+		WithMap("prices", "EUR", 1.5).
+		WithRef("alias", "tags").
+		WithCycle().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := rehydrate.Parse(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	if root["name"] != "Ada" {
+		t.Errorf("unexpected name %v", root["name"])
+	}
+	if !root["created"].(time.Time).Equal(created) {
+		t.Errorf("unexpected date %v", root["created"])
+	}
+	if bigIntString(root["id"]) != "7" {
+		t.Errorf("unexpected id %v", root["id"])
+	}
+	scores := root["scores"].([]interface{})
+	if !math.IsNaN(scores[1].(float64)) || scores[2] != nil {
+		t.Errorf("unexpected scores %v", scores)
+	}
+	if tags := root["tags"].(*rehydrate.Set); !tags.Has("b") || root["alias"] != tags {
+		t.Errorf("unexpected tags %v, alias %v", root["tags"], root["alias"])
+	}
+	if price, _ := root["prices"].(*rehydrate.OrderedMap).Get("EUR"); price != 1.5 {
+		t.Errorf("unexpected price %v", price)
+	}
+	if self := root["self"].(map[string]interface{}); self["name"] != "Ada" {
+		t.Error("expected a cycle back to the root")
+	}
+}
+
+func TestFixtureErrors(t *testing.T) {
+	if _, err := testutil.Fixture().WithMap("m", "k").Build(); err == nil {
+		t.Error("expected an error for an odd WithMap")
+	}
+	if _, err := testutil.Fixture().WithRef("a", "missing").Build(); err == nil {
+		t.Error("expected an error for an unknown WithRef key")
+	}
+	if _, err := testutil.Fixture().With("c", make(chan int)).Build(); err == nil {
+		t.Error("expected an error for an unsupported value")
+	}
+}
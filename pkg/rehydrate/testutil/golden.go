@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// UpdateEnv is the environment variable that makes the golden helpers
+// rewrite their files with the current output instead of comparing:
+//
+//	REHYDRATE_UPDATE_GOLDEN=1 go test ./...
+const UpdateEnv = "REHYDRATE_UPDATE_GOLDEN"
+
+// Golden compares got with the file testdata/<name>.golden, relative to the
+// directory of the test, and fails t on any difference.
+func Golden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with %s=1 to create it)", err, UpdateEnv)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from golden file\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// RehydrateGolden renders payload with rehydrate.RehydrateWith and compares
+// the JSON with the golden file name.
+func RehydrateGolden(t testing.TB, name, payload string, revivers rehydrate.Revivers, opts ...rehydrate.Option) {
+	t.Helper()
+	out, err := rehydrate.RehydrateWith(payload, revivers, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Golden(t, name, []byte(out+"\n"))
+}
+
+// DumpGolden hydrates payload and compares its rehydrate.Dump tree with the
+// golden file name. Unlike the JSON rendering, the tree shows the Go types
+// revivers produced, and shared or cyclic values.
+func DumpGolden(t testing.TB, name, payload string, opts ...rehydrate.Option) {
+	t.Helper()
+	v, err := rehydrate.ParseWithOptions(payload, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := rehydrate.Dump(v, &buf); err != nil {
+		t.Fatal(err)
+	}
+	Golden(t, name, buf.Bytes())
+}
@@ -0,0 +1,22 @@
+package testutil_test
+
+import (
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/testutil"
+)
+
+func TestGolden(t *testing.T) {
+	payload := testutil.Fixture().
+		WithTag("price", "Money", map[string]interface{}{"amount": 42, "currency": "EUR"}).
+		WithSet("tags", "a").
+		Payload()
+	revivers := rehydrate.Revivers{
+		"Money": func(v interface{}) (interface{}, error) {
+			return v.(map[string]interface{})["amount"], nil
+		},
+	}
+	testutil.RehydrateGolden(t, "money", payload, revivers)
+	testutil.DumpGolden(t, "money-dump", payload, rehydrate.WithRevivers(revivers))
+}
@@ -0,0 +1,14 @@
+//go:build !rehydrate_min
+
+package testutil
+
+import "github.com/necodeus/rehydrate_go/pkg/rehydrate"
+
+// regExpEntry returns the entry of a *rehydrate.RegExp.
+func regExpEntry(v interface{}) ([]interface{}, bool) {
+	re, ok := v.(*rehydrate.RegExp)
+	if !ok {
+		return nil, false
+	}
+	return []interface{}{rehydrate.TagRegExp.String(), re.Source, re.Flags}, true
+}
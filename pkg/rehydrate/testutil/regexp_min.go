@@ -0,0 +1,8 @@
+//go:build rehydrate_min
+
+package testutil
+
+// regExpEntry reports false: the minimal build has no RegExp type.
+func regExpEntry(v interface{}) ([]interface{}, bool) {
+	return nil, false
+}
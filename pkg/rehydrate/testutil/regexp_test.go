@@ -0,0 +1,33 @@
+//go:build !rehydrate_min
+
+package testutil_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/testutil"
+)
+
+func TestFixtureRegExp(t *testing.T) {
+	re, err := rehydrate.CompileRegExp("a+", "gi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := testutil.Fixture().
+		With("js", re).
+		With("go", regexp.MustCompile("(?i)b")).
+		With("plain", regexp.MustCompile("(?:c)")).
+		Payload()
+	v, err := rehydrate.Parse(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	for key, want := range map[string]string{"js": "/a+/gi", "go": "/b/i", "plain": "/(?:c)/"} {
+		if got := root[key].(*rehydrate.RegExp).String(); got != want {
+			t.Errorf("%s = %s, want %s", key, got, want)
+		}
+	}
+}
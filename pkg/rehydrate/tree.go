@@ -0,0 +1,249 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// NodeKind classifies the entries of a payload's value table.
+type NodeKind int
+
+const (
+	// NodePrimitive is a string, number, boolean or null.
+	NodePrimitive NodeKind = iota
+	// NodeArray is a plain array of references.
+	NodeArray
+	// NodeObject is a plain object mapping keys to references.
+	NodeObject
+	// NodeTagged is an entry such as ["Date", ...] or ["Set", ...], or a
+	// custom tag handled by a reviver.
+	NodeTagged
+)
+
+var nodeKindNames = [...]string{
+	NodePrimitive: "primitive",
+	NodeArray:     "array",
+	NodeObject:    "object",
+	NodeTagged:    "tagged",
+}
+
+func (k NodeKind) String() string {
+	if k < 0 || int(k) >= len(nodeKindNames) {
+		return ""
+	}
+	return nodeKindNames[k]
+}
+
+// NodeRef is a reference held by a node: the index of another node or one of
+// the negative sentinels such as UNDEFINED or HOLE.
+type NodeRef int
+
+// IsSentinel reports whether the reference is a sentinel rather than an
+// index.
+func (r NodeRef) IsSentinel() bool {
+	return r < 0
+}
+
+// Node is a single entry of a payload's value table, decoded but not
+// hydrated.
+type Node struct {
+	Index int
+	Kind  NodeKind
+	// Tag is the type tag of tagged nodes.
+	Tag string
+	// Args are the references held by the node, in payload order: array
+	// elements, object and null-prototype object values, Set items, Map keys
+	// and values alternating, or the value of a custom tag.
+	Args []NodeRef
+	// Keys are the keys of objects and null-prototype objects, parallel to
+	// Args. Object keys are sorted.
+	Keys []string
+	// Value is the value of a primitive node. For built-in tags that hold
+	// inline data, such as the string of a Date or the base64 data of a
+	// typed array, it is a []interface{} of that data.
+	Value interface{}
+	// Raw is the node's encoding in the payload.
+	Raw json.RawMessage
+}
+
+// Tree is the value table of a payload as a list of nodes, for tools that
+// need structural access to a payload without hydrating it.
+type Tree struct {
+	Nodes []*Node
+}
+
+// ParseTree decodes the value table of serialized into nodes and checks
+// that every reference is in range. A payload consisting of a single
+// sentinel yields an empty tree.
+func ParseTree(serialized string) (*Tree, error) {
+	values, err := unmarshalTable(serialized)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return &Tree{}, nil
+	}
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tree{Nodes: make([]*Node, len(values))}
+	for i, value := range values {
+		n, err := newNode(i, value, raw[i])
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		for _, ref := range n.Args {
+			if ref < NEGATIVE_ZERO {
+				return nil, fmt.Errorf("index %d: %w", i, &ErrUnsupportedSentinel{Value: int(ref)})
+			}
+			if int(ref) >= len(values) {
+				return nil, fmt.Errorf("%w: index %d out of range", ErrBadReference, ref)
+			}
+		}
+		t.Nodes[i] = n
+	}
+	return t, nil
+}
+
+func newNode(index int, value interface{}, raw json.RawMessage) (*Node, error) {
+	n := &Node{Index: index, Raw: raw}
+	ref := func(v interface{}) error {
+		i, err := toInt(v)
+		if err != nil {
+			return err
+		}
+		n.Args = append(n.Args, NodeRef(i))
+		return nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		n.Kind = NodeObject
+		n.Keys = sortedKeys(v)
+		for _, key := range n.Keys {
+			if err := ref(v[key]); err != nil {
+				return nil, err
+			}
+		}
+	case []interface{}:
+		typeStr, tagged := "", false
+		if len(v) > 0 {
+			typeStr, tagged = v[0].(string)
+		}
+		if !tagged {
+			n.Kind = NodeArray
+		} else {
+			n.Kind = NodeTagged
+			n.Tag = typeStr
+		}
+		slots := refSlots(typeStr, tagged, len(v))
+		isSlot := make(map[int]bool, len(slots))
+		for _, slot := range slots {
+			isSlot[slot] = true
+			if err := ref(v[slot]); err != nil {
+				return nil, err
+			}
+		}
+		if !tagged {
+			break
+		}
+		if tag, _ := ParseTag(typeStr); tag == TagNull {
+			for i := 1; i < len(v); i += 2 {
+				key, _ := v[i].(string)
+				n.Keys = append(n.Keys, key)
+			}
+			break
+		}
+		var inline []interface{}
+		for i := 1; i < len(v); i++ {
+			if !isSlot[i] {
+				inline = append(inline, v[i])
+			}
+		}
+		if inline != nil {
+			n.Value = inline
+		}
+	default:
+		n.Kind = NodePrimitive
+		n.Value = v
+	}
+	return n, nil
+}
+
+// Root returns the root node, or nil for an empty tree.
+func (t *Tree) Root() *Node {
+	if len(t.Nodes) == 0 {
+		return nil
+	}
+	return t.Nodes[0]
+}
+
+// At returns the node ref points to. It reports false for sentinels.
+func (t *Tree) At(ref NodeRef) (*Node, bool) {
+	if ref.IsSentinel() || int(ref) >= len(t.Nodes) {
+		return nil, false
+	}
+	return t.Nodes[ref], true
+}
+
+// Children returns the nodes referenced by n, in the order of n.Args,
+// skipping sentinels.
+func (t *Tree) Children(n *Node) []*Node {
+	var out []*Node
+	for _, ref := range n.Args {
+		if child, ok := t.At(ref); ok {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// Child returns the reference n holds under key: an object key, an array
+// or Set position, or the string form of a Map key.
+func (t *Tree) Child(n *Node, key string) (NodeRef, bool) {
+	switch {
+	case n.Keys != nil:
+		for i, k := range n.Keys {
+			if k == key {
+				return n.Args[i], true
+			}
+		}
+	case n.Kind == NodeArray || n.Tag == TagSet.String():
+		i, err := strconv.Atoi(key)
+		if err == nil && i >= 0 && i < len(n.Args) {
+			return n.Args[i], true
+		}
+	case n.Tag == TagMap.String():
+		for i := 0; i+1 < len(n.Args); i += 2 {
+			if k, ok := t.At(n.Args[i]); ok && k.Kind == NodePrimitive {
+				if s, ok := MapKeyString(k.Value); ok && s == key {
+					return n.Args[i+1], true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// Lookup follows path, a JSON Pointer like those of Search results or a path
+// in the syntax of Path.Dotted, from the root and returns the reference it
+// leads to.
+func (t *Tree) Lookup(path string) (NodeRef, error) {
+	if len(t.Nodes) == 0 {
+		return 0, fmt.Errorf("%w: empty tree", ErrBadReference)
+	}
+	ref := NodeRef(0)
+	for _, segment := range splitPath(path) {
+		n, ok := t.At(ref)
+		if !ok {
+			return 0, fmt.Errorf("%w: %q follows a sentinel", ErrBadReference, segment)
+		}
+		if ref, ok = t.Child(n, segment); !ok {
+			return 0, fmt.Errorf("%w: no %q in node %d", ErrBadReference, segment, n.Index)
+		}
+	}
+	return ref, nil
+}
@@ -0,0 +1,54 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestParseTree(t *testing.T) {
+	input := `[{"when":1,"tags":2,"prices":4,"list":7},["Date","2024-01-02T00:00:00.000Z"],["Set",3],"a",["Map",5,6],"EUR",9.5,[-1,3,-2]]`
+	tree, err := rehydrate.ParseTree(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := tree.Root()
+	if root.Kind != rehydrate.NodeObject || !reflect.DeepEqual(root.Keys, []string{"list", "prices", "tags", "when"}) {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	if !reflect.DeepEqual(root.Args, []rehydrate.NodeRef{7, 4, 2, 1}) {
+		t.Errorf("unexpected root args %v", root.Args)
+	}
+	date := tree.Nodes[1]
+	if date.Kind != rehydrate.NodeTagged || date.Tag != "Date" || !reflect.DeepEqual(date.Value, []interface{}{"2024-01-02T00:00:00.000Z"}) {
+		t.Errorf("unexpected date %+v", date)
+	}
+	if children := tree.Children(tree.Nodes[7]); len(children) != 1 || children[0].Value != "a" {
+		t.Errorf("unexpected list children %v", children)
+	}
+
+	for path, want := range map[string]rehydrate.NodeRef{
+		"":              0,
+		"tags[0]":       3,
+		`prices["EUR"]`: 6,
+		"list[0]":       -1,
+		"list[1]":       3,
+	} {
+		got, err := tree.Lookup(path)
+		if err != nil || got != want {
+			t.Errorf("Lookup(%q) = %v, %v; want %v", path, got, err, want)
+		}
+	}
+	if _, err := tree.Lookup("missing"); !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("expected ErrBadReference, got %v", err)
+	}
+}
+
+func TestParseTreeOutOfRange(t *testing.T) {
+	if _, err := rehydrate.ParseTree(`[[1,5],"x"]`); !errors.Is(err, rehydrate.ErrBadReference) {
+		t.Errorf("expected ErrBadReference, got %v", err)
+	}
+}
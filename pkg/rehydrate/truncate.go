@@ -0,0 +1,56 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Truncated stands in for a string or binary value that was longer than the
+// limit set with WithMaxStringLength or WithMaxBinaryInline.
+type Truncated struct {
+	// Value is the kept prefix: a string, or the elements of a typed array
+	// or ArrayBuffer of the same type as Parse returns for whole ones.
+	Value interface{}
+	// Length is the length in bytes of the original value.
+	Length int
+}
+
+// MarshalJSON renders the value as {"length": n, "value": prefix}.
+func (t *Truncated) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Length int         `json:"length"`
+		Value  interface{} `json:"value"`
+	}{t.Length, t.Value})
+}
+
+// WithMaxStringLength truncates strings longer than n bytes to at most n
+// bytes, cut at a character boundary, and wraps them in a *Truncated
+// recording the original length. Object keys are never truncated. A limit of
+// zero or less disables truncation.
+func WithMaxStringLength(n int) Option {
+	return func(o *options) {
+		o.maxString = n
+	}
+}
+
+// WithMaxBinaryInline truncates typed arrays and ArrayBuffers longer than n
+// bytes to the elements held in their first n bytes, wrapped in a *Truncated
+// recording the original length in bytes. Only the kept prefix is decoded. A
+// limit of zero or less disables truncation.
+func WithMaxBinaryInline(n int) Option {
+	return func(o *options) {
+		o.maxBinary = n
+	}
+}
+
+func (o *options) truncateString(s string) interface{} {
+	n := o.maxString
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return &Truncated{Value: s[:cut], Length: len(s)}
+}
@@ -0,0 +1,84 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestTruncation(t *testing.T) {
+	input := `[{"short":1,"long":2,"bytes":3,"small":4},"abc","héllo wörld",["Uint8Array","AQIDBAUGBwgJ"],["Uint8Array","AQI="]]`
+	v, err := rehydrate.ParseWithOptions(input, rehydrate.WithMaxStringLength(5), rehydrate.WithMaxBinaryInline(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+
+	if root["short"] != "abc" {
+		t.Errorf("short = %v", root["short"])
+	}
+	long := root["long"].(*rehydrate.Truncated)
+	if long.Value != "héll" || long.Length != len("héllo wörld") {
+		t.Errorf("long = %q (%d)", long.Value, long.Length)
+	}
+	bytes := root["bytes"].(*rehydrate.Truncated)
+	if string(bytes.Value.([]byte)) != "\x01\x02\x03\x04" || bytes.Length != 9 {
+		t.Errorf("bytes = %v (%d)", bytes.Value, bytes.Length)
+	}
+	if got := root["small"].([]byte); len(got) != 2 {
+		t.Errorf("small = %v", got)
+	}
+
+	out, err := rehydrate.RehydrateWith(`["abcdefgh"]`, nil, rehydrate.WithMaxStringLength(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"length\": 8,\n  \"value\": \"abc\"\n}"; out != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+}
+
+func TestTruncatedTypedArrays(t *testing.T) {
+	// 1.0, 2.0 and 3.0 as float32, and the bytes 1 to 9.
+	floats := "AACAPwAAAEAAAEBA"
+	input := `[{"floats":1,"ints":2,"buffer":3,"whole":4},["Float32Array","` + floats + `"],` +
+		`["Uint16Array","AQACAAMABAA="],["ArrayBuffer","AQIDBAUGBwgJ"],["Float32Array","AACAPw=="]]`
+	v, err := rehydrate.ParseWithOptions(input, rehydrate.WithMaxBinaryInline(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+
+	// The limit is rounded down to whole elements, which are decoded to the
+	// type a whole array would hydrate to.
+	for key, want := range map[string]*rehydrate.Truncated{
+		"floats": {Value: []float32{1}, Length: 12},
+		"ints":   {Value: []uint16{1, 2, 3}, Length: 8},
+		"buffer": {Value: rehydrate.ArrayBuffer{1, 2, 3, 4, 5, 6, 7}, Length: 9},
+	} {
+		if got := root[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %#v, want %#v", key, got, want)
+		}
+	}
+	if got := root["whole"]; !reflect.DeepEqual(got, []float32{1}) {
+		t.Errorf("whole = %#v", got)
+	}
+
+	annotated, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithMaxBinaryInline(7), rehydrate.WithAnnotatedOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := rehydrate.UnmarshalAnnotated([]byte(annotated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := back.(map[string]interface{})["floats"]; !reflect.DeepEqual(got, root["floats"]) {
+		t.Errorf("annotated round trip: got %#v", got)
+	}
+
+	if _, err := rehydrate.ParseWithOptions(`[["Float32Array","AQIDBAUGBwgJ"]]`, rehydrate.WithMaxBinaryInline(4)); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("partial element: got %v, want ErrInvalidInput", err)
+	}
+}
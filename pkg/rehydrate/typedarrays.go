@@ -0,0 +1,161 @@
+package rehydrate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// ArrayBuffer is the hydrated form of an ArrayBuffer, raw memory without an
+// element type. Uint8Arrays hydrate to []byte.
+type ArrayBuffer []byte
+
+// WithByteOrder decodes the elements of typed arrays in the given byte
+// order. Payloads carry the memory of the serializing platform, which is
+// little-endian, the default, on all common hardware.
+func WithByteOrder(order binary.ByteOrder) Option {
+	return func(o *options) {
+		o.byteOrder = order
+	}
+}
+
+// WithRawTypedArrays hydrates typed arrays to the []byte holding their
+// memory, as earlier releases did, instead of a slice of their elements.
+func WithRawTypedArrays() Option {
+	return func(o *options) {
+		o.rawTypedArrays = true
+	}
+}
+
+func (o *options) keepRawTypedArrays() bool {
+	return o.rawTypedArrays || o.compat == V1
+}
+
+// typedArray converts the decoded data of a value tagged tag to the slice it
+// hydrates to.
+func (o *options) typedArray(tag Tag, data []byte) (interface{}, error) {
+	if o.keepRawTypedArrays() {
+		return data, nil
+	}
+	order := o.byteOrder
+	if order == nil {
+		order = binary.LittleEndian
+	}
+	return decodeTypedArray(tag, data, order)
+}
+
+// decodeTypedArray returns the elements of a typed array held in data:
+// []int8, []int16, []uint16, []int32, []uint32, []float32, []float64, or the
+// BigInt values of BigInt64Array and BigUint64Array. ArrayBuffer data is
+// returned as an ArrayBuffer, and Uint8Array and Uint8ClampedArray data
+// unchanged.
+func decodeTypedArray(tag Tag, data []byte, order binary.ByteOrder) (interface{}, error) {
+	size := tag.elementSize()
+	if size == 0 {
+		return nil, fmt.Errorf("%s is not a typed array", tag)
+	}
+	if len(data)%size != 0 {
+		return nil, fmt.Errorf("byte length %d of %s is not a multiple of %d", len(data), tag, size)
+	}
+	n := len(data) / size
+	switch tag {
+	case TagArrayBuffer:
+		return ArrayBuffer(data), nil
+	case TagInt8Array:
+		out := make([]int8, n)
+		for i, b := range data {
+			out[i] = int8(b)
+		}
+		return out, nil
+	case TagInt16Array:
+		out := make([]int16, n)
+		for i := range out {
+			out[i] = int16(order.Uint16(data[2*i:]))
+		}
+		return out, nil
+	case TagUint16Array:
+		out := make([]uint16, n)
+		for i := range out {
+			out[i] = order.Uint16(data[2*i:])
+		}
+		return out, nil
+	case TagInt32Array:
+		out := make([]int32, n)
+		for i := range out {
+			out[i] = int32(order.Uint32(data[4*i:]))
+		}
+		return out, nil
+	case TagUint32Array:
+		out := make([]uint32, n)
+		for i := range out {
+			out[i] = order.Uint32(data[4*i:])
+		}
+		return out, nil
+	case TagFloat32Array:
+		out := make([]float32, n)
+		for i := range out {
+			out[i] = math.Float32frombits(order.Uint32(data[4*i:]))
+		}
+		return out, nil
+	case TagFloat64Array:
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = math.Float64frombits(order.Uint64(data[8*i:]))
+		}
+		return out, nil
+	case TagBigInt64Array, TagBigUint64Array:
+		words := make([]uint64, n)
+		for i := range words {
+			words[i] = order.Uint64(data[8*i:])
+		}
+		return bigInt64s(words, tag == TagBigInt64Array), nil
+	}
+	return data, nil
+}
+
+// typedArrayElements returns the elements of a typed numeric slice as
+// flattened by Flatten: float32 for Float32Arrays, the decimal digits of
+// BigInt elements and float64 otherwise.
+func typedArrayElements(v interface{}) ([]interface{}, bool) {
+	if digits, ok := bigIntSliceDigits(v); ok {
+		items := make([]interface{}, len(digits))
+		for i, d := range digits {
+			items[i] = d
+		}
+		return items, true
+	}
+	tag, ok := typedArrayTag(v)
+	if !ok || tag == TagArrayBuffer {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		switch e := rv.Index(i).Interface().(type) {
+		case float32:
+			items[i] = e
+		case int64:
+			items[i] = strconv.FormatInt(e, 10)
+		case uint64:
+			items[i] = strconv.FormatUint(e, 10)
+		default:
+			items[i] = rv.Index(i).Convert(reflect.TypeOf(float64(0))).Interface()
+		}
+	}
+	return items, true
+}
+
+// binaryData returns the memory of binary values: the bytes of []byte and
+// ArrayBuffer values and the little-endian encoding of typed numeric slices.
+func binaryData(v interface{}) ([]byte, bool) {
+	switch value := v.(type) {
+	case []byte:
+		return value, true
+	case ArrayBuffer:
+		return value, true
+	}
+	_, data, ok := typedArray(v)
+	return data, ok
+}
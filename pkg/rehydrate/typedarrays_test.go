@@ -0,0 +1,87 @@
+package rehydrate_test
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func typedArrayPayload(tag string, data []byte) string {
+	return `[["` + tag + `","` + base64.StdEncoding.EncodeToString(data) + `"]]`
+}
+
+func TestTypedArrays(t *testing.T) {
+	f32 := binary.LittleEndian.AppendUint32(nil, math.Float32bits(1.5))
+	f64 := binary.LittleEndian.AppendUint64(nil, math.Float64bits(-0.25))
+	tests := []struct {
+		tag  string
+		data []byte
+		want interface{}
+	}{
+		{"Int8Array", []byte{1, 0xff}, []int8{1, -1}},
+		{"Uint8Array", []byte{1, 0xff}, []byte{1, 0xff}},
+		{"Uint8ClampedArray", []byte{1, 0xff}, []byte{1, 0xff}},
+		{"ArrayBuffer", []byte{1, 2, 3}, rehydrate.ArrayBuffer{1, 2, 3}},
+		{"Int16Array", []byte{1, 0, 0xfe, 0xff}, []int16{1, -2}},
+		{"Uint16Array", []byte{1, 0, 0xfe, 0xff}, []uint16{1, 0xfffe}},
+		{"Int32Array", []byte{1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}, []int32{1, -1}},
+		{"Uint32Array", []byte{1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}, []uint32{1, math.MaxUint32}},
+		{"Float32Array", f32, []float32{1.5}},
+		{"Float64Array", f64, []float64{-0.25}},
+		{"Float64Array", nil, []float64{}},
+	}
+	for _, tt := range tests {
+		got, err := rehydrate.Parse(typedArrayPayload(tt.tag, tt.data), nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.tag, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestTypedArrayOptions(t *testing.T) {
+	payload := typedArrayPayload("Uint16Array", []byte{1, 2})
+
+	got, err := rehydrate.ParseWithOptions(payload, rehydrate.WithByteOrder(binary.BigEndian))
+	if err != nil || !reflect.DeepEqual(got, []uint16{0x0102}) {
+		t.Errorf("WithByteOrder: got %#v, %v", got, err)
+	}
+	got, err = rehydrate.ParseWithOptions(payload, rehydrate.WithRawTypedArrays())
+	if err != nil || !reflect.DeepEqual(got, []byte{1, 2}) {
+		t.Errorf("WithRawTypedArrays: got %#v, %v", got, err)
+	}
+	got, err = rehydrate.ParseWithOptions(payload, rehydrate.WithCompatibility(rehydrate.V1))
+	if err != nil || !reflect.DeepEqual(got, []byte{1, 2}) {
+		t.Errorf("V1: got %#v, %v", got, err)
+	}
+
+	// The element size is only checked when decoding elements.
+	odd := typedArrayPayload("Int32Array", []byte{1, 2, 3})
+	if _, err := rehydrate.Parse(odd, nil); !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("misaligned: got %v, want ErrInvalidInput", err)
+	}
+	if _, err := rehydrate.ParseWithOptions(odd, rehydrate.WithRawTypedArrays()); err != nil {
+		t.Errorf("misaligned raw: %v", err)
+	}
+}
+
+func TestTypedArrayRoundTrip(t *testing.T) {
+	for _, v := range []interface{}{[]int8{-1}, []int16{1, -2}, []uint32{7}, []float32{0.5}, []float64{math.Inf(1)}} {
+		s, err := rehydrate.Stringify(v, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rehydrate.Parse(s, nil)
+		if err != nil || !reflect.DeepEqual(got, v) {
+			t.Errorf("%s: got %#v, %v, want %#v", s, got, err, v)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package rehydrate
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// urlKeySuffixes and urlKeys recognise the object keys holding URLs, such
+// as imageUrl, avatar_src or href, compared in lower case and without a
+// plural s.
+var (
+	urlKeySuffixes = []string{"url", "uri", "href", "src", "link"}
+	urlKeys        = map[string]bool{
+		"action": true, "avatar": true, "canonical": true, "icon": true, "image": true,
+		"img": true, "logo": true, "permalink": true, "poster": true, "thumbnail": true,
+	}
+)
+
+// ResolveURLs returns a copy of the hydrated value v in which relative URLs
+// are resolved against base, typically the URL of the page the payload was
+// scraped from. It resolves
+//
+//   - *url.URL values, as returned by revivers
+//   - strings held under a key naming a URL, such as href, src, imageUrl or
+//     links, including the elements of arrays and Sets held under such a key
+//   - strings elsewhere that start with /, ./ or ../
+//
+// Strings containing whitespace, absolute URLs such as mailto: links, and
+// object and Map keys are left as they are. Shared and cyclic values keep
+// their shape in the copy. A nil base returns v unchanged.
+func ResolveURLs(v interface{}, base *url.URL) interface{} {
+	if base == nil {
+		return v
+	}
+	r := &urlResolver{base: base, seen: make(map[uintptr]interface{})}
+	return r.resolve("", v)
+}
+
+type urlResolver struct {
+	base *url.URL
+	seen map[uintptr]interface{}
+}
+
+// resolve returns the copy of v, held under the object or Map key key.
+func (r *urlResolver) resolve(key string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return r.resolveString(key, value)
+	case *url.URL:
+		if value == nil {
+			return v
+		}
+		return r.base.ResolveReference(value)
+	case []interface{}:
+		if len(value) == 0 {
+			return value
+		}
+		id := reflect.ValueOf(value).Pointer()
+		if copied, ok := r.seen[id]; ok {
+			return copied
+		}
+		arr := make([]interface{}, len(value))
+		r.seen[id] = arr
+		for i, item := range value {
+			arr[i] = r.resolve(key, item)
+		}
+		return arr
+	case map[string]interface{}:
+		id := reflect.ValueOf(value).Pointer()
+		if copied, ok := r.seen[id]; ok {
+			return copied
+		}
+		m := make(map[string]interface{}, len(value))
+		r.seen[id] = m
+		for k, item := range value {
+			m[k] = r.resolve(k, item)
+		}
+		return m
+	case *OrderedMap:
+		id := reflect.ValueOf(value).Pointer()
+		if copied, ok := r.seen[id]; ok {
+			return copied
+		}
+		m := NewOrderedMap()
+		r.seen[id] = m
+		for _, e := range value.entries {
+			k, _ := e.Key.(string)
+			m.set(e.Key, e.KeyRef, r.resolve(k, e.Value))
+		}
+		return m
+	case *Set:
+		id := reflect.ValueOf(value).Pointer()
+		if copied, ok := r.seen[id]; ok {
+			return copied
+		}
+		set := NewSet()
+		r.seen[id] = set
+		for _, e := range value.m.entries {
+			set.add(r.resolve(key, e.Key), e.KeyRef)
+		}
+		return set
+	default:
+		return v
+	}
+}
+
+func (r *urlResolver) resolveString(key, s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n") {
+		return s
+	}
+	if !isURLKey(key) && !strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "./") && !strings.HasPrefix(s, "../") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.IsAbs() {
+		return s
+	}
+	return r.base.ResolveReference(u).String()
+}
+
+func isURLKey(key string) bool {
+	key = strings.TrimSuffix(strings.ToLower(key), "s")
+	if urlKeys[key] {
+		return true
+	}
+	for _, suffix := range urlKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package rehydrate_test
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestResolveURLs(t *testing.T) {
+	payload := `[{"product":1,"links":7,"gallery":9,"title":12,"contact":13,"note":14},` +
+		`{"imageUrl":2,"href":3,"src":4,"slug":5,"canonical":6},` +
+		`"img/a.png","../b","//cdn.example.com/c.png","shoes","https://shop.example.com/p/1",` +
+		`["Set",8],"help",` +
+		`["Map",10,11],"hero","./hero.jpg",` +
+		`"a / b","mailto:shop@example.com","see /help"]`
+	v, err := rehydrate.Parse(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://shop.example.com/en/products/")
+	got := rehydrate.ResolveURLs(v, base).(map[string]interface{})
+
+	product := got["product"].(map[string]interface{})
+	for key, want := range map[string]string{
+		"imageUrl":  "https://shop.example.com/en/products/img/a.png",
+		"href":      "https://shop.example.com/en/b",
+		"src":       "https://cdn.example.com/c.png",
+		"slug":      "shoes",
+		"canonical": "https://shop.example.com/p/1",
+	} {
+		if product[key] != want {
+			t.Errorf("%s: got %v, want %s", key, product[key], want)
+		}
+	}
+	if links := got["links"].(*rehydrate.Set).Values(); !reflect.DeepEqual(links, []interface{}{"https://shop.example.com/en/products/help"}) {
+		t.Errorf("links: got %v", links)
+	}
+	gallery := got["gallery"].(*rehydrate.OrderedMap)
+	if hero, _ := gallery.Get("hero"); hero != "https://shop.example.com/en/products/hero.jpg" {
+		t.Errorf("gallery: got %v", hero)
+	}
+	for _, key := range []string{"title", "contact", "note"} {
+		if got[key] != v.(map[string]interface{})[key] {
+			t.Errorf("%s: got %v", key, got[key])
+		}
+	}
+
+	// The input is not modified.
+	if v.(map[string]interface{})["product"].(map[string]interface{})["href"] != "../b" {
+		t.Error("input modified")
+	}
+}
+
+func TestResolveURLsValues(t *testing.T) {
+	base, _ := url.Parse("https://example.com/a/")
+	rel, _ := url.Parse("b?x=1")
+	got := rehydrate.ResolveURLs([]interface{}{rel, 1.0}, base).([]interface{})
+	if u, ok := got[0].(*url.URL); !ok || u.String() != "https://example.com/a/b?x=1" {
+		t.Errorf("got %v", got[0])
+	}
+	if got[1] != 1.0 {
+		t.Errorf("got %v", got[1])
+	}
+
+	cyclic := map[string]interface{}{"src": "x.png"}
+	cyclic["self"] = cyclic
+	out := rehydrate.ResolveURLs(cyclic, base).(map[string]interface{})
+	if out["src"] != "https://example.com/a/x.png" || reflect.ValueOf(out["self"]).Pointer() != reflect.ValueOf(out).Pointer() {
+		t.Error("expected the cycle to be kept in the copy")
+	}
+	if rehydrate.ResolveURLs("./x", nil) != "./x" {
+		t.Error("expected a nil base to leave the value unchanged")
+	}
+}
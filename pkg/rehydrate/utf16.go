@@ -0,0 +1,156 @@
+package rehydrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// UTF16String is a JavaScript string kept as raw UTF-16 code units. It is
+// produced by WithUTF16Strings for strings that contain lone surrogates and
+// therefore have no faithful UTF-8 representation.
+type UTF16String []uint16
+
+// String converts the code units to UTF-8, replacing lone surrogates with
+// U+FFFD.
+func (s UTF16String) String() string {
+	return string(utf16.Decode(s))
+}
+
+// MarshalJSON encodes the string with lone surrogates written as \uXXXX
+// escapes, matching JSON.stringify.
+func (s UTF16String) MarshalJSON() ([]byte, error) {
+	var buf, run bytes.Buffer
+	flush := func() error {
+		if run.Len() == 0 {
+			return nil
+		}
+		quoted, err := json.Marshal(run.String())
+		if err != nil {
+			return err
+		}
+		buf.Write(quoted[1 : len(quoted)-1])
+		run.Reset()
+		return nil
+	}
+
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		u := s[i]
+		if utf16.IsSurrogate(rune(u)) {
+			if i+1 < len(s) {
+				if r := utf16.DecodeRune(rune(u), rune(s[i+1])); r != utf8.RuneError {
+					run.WriteRune(r)
+					i++
+					continue
+				}
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, `\u%04x`, u)
+			continue
+		}
+		run.WriteRune(rune(u))
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('"')
+	return buf.Bytes(), nil
+}
+
+// restoreLoneSurrogates replaces string entries of values whose source
+// contains lone surrogate escapes with their UTF16String form.
+func restoreLoneSurrogates(serialized string, values []interface{}) error {
+	if !strings.Contains(serialized, `\u`) {
+		return nil
+	}
+	raw, err := unmarshalRawTable(serialized)
+	if err != nil {
+		return err
+	}
+	for i, entry := range raw {
+		if _, ok := values[i].(string); !ok || !bytes.Contains(entry, []byte(`\`)) {
+			continue
+		}
+		units, err := decodeUTF16(entry)
+		if err != nil {
+			return fmt.Errorf("%w: index %d: %w", ErrInvalidInput, i, err)
+		}
+		if hasLoneSurrogate(units) {
+			values[i] = UTF16String(units)
+		}
+	}
+	return nil
+}
+
+func hasLoneSurrogate(units []uint16) bool {
+	for i := 0; i < len(units); i++ {
+		if !utf16.IsSurrogate(rune(units[i])) {
+			continue
+		}
+		if i+1 < len(units) && utf16.DecodeRune(rune(units[i]), rune(units[i+1])) != utf8.RuneError {
+			i++
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// decodeUTF16 decodes a JSON string literal into UTF-16 code units without
+// validating surrogate pairs.
+func decodeUTF16(literal []byte) ([]uint16, error) {
+	literal = bytes.TrimSpace(literal)
+	if len(literal) < 2 || literal[0] != '"' || literal[len(literal)-1] != '"' {
+		return nil, fmt.Errorf("not a string literal")
+	}
+	body := literal[1 : len(literal)-1]
+
+	units := make([]uint16, 0, len(body))
+	for len(body) > 0 {
+		if body[0] != '\\' {
+			r, size := utf8.DecodeRune(body)
+			units = utf16.AppendRune(units, r)
+			body = body[size:]
+			continue
+		}
+		if len(body) < 2 {
+			return nil, fmt.Errorf("truncated escape")
+		}
+		switch body[1] {
+		case '"', '\\', '/':
+			units = append(units, uint16(body[1]))
+		case 'b':
+			units = append(units, '\b')
+		case 'f':
+			units = append(units, '\f')
+		case 'n':
+			units = append(units, '\n')
+		case 'r':
+			units = append(units, '\r')
+		case 't':
+			units = append(units, '\t')
+		case 'u':
+			if len(body) < 6 {
+				return nil, fmt.Errorf("truncated escape")
+			}
+			u, err := strconv.ParseUint(string(body[2:6]), 16, 16)
+			if err != nil {
+				return nil, err
+			}
+			units = append(units, uint16(u))
+			body = body[6:]
+			continue
+		default:
+			return nil, fmt.Errorf("invalid escape %q", body[:2])
+		}
+		body = body[2:]
+	}
+	return units, nil
+}
@@ -0,0 +1,34 @@
+package rehydrate_test
+
+import (
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestUTF16StringsPreserveLoneSurrogates(t *testing.T) {
+	input := `[[1,2],"a\ud800b","😀"]`
+	result, err := rehydrate.ParseWithOptions(input, rehydrate.WithUTF16Strings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	arr := result.([]interface{})
+	lone, ok := arr[0].(rehydrate.UTF16String)
+	if !ok {
+		t.Fatalf("expected UTF16String, got %T", arr[0])
+	}
+	if len(lone) != 3 || lone[1] != 0xd800 {
+		t.Fatalf("unexpected code units %x", []uint16(lone))
+	}
+	if arr[1] != "😀" {
+		t.Fatalf("valid pairs should stay strings, got %#v", arr[1])
+	}
+
+	out, err := rehydrate.RehydrateWith(input, nil, rehydrate.WithUTF16Strings(), rehydrate.WithIndent("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `["a\ud800b","😀"]`; out != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+}
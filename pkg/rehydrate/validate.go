@@ -0,0 +1,177 @@
+package rehydrate
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// WithReviverValidation checks every reviver result as soon as the reviver
+// returns, instead of letting unencodable values such as channels or
+// functions fail much later when the output is rendered. A failing check is
+// reported as a *TypeError naming the tag and the path of the value.
+// RehydrateWith always validates, since it renders its result.
+func WithReviverValidation() Option {
+	return func(o *options) {
+		o.validateRevivers = true
+	}
+}
+
+// WithOpaqueTags exempts the results of the revivers for tags from
+// validation, for revivers that deliberately return Go values meant to be
+// consumed directly rather than rendered.
+func WithOpaqueTags(tags ...string) Option {
+	return func(o *options) {
+		if o.opaqueTags == nil {
+			o.opaqueTags = make(map[string]bool)
+		}
+		for _, tag := range tags {
+			o.opaqueTags[tag] = true
+		}
+	}
+}
+
+// validateRevived checks the result res of the reviver for the entry at
+// index, which was called with in.
+func (h *hydrator) validateRevived(tag string, index int, in, res interface{}) error {
+	if !h.opts.validateRevivers || h.opts.opaqueTags[tag] {
+		return nil
+	}
+	c := &renderChecker{seen: make(map[uintptr]bool)}
+	// Passthrough revivers return their input, which the hydrator built and
+	// need not be checked again.
+	if id, ok := containerID(in); ok {
+		c.seen[id] = true
+	}
+	if err := c.check("", reflect.ValueOf(res)); err != nil {
+		return fmt.Errorf("%w: reviver result at %s: %w", ErrInvalidInput, displayPath(h.pathOf(index)), err)
+	}
+	return nil
+}
+
+// pathOf returns the path of the entry at index. Without path tracking it
+// searches the value table for the first path leading to the entry, which is
+// only worth doing when reporting an error.
+func (h *hydrator) pathOf(index int) string {
+	if h.opts.trackPaths() {
+		return h.currentPath()
+	}
+	paths := map[int]string{0: ""}
+	queue := []int{0}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		if i == index {
+			return paths[i]
+		}
+		for _, child := range tableChildren(h.values, i, paths[i]) {
+			if _, seen := paths[child.index]; child.index >= 0 && !seen {
+				paths[child.index] = child.path
+				queue = append(queue, child.index)
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// renderChecker reports values encoding/json cannot marshal, allowing for
+// the conversions applied by ConvertUnsupportedTypes.
+type renderChecker struct {
+	seen map[uintptr]bool
+}
+
+func (c *renderChecker) check(path string, v reflect.Value) error {
+	if !v.IsValid() {
+		return nil
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return fmt.Errorf("value of type %s at %s cannot be rendered as JSON", t, displayPath(path))
+
+	case reflect.Interface:
+		return c.check(path, v.Elem())
+
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if v.IsNil() || c.seen[v.Pointer()] {
+			return nil
+		}
+		c.seen[v.Pointer()] = true
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return c.check(path, v.Elem())
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := c.check(indexPath(path, i), v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		switch key := t.Key(); {
+		case key.Kind() != reflect.Interface && renderableKey(reflect.Zero(key)):
+		case key.Kind() == reflect.Interface && t.Elem().Kind() == reflect.Interface:
+			// map[interface{}]interface{} has its keys formatted with %v.
+		case key.Kind() == reflect.Interface && t.Elem() == reflect.TypeOf(struct{}{}):
+			// map[interface{}]struct{} is a set and becomes an array.
+			return nil
+		case key.Kind() == reflect.Interface:
+			// Other maps with interface keys encode as long as every
+			// dynamic key does.
+			iter := v.MapRange()
+			for iter.Next() {
+				if !renderableKey(iter.Key().Elem()) {
+					return fmt.Errorf("map key of type %T at %s cannot be rendered as JSON", iter.Key().Interface(), displayPath(path))
+				}
+			}
+		default:
+			return fmt.Errorf("map with %s keys at %s cannot be rendered as JSON", key, displayPath(path))
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := c.check(keyPath(path, fmt.Sprint(iter.Key().Interface())), iter.Value()); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || field.Tag.Get("json") == "-" {
+				continue
+			}
+			if err := c.check(keyPath(path, field.Name), v.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renderableKey reports whether encoding/json accepts k as a map key.
+func renderableKey(k reflect.Value) bool {
+	if !k.IsValid() {
+		return false
+	}
+	if k.Type().Implements(textMarshalerType) {
+		return true
+	}
+	kind := k.Kind()
+	return kind == reflect.String || kind >= reflect.Int && kind <= reflect.Uintptr
+}
@@ -0,0 +1,72 @@
+package rehydrate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+type handle struct {
+	Name     string
+	Callback func()
+}
+
+func TestReviverValidation(t *testing.T) {
+	input := `[{"user":1},{"session":2},["Session",3],"abc"]`
+	revivers := rehydrate.Revivers{
+		"Session": func(v interface{}) (interface{}, error) {
+			return map[string]interface{}{"h": handle{Name: v.(string)}}, nil
+		},
+	}
+
+	_, err := rehydrate.RehydrateWith(input, revivers)
+	var typeErr *rehydrate.TypeError
+	if !errors.As(err, &typeErr) || typeErr.Tag != "Session" || !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Fatalf("expected a Session TypeError, got %v", err)
+	}
+	for _, want := range []string{"/user/session", "/h/Callback", "func()"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	// Parse only validates on request.
+	if _, err := rehydrate.ParseWithOptions(input, rehydrate.WithRevivers(revivers)); err != nil {
+		t.Errorf("unexpected error without validation: %v", err)
+	}
+	if _, err := rehydrate.ParseWithOptions(input, rehydrate.WithRevivers(revivers), rehydrate.WithReviverValidation(), rehydrate.WithOpaqueTags("Session")); err != nil {
+		t.Errorf("opaque tags must not be validated: %v", err)
+	}
+}
+
+func TestReviverValidationAcceptsRenderableValues(t *testing.T) {
+	input := `[["Money",1],{"amount":2,"tags":3},42,["Set",4],"x"]`
+	out, err := rehydrate.RehydrateWith(input, rehydrate.Revivers{
+		"Money": func(v interface{}) (interface{}, error) {
+			obj := v.(map[string]interface{})
+			return struct {
+				Amount interface{}
+				Tags   interface{}
+				Counts map[interface{}]int
+				skip   chan int
+			}{obj["amount"], obj["tags"], map[interface{}]int{"k": 1, 2: 3}, nil}, nil
+		},
+	}, rehydrate.WithIndent("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"Amount":42`) {
+		t.Errorf("unexpected output %s", out)
+	}
+
+	_, err = rehydrate.RehydrateWith(input, rehydrate.Revivers{
+		"Money": func(v interface{}) (interface{}, error) {
+			return map[interface{}]int{1.5: 1}, nil
+		},
+	})
+	if !errors.Is(err, rehydrate.ErrInvalidInput) {
+		t.Errorf("expected a float map key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package rehydrate
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// walker traverses a hydrated value depth first. Containers reached a second
+// time, through sharing or a cycle, are not descended into again.
+type walker struct {
+	// visit is called for every value. Returning false skips its children.
+	visit func(path string, v interface{}) bool
+	// visitKey, if set, is called for every Map key.
+	visitKey func(path string, key interface{})
+
+	seen map[uintptr]bool
+}
+
+func (w *walker) walk(path string, v interface{}) {
+	if !w.visit(path, v) {
+		return
+	}
+	if id, ok := containerID(v); ok {
+		if w.seen == nil {
+			w.seen = make(map[uintptr]bool)
+		}
+		if w.seen[id] {
+			return
+		}
+		w.seen[id] = true
+	}
+
+	switch value := v.(type) {
+	case []interface{}:
+		for i, item := range value {
+			w.walk(indexPath(path, i), item)
+		}
+	case map[string]interface{}, NullObject:
+		obj := objectOf(value)
+		for _, key := range sortedKeys(obj) {
+			w.walk(keyPath(path, key), obj[key])
+		}
+	case *OrderedMap:
+		for _, e := range value.entries {
+			p := mapKeyPath(path, e.Key)
+			if w.visitKey != nil {
+				w.visitKey(p, e.Key)
+			}
+			w.walk(p, e.Value)
+		}
+	case *Set:
+		for i, item := range value.Values() {
+			w.walk(indexPath(path, i), item)
+		}
+	case *SampledArray:
+		for i, item := range value.Values {
+			w.walk(indexPath(path, value.Indices[i]), item)
+		}
+	}
+}
+
+// containerID returns an identity for values that can be shared or cyclic.
+func containerID(v interface{}) (uintptr, bool) {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}, NullObject, *OrderedMap, *Set, *SampledArray:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice && rv.Len() == 0 {
+			return 0, false
+		}
+		return rv.Pointer(), true
+	}
+	return 0, false
+}
+
+// objectOf returns the fields of a map[string]interface{} or NullObject.
+func objectOf(v interface{}) map[string]interface{} {
+	if obj, ok := v.(NullObject); ok {
+		return obj
+	}
+	return v.(map[string]interface{})
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isIdentifier reports whether key is an ASCII JavaScript identifier.
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c == '_', c == '$':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// KeyPath, IndexPath and MapKeyPath extend path by an object key, an array
+// or Set position and a Map key, as the JSON Pointers of Search results,
+// reports and Path.String, for tools that navigate a hydrated value
+// themselves.
+func KeyPath(path, key string) string { return keyPath(path, key) }
+
+// IndexPath extends path by a position; see KeyPath.
+func IndexPath(path string, i int) string { return indexPath(path, i) }
+
+// MapKeyPath extends path by a Map key; see KeyPath.
+func MapKeyPath(path string, key interface{}) string { return mapKeyPath(path, key) }
+
+// keyPath appends an object key to path: /user/name, escaping ~ and / as
+// RFC 6901 requires.
+func keyPath(path, key string) string {
+	return path + "/" + pointerEscaper.Replace(key)
+}
+
+// indexPath appends an array or Set position to path: /items/0.
+func indexPath(path string, i int) string {
+	return path + "/" + strconv.Itoa(i)
+}
+
+// mapKeyPath appends the string form of a Map key to path: /prices/EUR or
+// /scores/1000000. Keys without a string form are addressed as ?.
+func mapKeyPath(path string, key interface{}) string {
+	if s, ok := MapKeyString(key); ok {
+		return keyPath(path, s)
+	}
+	return path + "/?"
+}
@@ -0,0 +1,21 @@
+// Package wasm exposes the rehydrate package to JavaScript when compiled with
+// GOOS=js GOARCH=wasm, so browser-side tooling runs exactly the same
+// hydration logic as Go backends.
+//
+// A minimal WebAssembly program registers the bindings and blocks:
+//
+//	func main() {
+//		wasm.Register("rehydrate", nil)
+//		select {}
+//	}
+//
+// after which JavaScript can call
+//
+//	rehydrate.parse(payload)     // hydrated value as native JS objects
+//	rehydrate.rehydrate(payload) // hydrated value as a JSON string
+//	rehydrate.stringify(value)   // payload serializing a JS value
+//
+// The functions return an Error object instead of a result when the payload
+// is invalid or the value cannot be serialized. Go code converts between
+// hydrated values and JavaScript with ToJS and FromJS.
+package wasm
@@ -0,0 +1,240 @@
+//go:build js && wasm
+
+package wasm
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"syscall/js"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// Stringify serializes a JavaScript value into a payload, converting it with
+// FromJS first.
+func Stringify(v js.Value, opts ...rehydrate.Option) (string, error) {
+	value, err := FromJS(v)
+	if err != nil {
+		return "", err
+	}
+	return rehydrate.StringifyWithOptions(value, opts...)
+}
+
+// FromJS converts a JavaScript value to the Go value it hydrates to, the
+// inverse of ToJS: Dates become time.Time, Sets *rehydrate.Set, Maps
+// *rehydrate.OrderedMap, RegExps *rehydrate.RegExp, BigInts *big.Int, boxed
+// primitives *rehydrate.Wrapped, Uint8Arrays and ArrayBuffers []byte and
+// rehydrate.ArrayBuffer, and other typed arrays slices of their element
+// type. undefined becomes nil. Objects referenced more than once, including
+// cycles, are converted once. Functions, symbols, invalid Dates and RegExps
+// Go cannot compile fail with an error wrapping rehydrate.ErrInvalidInput.
+func FromJS(v js.Value) (interface{}, error) {
+	d := &decoder{seen: js.Global().Get("Map").New()}
+	return d.decode(v)
+}
+
+type decoder struct {
+	// seen maps each converted object to its index in values.
+	seen   js.Value
+	values []interface{}
+}
+
+func (d *decoder) decode(v js.Value) (interface{}, error) {
+	global := js.Global()
+	// syscall/js has no Type for BigInts, and Type panics on them.
+	if n, ok := bigInt(v); ok {
+		return n, nil
+	}
+	switch v.Type() {
+	case js.TypeUndefined, js.TypeNull:
+		return nil, nil
+	case js.TypeBoolean:
+		return v.Bool(), nil
+	case js.TypeNumber:
+		return v.Float(), nil
+	case js.TypeString:
+		return v.String(), nil
+	case js.TypeObject:
+	default:
+		return nil, fmt.Errorf("%w: cannot convert a JavaScript %s", rehydrate.ErrInvalidInput, v.Type())
+	}
+
+	if ref := d.seen.Call("get", v); !ref.IsUndefined() {
+		return d.values[ref.Int()], nil
+	}
+	switch {
+	case v.InstanceOf(global.Get("Date")):
+		ms := v.Call("getTime").Float()
+		if math.IsNaN(ms) {
+			return nil, fmt.Errorf("%w: invalid Date", rehydrate.ErrInvalidInput)
+		}
+		return time.UnixMilli(int64(ms)).UTC(), nil
+	case v.InstanceOf(global.Get("RegExp")):
+		re, err := rehydrate.CompileRegExp(v.Get("source").String(), v.Get("flags").String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", rehydrate.ErrInvalidInput, err)
+		}
+		return re, nil
+	case v.InstanceOf(global.Get("ArrayBuffer")):
+		return rehydrate.ArrayBuffer(bytesOf(global.Get("Uint8Array").New(v))), nil
+	case global.Get("ArrayBuffer").Call("isView", v).Bool():
+		return typedArrayFromJS(v)
+	}
+	for _, kind := range []string{"Number", "String", "Boolean", "BigInt"} {
+		if v.InstanceOf(global.Get(kind)) {
+			value, err := d.decode(v.Call("valueOf"))
+			if err != nil {
+				return nil, err
+			}
+			return &rehydrate.Wrapped{Kind: kind, Value: value}, nil
+		}
+	}
+
+	switch {
+	case global.Get("Array").Call("isArray", v).Bool():
+		out := make([]interface{}, v.Length())
+		d.remember(v, out)
+		for i := range out {
+			item, err := d.decode(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = item
+		}
+		return out, nil
+	case v.InstanceOf(global.Get("Set")):
+		out := rehydrate.NewSet()
+		d.remember(v, out)
+		values := global.Get("Array").Call("from", v)
+		for i := 0; i < values.Length(); i++ {
+			item, err := d.decode(values.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out.Add(item)
+		}
+		return out, nil
+	case v.InstanceOf(global.Get("Map")):
+		out := rehydrate.NewOrderedMap()
+		d.remember(v, out)
+		entries := global.Get("Array").Call("from", v)
+		for i := 0; i < entries.Length(); i++ {
+			key, err := d.decode(entries.Index(i).Index(0))
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.decode(entries.Index(i).Index(1))
+			if err != nil {
+				return nil, err
+			}
+			out.Set(key, value)
+		}
+		return out, nil
+	}
+
+	out := make(map[string]interface{})
+	d.remember(v, out)
+	keys := global.Get("Object").Call("keys", v)
+	for i := 0; i < keys.Length(); i++ {
+		key := keys.Index(i).String()
+		item, err := d.decode(v.Get(key))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		out[key] = item
+	}
+	return out, nil
+}
+
+func (d *decoder) remember(v js.Value, out interface{}) {
+	d.seen.Call("set", v, len(d.values))
+	d.values = append(d.values, out)
+}
+
+// bigInt converts a JavaScript BigInt primitive, which unlike its boxed
+// form is not identical to Object(v).
+func bigInt(v js.Value) (*big.Int, bool) {
+	global := js.Global()
+	boxed := global.Get("Object").Invoke(v)
+	if boxed.Equal(v) || !boxed.InstanceOf(global.Get("BigInt")) {
+		return nil, false
+	}
+	return new(big.Int).SetString(global.Get("String").Invoke(v).String(), 10)
+}
+
+// bytesOf copies the contents of a Uint8Array.
+func bytesOf(arr js.Value) []byte {
+	out := make([]byte, arr.Length())
+	js.CopyBytesToGo(out, arr)
+	return out
+}
+
+// typedArrayFromJS converts a typed array to a slice of its element type.
+func typedArrayFromJS(v js.Value) (interface{}, error) {
+	kind := v.Get("constructor").Get("name").String()
+	n := v.Length()
+	switch kind {
+	case "Uint8Array", "Uint8ClampedArray":
+		return bytesOf(js.Global().Get("Uint8Array").New(v.Get("buffer"), v.Get("byteOffset"), n)), nil
+	case "Int8Array":
+		out := make([]int8, n)
+		for i := range out {
+			out[i] = int8(v.Index(i).Int())
+		}
+		return out, nil
+	case "Int16Array":
+		out := make([]int16, n)
+		for i := range out {
+			out[i] = int16(v.Index(i).Int())
+		}
+		return out, nil
+	case "Uint16Array":
+		out := make([]uint16, n)
+		for i := range out {
+			out[i] = uint16(v.Index(i).Int())
+		}
+		return out, nil
+	case "Int32Array":
+		out := make([]int32, n)
+		for i := range out {
+			out[i] = int32(v.Index(i).Int())
+		}
+		return out, nil
+	case "Uint32Array":
+		out := make([]uint32, n)
+		for i := range out {
+			out[i] = uint32(v.Index(i).Float())
+		}
+		return out, nil
+	case "Float32Array":
+		out := make([]float32, n)
+		for i := range out {
+			out[i] = float32(v.Index(i).Float())
+		}
+		return out, nil
+	case "Float64Array":
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = v.Index(i).Float()
+		}
+		return out, nil
+	case "BigInt64Array":
+		out := make([]int64, n)
+		for i := range out {
+			n, _ := bigInt(v.Index(i))
+			out[i] = n.Int64()
+		}
+		return out, nil
+	case "BigUint64Array":
+		out := make([]uint64, n)
+		for i := range out {
+			n, _ := bigInt(v.Index(i))
+			out[i] = n.Uint64()
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%w: cannot convert a JavaScript %s", rehydrate.ErrInvalidInput, strconv.Quote(kind))
+}
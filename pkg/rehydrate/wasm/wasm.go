@@ -0,0 +1,294 @@
+//go:build js && wasm
+
+package wasm
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strconv"
+	"syscall/js"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+// Register installs the bindings as a global object with the given name.
+// Revivers from DefaultNuxtRevivers are applied, with extra taking
+// precedence.
+func Register(name string, extra rehydrate.Revivers) {
+	revivers := rehydrate.DefaultNuxtRevivers()
+	for tag, fn := range extra {
+		revivers[tag] = fn
+	}
+
+	obj := js.Global().Get("Object").New()
+	obj.Set("parse", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) < 1 {
+			return jsError("parse: missing payload")
+		}
+		v, err := rehydrate.ParseWithOptions(args[0].String(), rehydrate.WithRevivers(revivers))
+		if err != nil {
+			return jsError(err.Error())
+		}
+		return ToJS(v)
+	}))
+	obj.Set("rehydrate", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) < 1 {
+			return jsError("rehydrate: missing payload")
+		}
+		out, err := rehydrate.RehydrateWith(args[0].String(), extra)
+		if err != nil {
+			return jsError(err.Error())
+		}
+		return out
+	}))
+	obj.Set("stringify", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) < 1 {
+			return jsError("stringify: missing value")
+		}
+		out, err := Stringify(args[0])
+		if err != nil {
+			return jsError(err.Error())
+		}
+		return out
+	}))
+	js.Global().Set(name, obj)
+}
+
+// jsError returns a JavaScript Error. Exceptions thrown by JavaScript code
+// called from Go panic in Go, so failures are returned rather than thrown.
+func jsError(msg string) js.Value {
+	return js.Global().Get("Error").New(msg)
+}
+
+// ToJS converts a hydrated value to its native JavaScript counterpart: Date,
+// Set, Map, RegExp, BigInt, typed array and boxed primitive values are
+// recreated rather than flattened to JSON, and shared or cyclic references
+// stay shared. Tagged values other than BigInts and RegExps become
+// {tag, args} objects. Numbers hydrated with WithNumberMode become Numbers,
+// or BigInts when they are integers beyond the precision of a Number.
+func ToJS(v interface{}) js.Value {
+	e := &encoder{seen: make(map[uintptr]js.Value)}
+	return e.encode(v)
+}
+
+// typedArrays names the JavaScript typed array holding elements of each kind.
+var typedArrays = map[reflect.Kind]string{
+	reflect.Int8:    "Int8Array",
+	reflect.Int16:   "Int16Array",
+	reflect.Uint16:  "Uint16Array",
+	reflect.Int32:   "Int32Array",
+	reflect.Uint32:  "Uint32Array",
+	reflect.Float32: "Float32Array",
+	reflect.Float64: "Float64Array",
+}
+
+type encoder struct {
+	seen map[uintptr]js.Value
+}
+
+// containerID identifies containers so shared values are converted once.
+func containerID(v interface{}) (uintptr, bool) {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}, *rehydrate.OrderedMap, *rehydrate.Set, *rehydrate.SampledArray:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Slice && rv.Len() == 0 {
+			return 0, false
+		}
+		return rv.Pointer(), true
+	}
+	return 0, false
+}
+
+func (e *encoder) encode(v interface{}) js.Value {
+	id, isContainer := containerID(v)
+	if out, ok := e.seen[id]; isContainer && ok {
+		return out
+	}
+
+	global := js.Global()
+	switch value := v.(type) {
+	case nil:
+		return js.Null()
+	case bool, float64, string:
+		return js.ValueOf(value)
+	case rehydrate.UTF16String:
+		codes := make([]interface{}, len(value))
+		for i, c := range value {
+			codes[i] = int(c)
+		}
+		return global.Get("String").Get("fromCharCode").Invoke(codes...)
+	case time.Time:
+		return global.Get("Date").New(float64(value.UnixMilli()))
+	case *big.Int:
+		return global.Get("BigInt").Invoke(value.String())
+	case *rehydrate.RegExp:
+		return global.Get("RegExp").New(value.Source, value.Flags)
+	case []byte:
+		arr := global.Get("Uint8Array").New(len(value))
+		js.CopyBytesToJS(arr, value)
+		return arr
+	case rehydrate.ArrayBuffer:
+		arr := global.Get("Uint8Array").New(len(value))
+		js.CopyBytesToJS(arr, value)
+		return arr.Get("buffer")
+	case []int8, []int16, []uint16, []int32, []uint32, []float32, []float64:
+		rv := reflect.ValueOf(value)
+		arr := global.Get(typedArrays[rv.Type().Elem().Kind()]).New(rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			arr.SetIndex(i, rv.Index(i).Interface())
+		}
+		return arr
+	case []*big.Int:
+		return bigInt64Array(value)
+	case []int64:
+		arr := global.Get("BigInt64Array").New(len(value))
+		for i, n := range value {
+			arr.SetIndex(i, global.Get("BigInt").Invoke(strconv.FormatInt(n, 10)))
+		}
+		return arr
+	case []uint64:
+		arr := global.Get("BigUint64Array").New(len(value))
+		for i, n := range value {
+			arr.SetIndex(i, global.Get("BigInt").Invoke(strconv.FormatUint(n, 10)))
+		}
+		return arr
+	case json.Number:
+		return number(string(value))
+	case int64:
+		return number(strconv.FormatInt(value, 10))
+	case []interface{}:
+		out := global.Get("Array").New(len(value))
+		e.remember(id, isContainer, out)
+		for i, item := range value {
+			out.SetIndex(i, e.encode(item))
+		}
+		return out
+	case map[string]interface{}:
+		out := global.Get("Object").New()
+		e.remember(id, isContainer, out)
+		for k, item := range value {
+			out.Set(k, e.encode(item))
+		}
+		return out
+	case *rehydrate.Set:
+		out := global.Get("Set").New()
+		e.remember(id, isContainer, out)
+		for _, item := range value.Values() {
+			out.Call("add", e.encode(item))
+		}
+		return out
+	case *rehydrate.Wrapped:
+		// Object boxes a primitive in its wrapper, as new Number(1) does.
+		return global.Get("Object").Invoke(e.encode(value.Value))
+	case *rehydrate.Tagged:
+		return e.tagged(value)
+	case *rehydrate.LazyRef:
+		out := global.Get("Object").New()
+		out.Set("lazyRef", value.Index)
+		return out
+	case *rehydrate.Truncated:
+		out := global.Get("Object").New()
+		out.Set("length", value.Length)
+		out.Set("value", e.encode(value.Value))
+		return out
+	case *rehydrate.SampledArray:
+		out := global.Get("Object").New()
+		e.remember(id, isContainer, out)
+		indices := make([]interface{}, len(value.Indices))
+		values := global.Get("Array").New(len(value.Values))
+		for i, item := range value.Values {
+			indices[i] = value.Indices[i]
+			values.SetIndex(i, e.encode(item))
+		}
+		out.Set("length", value.Length)
+		out.Set("indices", indices)
+		out.Set("values", values)
+		return out
+	case *rehydrate.OrderedMap:
+		out := global.Get("Map").New()
+		e.remember(id, isContainer, out)
+		for _, entry := range value.Entries() {
+			out.Call("set", e.encode(entry.Key), e.encode(entry.Value))
+		}
+		return out
+	}
+	return js.Undefined()
+}
+
+// bigInt64Array recreates the BigInt64Array or BigUint64Array ns were
+// hydrated from: a BigInt64Array unless an element only fits a
+// BigUint64Array, as with annotated output. Elements fitting neither, which
+// no typed array holds, make it an Array of BigInts.
+func bigInt64Array(ns []*big.Int) js.Value {
+	signed, unsigned := true, true
+	for _, n := range ns {
+		signed = signed && n.IsInt64()
+		unsigned = unsigned && n.IsUint64()
+	}
+	kind := "Array"
+	switch {
+	case signed:
+		kind = "BigInt64Array"
+	case unsigned:
+		kind = "BigUint64Array"
+	}
+	global := js.Global()
+	out := global.Get(kind).New(len(ns))
+	for i, n := range ns {
+		out.SetIndex(i, global.Get("BigInt").Invoke(n.String()))
+	}
+	return out
+}
+
+// number converts the literal of a number hydrated with WithNumberMode to a
+// Number, or to a BigInt for integers a Number cannot hold exactly, so no
+// precision the number mode kept is lost.
+func number(literal string) js.Value {
+	if n, ok := new(big.Int).SetString(literal, 10); ok {
+		if _, acc := new(big.Float).SetInt(n).Float64(); acc != big.Exact {
+			return js.Global().Get("BigInt").Invoke(literal)
+		}
+	}
+	f, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return js.Global().Get("Number").Invoke(literal)
+	}
+	return js.ValueOf(f)
+}
+
+// tagged recreates the BigInts and RegExps of builds with the rehydrate_min
+// tag and converts other Tagged values to {tag, args} objects.
+func (e *encoder) tagged(t *rehydrate.Tagged) js.Value {
+	global := js.Global()
+	switch t.Tag {
+	case rehydrate.TagBigInt.String():
+		if len(t.Args) == 1 {
+			if digits, ok := t.Args[0].(string); ok {
+				return global.Get("BigInt").Invoke(digits)
+			}
+		}
+	case rehydrate.TagRegExp.String():
+		if len(t.Args) == 2 {
+			source, ok := t.Args[0].(string)
+			if flags, ok2 := t.Args[1].(string); ok && ok2 {
+				return global.Get("RegExp").New(source, flags)
+			}
+		}
+	}
+	args := global.Get("Array").New(len(t.Args))
+	for i, arg := range t.Args {
+		args.SetIndex(i, e.encode(arg))
+	}
+	out := global.Get("Object").New()
+	out.Set("tag", t.Tag)
+	out.Set("args", args)
+	return out
+}
+
+func (e *encoder) remember(id uintptr, ok bool, out js.Value) {
+	if ok {
+		e.seen[id] = out
+	}
+}
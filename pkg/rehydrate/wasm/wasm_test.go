@@ -0,0 +1,190 @@
+//go:build js && wasm
+
+package wasm_test
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"syscall/js"
+	"testing"
+	"time"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate/wasm"
+)
+
+func TestToJS(t *testing.T) {
+	global := js.Global()
+	v, err := rehydrate.ParseWithOptions(`[{"n":1,"s":2,"tags":3,"d":5},["Object",7],["Object","x"],["Set",4],"a",["Date","2024-01-02T03:04:05.000Z"],0,7]`,
+		rehydrate.WithWrappedPrimitives())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := wasm.ToJS(v)
+	if n := out.Get("n"); !n.InstanceOf(global.Get("Number")) || n.Call("valueOf").Int() != 7 {
+		t.Errorf("n = %v, want a boxed Number", n)
+	}
+	if s := out.Get("s"); !s.InstanceOf(global.Get("String")) || s.Call("valueOf").String() != "x" {
+		t.Errorf("s = %v, want a boxed String", s)
+	}
+	if tags := out.Get("tags"); !tags.InstanceOf(global.Get("Set")) || !tags.Call("has", "a").Bool() {
+		t.Errorf("tags = %v, want a Set holding a", tags)
+	}
+	if d := out.Get("d"); !d.InstanceOf(global.Get("Date")) || d.Call("toISOString").String() != "2024-01-02T03:04:05.000Z" {
+		t.Errorf("d = %v", d)
+	}
+
+	big := wasm.ToJS(&rehydrate.Tagged{Tag: "BigInt", Args: []interface{}{"123"}})
+	// Type panics on BigInts, which are primitives rather than instances.
+	if s := global.Get("String").Invoke(big).String(); s != "123" || big.InstanceOf(global.Get("BigInt")) {
+		t.Errorf("BigInt Tagged = %s", s)
+	}
+	re := wasm.ToJS(&rehydrate.Tagged{Tag: "RegExp", Args: []interface{}{"a+", "gi"}})
+	if !re.InstanceOf(global.Get("RegExp")) || re.Get("flags").String() != "gi" {
+		t.Errorf("RegExp Tagged = %v", re)
+	}
+	other := wasm.ToJS(&rehydrate.Tagged{Tag: "Point", Args: []interface{}{1.0, 2.0}})
+	if other.Get("tag").String() != "Point" || other.Get("args").Index(1).Int() != 2 {
+		t.Errorf("Tagged = %v", other)
+	}
+}
+
+func TestToJSNumbers(t *testing.T) {
+	global := js.Global()
+	str := func(v js.Value) string { return global.Get("String").Invoke(v).String() }
+	// Type panics on BigInts, so typeof is asked of JavaScript instead.
+	typeOf := func(v js.Value) string { return eval(`v => typeof v`).Invoke(v).String() }
+
+	// BigInt64Array data hydrates to []*big.Int, or []int64 in the minimal
+	// build, and must come back as the same typed array.
+	v, err := rehydrate.ParseWithOptions(`[{"s":1,"u":2},["BigInt64Array","//////////8BAAAAAAAAAA=="],["BigUint64Array","//////////8="]]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := wasm.ToJS(v)
+	if s := out.Get("s"); !s.InstanceOf(global.Get("BigInt64Array")) || str(s) != "-1,1" {
+		t.Errorf("s = %s", str(s))
+	}
+	if u := out.Get("u"); !u.InstanceOf(global.Get("BigUint64Array")) || str(u) != "18446744073709551615" {
+		t.Errorf("u = %s", str(u))
+	}
+	for _, c := range []struct {
+		v    interface{}
+		kind string
+	}{
+		{[]int64{-1, 2}, "BigInt64Array"},
+		{[]uint64{1 << 63}, "BigUint64Array"},
+	} {
+		if got := wasm.ToJS(c.v); !got.InstanceOf(global.Get(c.kind)) {
+			t.Errorf("%#v = %s, want a %s", c.v, str(got), c.kind)
+		}
+	}
+
+	for _, mode := range []rehydrate.NumberMode{rehydrate.NumberJSON, rehydrate.NumberInt64} {
+		v, err := rehydrate.ParseWithOptions(`[{"id":1,"ratio":2,"n":3},9007199254740993,0.5,42]`, rehydrate.WithNumberMode(mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := wasm.ToJS(v)
+		// Integers a Number cannot hold exactly become BigInts.
+		if id := out.Get("id"); typeOf(id) != "bigint" || str(id) != "9007199254740993" {
+			t.Errorf("mode %d: id = %s", mode, str(id))
+		}
+		if r := out.Get("ratio"); typeOf(r) != "number" || r.Float() != 0.5 {
+			t.Errorf("mode %d: ratio = %s", mode, str(r))
+		}
+		if n := out.Get("n"); typeOf(n) != "number" || n.Int() != 42 {
+			t.Errorf("mode %d: n = %s", mode, str(n))
+		}
+	}
+}
+
+// eval runs JavaScript source, returning the value of its expression.
+func eval(src string) js.Value {
+	return js.Global().Call("eval", "("+src+")")
+}
+
+func TestFromJS(t *testing.T) {
+	v := eval(`(() => {
+		const o = {n: 1.5, s: "x", ok: true, none: null, big: 12345678901234567890n,
+			boxed: new Number(2), d: new Date(Date.UTC(2024, 0, 2)), re: /a+/gi,
+			set: new Set(["a"]), map: new Map([[1, "one"]]),
+			bytes: new Uint8Array([1, 2]), words: new Int16Array([-1, 2]),
+			buf: new Uint8Array([3]).buffer};
+		o.self = o;
+		return o;
+	})()`)
+	got, err := wasm.FromJS(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := got.(map[string]interface{})
+	want, _ := new(big.Int).SetString("12345678901234567890", 10)
+	if obj["n"] != 1.5 || obj["s"] != "x" || obj["ok"] != true || obj["none"] != nil {
+		t.Errorf("primitives = %v", obj)
+	}
+	if n, ok := obj["big"].(*big.Int); !ok || n.Cmp(want) != 0 {
+		t.Errorf("big = %v", obj["big"])
+	}
+	if w, ok := obj["boxed"].(*rehydrate.Wrapped); !ok || w.Kind != "Number" || w.Value != 2.0 {
+		t.Errorf("boxed = %#v", obj["boxed"])
+	}
+	if d, ok := obj["d"].(time.Time); !ok || !d.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("d = %v", obj["d"])
+	}
+	if re, ok := obj["re"].(*rehydrate.RegExp); !ok || re.Source != "a+" || re.Flags != "gi" {
+		t.Errorf("re = %#v", obj["re"])
+	}
+	if set, ok := obj["set"].(*rehydrate.Set); !ok || !set.Has("a") {
+		t.Errorf("set = %v", obj["set"])
+	}
+	if m, ok := obj["map"].(*rehydrate.OrderedMap); !ok {
+		t.Errorf("map = %v", obj["map"])
+	} else if one, _ := m.Get(1.0); one != "one" {
+		t.Errorf("map[1] = %v", one)
+	}
+	if !reflect.DeepEqual(obj["bytes"], []byte{1, 2}) || !reflect.DeepEqual(obj["words"], []int16{-1, 2}) {
+		t.Errorf("bytes = %#v, words = %#v", obj["bytes"], obj["words"])
+	}
+	if !reflect.DeepEqual(obj["buf"], rehydrate.ArrayBuffer{3}) {
+		t.Errorf("buf = %#v", obj["buf"])
+	}
+	if self, ok := obj["self"].(map[string]interface{}); !ok || reflect.ValueOf(self).Pointer() != reflect.ValueOf(obj).Pointer() {
+		t.Error("expected a cycle back to the root")
+	}
+
+	for _, src := range []string{`{f() {}}`, `[Symbol("s")]`, `new Date(NaN)`, `/(?=a)/`} {
+		if _, err := wasm.FromJS(eval(src)); !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("FromJS(%s): got %v, want ErrInvalidInput", src, err)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	wasm.Register("rehydrateTest", nil)
+	api := js.Global().Get("rehydrateTest")
+
+	payload := api.Call("stringify", eval(`{when: new Date(0), tags: new Set([1n])}`))
+	if payload.Type() != js.TypeString {
+		t.Fatalf("stringify returned %v", payload)
+	}
+	if want := `[{"tags":1,"when":3},["Set",2],["BigInt","1"],["Date","1970-01-01T00:00:00.000Z"]]`; payload.String() != want {
+		t.Errorf("stringify = %s, want %s", payload.String(), want)
+	}
+	back := api.Call("parse", payload)
+	if !back.Get("when").InstanceOf(js.Global().Get("Date")) || back.Get("tags").Get("size").Int() != 1 {
+		t.Errorf("parse(stringify(v)) = %v", back)
+	}
+	if out := api.Call("rehydrate", payload).String(); !strings.Contains(out, `"when": "1970-01-01T00:00:00Z"`) {
+		t.Errorf("rehydrate = %s", out)
+	}
+
+	if err := api.Call("stringify", eval(`() => 1`)); !err.InstanceOf(js.Global().Get("Error")) {
+		t.Errorf("stringify(function) = %v, want an Error", err)
+	}
+	if err := api.Call("parse", "["); !err.InstanceOf(js.Global().Get("Error")) {
+		t.Errorf("parse([) = %v, want an Error", err)
+	}
+}
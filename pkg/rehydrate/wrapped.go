@@ -0,0 +1,86 @@
+package rehydrate
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Wrapped is the hydrated form of a boxed primitive, such as new Number(5)
+// or new String("x"), when WithWrappedPrimitives is in use. It renders as the
+// primitive.
+type Wrapped struct {
+	// Kind is the constructor of the box: Number, String, Boolean or
+	// BigInt.
+	Kind string
+	// Value is the primitive: a float64, string, bool or *big.Int.
+	Value interface{}
+}
+
+// MarshalJSON renders the wrapped primitive.
+func (w *Wrapped) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.Value)
+}
+
+// WithWrappedPrimitives hydrates boxed primitives to *Wrapped instead of the
+// primitive they box, for consumers that need to tell them apart.
+func WithWrappedPrimitives() Option {
+	return func(o *options) {
+		o.wrapPrimitives = true
+	}
+}
+
+// hydrateObject hydrates an ["Object", ...] entry. devalue writes boxed
+// primitives as ["Object", literal]; the form ["Object", kind, literal],
+// naming the constructor explicitly, is accepted as well. Unlike other
+// arguments, the literal is the value itself rather than a reference.
+func (h *hydrator) hydrateObject(index int, typeStr string, arr []interface{}) (interface{}, error) {
+	var w *Wrapped
+	switch len(arr) {
+	case 2:
+		w = wrapLiteral(arr[1])
+	case 3:
+		if kind, ok := arr[1].(string); ok {
+			w = wrapKind(kind, arr[2])
+		}
+	}
+	if w == nil {
+		return nil, typeError(typeStr, index, fmt.Errorf("%w: invalid boxed primitive", ErrInvalidInput))
+	}
+
+	var v interface{} = w.Value
+	if h.opts.wrapPrimitives {
+		v = w
+	}
+	h.store(index, v)
+	return v, nil
+}
+
+func wrapLiteral(v interface{}) *Wrapped {
+	switch v.(type) {
+	case float64:
+		return &Wrapped{Kind: "Number", Value: v}
+	case string:
+		return &Wrapped{Kind: "String", Value: v}
+	case bool:
+		return &Wrapped{Kind: "Boolean", Value: v}
+	}
+	return nil
+}
+
+func wrapKind(kind string, v interface{}) *Wrapped {
+	if kind == "BigInt" {
+		s, ok := v.(string)
+		if !ok {
+			return nil
+		}
+		n, ok := parseBigInt(s)
+		if !ok {
+			return nil
+		}
+		return &Wrapped{Kind: kind, Value: n}
+	}
+	if w := wrapLiteral(v); w != nil && w.Kind == kind {
+		return w
+	}
+	return nil
+}
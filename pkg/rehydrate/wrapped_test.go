@@ -0,0 +1,89 @@
+//go:build !rehydrate_min
+
+package rehydrate_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/necodeus/rehydrate_go/pkg/rehydrate"
+)
+
+func TestBoxedPrimitives(t *testing.T) {
+	// Fixtures as written by devalue's stringify, plus the explicit form.
+	payload := `[{"n":1,"s":2,"b":3,"explicit":4,"big":5},["Object",5],["Object","x"],["Object",true],["Object","Number",-1.5],["Object","BigInt","123"]]`
+
+	v, err := rehydrate.ParseWithOptions(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := v.(map[string]interface{})
+	want := map[string]interface{}{"n": 5.0, "s": "x", "b": true, "explicit": -1.5, "big": big.NewInt(123)}
+	if !reflect.DeepEqual(root, want) {
+		t.Errorf("got %#v, want %#v", root, want)
+	}
+
+	v, err = rehydrate.ParseWithOptions(payload, rehydrate.WithWrappedPrimitives())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = v.(map[string]interface{})
+	wrapped := map[string]interface{}{
+		"n":        &rehydrate.Wrapped{Kind: "Number", Value: 5.0},
+		"s":        &rehydrate.Wrapped{Kind: "String", Value: "x"},
+		"b":        &rehydrate.Wrapped{Kind: "Boolean", Value: true},
+		"explicit": &rehydrate.Wrapped{Kind: "Number", Value: -1.5},
+		"big":      &rehydrate.Wrapped{Kind: "BigInt", Value: big.NewInt(123)},
+	}
+	if !reflect.DeepEqual(root, wrapped) {
+		t.Errorf("got %#v, want %#v", root, wrapped)
+	}
+
+	out, err := rehydrate.RehydrateWith(payload, nil, rehydrate.WithWrappedPrimitives(), rehydrate.WithIndent("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `{"b":true,"big":123,"explicit":-1.5,"n":5,"s":"x"}` {
+		t.Errorf("unexpected output %s", out)
+	}
+
+	var buf bytes.Buffer
+	if err := rehydrate.Dump(root["n"], &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "Number(5)\n" {
+		t.Errorf("unexpected dump %q", buf.String())
+	}
+
+	annotated, err := rehydrate.MarshalAnnotated(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := rehydrate.UnmarshalAnnotated(annotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, root) {
+		t.Errorf("annotated round trip: got %#v", back)
+	}
+}
+
+func TestBoxedPrimitiveErrors(t *testing.T) {
+	for _, payload := range []string{
+		`[["Object"]]`,
+		`[["Object",{}]]`,
+		`[["Object",null]]`,
+		`[["Object","Number","5"]]`,
+		`[["Object","BigInt","1.5"]]`,
+		`[["Object","Symbol","x"]]`,
+	} {
+		_, err := rehydrate.ParseWithOptions(payload)
+		var typeErr *rehydrate.TypeError
+		if !errors.As(err, &typeErr) || !errors.Is(err, rehydrate.ErrInvalidInput) {
+			t.Errorf("%s: expected a TypeError wrapping ErrInvalidInput, got %v", payload, err)
+		}
+	}
+}